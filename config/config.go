@@ -10,6 +10,13 @@ type Config struct {
 	Server   ServerConfig   `mapstructure:"server"`
 	Database DatabaseConfig `mapstructure:"database"`
 	JWT      JWTConfig      `mapstructure:"jwt"`
+	Storage  StorageConfig  `mapstructure:"storage"`
+	Alerts   AlertsConfig   `mapstructure:"alerts"`
+	Mail     MailConfig     `mapstructure:"mail"`
+	Search   SearchConfig   `mapstructure:"search"`
+	Trash    TrashConfig    `mapstructure:"trash"`
+	Audit    AuditConfig    `mapstructure:"audit"`
+	Auth     AuthConfig     `mapstructure:"auth"`
 }
 
 type ServerConfig struct {
@@ -22,6 +29,43 @@ type JWTConfig struct {
 	ExpireTime int    `mapstructure:"expire_time"` // 单位：小时
 }
 
+type StorageConfig struct {
+	Path          string `mapstructure:"path"`            // 本地文件存储目录
+	BaseURL       string `mapstructure:"base_url"`        // 文件访问 URL 前缀
+	MaxUploadSize int64  `mapstructure:"max_upload_size"` // 单位：字节
+}
+
+type AlertsConfig struct {
+	Enabled  bool `mapstructure:"enabled"`  // 是否启动低库存告警评估任务
+	Interval int  `mapstructure:"interval"` // 评估间隔，单位：秒
+}
+
+type MailConfig struct {
+	Host     string `mapstructure:"host"` // 为空时不发送邮件
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+type SearchConfig struct {
+	Backend string `mapstructure:"backend"` // mysql 使用 FULLTEXT 索引，memory 在进程内检索；留空时按数据库类型选择
+}
+
+type TrashConfig struct {
+	RetentionDays int `mapstructure:"retention_days"` // 软删除记录保留天数，到期后永久删除；0 表示永久保留
+	Interval      int `mapstructure:"interval"`       // 清理间隔，单位：秒
+}
+
+type AuditConfig struct {
+	RetentionDays int `mapstructure:"retention_days"` // 审计日志保留天数，到期后删除；0 表示永久保留
+	Interval      int `mapstructure:"interval"`       // 清理间隔，单位：秒
+}
+
+type AuthConfig struct {
+	AdminUsers []string `mapstructure:"admin_users"` // 平台管理员用户 ID，可访问所有租户及不属于任何租户的回收站记录与审计日志
+}
+
 type DatabaseConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
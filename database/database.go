@@ -32,6 +32,10 @@ func InitDB() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	if err := RegisterVersioning(DB); err != nil {
+		log.Fatal("Failed to register versioning callback:", err)
+	}
+
 	log.Println("Database connected successfully")
 }
 
@@ -0,0 +1,98 @@
+// Package dbtest opens throwaway databases for tests of code that reaches
+// the database through database.GetDB
+package dbtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"concierge-be/database"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// MySQLEnv names the variable holding the DSN of a scratch MySQL database
+// for OpenMySQL. Its tables are dropped and recreated by every test.
+const MySQLEnv = "CONCIERGE_TEST_MYSQL_DSN"
+
+// Open creates a database in the test's temporary directory, migrates
+// models into it and installs it as database.DB until the test ends.
+// Connections are limited to one, so concurrent callers take turns and
+// nothing a test does depends on row locks; use OpenMySQL for that.
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := database.RegisterVersioning(db); err != nil {
+		t.Fatalf("register versioning: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		sqlDB.Close()
+	})
+	return db
+}
+
+// OpenMySQL migrates models into the scratch database named by MySQLEnv and
+// installs it as database.DB until the test ends. Unlike Open it keeps a
+// pool of connections, so concurrent callers only wait on the locks they
+// take. The test is skipped when MySQLEnv is not set.
+func OpenMySQL(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv(MySQLEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", MySQLEnv)
+	}
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+
+	if err := database.RegisterVersioning(db); err != nil {
+		t.Fatalf("register versioning: %v", err)
+	}
+	if err := db.Migrator().DropTable(models...); err != nil {
+		t.Fatalf("clear test database: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Migrator().DropTable(models...)
+		sqlDB.Close()
+	})
+	return db
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for a cursor that cannot be decoded or was
+// issued for a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a keyset-paginated listing: the sort it was
+// issued for and the sort value and ID of the last row returned. It is
+// handed to clients as an opaque string.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses a cursor string, checking it belongs to the given sort
+func DecodeCursor(value, sort string, desc bool) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sort || cursor.Desc != desc {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// SeekAfter narrows db to the rows that follow (value, id) when ordered by
+// column then idColumn, ascending or descending, and applies that order
+func SeekAfter(db *gorm.DB, column, idColumn string, desc bool, value interface{}, id string) *gorm.DB {
+	op, dir := ">", "ASC"
+	if desc {
+		op, dir = "<", "DESC"
+	}
+	return db.Where("("+column+" "+op+" ? OR ("+column+" = ? AND "+idColumn+" "+op+" ?))", value, value, id).
+		Order(column + " " + dir + ", " + idColumn + " " + dir)
+}
+
+// OrderBy applies the keyset order without a starting position
+func OrderBy(db *gorm.DB, column, idColumn string, desc bool) *gorm.DB {
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	return db.Order(column + " " + dir + ", " + idColumn + " " + dir)
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVersionConflict is returned when a row was changed by someone else since it was read
+var ErrVersionConflict = errors.New("version conflict")
+
+// versionField is the struct field used for optimistic concurrency control
+const versionField = "Version"
+
+// RegisterVersioning installs a create callback that starts every versioned
+// model at version 1
+func RegisterVersioning(db *gorm.DB) error {
+	return db.Callback().Create().Before("gorm:create").Register("versioning:init", initVersion)
+}
+
+func initVersion(db *gorm.DB) {
+	if db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(versionField)
+	if field == nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	setInitial := func(rv reflect.Value) {
+		if _, zero := field.ValueOf(ctx, rv); zero {
+			_ = field.Set(ctx, rv, int64(1))
+		}
+	}
+
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			elem := reflect.Indirect(rv.Index(i))
+			if elem.Kind() == reflect.Struct {
+				setInitial(elem)
+			}
+		}
+	case reflect.Struct:
+		setInitial(rv)
+	}
+}
+
+// UpdateVersioned saves every column of model, but only if the stored row is
+// still at the version held in *version. On success *version is advanced;
+// if the row has moved on ErrVersionConflict is returned and *version is left
+// unchanged. Columns listed in omit are left untouched.
+func UpdateVersioned(db *gorm.DB, model interface{}, version *int64, omit ...string) error {
+	expected := *version
+	*version = expected + 1
+
+	result := db.Model(model).
+		Where("version = ?", expected).
+		Select("*").
+		Omit(append([]string{clause.Associations, "created_at"}, omit...)...).
+		Updates(model)
+	if result.Error != nil {
+		*version = expected
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		*version = expected
+		return ErrVersionConflict
+	}
+	return nil
+}
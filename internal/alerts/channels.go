@@ -0,0 +1,142 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"concierge-be/config"
+
+	"github.com/google/uuid"
+)
+
+// Channel delivers alert messages to one kind of destination. Enabled
+// reports whether the tenant's settings give the channel somewhere to send.
+type Channel interface {
+	Name() string
+	Enabled(settings *NotificationSettings) bool
+	Send(ctx context.Context, settings *NotificationSettings, msg *Message) error
+}
+
+// EmailChannel sends alerts to the tenant's email recipients over SMTP
+type EmailChannel struct {
+	cfg config.MailConfig
+}
+
+func NewEmailChannel() *EmailChannel {
+	return &EmailChannel{cfg: config.AppConfig.Mail}
+}
+
+func (ch *EmailChannel) Name() string { return "email" }
+
+// Enabled requires both an SMTP server and at least one recipient
+func (ch *EmailChannel) Enabled(settings *NotificationSettings) bool {
+	return ch.cfg.Host != "" && len(settings.EmailRecipients) > 0
+}
+
+// Send emails msg to every recipient in one message
+func (ch *EmailChannel) Send(_ context.Context, settings *NotificationSettings, msg *Message) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", ch.cfg.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(settings.EmailRecipients, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", msg.Subject)
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(msg.Body)
+	body.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if ch.cfg.Username != "" {
+		auth = smtp.PlainAuth("", ch.cfg.Username, ch.cfg.Password, ch.cfg.Host)
+	}
+	addr := fmt.Sprintf("%s:%d", ch.cfg.Host, ch.cfg.Port)
+	return smtp.SendMail(addr, auth, ch.cfg.From, settings.EmailRecipients, []byte(body.String()))
+}
+
+// WebhookChannel posts alerts as JSON to the tenant's webhook URL. When a
+// secret is set the body is signed with HMAC-SHA256 in X-Concierge-Signature.
+type WebhookChannel struct {
+	client *http.Client
+}
+
+func NewWebhookChannel() *WebhookChannel {
+	return &WebhookChannel{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (ch *WebhookChannel) Name() string { return "webhook" }
+
+func (ch *WebhookChannel) Enabled(settings *NotificationSettings) bool {
+	return settings.WebhookURL != ""
+}
+
+// Send posts msg and treats any non-2xx response as a failure
+func (ch *WebhookChannel) Send(ctx context.Context, settings *NotificationSettings, msg *Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, settings.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Concierge-Event", msg.Event)
+	if settings.WebhookSecret != "" {
+		req.Header.Set("X-Concierge-Signature", "sha256="+signPayload(settings.WebhookSecret, payload))
+	}
+
+	resp, err := ch.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signPayload returns the hex HMAC-SHA256 of payload under secret
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// InAppChannel writes alerts to the tenant's in-app notification feed
+type InAppChannel struct {
+	repo *Repository
+}
+
+func NewInAppChannel(repo *Repository) *InAppChannel {
+	return &InAppChannel{repo: repo}
+}
+
+func (ch *InAppChannel) Name() string { return "in_app" }
+
+func (ch *InAppChannel) Enabled(settings *NotificationSettings) bool {
+	return settings.InAppEnabled
+}
+
+// Send appends msg to the feed
+func (ch *InAppChannel) Send(_ context.Context, _ *NotificationSettings, msg *Message) error {
+	alertID := msg.AlertID
+	return ch.repo.CreateNotification(&Notification{
+		ID:       uuid.New().String(),
+		TenantID: msg.TenantID,
+		AlertID:  &alertID,
+		Event:    msg.Event,
+		Title:    msg.Subject,
+		Body:     msg.Body,
+	})
+}
@@ -0,0 +1,486 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"concierge-be/internal/amenities"
+
+	"github.com/google/uuid"
+)
+
+// Evaluator periodically compares stock with minimums, opens an alert when
+// an amenity falls below its minimum (in total or at a location) and
+// resolves it once restocked. It likewise opens an alert for each lot
+// within the tenant's expiry warning, resolved once the lot is used up or
+// written off. Alerts are written to the in-app feed as they happen and
+// delivered to external channels outside each tenant's quiet hours.
+type Evaluator struct {
+	repo        *Repository
+	service     *Service
+	amenityRepo *amenities.Repository
+	feed        Channel
+	channels    []Channel
+	interval    time.Duration
+	now         func() time.Time
+}
+
+// NewEvaluator creates an evaluator that runs every interval and delivers
+// through the email and webhook channels
+func NewEvaluator(interval time.Duration) *Evaluator {
+	repo := NewRepository()
+	return &Evaluator{
+		repo:        repo,
+		service:     NewService(),
+		amenityRepo: amenities.NewRepository(),
+		feed:        NewInAppChannel(repo),
+		channels:    []Channel{NewEmailChannel(), NewWebhookChannel()},
+		interval:    interval,
+		now:         time.Now,
+	}
+}
+
+// AddChannel registers another external delivery channel
+func (e *Evaluator) AddChannel(channel Channel) {
+	e.channels = append(e.channels, channel)
+}
+
+// Start runs the evaluator in the background until ctx is cancelled
+func (e *Evaluator) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := e.Evaluate(ctx); err != nil {
+				log.Printf("low-stock evaluator: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// lowStock is one amenity, in total or at one location, below its
+// minimum, or one lot of it about to expire
+type lowStock struct {
+	alertType    string
+	tenantID     string
+	amenityID    string
+	amenityName  string
+	locationID   *string
+	locationName string
+	stock        int
+	minimumStock int
+	lotID        *string
+	lotNumber    string
+	expiresAt    *time.Time
+}
+
+// alertKey identifies the amenity and location an alert is about
+func alertKey(amenityID string, locationID *string) string {
+	if locationID == nil {
+		return amenityID
+	}
+	return amenityID + ":" + *locationID
+}
+
+// lotAlertKey identifies the lot an expiry alert is about
+func lotAlertKey(lotID string) string {
+	return "lot:" + lotID
+}
+
+// openKey is the key an open alert was raised under
+func openKey(alert Alert) string {
+	if alert.Type == TypeExpiry && alert.LotID != nil {
+		return lotAlertKey(*alert.LotID)
+	}
+	return alertKey(alert.AmenityID, alert.LocationID)
+}
+
+// Evaluate runs one evaluation: open new alerts, resolve restocked ones and
+// deliver anything pending
+func (e *Evaluator) Evaluate(ctx context.Context) error {
+	low, err := e.findLowStock()
+	if err != nil {
+		return fmt.Errorf("failed to load low stock: %w", err)
+	}
+	if err := e.findExpiringLots(low); err != nil {
+		return fmt.Errorf("failed to load expiring lots: %w", err)
+	}
+
+	open, err := e.repo.GetOpenAlerts()
+	if err != nil {
+		return fmt.Errorf("failed to load open alerts: %w", err)
+	}
+	openByKey := make(map[string]Alert, len(open))
+	for _, alert := range open {
+		openByKey[openKey(alert)] = alert
+	}
+
+	now := e.now()
+	for key, item := range low {
+		if alert, ok := openByKey[key]; ok {
+			if alert.Stock != item.stock {
+				if err := e.repo.UpdateOpenStock(alert.ID, item.stock); err != nil {
+					log.Printf("low-stock evaluator: update alert %s: %v", alert.ID, err)
+				}
+			}
+			continue
+		}
+		e.trigger(ctx, key, item, now)
+	}
+
+	for key, alert := range openByKey {
+		if _, ok := low[key]; ok {
+			continue
+		}
+		e.resolve(ctx, alert, now)
+	}
+
+	return e.deliver(ctx)
+}
+
+// findLowStock collects every amenity total and location balance below its minimum
+func (e *Evaluator) findLowStock() (map[string]lowStock, error) {
+	low := make(map[string]lowStock)
+
+	totals, err := e.amenityRepo.GetAllLowStock()
+	if err != nil {
+		return nil, err
+	}
+	for _, amenity := range totals {
+		low[alertKey(amenity.ID, nil)] = lowStock{
+			alertType:    TypeLowStock,
+			tenantID:     amenity.TenantID,
+			amenityID:    amenity.ID,
+			amenityName:  amenity.ItemName,
+			stock:        amenity.Stock,
+			minimumStock: amenity.MinimumStock,
+		}
+	}
+
+	stocks, err := e.amenityRepo.GetAllLowStockAtLocations()
+	if err != nil {
+		return nil, err
+	}
+	for _, stock := range stocks {
+		locationID := stock.LocationID
+		item := lowStock{
+			alertType:    TypeLowStock,
+			tenantID:     stock.TenantID,
+			amenityID:    stock.AmenityID,
+			locationID:   &locationID,
+			stock:        stock.Quantity,
+			minimumStock: stock.MinimumStock,
+		}
+		if stock.Amenity != nil {
+			item.amenityName = stock.Amenity.ItemName
+		}
+		if stock.Location != nil {
+			item.locationName = stock.Location.Name
+		}
+		low[alertKey(stock.AmenityID, &locationID)] = item
+	}
+
+	return low, nil
+}
+
+// findExpiringLots adds every lot within its tenant's expiry warning, or
+// already expired, to low
+func (e *Evaluator) findExpiringLots(low map[string]lowStock) error {
+	now := e.now()
+	horizon := time.Date(now.Year(), now.Month(), now.Day()+maxExpiryWarningDays, 0, 0, 0, 0, now.Location())
+	lots, err := e.amenityRepo.GetLots(amenities.LotFilter{ExpiresBefore: &horizon})
+	if err != nil {
+		return err
+	}
+
+	warningDays := make(map[string]int)
+	for _, lot := range lots {
+		days, ok := warningDays[lot.TenantID]
+		if !ok {
+			settings, err := e.service.GetSettings(lot.TenantID)
+			if err != nil {
+				log.Printf("low-stock evaluator: load settings for tenant %s: %v", lot.TenantID, err)
+				continue
+			}
+			days = settings.ExpiryWarningDays
+			warningDays[lot.TenantID] = days
+		}
+		cutoff := time.Date(now.Year(), now.Month(), now.Day()+days, 0, 0, 0, 0, now.Location())
+		if lot.ExpiresAt == nil || lot.ExpiresAt.After(cutoff) {
+			continue
+		}
+
+		lotID, locationID := lot.ID, lot.LocationID
+		item := lowStock{
+			alertType:  TypeExpiry,
+			tenantID:   lot.TenantID,
+			amenityID:  lot.AmenityID,
+			locationID: &locationID,
+			stock:      lot.Quantity,
+			lotID:      &lotID,
+			lotNumber:  lot.LotNumber,
+			expiresAt:  lot.ExpiresAt,
+		}
+		if lot.Amenity != nil {
+			item.amenityName = lot.Amenity.ItemName
+		}
+		if lot.Location != nil {
+			item.locationName = lot.Location.Name
+		}
+		low[lotAlertKey(lot.ID)] = item
+	}
+	return nil
+}
+
+// trigger opens an alert for item and writes it to the in-app feed
+func (e *Evaluator) trigger(ctx context.Context, key string, item lowStock, now time.Time) {
+	alert := &Alert{
+		ID:           uuid.New().String(),
+		TenantID:     item.tenantID,
+		Type:         item.alertType,
+		AmenityID:    item.amenityID,
+		AmenityName:  item.amenityName,
+		LocationID:   item.locationID,
+		LocationName: item.locationName,
+		OpenKey:      &key,
+		Status:       StatusOpen,
+		Stock:        item.stock,
+		MinimumStock: item.minimumStock,
+		LotID:        item.lotID,
+		LotNumber:    item.lotNumber,
+		ExpiresAt:    item.expiresAt,
+		TriggeredAt:  now,
+	}
+	created, err := e.repo.OpenAlert(alert)
+	if err != nil {
+		log.Printf("low-stock evaluator: open alert for %s: %v", key, err)
+		return
+	}
+	if created {
+		e.writeFeed(ctx, alert, triggeredEvent(alert))
+	}
+}
+
+// resolve closes an alert that is no longer low and writes it to the in-app feed
+func (e *Evaluator) resolve(ctx context.Context, alert Alert, now time.Time) {
+	stock, err := e.currentStock(alert)
+	if err != nil {
+		log.Printf("low-stock evaluator: load stock for alert %s: %v", alert.ID, err)
+		return
+	}
+
+	resolved, err := e.repo.ResolveAlert(alert.ID, stock, now)
+	if err != nil {
+		log.Printf("low-stock evaluator: resolve alert %s: %v", alert.ID, err)
+		return
+	}
+	if resolved {
+		alert.Status = StatusResolved
+		alert.Stock = stock
+		alert.ResolvedAt = &now
+		e.writeFeed(ctx, &alert, resolvedEvent(&alert))
+	}
+}
+
+// currentStock reads the stock level an alert watches, or what is left of
+// its lot. A deleted amenity reads as zero.
+func (e *Evaluator) currentStock(alert Alert) (int, error) {
+	if alert.Type == TypeExpiry && alert.LotID != nil {
+		lot, err := e.amenityRepo.GetLot(*alert.LotID)
+		if err != nil {
+			return 0, nil
+		}
+		return lot.Quantity, nil
+	}
+
+	amenity, err := e.amenityRepo.GetByID(alert.AmenityID)
+	if err != nil {
+		return 0, nil
+	}
+	if alert.LocationID == nil {
+		return amenity.Stock, nil
+	}
+	for _, stock := range amenity.Locations {
+		if stock.LocationID == *alert.LocationID {
+			return stock.Quantity, nil
+		}
+	}
+	return 0, nil
+}
+
+// writeFeed records an alert event in the tenant's in-app feed
+func (e *Evaluator) writeFeed(ctx context.Context, alert *Alert, event string) {
+	settings, err := e.service.GetSettings(alert.TenantID)
+	if err != nil {
+		log.Printf("low-stock evaluator: load settings for tenant %s: %v", alert.TenantID, err)
+		return
+	}
+	if !e.feed.Enabled(settings) {
+		return
+	}
+	if err := e.feed.Send(ctx, settings, buildMessage(alert, event)); err != nil {
+		log.Printf("low-stock evaluator: %s for alert %s: %v", e.feed.Name(), alert.ID, err)
+	}
+}
+
+// deliver sends pending alert events to external channels. Events for
+// tenants in quiet hours stay pending until the quiet hours end; an alert
+// that resolves before its trigger was delivered is never delivered.
+func (e *Evaluator) deliver(ctx context.Context) error {
+	pending, err := e.repo.GetUndelivered()
+	if err != nil {
+		return fmt.Errorf("failed to load undelivered alerts: %w", err)
+	}
+
+	settingsByTenant := make(map[string]*NotificationSettings)
+	for i := range pending {
+		alert := &pending[i]
+
+		settings, ok := settingsByTenant[alert.TenantID]
+		if !ok {
+			settings, err = e.service.GetSettings(alert.TenantID)
+			if err != nil {
+				log.Printf("low-stock evaluator: load settings for tenant %s: %v", alert.TenantID, err)
+				continue
+			}
+			settingsByTenant[alert.TenantID] = settings
+		}
+
+		now := e.now()
+		if InQuietHours(settings, now) {
+			continue
+		}
+
+		event, column := triggeredEvent(alert), "notified_at"
+		if alert.Status == StatusResolved {
+			event, column = resolvedEvent(alert), "resolution_notified_at"
+		}
+
+		claimed, err := e.repo.ClaimDelivery(alert.ID, column, now)
+		if err != nil || !claimed {
+			continue
+		}
+
+		msg := buildMessage(alert, event)
+		attempted, failed := 0, 0
+		for _, channel := range e.channels {
+			if !channel.Enabled(settings) {
+				continue
+			}
+			attempted++
+			if err := channel.Send(ctx, settings, msg); err != nil {
+				failed++
+				log.Printf("low-stock evaluator: %s for alert %s: %v", channel.Name(), alert.ID, err)
+			}
+		}
+
+		// Retry next time only if nothing got through
+		if attempted > 0 && failed == attempted {
+			if err := e.repo.ReleaseDelivery(alert.ID, column); err != nil {
+				log.Printf("low-stock evaluator: release alert %s: %v", alert.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// triggeredEvent is the event an alert of its type opening sends
+func triggeredEvent(alert *Alert) string {
+	if alert.Type == TypeExpiry {
+		return EventExpiryTriggered
+	}
+	return EventTriggered
+}
+
+// resolvedEvent is the event an alert of its type resolving sends
+func resolvedEvent(alert *Alert) string {
+	if alert.Type == TypeExpiry {
+		return EventExpiryResolved
+	}
+	return EventResolved
+}
+
+// buildMessage renders an alert event for delivery
+func buildMessage(alert *Alert, event string) *Message {
+	where := ""
+	if alert.LocationID != nil {
+		where = " at " + alert.LocationName
+	}
+	name := singleLine(alert.AmenityName)
+	where = singleLine(where)
+
+	msg := &Message{
+		Event:        event,
+		Type:         alert.Type,
+		AlertID:      alert.ID,
+		TenantID:     alert.TenantID,
+		AmenityID:    alert.AmenityID,
+		AmenityName:  alert.AmenityName,
+		LocationID:   alert.LocationID,
+		LocationName: alert.LocationName,
+		Stock:        alert.Stock,
+		MinimumStock: alert.MinimumStock,
+		LotID:        alert.LotID,
+		LotNumber:    alert.LotNumber,
+		ExpiresAt:    alert.ExpiresAt,
+		OccurredAt:   alert.TriggeredAt,
+	}
+	if alert.Type == TypeExpiry {
+		expiryMessage(msg, alert, event, name, where)
+		return msg
+	}
+
+	if event == EventResolved {
+		if alert.ResolvedAt != nil {
+			msg.OccurredAt = *alert.ResolvedAt
+		}
+		msg.Subject = fmt.Sprintf("Restocked: %s%s", name, where)
+		msg.Body = fmt.Sprintf("%s%s is back to %d, at or above the minimum of %d.", name, where, alert.Stock, alert.MinimumStock)
+		return msg
+	}
+
+	msg.Subject = fmt.Sprintf("Low stock: %s%s", name, where)
+	msg.Body = fmt.Sprintf("%s%s is down to %d, below the minimum of %d.", name, where, alert.Stock, alert.MinimumStock)
+	return msg
+}
+
+// expiryMessage words the subject and body of an expiry alert event
+func expiryMessage(msg *Message, alert *Alert, event, name, where string) {
+	lot := "A lot"
+	if alert.LotNumber != "" {
+		lot = "Lot " + singleLine(alert.LotNumber)
+	}
+
+	if event == EventExpiryResolved {
+		if alert.ResolvedAt != nil {
+			msg.OccurredAt = *alert.ResolvedAt
+		}
+		msg.Subject = fmt.Sprintf("Cleared: %s%s", name, where)
+		msg.Body = fmt.Sprintf("%s of %s%s is used up or written off.", lot, name, where)
+		return
+	}
+
+	date, verb := "", "expires"
+	if alert.ExpiresAt != nil {
+		date = alert.ExpiresAt.Format("2006-01-02")
+		if alert.ExpiresAt.Before(alert.TriggeredAt.Truncate(24 * time.Hour)) {
+			verb = "expired"
+		}
+	}
+	msg.Subject = fmt.Sprintf("Expiring: %s%s", name, where)
+	msg.Body = fmt.Sprintf("%s of %s%s %s on %s with %d left.", lot, name, where, verb, date, alert.Stock)
+}
+
+// singleLine strips line breaks so names cannot inject email headers
+func singleLine(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
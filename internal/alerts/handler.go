@@ -0,0 +1,125 @@
+package alerts
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
+// GetSettings handles GET /api/v1/tenants/:id/notification-settings
+func (h *Handler) GetSettings(c *gin.Context) {
+	settings, err := h.serviceFor(c).GetSettings(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, settings)
+}
+
+// UpdateSettings handles PUT /api/v1/tenants/:id/notification-settings
+func (h *Handler) UpdateSettings(c *gin.Context) {
+	var req UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	settings, err := h.serviceFor(c).UpdateSettings(c.Param("id"), &req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "failed to") {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, settings)
+}
+
+// GetAlerts handles GET /api/v1/alerts?tenantId=&status=&type=
+func (h *Handler) GetAlerts(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+	page, pageSize := pagination(c)
+
+	alerts, total, err := h.serviceFor(c).GetAlerts(tenantID, c.Query("status"), c.Query("type"), page, pageSize)
+	if err != nil {
+		if err.Error() == "invalid alert status" || err.Error() == "invalid alert type" {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithPagination(c, alerts, page, pageSize, int(total))
+}
+
+// GetNotifications handles GET /api/v1/notifications?tenantId=&unread=true
+func (h *Handler) GetNotifications(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+	page, pageSize := pagination(c)
+
+	notifications, total, err := h.serviceFor(c).GetNotifications(tenantID, c.Query("unread") == "true", page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithPagination(c, notifications, page, pageSize, int(total))
+}
+
+// MarkNotificationRead handles POST /api/v1/notifications/:id/read
+func (h *Handler) MarkNotificationRead(c *gin.Context) {
+	if err := h.serviceFor(c).MarkNotificationRead(c.Param("id")); err != nil {
+		if err.Error() == "notification not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Notification marked as read"})
+}
+
+// pagination reads page and pageSize query parameters with the usual defaults
+func pagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
@@ -0,0 +1,137 @@
+package alerts
+
+import (
+	"time"
+)
+
+// Alert statuses
+const (
+	StatusOpen     = "open"
+	StatusResolved = "resolved"
+)
+
+// Alert types
+const (
+	TypeLowStock = "low_stock"
+	TypeExpiry   = "expiry"
+)
+
+// Alert events sent to notification channels
+const (
+	EventTriggered       = "low_stock.triggered"
+	EventResolved        = "low_stock.resolved"
+	EventExpiryTriggered = "expiry.triggered"
+	EventExpiryResolved  = "expiry.resolved"
+)
+
+// DefaultExpiryWarningDays is how many days before a lot expires its alert
+// opens, for tenants that have not chosen
+const DefaultExpiryWarningDays = 30
+
+// Alert records an amenity running below its minimum, either in total or at
+// one location, or a lot of it close to or past its expiry date. At most
+// one alert per amenity and location, or per lot, is open at a time:
+// OpenKey is unique while the alert is open and cleared when it resolves,
+// so repeated evaluations never raise duplicates. An expiry alert resolves
+// once its lot is used up or written off; Stock is what is left of the lot.
+type Alert struct {
+	ID                   string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID             string     `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	Type                 string     `gorm:"type:varchar(20);not null;default:low_stock;index" json:"type"`
+	AmenityID            string     `gorm:"type:varchar(36);not null;index" json:"amenityId"`
+	AmenityName          string     `gorm:"type:varchar(100)" json:"amenityName"`
+	LocationID           *string    `gorm:"type:varchar(36)" json:"locationId"` // nil for the amenity's total
+	LocationName         string     `gorm:"type:varchar(100)" json:"locationName,omitempty"`
+	LotID                *string    `gorm:"type:varchar(36)" json:"lotId,omitempty"`
+	LotNumber            string     `gorm:"type:varchar(64)" json:"lotNumber,omitempty"`
+	ExpiresAt            *time.Time `gorm:"type:date" json:"expiresAt,omitempty"`
+	OpenKey              *string    `gorm:"type:varchar(80);uniqueIndex" json:"-"`
+	Status               string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	Stock                int        `gorm:"not null" json:"stock"`
+	MinimumStock         int        `gorm:"not null" json:"minimumStock"`
+	TriggeredAt          time.Time  `json:"triggeredAt"`
+	ResolvedAt           *time.Time `json:"resolvedAt"`
+	NotifiedAt           *time.Time `json:"notifiedAt"`
+	ResolutionNotifiedAt *time.Time `json:"resolutionNotifiedAt"`
+	CreatedAt            time.Time  `json:"createdAt"`
+	UpdatedAt            time.Time  `json:"updatedAt"`
+}
+
+func (Alert) TableName() string {
+	return "alerts"
+}
+
+// NotificationSettings controls where a tenant's alerts are delivered, and
+// how early lots about to expire are flagged.
+// Email and webhook deliveries are held during quiet hours; the in-app feed
+// is always written.
+type NotificationSettings struct {
+	TenantID          string    `gorm:"type:varchar(36);primaryKey" json:"tenantId"`
+	EmailRecipients   []string  `gorm:"type:text;serializer:json" json:"emailRecipients"`
+	WebhookURL        string    `gorm:"type:varchar(500)" json:"webhookUrl"`
+	WebhookSecret     string    `gorm:"type:varchar(255)" json:"-"`
+	InAppEnabled      bool      `gorm:"not null" json:"inAppEnabled"`
+	QuietHoursStart   string    `gorm:"type:varchar(5)" json:"quietHoursStart"`       // HH:MM, empty for none
+	QuietHoursEnd     string    `gorm:"type:varchar(5)" json:"quietHoursEnd"`         // HH:MM
+	Timezone          string    `gorm:"type:varchar(64)" json:"timezone"`             // IANA name, UTC if empty
+	ExpiryWarningDays int       `gorm:"not null;default:30" json:"expiryWarningDays"` // days before a lot expires its alert opens
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+
+	// WebhookSecretSet reports whether a signing secret is configured without revealing it
+	WebhookSecretSet bool `gorm:"-" json:"webhookSecretSet"`
+}
+
+func (NotificationSettings) TableName() string {
+	return "notification_settings"
+}
+
+// Notification is an entry in a tenant's in-app notification feed
+type Notification struct {
+	ID        string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID  string     `gorm:"type:varchar(36);not null;index:idx_notification_tenant_created" json:"tenantId"`
+	AlertID   *string    `gorm:"type:varchar(36);index" json:"alertId"`
+	Event     string     `gorm:"type:varchar(50);not null" json:"event"`
+	Title     string     `gorm:"type:varchar(200);not null" json:"title"`
+	Body      string     `gorm:"type:text" json:"body"`
+	ReadAt    *time.Time `json:"readAt"`
+	CreatedAt time.Time  `gorm:"index:idx_notification_tenant_created" json:"createdAt"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// UpdateSettingsRequest represents the request body for updating a tenant's
+// notification settings. Omitted fields are left unchanged; an empty
+// webhookSecret clears it.
+type UpdateSettingsRequest struct {
+	EmailRecipients   []string `json:"emailRecipients"`
+	WebhookURL        *string  `json:"webhookUrl"`
+	WebhookSecret     *string  `json:"webhookSecret"`
+	InAppEnabled      *bool    `json:"inAppEnabled"`
+	QuietHoursStart   *string  `json:"quietHoursStart"`
+	QuietHoursEnd     *string  `json:"quietHoursEnd"`
+	Timezone          *string  `json:"timezone"`
+	ExpiryWarningDays *int     `json:"expiryWarningDays"`
+}
+
+// Message is what a channel delivers for an alert event
+type Message struct {
+	Event        string     `json:"event"`
+	Type         string     `json:"type"`
+	AlertID      string     `json:"alertId"`
+	TenantID     string     `json:"tenantId"`
+	AmenityID    string     `json:"amenityId"`
+	AmenityName  string     `json:"amenityName"`
+	LocationID   *string    `json:"locationId"`
+	LocationName string     `json:"locationName,omitempty"`
+	Stock        int        `json:"stock"`
+	MinimumStock int        `json:"minimumStock"`
+	LotID        *string    `json:"lotId,omitempty"`
+	LotNumber    string     `json:"lotNumber,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	OccurredAt   time.Time  `json:"occurredAt"`
+	Subject      string     `json:"subject"`
+	Body         string     `json:"body"`
+}
@@ -0,0 +1,169 @@
+package alerts
+
+import (
+	"errors"
+	"time"
+
+	"concierge-be/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		db: database.GetDB(),
+	}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// OpenAlert creates an open alert unless one with the same OpenKey is
+// already open. It reports whether a new alert was created.
+func (r *Repository) OpenAlert(alert *Alert) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(alert)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetOpenAlerts retrieves every open alert
+func (r *Repository) GetOpenAlerts() ([]Alert, error) {
+	var alerts []Alert
+	err := r.db.Where("status = ?", StatusOpen).Find(&alerts).Error
+	return alerts, err
+}
+
+// UpdateOpenStock records the latest stock level seen for an open alert
+func (r *Repository) UpdateOpenStock(id string, stock int) error {
+	return r.db.Model(&Alert{}).Where("id = ? AND status = ?", id, StatusOpen).Update("stock", stock).Error
+}
+
+// ResolveAlert marks an open alert resolved and frees its OpenKey. It
+// reports false if another evaluator resolved it first.
+func (r *Repository) ResolveAlert(id string, stock int, at time.Time) (bool, error) {
+	result := r.db.Model(&Alert{}).
+		Where("id = ? AND status = ?", id, StatusOpen).
+		Updates(map[string]interface{}{
+			"status":      StatusResolved,
+			"open_key":    nil,
+			"stock":       stock,
+			"resolved_at": at,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetUndelivered retrieves alerts whose triggered or resolved event has not
+// been delivered to external channels yet. A resolution is only delivered
+// if the trigger was.
+func (r *Repository) GetUndelivered() ([]Alert, error) {
+	var alerts []Alert
+	err := r.db.
+		Where("(status = ? AND notified_at IS NULL) OR (status = ? AND notified_at IS NOT NULL AND resolution_notified_at IS NULL)",
+			StatusOpen, StatusResolved).
+		Order("triggered_at ASC").
+		Find(&alerts).Error
+	return alerts, err
+}
+
+// ClaimDelivery stamps column (notified_at or resolution_notified_at) if it
+// is still empty, so only one evaluator delivers each event
+func (r *Repository) ClaimDelivery(id, column string, at time.Time) (bool, error) {
+	result := r.db.Model(&Alert{}).Where("id = ?", id).Where(column+" IS NULL").Update(column, at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseDelivery clears a claimed delivery so it is retried
+func (r *Repository) ReleaseDelivery(id, column string) error {
+	return r.db.Model(&Alert{}).Where("id = ?", id).Update(column, nil).Error
+}
+
+// GetAlerts retrieves a tenant's alerts, newest first
+func (r *Repository) GetAlerts(tenantID, status, alertType string, page, pageSize int) ([]Alert, int64, error) {
+	var alerts []Alert
+	var total int64
+
+	db := r.db.Model(&Alert{}).Where("tenant_id = ?", tenantID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if alertType != "" {
+		db = db.Where("type = ?", alertType)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("triggered_at DESC").Offset(offset).Limit(pageSize).Find(&alerts).Error
+	return alerts, total, err
+}
+
+// GetSettings retrieves a tenant's notification settings
+func (r *Repository) GetSettings(tenantID string) (*NotificationSettings, error) {
+	var settings NotificationSettings
+	err := r.db.Where("tenant_id = ?", tenantID).First(&settings).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("notification settings not found")
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SaveSettings creates or updates a tenant's notification settings
+func (r *Repository) SaveSettings(settings *NotificationSettings) error {
+	return r.db.Save(settings).Error
+}
+
+// CreateNotification appends an entry to a tenant's in-app feed
+func (r *Repository) CreateNotification(notification *Notification) error {
+	return r.db.Create(notification).Error
+}
+
+// GetNotifications retrieves a tenant's in-app feed, newest first
+func (r *Repository) GetNotifications(tenantID string, unreadOnly bool, page, pageSize int) ([]Notification, int64, error) {
+	var notifications []Notification
+	var total int64
+
+	db := r.db.Model(&Notification{}).Where("tenant_id = ?", tenantID)
+	if unreadOnly {
+		db = db.Where("read_at IS NULL")
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error
+	return notifications, total, err
+}
+
+// MarkNotificationRead marks a feed entry as read
+func (r *Repository) MarkNotificationRead(id string, at time.Time) error {
+	result := r.db.Model(&Notification{}).Where("id = ? AND read_at IS NULL", id).Update("read_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := r.db.Model(&Notification{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("notification not found")
+		}
+	}
+	return nil
+}
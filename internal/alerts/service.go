@@ -0,0 +1,190 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"time"
+)
+
+// maxExpiryWarningDays is the earliest a lot's expiry can be flagged
+const maxExpiryWarningDays = 365
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService() *Service {
+	return &Service{
+		repo: NewRepository(),
+	}
+}
+
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	copied := *s
+	copied.repo = s.repo.WithTx(s.repo.db.WithContext(ctx))
+	return &copied
+}
+
+// defaultSettings are used for tenants that have not saved any: the in-app
+// feed only, with no quiet hours
+func defaultSettings(tenantID string) *NotificationSettings {
+	return &NotificationSettings{
+		TenantID:          tenantID,
+		EmailRecipients:   []string{},
+		InAppEnabled:      true,
+		ExpiryWarningDays: DefaultExpiryWarningDays,
+	}
+}
+
+// GetSettings retrieves a tenant's notification settings, or the defaults
+// if it has none
+func (s *Service) GetSettings(tenantID string) (*NotificationSettings, error) {
+	settings, err := s.repo.GetSettings(tenantID)
+	if err != nil {
+		if err.Error() == "notification settings not found" {
+			settings = defaultSettings(tenantID)
+		} else {
+			return nil, err
+		}
+	}
+	if settings.EmailRecipients == nil {
+		settings.EmailRecipients = []string{}
+	}
+	settings.WebhookSecretSet = settings.WebhookSecret != ""
+	return settings, nil
+}
+
+// UpdateSettings validates and saves a tenant's notification settings
+func (s *Service) UpdateSettings(tenantID string, req *UpdateSettingsRequest) (*NotificationSettings, error) {
+	settings, err := s.GetSettings(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.EmailRecipients != nil {
+		for _, recipient := range req.EmailRecipients {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return nil, fmt.Errorf("invalid email recipient: %s", recipient)
+			}
+		}
+		settings.EmailRecipients = req.EmailRecipients
+	}
+	if req.WebhookURL != nil {
+		if *req.WebhookURL != "" {
+			u, err := url.Parse(*req.WebhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, errors.New("invalid webhook URL")
+			}
+		}
+		settings.WebhookURL = *req.WebhookURL
+	}
+	if req.WebhookSecret != nil {
+		settings.WebhookSecret = *req.WebhookSecret
+	}
+	if req.InAppEnabled != nil {
+		settings.InAppEnabled = *req.InAppEnabled
+	}
+	if req.QuietHoursStart != nil {
+		settings.QuietHoursStart = *req.QuietHoursStart
+	}
+	if req.QuietHoursEnd != nil {
+		settings.QuietHoursEnd = *req.QuietHoursEnd
+	}
+	if req.Timezone != nil {
+		settings.Timezone = *req.Timezone
+	}
+	if req.ExpiryWarningDays != nil {
+		if *req.ExpiryWarningDays < 1 || *req.ExpiryWarningDays > maxExpiryWarningDays {
+			return nil, fmt.Errorf("expiry warning must be between 1 and %d days", maxExpiryWarningDays)
+		}
+		settings.ExpiryWarningDays = *req.ExpiryWarningDays
+	}
+
+	if (settings.QuietHoursStart == "") != (settings.QuietHoursEnd == "") {
+		return nil, errors.New("quiet hours need both a start and an end")
+	}
+	if settings.QuietHoursStart != "" {
+		if _, err := parseClock(settings.QuietHoursStart); err != nil {
+			return nil, errors.New("quiet hours must be HH:MM")
+		}
+		if _, err := parseClock(settings.QuietHoursEnd); err != nil {
+			return nil, errors.New("quiet hours must be HH:MM")
+		}
+	}
+	if _, err := time.LoadLocation(settings.Timezone); err != nil {
+		return nil, errors.New("invalid timezone")
+	}
+
+	if err := s.repo.SaveSettings(settings); err != nil {
+		return nil, fmt.Errorf("failed to save notification settings: %w", err)
+	}
+
+	settings.WebhookSecretSet = settings.WebhookSecret != ""
+	return settings, nil
+}
+
+// GetAlerts retrieves a tenant's alerts, optionally by status and type
+func (s *Service) GetAlerts(tenantID, status, alertType string, page, pageSize int) ([]Alert, int64, error) {
+	if status != "" && status != StatusOpen && status != StatusResolved {
+		return nil, 0, errors.New("invalid alert status")
+	}
+	if alertType != "" && alertType != TypeLowStock && alertType != TypeExpiry {
+		return nil, 0, errors.New("invalid alert type")
+	}
+	return s.repo.GetAlerts(tenantID, status, alertType, page, pageSize)
+}
+
+// GetNotifications retrieves a tenant's in-app notification feed
+func (s *Service) GetNotifications(tenantID string, unreadOnly bool, page, pageSize int) ([]Notification, int64, error) {
+	return s.repo.GetNotifications(tenantID, unreadOnly, page, pageSize)
+}
+
+// MarkNotificationRead marks a feed entry as read
+func (s *Service) MarkNotificationRead(id string) error {
+	return s.repo.MarkNotificationRead(id, time.Now())
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// InQuietHours reports whether now falls inside the tenant's quiet hours,
+// evaluated in the tenant's timezone. A window whose end is before its start
+// runs past midnight, e.g. 22:00 to 07:00.
+func InQuietHours(settings *NotificationSettings, now time.Time) bool {
+	if settings.QuietHoursStart == "" || settings.QuietHoursEnd == "" {
+		return false
+	}
+	start, err := parseClock(settings.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(settings.QuietHoursEnd)
+	if err != nil {
+		return false
+	}
+
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	if start == end {
+		return false
+	}
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
@@ -0,0 +1,109 @@
+package amenities
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Stock is valued at weighted-average cost. An amenity's StockValue is what
+// its whole stock cost, in minor units of the tenant's currency; stock added
+// with a unit cost raises it by what that stock cost, and any other stock
+// moves in or out at the average cost of the stock already held.
+
+// averageCost is the cost of one base unit, rounded to the nearest minor unit
+func (a *Amenity) averageCost() int64 {
+	if a.Stock <= 0 {
+		return 0
+	}
+	return ShareOf(a.StockValue, 1, a.Stock)
+}
+
+// ShareOf returns the part of value that quantity of total accounts for,
+// rounded half up. It is how stock held at one location, or a quantity
+// taken, is valued at average cost; quantity must not exceed total.
+func ShareOf(value int64, quantity, total int) int64 {
+	share, _ := scale(value, quantity, total)
+	return share
+}
+
+// scale returns value * quantity / total rounded half up, refusing results
+// that do not fit in a stock value
+func scale(value int64, quantity, total int) (int64, error) {
+	if total <= 0 || quantity <= 0 || value <= 0 {
+		return 0, nil
+	}
+	result := new(big.Int).Mul(big.NewInt(value), big.NewInt(int64(quantity)))
+	result.Mul(result, big.NewInt(2))
+	result.Add(result, big.NewInt(int64(total)))
+	result.Quo(result, big.NewInt(2*int64(total)))
+	if !result.IsInt64() {
+		return 0, errors.New("stock value is too large")
+	}
+	return result.Int64(), nil
+}
+
+// costOf multiplies a quantity by a unit cost, refusing totals that do not
+// fit in a stock value
+func costOf(quantity int, unitCost int64) (int64, error) {
+	return scale(unitCost, quantity, 1)
+}
+
+// valueMovement works out how much the movement changes the amenity's
+// stock value. Transfers leave it alone, since stock is valued the same at
+// every location.
+func valueMovement(amenity *Amenity, movement *StockMovement) (int64, error) {
+	switch {
+	case movement.TransferID != nil:
+		return 0, nil
+	case movement.Delta > 0 && movement.value != nil:
+		return *movement.value, nil
+	case movement.Delta > 0 && movement.UnitCost != nil:
+		quantity := movement.Delta
+		if movement.Unit != "" {
+			quantity = movement.UnitQuantity
+		}
+		return costOf(quantity, *movement.UnitCost)
+	case movement.Delta > 0:
+		return scale(amenity.StockValue, movement.Delta, amenity.Stock)
+	case amenity.Stock+movement.Delta == 0:
+		// The last of the stock takes whatever value is left, so rounding
+		// never strands value on an empty shelf
+		return -amenity.StockValue, nil
+	default:
+		return -ShareOf(amenity.StockValue, -movement.Delta, amenity.Stock), nil
+	}
+}
+
+// checkUnitCost validates the unit cost given for a stock change
+func checkUnitCost(unitCost *int64, adding bool) error {
+	if unitCost == nil {
+		return nil
+	}
+	if !adding {
+		return errors.New("unit cost only applies to stock being added")
+	}
+	if *unitCost < 0 {
+		return errors.New("unit cost cannot be negative")
+	}
+	return nil
+}
+
+// GetAverageCosts retrieves the average cost of one base unit of each
+// amenity that has stock with a value. Amenities without one are left out.
+func (r *Repository) GetAverageCosts(amenityIDs []string) (map[string]int64, error) {
+	costs := make(map[string]int64, len(amenityIDs))
+	if len(amenityIDs) == 0 {
+		return costs, nil
+	}
+	var list []Amenity
+	err := r.db.Select("id", "stock", "stock_value").
+		Where("id IN ? AND stock > 0 AND stock_value > 0", amenityIDs).
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, amenity := range list {
+		costs[amenity.ID] = amenity.AverageCost
+	}
+	return costs, nil
+}
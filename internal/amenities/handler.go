@@ -1,11 +1,18 @@
 package amenities
 
 import (
+	"concierge-be/database"
+	"concierge-be/internal/barcodes"
+	"concierge-be/internal/quotas"
 	"concierge-be/utils"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type Handler struct {
@@ -18,6 +25,11 @@ func NewHandler() *Handler {
 	}
 }
 
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
 // CreateAmenity handles POST /api/v1/amenities
 func (h *Handler) CreateAmenity(c *gin.Context) {
 	var req CreateAmenityRequest
@@ -26,12 +38,28 @@ func (h *Handler) CreateAmenity(c *gin.Context) {
 		return
 	}
 
-	amenity, err := h.service.CreateAmenity(&req)
+	amenity, err := h.serviceFor(c).CreateAmenity(&req, utils.ActorID(c))
 	if err != nil {
+		if errors.Is(err, quotas.ErrQuotaExceeded) {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+		if err.Error() == "tenant not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
 		if err.Error() == "item name already exists for this tenant" {
 			utils.ErrorResponse(c, http.StatusConflict, err.Error())
 			return
 		}
+		if err.Error() == "stock quantity cannot be negative" || err.Error() == "location not found" || isCostError(err) || isKitError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if status, ok := identifierErrorStatus(err); ok {
+			utils.ErrorResponse(c, status, err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -47,68 +75,350 @@ func (h *Handler) CreateAmenity(c *gin.Context) {
 func (h *Handler) GetAmenity(c *gin.Context) {
 	id := c.Param("id")
 
-	amenity, err := h.service.GetAmenityByID(id)
+	amenity, err := h.serviceFor(c).GetAmenityByID(id)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Amenity not found")
 		return
 	}
 
+	utils.SetETag(c, amenity.Version)
 	utils.SuccessResponse(c, amenity)
 }
 
-// GetAllAmenities handles GET /api/v1/amenities
-// Supports filtering by tenantId, categoryId, and lowStock query parameters
-func (h *Handler) GetAllAmenities(c *gin.Context) {
+// identifierErrorStatus maps an invalid or already used SKU or barcode,
+// or an invalid unit of measure, to its status
+func identifierErrorStatus(err error) (int, bool) {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid sku"), strings.HasPrefix(err.Error(), "invalid barcode"), isUnitError(err):
+		return http.StatusBadRequest, true
+	case strings.HasSuffix(err.Error(), "is already used by another amenity"):
+		return http.StatusConflict, true
+	}
+	return 0, false
+}
+
+// isUnitError reports whether err is about a unit of measure or a quantity
+// that cannot be converted
+func isUnitError(err error) bool {
+	return strings.HasPrefix(err.Error(), "invalid unit") || strings.HasPrefix(err.Error(), "unknown unit") ||
+		strings.Contains(err.Error(), "is not a whole number of") || err.Error() == "quantity is too large"
+}
+
+// isKitError reports whether err is about what a kit is made of or an
+// attempt to stock a kit directly
+func isKitError(err error) bool {
+	return strings.HasPrefix(err.Error(), "invalid kit") || err.Error() == "kits hold no stock of their own" ||
+		err.Error() == "amenity is not a kit"
+}
+
+// isCostError reports whether err is about the cost of stock being added
+func isCostError(err error) bool {
+	return strings.HasPrefix(err.Error(), "unit cost") || err.Error() == "stock value is too large"
+}
+
+// ConvertQuantity handles GET /api/v1/amenities/:id/convert?quantity=&from=&to=
+// Converts a quantity between two of the amenity's units (the base unit
+// when omitted). Returns 400 rather than rounding when the result is not whole.
+func (h *Handler) ConvertQuantity(c *gin.Context) {
+	quantity, err := strconv.Atoi(c.Query("quantity"))
+	if err != nil || quantity < 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "quantity must be a non-negative integer")
+		return
+	}
+
+	result, err := h.serviceFor(c).ConvertQuantity(c.Param("id"), quantity, c.Query("from"), c.Query("to"))
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "amenity not found") {
+			utils.ErrorResponse(c, http.StatusNotFound, "Amenity not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, result)
+}
+
+// GetAmenityByCode handles GET /api/v1/amenities/by-barcode/*code?tenantId=
+// Looks an amenity up by any of its barcodes or its SKU, as scanned. The
+// code is a wildcard so Code 128 values may contain slashes.
+func (h *Handler) GetAmenityByCode(c *gin.Context) {
 	tenantID := c.Query("tenantId")
-	categoryID := c.Query("categoryId")
-	lowStock := c.Query("lowStock")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
 
-	var amenities []Amenity
-	var err error
+	code := strings.TrimPrefix(c.Param("code"), "/")
+	if code == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	amenity, err := h.serviceFor(c).GetAmenityByCode(tenantID, code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "No amenity has this barcode or SKU")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SetETag(c, amenity.Version)
+	utils.SuccessResponse(c, amenity)
+}
+
+// ApplyScans handles POST /api/v1/amenities/scans
+// Applies a batch of scans as stock movements. Each event succeeds or fails
+// on its own; the response reports the outcome of every one.
+func (h *Handler) ApplyScans(c *gin.Context) {
+	var req ScanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.serviceFor(c).ApplyScans(&req, utils.ActorID(c))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-	// Priority: lowStock > categoryId > tenantId > all
-	if lowStock == "true" && tenantID != "" {
-		amenities, err = h.service.GetLowStockAmenities(tenantID)
-	} else if categoryID != "" {
-		amenities, err = h.service.GetAmenitiesByCategoryID(categoryID)
-	} else if tenantID != "" {
-		amenities, err = h.service.GetAmenitiesByTenantID(tenantID)
+	utils.SuccessResponse(c, result)
+}
+
+// GetLabel handles GET /api/v1/amenities/:id/label?format=png|pdf&code=
+// Renders the amenity's barcode as a PNG image, or as a printable label
+// with its name and SKU as a PDF. code picks one of its barcodes or its SKU.
+func (h *Handler) GetLabel(c *gin.Context) {
+	format := c.DefaultQuery("format", "pdf")
+	if format != "png" && format != "pdf" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "format must be png or pdf")
+		return
+	}
+
+	amenity, err := h.serviceFor(c).GetAmenityByID(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Amenity not found")
+		return
+	}
+	label, err := h.service.LabelFor(amenity, c.Query("code"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var body []byte
+	contentType := "application/pdf"
+	if format == "png" {
+		body, err = barcodes.RenderPNG(label.Symbology, label.Code)
+		contentType = "image/png"
 	} else {
-		amenities, err = h.service.GetAllAmenities()
+		body, err = barcodes.RenderPDF([]barcodes.Label{*label}, barcodes.LayoutSingle)
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=label-%s.%s", amenity.ID, format))
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// PrintLabels handles POST /api/v1/amenities/labels
+// Renders the labels of several amenities on A4 sheets of 3x8 labels
+func (h *Handler) PrintLabels(c *gin.Context) {
+	var req LabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	labels, err := h.serviceFor(c).Labels(&req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "amenity not found") {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
+	body, err := barcodes.RenderPDF(labels, barcodes.LayoutSheet)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.SuccessResponse(c, amenities)
+	c.Header("Content-Disposition", "attachment; filename=labels.pdf")
+	c.Data(http.StatusOK, "application/pdf", body)
+}
+
+// GetAllAmenities handles GET /api/v1/amenities?tenantId=
+// Supports the filters of ParseListFilter, sort=itemName|stock|updatedAt with
+// order=asc|desc, and page/pageSize pagination. Passing cursor (empty for the
+// first page) switches to cursor pagination, which stays stable while rows change.
+func (h *Handler) GetAllAmenities(c *gin.Context) {
+	filter, err := ParseListFilter(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sort := ListSort{Field: c.DefaultQuery("sort", SortItemName)}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		sort.Desc = true
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "order must be asc or desc")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		amenities, total, next, err := h.serviceFor(c).ListAmenitiesAfter(filter, sort, cursor, pageSize)
+		if err != nil {
+			listErrorResponse(c, err)
+			return
+		}
+		utils.SuccessResponseWithCursor(c, amenities, pageSize, int(total), next)
+		return
+	}
+
+	amenities, total, err := h.serviceFor(c).ListAmenities(filter, sort, page, pageSize)
+	if err != nil {
+		listErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponseWithPagination(c, amenities, page, pageSize, int(total))
+}
+
+// ParseListFilter reads the amenity listing filters: tenantId (required),
+// categoryId, includeSubcategories, kind, available, lowStock, minStock,
+// maxStock and updatedSince (RFC3339 or YYYY-MM-DD)
+func ParseListFilter(c *gin.Context) (ListFilter, error) {
+	filter := ListFilter{
+		TenantID:             c.Query("tenantId"),
+		CategoryID:           c.Query("categoryId"),
+		IncludeSubcategories: c.Query("includeSubcategories") == "true",
+		LowStock:             c.Query("lowStock") == "true",
+		Kind:                 c.Query("kind"),
+	}
+	if filter.TenantID == "" {
+		return filter, errors.New("tenantId query parameter is required")
+	}
+	if filter.Kind != "" && filter.Kind != KindItem && filter.Kind != KindKit {
+		return filter, errors.New("kind must be item or kit")
+	}
+
+	if value := c.Query("available"); value != "" {
+		available, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("available must be true or false")
+		}
+		filter.Available = &available
+	}
+	var err error
+	if filter.MinStock, err = intParam(c, "minStock"); err != nil {
+		return filter, err
+	}
+	if filter.MaxStock, err = intParam(c, "maxStock"); err != nil {
+		return filter, err
+	}
+	if value := c.Query("updatedSince"); value != "" {
+		t, _, err := utils.ParseDateParam(value)
+		if err != nil {
+			return filter, errors.New("updatedSince must be RFC3339 or YYYY-MM-DD")
+		}
+		filter.UpdatedSince = &t
+	}
+	return filter, nil
+}
+
+// intParam parses an optional whole-number query parameter
+func intParam(c *gin.Context, name string) (*int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a whole number", name)
+	}
+	return &n, nil
+}
+
+// listErrorResponse maps amenity listing errors to HTTP responses
+func listErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, database.ErrInvalidCursor) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "cursor is invalid or was issued for a different sort")
+		return
+	}
+	if err.Error() == "invalid sort field" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "sort must be itemName, stock or updatedAt")
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 }
 
 // UpdateAmenity handles PUT /api/v1/amenities/:id
+// Requires If-Match with the version from the amenity's ETag
 func (h *Handler) UpdateAmenity(c *gin.Context) {
 	id := c.Param("id")
 
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	var req UpdateAmenityRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	amenity, err := h.service.UpdateAmenity(id, &req)
+	amenity, err := h.serviceFor(c).UpdateAmenity(id, &req, version, utils.ActorID(c))
 	if err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			utils.ErrorResponse(c, http.StatusPreconditionFailed, "Amenity has been modified; reload and retry")
+			return
+		}
 		if err.Error() == "item name already exists for this tenant" {
 			utils.ErrorResponse(c, http.StatusConflict, err.Error())
 			return
 		}
+		if err.Error() == "stock quantity cannot be negative" || err.Error() == "location not found" || err.Error() == "stock is split across locations; locationId is required" || isKitError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if status, ok := identifierErrorStatus(err); ok {
+			utils.ErrorResponse(c, status, err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	utils.SetETag(c, amenity.Version)
 	utils.SuccessResponse(c, amenity)
 }
 
 // UpdateStock handles PATCH /api/v1/amenities/:id/stock
+// Sets stock at ?locationId, or the amenity's total if omitted, to ?quantity
+// in ?unit (the base unit if omitted), recorded as a movement with optional
+// ?reason and ?reference. The total can only be set while all the stock is
+// at the default location.
 func (h *Handler) UpdateStock(c *gin.Context) {
 	id := c.Param("id")
 	
@@ -124,9 +434,13 @@ func (h *Handler) UpdateStock(c *gin.Context) {
 		return
 	}
 
-	amenity, err := h.service.UpdateStock(id, quantity)
+	amenity, err := h.serviceFor(c).UpdateStock(id, quantity, c.Query("unit"), c.Query("locationId"), c.Query("reason"), c.Query("reference"), utils.ActorID(c))
 	if err != nil {
-		if err.Error() == "stock quantity cannot be negative" {
+		if errors.Is(err, ErrInsufficientUnexpiredStock) {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		if err.Error() == "stock quantity cannot be negative" || err.Error() == "invalid movement reason" || err.Error() == "location not found" || err.Error() == "stock is split across locations; locationId is required" || isUnitError(err) || isKitError(err) {
 			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -137,11 +451,319 @@ func (h *Handler) UpdateStock(c *gin.Context) {
 	utils.SuccessResponse(c, amenity)
 }
 
+// IncrementStock handles POST /api/v1/amenities/:id/stock/increment
+func (h *Handler) IncrementStock(c *gin.Context) {
+	h.adjustStock(c, h.serviceFor(c).IncrementStock)
+}
+
+// DecrementStock handles POST /api/v1/amenities/:id/stock/decrement
+// Returns 409 without changing stock if there is not enough on hand
+func (h *Handler) DecrementStock(c *gin.Context) {
+	h.adjustStock(c, h.serviceFor(c).DecrementStock)
+}
+
+func (h *Handler) adjustStock(c *gin.Context, adjust func(string, *StockAdjustmentRequest, *string) (*StockAdjustmentResult, error)) {
+	var req StockAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := adjust(c.Param("id"), &req, utils.ActorID(c))
+	if err != nil {
+		stockErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, result)
+}
+
+// TransferStock handles POST /api/v1/amenities/:id/transfers
+// Returns 409 without moving anything if the source location does not hold enough
+func (h *Handler) TransferStock(c *gin.Context) {
+	var req TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.serviceFor(c).TransferStock(c.Param("id"), &req, utils.ActorID(c))
+	if err != nil {
+		stockErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, result)
+}
+
+// stockErrorResponse writes the response for a failed stock movement
+func stockErrorResponse(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "insufficient stock", err.Error() == "insufficient stock in lot", errors.Is(err, ErrInsufficientUnexpiredStock):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case err.Error() == "invalid movement reason", err.Error() == "quantity must be greater than zero",
+		err.Error() == "location not found", err.Error() == "source and destination locations must differ",
+		err.Error() == "lot not found at this location", strings.HasPrefix(err.Error(), "invalid lot"),
+		isUnitError(err), isCostError(err), isKitError(err):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case strings.HasPrefix(err.Error(), "amenity not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, "Amenity not found")
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// SetLocationMinimum handles PUT /api/v1/amenities/:id/locations/:locationId/minimum
+func (h *Handler) SetLocationMinimum(c *gin.Context) {
+	var req LocationMinimumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stock, err := h.serviceFor(c).SetLocationMinimum(c.Param("id"), c.Param("locationId"), *req.MinimumStock)
+	if err != nil {
+		switch {
+		case err.Error() == "minimum stock cannot be negative":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case err.Error() == "location not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case strings.HasPrefix(err.Error(), "amenity not found"):
+			utils.ErrorResponse(c, http.StatusNotFound, "Amenity not found")
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, stock)
+}
+
+// GetLocationStock handles GET /api/v1/locations/:id/stock
+// With lowStock=true only amenities below the location's minimum are returned
+func (h *Handler) GetLocationStock(c *gin.Context) {
+	stocks, err := h.serviceFor(c).GetLocationStock(c.Param("id"), c.Query("lowStock") == "true")
+	if err != nil {
+		if err.Error() == "location not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, stocks)
+}
+
+// GetLowStockAtLocations handles GET /api/v1/locations/low-stock?tenantId=
+// Lists every amenity below its per-location minimum across the tenant
+func (h *Handler) GetLowStockAtLocations(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+
+	stocks, err := h.serviceFor(c).GetLowStockAtLocations(tenantID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, stocks)
+}
+
+// GetMovements handles GET /api/v1/amenities/:id/movements
+// Supports from/to (RFC3339 or YYYY-MM-DD, to is inclusive for dates), reason, locationId and pagination
+func (h *Handler) GetMovements(c *gin.Context) {
+	id := c.Param("id")
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := MovementFilter{Reason: c.Query("reason"), LocationID: c.Query("locationId")}
+	if from := c.Query("from"); from != "" {
+		t, _, err := utils.ParseDateParam(from)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "from must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filter.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, dateOnly, err := utils.ParseDateParam(to)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "to must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = &t
+	}
+
+	movements, total, err := h.serviceFor(c).GetMovements(id, filter, page, pageSize)
+	if err != nil {
+		if err.Error() == "invalid movement reason" {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.HasPrefix(err.Error(), "amenity not found") {
+			utils.ErrorResponse(c, http.StatusNotFound, "Amenity not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithPagination(c, movements, page, pageSize, int(total))
+}
+
+// GetLots handles GET /api/v1/amenities/:id/lots?includeEmpty=
+// Lists the amenity's lots, first to expire first. Used up lots are left
+// out unless includeEmpty=true.
+func (h *Handler) GetLots(c *gin.Context) {
+	lots, err := h.serviceFor(c).GetLots(c.Param("id"), c.Query("includeEmpty") == "true")
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "amenity not found") {
+			utils.ErrorResponse(c, http.StatusNotFound, "Amenity not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, lots)
+}
+
+// GetExpiringLots handles GET /api/v1/amenities/lots/expiring?tenantId=&days=&locationId=
+// Reports lots that expire within days (default 30, up to 365), and those
+// already expired, first to expire first
+func (h *Handler) GetExpiringLots(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 0 || days > 365 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "days must be between 0 and 365")
+		return
+	}
+
+	lots, err := h.serviceFor(c).GetExpiringLots(tenantID, c.Query("locationId"), days)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, lots)
+}
+
+// WriteOffLot handles POST /api/v1/amenities/:id/lots/:lotId/write-off
+// Removes what is left of an expired lot with an "expired" movement
+func (h *Handler) WriteOffLot(c *gin.Context) {
+	var req WriteOffRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	result, err := h.serviceFor(c).WriteOffLot(c.Param("id"), c.Param("lotId"), &req, utils.ActorID(c))
+	if err != nil {
+		switch err.Error() {
+		case "lot not found":
+			utils.ErrorResponse(c, http.StatusNotFound, "Lot not found")
+		case "lot has not expired", "lot is already used up":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			stockErrorResponse(c, err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, result)
+}
+
+// WriteOffExpired handles POST /api/v1/amenities/lots/write-off-expired
+// Writes off every expired lot of a tenant, or of one location, all or none
+func (h *Handler) WriteOffExpired(c *gin.Context) {
+	var req WriteOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.TenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId is required")
+		return
+	}
+
+	result, err := h.serviceFor(c).WriteOffExpired(&req, utils.ActorID(c))
+	if err != nil {
+		stockErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, result)
+}
+
+// IssueKit handles POST /api/v1/amenities/:id/issue
+// Takes every component of quantity kits (1 if omitted) from one location;
+// returns 409 without taking anything if a component falls short
+func (h *Handler) IssueKit(c *gin.Context) {
+	var req KitIssueRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	result, err := h.serviceFor(c).IssueKit(c.Param("id"), &req, utils.ActorID(c))
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "insufficient stock of"):
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		case err.Error() == "kits can only be issued as consumption or damage", err.Error() == "quantity is too large":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			stockErrorResponse(c, err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, result)
+}
+
+// GetKitAvailability handles GET /api/v1/amenities/:id/availability?locationId=
+// Reports how many kits the components' stock makes up, in total or at one location
+func (h *Handler) GetKitAvailability(c *gin.Context) {
+	availability, err := h.serviceFor(c).GetKitAvailability(c.Param("id"), c.Query("locationId"))
+	if err != nil {
+		stockErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, availability)
+}
+
 // DeleteAmenity handles DELETE /api/v1/amenities/:id
 func (h *Handler) DeleteAmenity(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.service.DeleteAmenity(id); err != nil {
+	if err := h.serviceFor(c).DeleteAmenity(id); err != nil {
+		if err.Error() == "amenity is in use by kits" {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
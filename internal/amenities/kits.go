@@ -0,0 +1,360 @@
+package amenities
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Amenity kinds. A kit is a fixed set of other amenities, such as a
+// turndown set or a VIP welcome pack. It is listed like any amenity but
+// holds no stock of its own: issuing one takes each of its components.
+const (
+	KindItem = "item"
+	KindKit  = "kit"
+)
+
+// maxComponents is the most amenities a kit can be made of
+const maxComponents = 50
+
+// KitComponent is one amenity a kit is made of. Quantity is how many base
+// units of it go into each kit.
+type KitComponent struct {
+	ID        string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID  string    `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	KitID     string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_kit_component" json:"kitId"`
+	AmenityID string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_kit_component;index" json:"amenityId"`
+	Quantity  int       `gorm:"not null" json:"quantity"`
+	SortOrder int       `gorm:"not null;default:0" json:"-"` // position in the kit's list
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Relationships
+	Amenity *Amenity `gorm:"foreignKey:AmenityID;references:ID" json:"amenity,omitempty"`
+}
+
+func (KitComponent) TableName() string {
+	return "kit_components"
+}
+
+// ComponentInput is a component given for a kit: Quantity of an amenity
+// in Unit, one of its units; the base unit if empty
+type ComponentInput struct {
+	AmenityID string `json:"amenityId" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,gt=0"`
+	Unit      string `json:"unit"`
+}
+
+// KitIssueRequest represents the request body for issuing kits. Every
+// component is taken from LocationID, or the default location, as a
+// consumption (the default) or damage movement.
+type KitIssueRequest struct {
+	Quantity   int    `json:"quantity"` // kits to issue; defaults to 1
+	LocationID string `json:"locationId"`
+	Reason     string `json:"reason"`
+	Reference  string `json:"reference"`
+	Note       string `json:"note"`
+}
+
+// KitIssueResult reports issued kits and the movement that took each
+// component. Value is what the components cost.
+type KitIssueResult struct {
+	IssueID   string                  `json:"issueId"`
+	KitID     string                  `json:"kitId"`
+	Quantity  int                     `json:"quantity"`
+	Value     int64                   `json:"value"`
+	Movements []StockAdjustmentResult `json:"movements"`
+}
+
+// KitAvailability reports how many kits the stock of their components
+// makes up, in total or at one location, and which component runs out first
+type KitAvailability struct {
+	KitID      string                  `json:"kitId"`
+	LocationID string                  `json:"locationId,omitempty"`
+	Available  int                     `json:"available"`
+	Components []ComponentAvailability `json:"components"`
+}
+
+// ComponentAvailability is one component's share of a kit's availability
+type ComponentAvailability struct {
+	AmenityID string `json:"amenityId"`
+	ItemName  string `json:"itemName"`
+	Quantity  int    `json:"quantity"`  // base units per kit
+	Stock     int    `json:"stock"`     // base units held
+	Available int    `json:"available"` // kits this component alone makes up
+}
+
+// orderedComponents preloads a kit's components in the order they were given
+func orderedComponents(db *gorm.DB) *gorm.DB {
+	return db.Order("sort_order ASC")
+}
+
+// withComponents preloads a kit's components and the amenities they are,
+// which kit availability is worked out from
+func withComponents(db *gorm.DB) *gorm.DB {
+	return db.Preload("Components", orderedComponents).Preload("Components.Amenity")
+}
+
+// IsKit reports whether the amenity is a kit
+func (a *Amenity) IsKit() bool {
+	return a.Kind == KindKit
+}
+
+// kitsAvailable works out how many kits the components' stock makes up.
+// A component that is no longer there makes none.
+func (a *Amenity) kitsAvailable() int {
+	available := math.MaxInt32
+	for _, component := range a.Components {
+		if component.Amenity == nil || component.Quantity <= 0 {
+			return 0
+		}
+		if n := component.Amenity.Stock / component.Quantity; n < available {
+			available = n
+		}
+	}
+	if available == math.MaxInt32 {
+		return 0
+	}
+	return available
+}
+
+// checkComponents validates a kit's components and returns them ready to
+// store. Each must be a distinct item of the tenant, not another kit.
+func (s *Service) checkComponents(tenantID, kitID string, inputs []ComponentInput) ([]KitComponent, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("invalid kit: a kit needs at least one component")
+	}
+	if len(inputs) > maxComponents {
+		return nil, fmt.Errorf("invalid kit: a kit can have at most %d components", maxComponents)
+	}
+
+	seen := make(map[string]bool, len(inputs))
+	components := make([]KitComponent, 0, len(inputs))
+	for i, input := range inputs {
+		if input.AmenityID == kitID {
+			return nil, errors.New("invalid kit: a kit cannot contain itself")
+		}
+		if seen[input.AmenityID] {
+			return nil, fmt.Errorf("invalid kit: amenity %s is listed twice", input.AmenityID)
+		}
+		seen[input.AmenityID] = true
+
+		amenity, err := s.repo.GetByID(input.AmenityID)
+		if err != nil || amenity.TenantID != tenantID {
+			return nil, fmt.Errorf("invalid kit: amenity %s not found", input.AmenityID)
+		}
+		if amenity.IsKit() {
+			return nil, fmt.Errorf("invalid kit: %q is a kit; kits cannot contain kits", amenity.ItemName)
+		}
+		if input.Quantity <= 0 {
+			return nil, fmt.Errorf("invalid kit: quantity of %q must be greater than zero", amenity.ItemName)
+		}
+		quantity, err := amenity.ToBase(input.Quantity, input.Unit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kit: %q: %v", amenity.ItemName, err)
+		}
+
+		components = append(components, KitComponent{
+			ID:        uuid.New().String(),
+			TenantID:  tenantID,
+			KitID:     kitID,
+			AmenityID: amenity.ID,
+			Quantity:  quantity,
+			SortOrder: i,
+		})
+	}
+	return components, nil
+}
+
+// CopyComponents copies a kit's components, with new IDs, for a kit of
+// another tenant, as when copying or importing a catalog. ids maps each
+// component amenity to its copy; it returns false if one was not copied.
+func CopyComponents(tenantID, kitID string, components []KitComponent, ids map[string]string) ([]KitComponent, bool) {
+	copies := make([]KitComponent, 0, len(components))
+	for _, component := range components {
+		amenityID, ok := ids[component.AmenityID]
+		if !ok {
+			return nil, false
+		}
+		copies = append(copies, KitComponent{
+			ID:        uuid.New().String(),
+			TenantID:  tenantID,
+			KitID:     kitID,
+			AmenityID: amenityID,
+			Quantity:  component.Quantity,
+			SortOrder: component.SortOrder,
+		})
+	}
+	return copies, true
+}
+
+// kitMovements builds the movements that take quantity kits' components
+// from a location, ordered by amenity so concurrent issues lock the
+// component rows in the same order
+func kitMovements(kit *Amenity, quantity int, req *KitIssueRequest, issueID string, actorID *string) ([]*StockMovement, error) {
+	components := append([]KitComponent(nil), kit.Components...)
+	sort.Slice(components, func(i, j int) bool { return components[i].AmenityID < components[j].AmenityID })
+
+	movements := make([]*StockMovement, 0, len(components))
+	for _, component := range components {
+		if component.Quantity > math.MaxInt32/quantity {
+			return nil, errors.New("quantity is too large")
+		}
+		movement := &StockMovement{
+			AmenityID:  component.AmenityID,
+			Delta:      -component.Quantity * quantity,
+			Reason:     req.Reason,
+			Reference:  req.Reference,
+			Note:       req.Note,
+			ActorID:    actorID,
+			KitID:      &kit.ID,
+			KitIssueID: &issueID,
+		}
+		if req.LocationID != "" {
+			movement.LocationID = &req.LocationID
+		}
+		movements = append(movements, movement)
+	}
+	return movements, nil
+}
+
+// IssueKit issues quantity kits, taking each component from one location
+// as its own movement. Either every component is taken or none; the issue
+// fails naming the first component that falls short.
+func (s *Service) IssueKit(id string, req *KitIssueRequest, actorID *string) (*KitIssueResult, error) {
+	kit, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("amenity not found: %w", err)
+	}
+	if !kit.IsKit() {
+		return nil, errors.New("amenity is not a kit")
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 {
+		return nil, errors.New("quantity must be greater than zero")
+	}
+	if req.Reason == "" {
+		req.Reason = MovementConsumption
+	}
+	if req.Reason != MovementConsumption && req.Reason != MovementDamage {
+		return nil, errors.New("kits can only be issued as consumption or damage")
+	}
+
+	issueID := uuid.New().String()
+	movements, err := kitMovements(kit, req.Quantity, req, issueID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(kit.Components))
+	for _, component := range kit.Components {
+		if component.Amenity == nil {
+			return nil, errors.New("invalid kit: a component is no longer available")
+		}
+		names[component.AmenityID] = component.Amenity.ItemName
+	}
+
+	result := &KitIssueResult{IssueID: issueID, KitID: kit.ID, Quantity: req.Quantity, Movements: []StockAdjustmentResult{}}
+	err = s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		for _, movement := range movements {
+			if err := repo.ApplyMovement(movement); err != nil {
+				if err.Error() == "stock quantity cannot be negative" {
+					return fmt.Errorf("insufficient stock of %q", names[movement.AmenityID])
+				}
+				return err
+			}
+			result.Value -= movement.Value
+			result.Movements = append(result.Movements, *movementResult(movement))
+		}
+		return nil
+	})
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "insufficient stock of") {
+			return nil, err
+		}
+		return nil, stockError(err)
+	}
+	return result, nil
+}
+
+// GetKitAvailability works out how many kits can be issued from the stock
+// of their components, in total or at one location
+func (s *Service) GetKitAvailability(id, locationID string) (*KitAvailability, error) {
+	kit, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("amenity not found: %w", err)
+	}
+	if !kit.IsKit() {
+		return nil, errors.New("amenity is not a kit")
+	}
+
+	var held map[string]int
+	if locationID != "" {
+		location, err := s.locations.GetByID(locationID)
+		if err != nil || location.TenantID != kit.TenantID {
+			return nil, errors.New("location not found")
+		}
+		ids := make([]string, 0, len(kit.Components))
+		for _, component := range kit.Components {
+			ids = append(ids, component.AmenityID)
+		}
+		if held, err = s.repo.GetStockAt(ids, locationID); err != nil {
+			return nil, fmt.Errorf("failed to load location stock: %w", err)
+		}
+	}
+
+	availability := &KitAvailability{KitID: kit.ID, LocationID: locationID, Available: math.MaxInt32, Components: []ComponentAvailability{}}
+	for _, component := range kit.Components {
+		entry := ComponentAvailability{AmenityID: component.AmenityID, Quantity: component.Quantity}
+		if component.Amenity != nil {
+			entry.ItemName = component.Amenity.ItemName
+			entry.Stock = component.Amenity.Stock
+			if held != nil {
+				entry.Stock = held[component.AmenityID]
+			}
+			if component.Quantity > 0 {
+				entry.Available = entry.Stock / component.Quantity
+			}
+		}
+		if entry.Available < availability.Available {
+			availability.Available = entry.Available
+		}
+		availability.Components = append(availability.Components, entry)
+	}
+	if len(kit.Components) == 0 {
+		availability.Available = 0
+	}
+	return availability, nil
+}
+
+// IsKitComponent reports whether an amenity is a component of any of its
+// tenant's live kits
+func (r *Repository) IsKitComponent(amenityID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&KitComponent{}).
+		Joins("JOIN amenities ON amenities.id = kit_components.kit_id AND amenities.deleted_at IS NULL").
+		Where("kit_components.amenity_id = ?", amenityID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetStockAt retrieves how much of each amenity is held at a location
+func (r *Repository) GetStockAt(amenityIDs []string, locationID string) (map[string]int, error) {
+	var stocks []AmenityStock
+	err := r.db.Where("amenity_id IN ? AND location_id = ?", amenityIDs, locationID).Find(&stocks).Error
+	if err != nil {
+		return nil, err
+	}
+	quantities := make(map[string]int, len(stocks))
+	for _, stock := range stocks {
+		quantities[stock.AmenityID] = stock.Quantity
+	}
+	return quantities, nil
+}
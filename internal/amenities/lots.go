@@ -0,0 +1,335 @@
+package amenities
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"concierge-be/internal/locations"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StockLot is a batch of an amenity received at a location, with the date
+// it expires, if it does. Quantity is what is left of it. Lots cover part or
+// all of the stock at their location; stock received without lot details
+// is untracked and is taken only once every lot there is used up.
+type StockLot struct {
+	ID              string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID        string     `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	AmenityID       string     `gorm:"type:varchar(36);not null;index:idx_lot_amenity_location,priority:1" json:"amenityId"`
+	LocationID      string     `gorm:"type:varchar(36);not null;index:idx_lot_amenity_location,priority:2" json:"locationId"`
+	LotNumber       string     `gorm:"type:varchar(64)" json:"lotNumber"`
+	Quantity        int        `gorm:"not null" json:"quantity"`
+	InitialQuantity int        `gorm:"not null" json:"initialQuantity"`
+	ReceivedAt      time.Time  `gorm:"not null" json:"receivedAt"`
+	ExpiresAt       *time.Time `gorm:"type:date;index" json:"expiresAt"` // nil for lots that do not expire
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+
+	// Relationships
+	Amenity  *Amenity            `gorm:"foreignKey:AmenityID;references:ID" json:"amenity,omitempty"`
+	Location *locations.Location `gorm:"foreignKey:LocationID;references:ID" json:"location,omitempty"`
+
+	// Expired reports whether the lot is past its expiry date
+	Expired bool `gorm:"-" json:"expired"`
+}
+
+func (StockLot) TableName() string {
+	return "stock_lots"
+}
+
+// AfterFind marks lots that are past their expiry date
+func (l *StockLot) AfterFind(tx *gorm.DB) error {
+	l.Expired = l.ExpiresAt != nil && l.ExpiresAt.Before(today())
+	return nil
+}
+
+// StockMovementLot records how much of a lot a movement received (positive)
+// or took (negative). Like the ledger itself it is append-only.
+type StockMovementLot struct {
+	ID         string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID   string    `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	MovementID string    `gorm:"type:varchar(36);not null;index" json:"movementId"`
+	LotID      string    `gorm:"type:varchar(36);not null;index" json:"lotId"`
+	Quantity   int       `gorm:"not null" json:"quantity"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func (StockMovementLot) TableName() string {
+	return "stock_movement_lots"
+}
+
+// ErrInsufficientUnexpiredStock is returned when stock handed out could
+// only be taken from expired lots
+var ErrInsufficientUnexpiredStock = errors.New("insufficient unexpired stock")
+
+// LotSpec describes the lot a movement adding stock receives into, or
+// names the one lot a movement removing stock takes from
+type LotSpec struct {
+	LotID     string     // removals only
+	LotNumber string     // additions only
+	ExpiresAt *time.Time // additions only
+}
+
+// lotShare is a quantity of one lot a movement received or took
+type lotShare struct {
+	lot      StockLot
+	quantity int
+}
+
+// LotFilter narrows a lot listing to one tenant. ExpiresBefore keeps lots
+// expiring on or before it, expired ones included.
+type LotFilter struct {
+	TenantID      string
+	AmenityID     string
+	LocationID    string
+	ExpiresBefore *time.Time
+	IncludeEmpty  bool
+}
+
+// WriteOffRequest represents the request body for writing off expired lots.
+// Writing off every expired lot needs TenantID, and LocationID narrows it
+// to one location; writing off a single lot needs neither.
+type WriteOffRequest struct {
+	TenantID   string `json:"tenantId"`
+	LocationID string `json:"locationId"`
+	Reference  string `json:"reference"`
+	Note       string `json:"note"`
+}
+
+// WriteOffResult reports the expired lots written off and the movement
+// that removed each
+type WriteOffResult struct {
+	WrittenOff int                     `json:"writtenOff"`
+	Quantity   int                     `json:"quantity"`
+	Movements  []StockAdjustmentResult `json:"movements"`
+}
+
+// maxLotNumberLength is the longest lot number a lot can have
+const maxLotNumberLength = 64
+
+// NewLotSpec reads the lot details of a stock change: the lot number and
+// expiry date (YYYY-MM-DD) of stock being added, or the one lot stock being
+// removed is taken from. It returns nil when none are given.
+func NewLotSpec(adding bool, lotID, lotNumber, expiresAt string) (*LotSpec, error) {
+	lotNumber = strings.TrimSpace(lotNumber)
+	if lotID == "" && lotNumber == "" && expiresAt == "" {
+		return nil, nil
+	}
+	if !adding {
+		if lotNumber != "" || expiresAt != "" {
+			return nil, errors.New("invalid lot: lotNumber and expiresAt only apply to stock being added")
+		}
+		return &LotSpec{LotID: lotID}, nil
+	}
+
+	if lotID != "" {
+		return nil, errors.New("invalid lot: lotId only applies to stock being removed")
+	}
+	if len(lotNumber) > maxLotNumberLength {
+		return nil, fmt.Errorf("invalid lot: lotNumber must be at most %d characters", maxLotNumberLength)
+	}
+	spec := &LotSpec{LotNumber: lotNumber}
+	if expiresAt != "" {
+		t, err := time.ParseInLocation("2006-01-02", expiresAt, time.Local)
+		if err != nil {
+			return nil, errors.New("invalid lot: expiresAt must be a date (YYYY-MM-DD)")
+		}
+		spec.ExpiresAt = &t
+	}
+	return spec, nil
+}
+
+// today is the start of the current day, the boundary lots expire at
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// fefoOrder takes lots in first-expiry-first-out order: lots without an
+// expiry date go last, and lots expiring the same day in the order they came
+const fefoOrder = "expires_at IS NULL, expires_at ASC, received_at ASC, id ASC"
+
+// moveLots keeps the lots at a location in step with a movement that has
+// just changed the stock there by movement.Delta. Stock added with lot
+// details, or arriving from a transfer of lots, opens new lots; stock
+// removed is taken from the lots first-expiry-first-out, or from the one
+// lot named, and from untracked stock only when the lots run out. Stock
+// handed out, by consumption or a kit issue, passes over expired lots
+// unless one is named, and is refused if only they could cover it. Lots
+// never hold more than their location, so that is otherwise always enough.
+// The caller holds the amenity's row lock.
+func (r *Repository) moveLots(tx *gorm.DB, movement *StockMovement) error {
+	shares := movement.lots
+	movement.lots = nil
+	if movement.Delta > 0 {
+		return r.receiveLots(tx, movement, shares)
+	}
+	if movement.Delta < 0 {
+		return r.takeLots(tx, movement)
+	}
+	return nil
+}
+
+// receiveLots opens the lots an addition brings in: the one described by
+// movement.Lot, or copies of the lots the other side of a transfer took
+func (r *Repository) receiveLots(tx *gorm.DB, movement *StockMovement, transferred []lotShare) error {
+	now := time.Now()
+	if movement.Lot != nil {
+		transferred = []lotShare{{
+			lot:      StockLot{LotNumber: movement.Lot.LotNumber, ExpiresAt: movement.Lot.ExpiresAt, ReceivedAt: now},
+			quantity: movement.Delta,
+		}}
+	}
+
+	for _, share := range transferred {
+		lot := StockLot{
+			ID:              uuid.New().String(),
+			TenantID:        movement.TenantID,
+			AmenityID:       movement.AmenityID,
+			LocationID:      *movement.LocationID,
+			LotNumber:       share.lot.LotNumber,
+			Quantity:        share.quantity,
+			InitialQuantity: share.quantity,
+			ReceivedAt:      share.lot.ReceivedAt,
+			ExpiresAt:       share.lot.ExpiresAt,
+		}
+		if err := tx.Create(&lot).Error; err != nil {
+			return err
+		}
+		if err := r.recordShare(tx, movement, lot, share.quantity); err != nil {
+			return err
+		}
+		movement.lots = append(movement.lots, lotShare{lot: lot, quantity: share.quantity})
+	}
+	return nil
+}
+
+// takeLots takes a removal from the lots at the movement's location
+func (r *Repository) takeLots(tx *gorm.DB, movement *StockMovement) error {
+	named := movement.Lot != nil && movement.Lot.LotID != ""
+	query := tx.Where("amenity_id = ? AND location_id = ? AND quantity > 0", movement.AmenityID, *movement.LocationID)
+	if named {
+		query = query.Where("id = ?", movement.Lot.LotID)
+	}
+	var lots []StockLot
+	if err := query.Order(fefoOrder).Find(&lots).Error; err != nil {
+		return err
+	}
+
+	needed := -movement.Delta
+	if named {
+		if len(lots) == 0 {
+			return errors.New("lot not found at this location")
+		}
+		if lots[0].Quantity < needed {
+			return errors.New("insufficient stock in lot")
+		}
+	} else if handsOut(movement) {
+		// Expired stock is written off, not handed out: pick from the lots
+		// in date and untracked stock, and refuse if they fall short.
+		// Adjustments and damage take expired lots first like any other.
+		inDate, expired := lots[:0], 0
+		for _, lot := range lots {
+			if lot.Expired {
+				expired += lot.Quantity
+			} else {
+				inDate = append(inDate, lot)
+			}
+		}
+		if expired > *movement.LocationBalanceAfter {
+			return ErrInsufficientUnexpiredStock
+		}
+		lots = inDate
+	}
+
+	for _, lot := range lots {
+		if needed == 0 {
+			break
+		}
+		taken := lot.Quantity
+		if taken > needed {
+			taken = needed
+		}
+		needed -= taken
+
+		err := tx.Model(&StockLot{}).Where("id = ?", lot.ID).Update("quantity", lot.Quantity-taken).Error
+		if err != nil {
+			return err
+		}
+		lot.Quantity -= taken
+		if err := r.recordShare(tx, movement, lot, -taken); err != nil {
+			return err
+		}
+		movement.lots = append(movement.lots, lotShare{lot: lot, quantity: taken})
+	}
+	return nil
+}
+
+// handsOut reports whether a removal hands stock out to guests or staff
+func handsOut(movement *StockMovement) bool {
+	return movement.Reason == MovementConsumption || movement.KitIssueID != nil
+}
+
+// recordShare appends what a movement did to a lot to the lot ledger
+func (r *Repository) recordShare(tx *gorm.DB, movement *StockMovement, lot StockLot, quantity int) error {
+	return tx.Create(&StockMovementLot{
+		ID:         uuid.New().String(),
+		TenantID:   movement.TenantID,
+		MovementID: movement.ID,
+		LotID:      lot.ID,
+		Quantity:   quantity,
+	}).Error
+}
+
+// GetLot retrieves a lot by ID
+func (r *Repository) GetLot(id string) (*StockLot, error) {
+	var lot StockLot
+	if err := r.db.Preload("Location").Where("id = ?", id).First(&lot).Error; err != nil {
+		return nil, err
+	}
+	return &lot, nil
+}
+
+// FindLotsByTenantID retrieves the lots with stock left of a tenant's live
+// amenities, without their relationships
+func (r *Repository) FindLotsByTenantID(tenantID string) ([]StockLot, error) {
+	var lots []StockLot
+	err := r.db.Model(&StockLot{}).
+		Joins("JOIN amenities ON amenities.id = stock_lots.amenity_id AND amenities.deleted_at IS NULL").
+		Where("stock_lots.tenant_id = ? AND stock_lots.quantity > 0", tenantID).
+		Order("stock_lots.received_at ASC, stock_lots.id ASC").
+		Find(&lots).Error
+	return lots, err
+}
+
+// GetLots retrieves the lots matching filter, first to expire first. Lots
+// of deleted amenities are left out.
+func (r *Repository) GetLots(filter LotFilter) ([]StockLot, error) {
+	query := r.db.Model(&StockLot{}).
+		Joins("JOIN amenities ON amenities.id = stock_lots.amenity_id AND amenities.deleted_at IS NULL")
+	if filter.TenantID != "" {
+		query = query.Where("stock_lots.tenant_id = ?", filter.TenantID)
+	}
+	if filter.AmenityID != "" {
+		query = query.Where("stock_lots.amenity_id = ?", filter.AmenityID)
+	}
+	if filter.LocationID != "" {
+		query = query.Where("stock_lots.location_id = ?", filter.LocationID)
+	}
+	if filter.ExpiresBefore != nil {
+		query = query.Where("stock_lots.expires_at <= ?", *filter.ExpiresBefore)
+	}
+	if !filter.IncludeEmpty {
+		query = query.Where("stock_lots.quantity > 0")
+	}
+
+	var lots []StockLot
+	err := query.Preload("Amenity").Preload("Location").
+		Order("stock_lots.expires_at IS NULL, stock_lots.expires_at ASC, stock_lots.received_at ASC, stock_lots.id ASC").
+		Find(&lots).Error
+	return lots, err
+}
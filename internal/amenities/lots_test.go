@@ -0,0 +1,92 @@
+package amenities
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTakeLotsSkipsExpiredLots(t *testing.T) {
+	db, repo, amenity := newStockTest(t)
+
+	receive := func(quantity int, expiresAt *time.Time) string {
+		t.Helper()
+		movement := &StockMovement{AmenityID: amenity.ID, Delta: quantity, Reason: MovementRestock}
+		if expiresAt != nil {
+			movement.Lot = &LotSpec{ExpiresAt: expiresAt}
+		}
+		if err := repo.ApplyMovement(movement); err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		if expiresAt == nil {
+			return ""
+		}
+		return movement.lots[0].lot.ID
+	}
+	remove := func(quantity int, reason string) error {
+		return repo.ApplyMovement(&StockMovement{AmenityID: amenity.ID, Delta: -quantity, Reason: reason})
+	}
+	take := func(quantity int, lotID string) error {
+		movement := &StockMovement{AmenityID: amenity.ID, Delta: -quantity, Reason: MovementConsumption}
+		if lotID != "" {
+			movement.Lot = &LotSpec{LotID: lotID}
+		}
+		return repo.ApplyMovement(movement)
+	}
+	left := func(lotID string) int {
+		t.Helper()
+		lot, err := repo.GetLot(lotID)
+		if err != nil {
+			t.Fatalf("load lot: %v", err)
+		}
+		return lot.Quantity
+	}
+
+	expiredAt := today().AddDate(0, 0, -3)
+	freshAt := today().AddDate(0, 0, 10)
+	expired := receive(5, &expiredAt)
+	fresh := receive(5, &freshAt)
+	receive(3, nil) // untracked
+
+	// The expired lot would come first, but is passed over
+	if err := take(6, ""); err != nil {
+		t.Fatalf("take 6: %v", err)
+	}
+	if left(expired) != 5 || left(fresh) != 0 {
+		t.Errorf("lots = expired %d, fresh %d, want 5 and 0", left(expired), left(fresh))
+	}
+
+	// Only 2 untracked units are in date; the rest has expired
+	if err := take(3, ""); !errors.Is(err, ErrInsufficientUnexpiredStock) {
+		t.Fatalf("take 3 with 2 in date: err = %v", err)
+	}
+	var stock Amenity
+	if err := db.First(&stock, "id = ?", amenity.ID).Error; err != nil {
+		t.Fatalf("load amenity: %v", err)
+	}
+	if stock.Stock != 7 || left(expired) != 5 {
+		t.Errorf("stock = %d, expired lot %d, want the refused movement to change nothing", stock.Stock, left(expired))
+	}
+	if err := take(2, ""); err != nil {
+		t.Fatalf("take the last 2 in date: %v", err)
+	}
+
+	// Naming the expired lot takes from it
+	if err := take(2, expired); err != nil {
+		t.Fatalf("take from the named expired lot: %v", err)
+	}
+	if left(expired) != 3 {
+		t.Errorf("expired lot = %d, want 3", left(expired))
+	}
+
+	// Adjustments and damage are not handed out, so take expired stock first
+	if err := remove(1, MovementAdjustment); err != nil {
+		t.Fatalf("adjust expired stock: %v", err)
+	}
+	if err := remove(1, MovementDamage); err != nil {
+		t.Fatalf("damage expired stock: %v", err)
+	}
+	if left(expired) != 1 {
+		t.Errorf("expired lot = %d, want 1", left(expired))
+	}
+}
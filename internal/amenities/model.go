@@ -2,27 +2,53 @@ package amenities
 
 import (
 	"concierge-be/internal/amenities_categories"
+	"concierge-be/internal/locations"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-// Amenity represents an amenity item (tenant-scoped)
+// Amenity represents an amenity item (tenant-scoped). Stock is the total
+// held across all locations; it is maintained by the movement ledger
+// alongside the per-location balances and is never written directly.
+// Stock is counted in BaseUnit; Units are the larger packs it comes in.
+// StockValue is what that stock cost, in minor units of the tenant's
+// currency; like Stock it only changes through the ledger. Kits hold no
+// stock; KitsAvailable is how many their Components make up.
 type Amenity struct {
-	ID           string    `gorm:"type:varchar(36);primaryKey" json:"id"`
-	TenantID     string    `gorm:"type:varchar(36);not null;index" json:"tenantId"`
-	CategoryID   string    `gorm:"type:varchar(36);not null;index" json:"categoryId"`
-	ItemName     string    `gorm:"type:varchar(100);not null" json:"itemName"`
-	Description  string    `gorm:"type:text" json:"description"`
-	Stock        int       `gorm:"default:0;not null" json:"stock"`
-	MinimumStock int       `gorm:"default:0;not null" json:"minimumStock"`
-	Available    bool      `gorm:"default:true" json:"available"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID            string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID      string         `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	CategoryID    string         `gorm:"type:varchar(36);not null;index" json:"categoryId"`
+	ItemName      string         `gorm:"type:varchar(100);not null" json:"itemName"`
+	SKU           string         `gorm:"type:varchar(64);index" json:"sku"` // empty for none; unique per tenant otherwise
+	Kind          string         `gorm:"type:varchar(10);not null;default:item;index" json:"kind"`
+	Description   string         `gorm:"type:text" json:"description"`
+	Stock         int            `gorm:"default:0;not null" json:"stock"`
+	StockValue    int64          `gorm:"default:0;not null" json:"stockValue"`
+	MinimumStock  int            `gorm:"default:0;not null" json:"minimumStock"`
+	BaseUnit      string         `gorm:"type:varchar(32);not null;default:unit" json:"baseUnit"`
+	PreferredUnit string         `gorm:"type:varchar(32)" json:"preferredUnit"` // unit listings show stock in; the base unit if empty
+	Available     bool           `gorm:"default:true" json:"available"`
+	Version       int64          `gorm:"not null;default:1" json:"version"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relationships
-	Category *amenities_categories.AmenityCategory `gorm:"foreignKey:CategoryID;references:ID" json:"category,omitempty"`
+	Category  *amenities_categories.AmenityCategory `gorm:"foreignKey:CategoryID;references:ID" json:"category,omitempty"`
+	Locations []AmenityStock                        `gorm:"foreignKey:AmenityID;references:ID" json:"locations,omitempty"`
+	Barcodes  []AmenityBarcode                      `gorm:"foreignKey:AmenityID;references:ID" json:"barcodes,omitempty"`
+	Units     []AmenityUnit                         `gorm:"foreignKey:AmenityID;references:ID" json:"units,omitempty"`
+	// Components are what a kit is made of
+	Components []KitComponent `gorm:"foreignKey:KitID;references:ID" json:"components,omitempty"`
+
+	// StockDisplay is Stock in the preferred unit, when that is not the base unit
+	StockDisplay *UnitQuantity `gorm:"-" json:"stockDisplay,omitempty"`
+	// AverageCost is the weighted-average cost of one base unit
+	AverageCost int64 `gorm:"-" json:"averageCost"`
+	// KitsAvailable is how many of a kit its components' stock makes up
+	KitsAvailable *int `gorm:"-" json:"kitsAvailable,omitempty"`
 }
 
 func (Amenity) TableName() string {
@@ -31,23 +57,212 @@ func (Amenity) TableName() string {
 
 // CreateAmenityRequest represents the request body for creating an amenity
 type CreateAmenityRequest struct {
-	TenantID     string `json:"tenantId" binding:"required"`
-	CategoryID   string `json:"categoryId" binding:"required"`
-	ItemName     string `json:"itemName" binding:"required"`
-	Description  string `json:"description"`
-	Stock        int    `json:"stock"`
-	MinimumStock int    `json:"minimumStock"`
-	Available    *bool  `json:"available"`
+	TenantID      string           `json:"tenantId" binding:"required"`
+	CategoryID    string           `json:"categoryId" binding:"required"`
+	ItemName      string           `json:"itemName" binding:"required"`
+	Kind          string           `json:"kind"`       // item (the default) or kit
+	Components    []ComponentInput `json:"components"` // what a kit is made of; kits only
+	SKU           string           `json:"sku"`
+	Barcodes      []BarcodeInput   `json:"barcodes"`
+	Description   string           `json:"description"`
+	Stock         int              `json:"stock"`
+	StockUnit     string           `json:"stockUnit"` // unit stock is given in; the base unit if empty
+	UnitCost      *int64           `json:"unitCost"`  // cost of one StockUnit of opening stock, in minor units
+	MinimumStock  int              `json:"minimumStock"`
+	BaseUnit      string           `json:"baseUnit"` // defaults to "unit"
+	PreferredUnit string           `json:"preferredUnit"`
+	Units         []UnitInput      `json:"units"`
+	Available     *bool            `json:"available"`
+	LocationID    string           `json:"locationId"` // where opening stock is placed; default location if empty
 }
 
 // UpdateAmenityRequest represents the request body for updating an amenity
 type UpdateAmenityRequest struct {
-	CategoryID   string `json:"categoryId"`
-	ItemName     string `json:"itemName"`
-	Description  string `json:"description"`
-	Stock        *int   `json:"stock"`
-	MinimumStock *int   `json:"minimumStock"`
-	Available    *bool  `json:"available"`
+	CategoryID    string            `json:"categoryId"`
+	ItemName      string            `json:"itemName"`
+	SKU           *string           `json:"sku"`      // "" clears the SKU
+	Barcodes      *[]BarcodeInput   `json:"barcodes"` // replaces every barcode when present; [] removes them
+	Description   string            `json:"description"`
+	Stock         *int              `json:"stock"`
+	StockUnit     string            `json:"stockUnit"` // unit stock is given in; the base unit if empty
+	MinimumStock  *int              `json:"minimumStock"`
+	BaseUnit      *string           `json:"baseUnit"`      // renames the base unit; quantities stay as they are
+	PreferredUnit *string           `json:"preferredUnit"` // "" shows stock in the base unit
+	Units         *[]UnitInput      `json:"units"`         // replaces every unit when present; [] removes them
+	Components    *[]ComponentInput `json:"components"`    // replaces a kit's components when present
+	Available     *bool             `json:"available"`
+	LocationID    string            `json:"locationId"` // location whose stock is set; the total if empty
+}
+
+// BarcodeInput is a barcode given for an amenity. Type is one of the
+// barcodes symbologies, detected from the code when empty.
+type BarcodeInput struct {
+	Code string `json:"code" binding:"required"`
+	Type string `json:"type"`
+}
+
+// StockAdjustmentRequest represents the request body for a relative stock change.
+// Quantity is in Unit, one of the amenity's units; the base unit if empty.
+// Stock being added can open a lot with LotNumber and ExpiresAt (YYYY-MM-DD);
+// stock being removed is taken from LotID only, if given, else first expiry
+// first out. UnitCost, for stock being added, is what one Unit cost in minor
+// units of the tenant's currency; without it stock is added at average cost.
+type StockAdjustmentRequest struct {
+	Quantity   int    `json:"quantity" binding:"required,gt=0"`
+	Unit       string `json:"unit"`
+	UnitCost   *int64 `json:"unitCost"`
+	LocationID string `json:"locationId"`
+	Reason     string `json:"reason"`
+	Reference  string `json:"reference"`
+	Note       string `json:"note"`
+	LotNumber  string `json:"lotNumber"`
+	ExpiresAt  string `json:"expiresAt"`
+	LotID      string `json:"lotId"`
+}
+
+// StockAdjustmentResult reports the outcome of a relative stock change.
+// Balance is the amenity's total; LocationBalance is what remains at the location.
+// Value is how much the change moved the amenity's stock value, and
+// StockValue what that value is now.
+type StockAdjustmentResult struct {
+	AmenityID       string `json:"amenityId"`
+	LocationID      string `json:"locationId"`
+	MovementID      string `json:"movementId"`
+	Delta           int    `json:"delta"`
+	Balance         int    `json:"balance"`
+	LocationBalance int    `json:"locationBalance"`
+	Value           int64  `json:"value"`
+	StockValue      int64  `json:"stockValue"`
+}
+
+// TransferRequest represents the request body for moving stock between locations
+type TransferRequest struct {
+	FromLocationID string `json:"fromLocationId" binding:"required"`
+	ToLocationID   string `json:"toLocationId" binding:"required"`
+	Quantity       int    `json:"quantity" binding:"required,gt=0"`
+	Unit           string `json:"unit"`  // the base unit if empty
+	LotID          string `json:"lotId"` // moves stock of this lot only; first expiry first out if empty
+	Reference      string `json:"reference"`
+	Note           string `json:"note"`
+}
+
+// TransferResult reports both sides of a stock transfer. Quantity is in
+// base units.
+type TransferResult struct {
+	TransferID string                `json:"transferId"`
+	AmenityID  string                `json:"amenityId"`
+	Quantity   int                   `json:"quantity"`
+	From       StockAdjustmentResult `json:"from"`
+	To         StockAdjustmentResult `json:"to"`
+}
+
+// LocationMinimumRequest represents the request body for a per-location low-stock rule
+type LocationMinimumRequest struct {
+	MinimumStock *int `json:"minimumStock" binding:"required"`
+}
+
+// AmenityStock is the quantity of an amenity held at one location, with an
+// optional low-stock threshold for that location
+type AmenityStock struct {
+	AmenityID    string    `gorm:"type:varchar(36);primaryKey" json:"amenityId"`
+	LocationID   string    `gorm:"type:varchar(36);primaryKey;index" json:"locationId"`
+	TenantID     string    `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	Quantity     int       `gorm:"default:0;not null" json:"quantity"`
+	MinimumStock int       `gorm:"default:0;not null" json:"minimumStock"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+
+	// Relationships
+	Amenity  *Amenity            `gorm:"foreignKey:AmenityID;references:ID" json:"amenity,omitempty"`
+	Location *locations.Location `gorm:"foreignKey:LocationID;references:ID" json:"location,omitempty"`
+}
+
+func (AmenityStock) TableName() string {
+	return "amenity_stocks"
+}
+
+// OpeningStock is stock an amenity is created with at one location, such as
+// stock carried over from an archive. Lots cover part or all of Quantity.
+type OpeningStock struct {
+	LocationID   string // the tenant's default location if empty
+	Quantity     int
+	MinimumStock int
+	Lots         []StockLot
+}
+
+// AmenityBarcode is one barcode printed on an amenity's packaging or shelf
+// label. A code identifies at most one of a tenant's live amenities, and is
+// never another amenity's SKU.
+type AmenityBarcode struct {
+	ID        string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID  string    `gorm:"type:varchar(36);not null;index:idx_barcode_tenant_code,priority:1" json:"tenantId"`
+	AmenityID string    `gorm:"type:varchar(36);not null;index" json:"amenityId"`
+	Code      string    `gorm:"type:varchar(255);not null;index:idx_barcode_tenant_code,priority:2" json:"code"`
+	Symbology string    `gorm:"type:varchar(20);not null" json:"type"`
+	SortOrder int       `gorm:"not null;default:0" json:"-"` // position in the amenity's list; the first is printed by default
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (AmenityBarcode) TableName() string {
+	return "amenity_barcodes"
+}
+
+// ScanRequest is a batch of barcode scans from a handheld scanner. Events
+// scanned without a location apply to LocationID, or the default location.
+type ScanRequest struct {
+	TenantID   string      `json:"tenantId" binding:"required"`
+	LocationID string      `json:"locationId"`
+	Events     []ScanEvent `json:"events" binding:"required,min=1,max=500,dive"`
+}
+
+// ScanEvent is one scan: a barcode or SKU and how many items were put on
+// the shelf (restock) or taken from it (consumption, the default, or damage)
+type ScanEvent struct {
+	Code       string `json:"code" binding:"required"`
+	Quantity   int    `json:"quantity"` // defaults to 1
+	Unit       string `json:"unit"`     // the base unit if empty
+	Reason     string `json:"reason"`
+	LocationID string `json:"locationId"`
+	Reference  string `json:"reference"` // defaults to "scan"
+	Note       string `json:"note"`
+}
+
+// Scan outcomes
+const (
+	ScanApplied = "applied"
+	ScanFailed  = "failed"
+)
+
+// ScanResult reports what became of one scan event. A failed event leaves
+// stock untouched and does not stop the rest of the batch.
+type ScanResult struct {
+	Index     int                    `json:"index"`
+	Code      string                 `json:"code"`
+	AmenityID string                 `json:"amenityId,omitempty"`
+	ItemName  string                 `json:"itemName,omitempty"`
+	Status    string                 `json:"status"`
+	Error     string                 `json:"error,omitempty"`
+	Movement  *StockAdjustmentResult `json:"movement,omitempty"`
+}
+
+// ScanBatchResult reports a processed scan batch
+type ScanBatchResult struct {
+	Applied int          `json:"applied"`
+	Failed  int          `json:"failed"`
+	Results []ScanResult `json:"results"`
+}
+
+// LabelRequest asks for a sheet of labels. Code picks which of an
+// amenity's barcodes to print; the first, or the SKU, by default.
+type LabelRequest struct {
+	TenantID string      `json:"tenantId" binding:"required"`
+	Items    []LabelItem `json:"items" binding:"required,min=1,max=200,dive"`
+}
+
+// LabelItem is one amenity to print labels for
+type LabelItem struct {
+	AmenityID string `json:"amenityId" binding:"required"`
+	Code      string `json:"code"`
+	Copies    int    `json:"copies"` // defaults to 1
 }
 
 // AmenityWithCategory represents an amenity with its category information
@@ -56,3 +271,122 @@ type AmenityWithCategory struct {
 	CategoryName string `json:"categoryName"`
 }
 
+// Stock movement reasons
+const (
+	MovementRestock     = "restock"
+	MovementConsumption = "consumption"
+	MovementDamage      = "damage"
+	MovementAdjustment  = "adjustment"
+	MovementTransfer    = "transfer"
+	MovementExpired     = "expired"
+)
+
+// ValidMovementReason reports whether reason is a known stock movement reason
+func ValidMovementReason(reason string) bool {
+	switch reason {
+	case MovementRestock, MovementConsumption, MovementDamage, MovementAdjustment, MovementTransfer, MovementExpired:
+		return true
+	}
+	return false
+}
+
+// StockMovement is an append-only ledger entry. Stock only ever changes by
+// recording a movement against a location. BalanceAfter is the amenity's
+// total stock the movement left behind and LocationBalanceAfter the stock
+// left at the location. Movements recorded before locations existed have
+// no location. Both sides of a transfer share a TransferID. Delta is in
+// base units; Unit and UnitQuantity keep the quantity as it was entered.
+// Value is the signed change the movement made to the amenity's stock
+// value, at UnitCost per Unit when stock was added with a cost and at
+// average cost otherwise, and ValueAfter the stock value it left behind.
+// Movements taking the components of issued kits carry the KitID, and those
+// of one issue share a KitIssueID.
+type StockMovement struct {
+	ID                   string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID             string    `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	AmenityID            string    `gorm:"type:varchar(36);not null;index:idx_movement_amenity_created" json:"amenityId"`
+	LocationID           *string   `gorm:"type:varchar(36);index" json:"locationId"`
+	Delta                int       `gorm:"not null" json:"delta"`
+	Reason               string    `gorm:"type:varchar(20);not null;index" json:"reason"`
+	ActorID              *string   `gorm:"type:varchar(36);index" json:"actorId"`
+	Reference            string    `gorm:"type:varchar(100)" json:"reference"`
+	Note                 string    `gorm:"type:text" json:"note"`
+	TransferID           *string   `gorm:"type:varchar(36);index" json:"transferId,omitempty"`
+	KitID                *string   `gorm:"type:varchar(36);index" json:"kitId,omitempty"`
+	KitIssueID           *string   `gorm:"type:varchar(36);index" json:"kitIssueId,omitempty"`
+	Unit                 string    `gorm:"type:varchar(32)" json:"unit,omitempty"`
+	UnitQuantity         int       `json:"unitQuantity,omitempty"`
+	UnitCost             *int64    `json:"unitCost,omitempty"`
+	Value                int64     `gorm:"not null;default:0" json:"value"`
+	ValueAfter           int64     `gorm:"not null;default:0" json:"valueAfter"`
+	BalanceAfter         int       `gorm:"not null" json:"balanceAfter"`
+	LocationBalanceAfter *int      `json:"locationBalanceAfter"`
+	CreatedAt            time.Time `gorm:"index:idx_movement_amenity_created" json:"createdAt"`
+
+	// Lot receives added stock into a new lot, or takes removed stock from
+	// one lot only; see Repository.moveLots
+	Lot *LotSpec `gorm:"-" json:"-"`
+	// lots are the lot quantities the movement received or took
+	lots []lotShare
+	// value fixes the value of stock added, as for an opening balance
+	// carried over with its value
+	value *int64
+}
+
+func (StockMovement) TableName() string {
+	return "stock_movements"
+}
+
+// BeforeUpdate keeps the ledger append-only
+func (StockMovement) BeforeUpdate(tx *gorm.DB) error {
+	return errors.New("stock movements are append-only")
+}
+
+// BeforeDelete keeps the ledger append-only
+func (StockMovement) BeforeDelete(tx *gorm.DB) error {
+	return errors.New("stock movements are append-only")
+}
+
+// ListFilter narrows an amenity listing to one tenant. Stock bounds are
+// inclusive; UpdatedSince keeps amenities changed at or after it.
+// IncludeSubcategories widens CategoryID to the categories below it.
+type ListFilter struct {
+	TenantID             string
+	Kind                 string
+	CategoryID           string
+	IncludeSubcategories bool
+	Available            *bool
+	LowStock             bool
+	MinStock             *int
+	MaxStock             *int
+	UpdatedSince         *time.Time
+}
+
+// Sort fields for amenity listings
+const (
+	SortItemName  = "itemName"
+	SortStock     = "stock"
+	SortUpdatedAt = "updatedAt"
+)
+
+// sortColumns maps each sort field to its column
+var sortColumns = map[string]string{
+	SortItemName:  "item_name",
+	SortStock:     "stock",
+	SortUpdatedAt: "updated_at",
+}
+
+// ListSort orders an amenity listing. Ties are broken by ID so pages and
+// cursors are stable.
+type ListSort struct {
+	Field string
+	Desc  bool
+}
+
+// MovementFilter narrows a stock movement listing
+type MovementFilter struct {
+	From       *time.Time
+	To         *time.Time
+	Reason     string
+	LocationID string
+}
@@ -1,36 +1,110 @@
 package amenities
 
 import (
+	"errors"
+	"math"
+
 	"concierge-be/database"
+	"concierge-be/internal/amenities_categories"
+	"concierge-be/internal/locations"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	locations *locations.Repository
 }
 
 func NewRepository() *Repository {
 	return &Repository{
-		db: database.GetDB(),
+		db:        database.GetDB(),
+		locations: locations.NewRepository(),
 	}
 }
 
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx, locations: r.locations.WithTx(tx)}
+}
+
 // Create creates a new amenity
 func (r *Repository) Create(amenity *Amenity) error {
 	return r.db.Create(amenity).Error
 }
 
-// GetByID retrieves an amenity by ID with category preloaded
+// GetByID retrieves an amenity by ID with category and per-location stock preloaded
 func (r *Repository) GetByID(id string) (*Amenity, error) {
 	var amenity Amenity
-	err := r.db.Preload("Category").Where("id = ?", id).First(&amenity).Error
+	err := r.db.Preload("Category").Preload("Locations.Location").Preload("Barcodes", orderedBarcodes).Preload("Units", orderedUnits).Scopes(withComponents).Where("id = ?", id).First(&amenity).Error
 	if err != nil {
 		return nil, err
 	}
 	return &amenity, nil
 }
 
+// TenantOf returns the tenant of amenity id, "" if it does not exist
+func (r *Repository) TenantOf(id string) (string, error) {
+	var tenantIDs []string
+	if err := r.db.Model(&Amenity{}).Where("id = ?", id).Pluck("tenant_id", &tenantIDs).Error; err != nil || len(tenantIDs) == 0 {
+		return "", err
+	}
+	return tenantIDs[0], nil
+}
+
+// orderedBarcodes preloads an amenity's barcodes in their listed order
+func orderedBarcodes(db *gorm.DB) *gorm.DB {
+	return db.Order("sort_order ASC, id ASC")
+}
+
+// GetByCode retrieves the live amenity of a tenant a scanned code belongs
+// to, matching its barcodes first and then its SKU
+func (r *Repository) GetByCode(tenantID, code string) (*Amenity, error) {
+	var ids []string
+	err := r.db.Model(&AmenityBarcode{}).
+		Joins("JOIN amenities ON amenities.id = amenity_barcodes.amenity_id AND amenities.deleted_at IS NULL").
+		Where("amenity_barcodes.tenant_id = ? AND amenity_barcodes.code = ?", tenantID, code).
+		Limit(1).Pluck("amenity_barcodes.amenity_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		err = r.db.Model(&Amenity{}).Where("tenant_id = ? AND sku = ?", tenantID, code).
+			Limit(1).Pluck("id", &ids).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(ids) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.GetByID(ids[0])
+}
+
+// CheckCodeExists checks if a code is already a SKU or barcode of another
+// live amenity of the tenant
+func (r *Repository) CheckCodeExists(tenantID, code, excludeID string) (bool, error) {
+	var count int64
+	query := r.db.Model(&Amenity{}).Where("tenant_id = ? AND sku = ?", tenantID, code)
+	if excludeID != "" {
+		query = query.Where("id != ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	query = r.db.Model(&AmenityBarcode{}).
+		Joins("JOIN amenities ON amenities.id = amenity_barcodes.amenity_id AND amenities.deleted_at IS NULL").
+		Where("amenity_barcodes.tenant_id = ? AND amenity_barcodes.code = ?", tenantID, code)
+	if excludeID != "" {
+		query = query.Where("amenity_barcodes.amenity_id != ?", excludeID)
+	}
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
 // GetByTenantID retrieves all amenities for a specific tenant
 func (r *Repository) GetByTenantID(tenantID string, includeCategory bool) ([]Amenity, error) {
 	var amenities []Amenity
@@ -40,43 +114,127 @@ func (r *Repository) GetByTenantID(tenantID string, includeCategory bool) ([]Ame
 		query = query.Preload("Category")
 	}
 	
-	err := query.Order("item_name ASC").Find(&amenities).Error
+	err := query.Preload("Units", orderedUnits).Scopes(withComponents).Order("item_name ASC").Find(&amenities).Error
 	if err != nil {
 		return nil, err
 	}
 	return amenities, nil
 }
 
-// GetByCategoryID retrieves all amenities for a specific category
-func (r *Repository) GetByCategoryID(categoryID string, includeCategory bool) ([]Amenity, error) {
-	var amenities []Amenity
-	query := r.db.Where("category_id = ?", categoryID)
-	
-	if includeCategory {
-		query = query.Preload("Category")
+// FindInBatchesByTenantID walks all amenities for a tenant in batches so
+// large catalogs can be streamed without loading every row at once
+func (r *Repository) FindInBatchesByTenantID(tenantID string, batchSize int, fn func([]Amenity) error) error {
+	var batch []Amenity
+	return r.db.Preload("Barcodes", orderedBarcodes).Preload("Units", orderedUnits).Scopes(withComponents).Where("tenant_id = ?", tenantID).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// filtered returns a reusable query for the amenities matching filter
+func (r *Repository) filtered(filter ListFilter) *gorm.DB {
+	query := r.db.Model(&Amenity{}).Where("tenant_id = ?", filter.TenantID)
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
 	}
-	
-	err := query.Order("item_name ASC").Find(&amenities).Error
-	if err != nil {
-		return nil, err
+	if filter.CategoryID != "" && filter.IncludeSubcategories {
+		var categories []amenities_categories.AmenityCategory
+		err := r.db.Select("id", "parent_id").Where("tenant_id = ?", filter.TenantID).Find(&categories).Error
+		if err != nil {
+			query.AddError(err)
+		}
+		query = query.Where("category_id IN ?", amenities_categories.Descendants(categories, filter.CategoryID))
+	} else if filter.CategoryID != "" {
+		query = query.Where("category_id = ?", filter.CategoryID)
 	}
-	return amenities, nil
+	if filter.Available != nil {
+		query = query.Where("available = ?", *filter.Available)
+	}
+	if filter.LowStock {
+		query = query.Where("stock < minimum_stock")
+	}
+	if filter.MinStock != nil {
+		query = query.Where("stock >= ?", *filter.MinStock)
+	}
+	if filter.MaxStock != nil {
+		query = query.Where("stock <= ?", *filter.MaxStock)
+	}
+	if filter.UpdatedSince != nil {
+		query = query.Where("updated_at >= ?", *filter.UpdatedSince)
+	}
+	return query.Session(&gorm.Session{})
 }
 
-// GetAll retrieves all amenities
-func (r *Repository) GetAll(includeCategory bool) ([]Amenity, error) {
+// List retrieves a page of the amenities matching filter, with their categories
+func (r *Repository) List(filter ListFilter, sort ListSort, page, pageSize int) ([]Amenity, int64, error) {
 	var amenities []Amenity
-	query := r.db
-	
-	if includeCategory {
-		query = query.Preload("Category")
+	var total int64
+
+	query := r.filtered(filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
-	
-	err := query.Order("item_name ASC").Find(&amenities).Error
-	if err != nil {
-		return nil, err
+
+	offset := (page - 1) * pageSize
+	err := database.OrderBy(query.Preload("Category").Preload("Barcodes", orderedBarcodes).Preload("Units", orderedUnits).Scopes(withComponents), sortColumns[sort.Field], "id", sort.Desc).
+		Offset(offset).Limit(pageSize).
+		Find(&amenities).Error
+
+	return amenities, total, err
+}
+
+// ListAfter retrieves up to limit amenities matching filter that follow
+// after in sort order, or the first ones if after is nil. total counts
+// every match, not just those after the cursor.
+func (r *Repository) ListAfter(filter ListFilter, sort ListSort, after interface{}, afterID string, limit int) ([]Amenity, int64, error) {
+	var amenities []Amenity
+	var total int64
+
+	query := r.filtered(filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column := sortColumns[sort.Field]
+	page := query.Preload("Category").Preload("Barcodes", orderedBarcodes).Preload("Units", orderedUnits).Scopes(withComponents)
+	if after != nil {
+		page = database.SeekAfter(page, column, "id", sort.Desc, after, afterID)
+	} else {
+		page = database.OrderBy(page, column, "id", sort.Desc)
+	}
+	err := page.Limit(limit).Find(&amenities).Error
+
+	return amenities, total, err
+}
+
+// FindInBatches walks the amenities matching filter in item name order,
+// with their categories, so filtered exports can be streamed
+func (r *Repository) FindInBatches(filter ListFilter, batchSize int, fn func([]Amenity) error) error {
+	query := r.filtered(filter).Preload("Category")
+
+	// gorm's FindInBatches pages by primary key, so seek by name instead
+	var lastName, lastID string
+	for first := true; ; first = false {
+		page := database.OrderBy(query, "item_name", "id", false)
+		if !first {
+			page = database.SeekAfter(query, "item_name", "id", false, lastName, lastID)
+		}
+
+		var batch []Amenity
+		if err := page.Limit(batchSize).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		lastName, lastID = batch[len(batch)-1].ItemName, batch[len(batch)-1].ID
 	}
-	return amenities, nil
 }
 
 // GetLowStock retrieves amenities with stock below minimum for a tenant
@@ -92,9 +250,77 @@ func (r *Repository) GetLowStock(tenantID string) ([]Amenity, error) {
 	return amenities, nil
 }
 
-// Update updates an existing amenity
+// GetLowStockByTenantIDs retrieves amenities with stock below minimum across several tenants
+func (r *Repository) GetLowStockByTenantIDs(tenantIDs []string) ([]Amenity, error) {
+	var amenities []Amenity
+	if len(tenantIDs) == 0 {
+		return amenities, nil
+	}
+	err := r.db.Preload("Category").
+		Where("tenant_id IN ? AND stock < minimum_stock", tenantIDs).
+		Order("tenant_id ASC, item_name ASC").
+		Find(&amenities).Error
+	if err != nil {
+		return nil, err
+	}
+	return amenities, nil
+}
+
+// GetAllLowStock retrieves amenities below their minimum in every tenant
+func (r *Repository) GetAllLowStock() ([]Amenity, error) {
+	var amenities []Amenity
+	err := r.db.Where("stock < minimum_stock").Order("tenant_id ASC, item_name ASC").Find(&amenities).Error
+	return amenities, err
+}
+
+// Update updates an existing amenity if it is still at amenity.Version,
+// returning database.ErrVersionConflict otherwise. Stock and its value are
+// never written here; they only change through ApplyMovement.
 func (r *Repository) Update(amenity *Amenity) error {
-	return r.db.Save(amenity).Error
+	return database.UpdateVersioned(r.db, amenity, &amenity.Version, "stock", "stock_value")
+}
+
+// UpdateDetails updates an amenity like Update and replaces its barcodes,
+// units and kit components with the ones given, leaving those passed as nil
+// alone. Given a stock movement, it also sets the stock like SetStock. All
+// of it happens in one transaction.
+func (r *Repository) UpdateDetails(amenity *Amenity, barcodes *[]AmenityBarcode, units *[]AmenityUnit, components *[]KitComponent, stock *StockMovement, quantity int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := r.WithTx(tx).Update(amenity); err != nil {
+			return err
+		}
+		if barcodes != nil {
+			if err := replaceChildren(tx, &AmenityBarcode{}, "amenity_id", amenity.ID, barcodes, len(*barcodes)); err != nil {
+				return err
+			}
+		}
+		if units != nil {
+			if err := replaceChildren(tx, &AmenityUnit{}, "amenity_id", amenity.ID, units, len(*units)); err != nil {
+				return err
+			}
+		}
+		if components != nil {
+			if err := replaceChildren(tx, &KitComponent{}, "kit_id", amenity.ID, components, len(*components)); err != nil {
+				return err
+			}
+		}
+		if stock != nil {
+			return r.WithTx(tx).SetStock(stock, quantity)
+		}
+		return nil
+	})
+}
+
+// replaceChildren deletes the rows of model whose column points at an
+// amenity and creates rows in their place
+func replaceChildren(tx *gorm.DB, model interface{}, column, amenityID string, rows interface{}, count int) error {
+	if err := tx.Where(column+" = ?", amenityID).Delete(model).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	return tx.Create(rows).Error
 }
 
 // Delete soft deletes an amenity
@@ -119,8 +345,380 @@ func (r *Repository) CheckItemNameExists(tenantID, itemName, excludeID string) (
 	return count > 0, nil
 }
 
-// UpdateStock updates the stock quantity for an amenity
-func (r *Repository) UpdateStock(id string, quantity int) error {
-	return r.db.Model(&Amenity{}).Where("id = ?", id).Update("stock", quantity).Error
+// CreateWithOpeningStock creates an amenity with zero stock and records its
+// initial stock, if any, as an opening adjustment movement at locationID
+// (the tenant's default location if empty), valued at amenity.StockValue
+func (r *Repository) CreateWithOpeningStock(amenity *Amenity, locationID string, actorID *string) error {
+	opening, value := amenity.Stock, amenity.StockValue
+	amenity.Stock, amenity.StockValue = 0, 0
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(amenity).Error; err != nil {
+			return err
+		}
+		if opening == 0 {
+			return nil
+		}
+
+		movement := &StockMovement{
+			AmenityID: amenity.ID,
+			Delta:     opening,
+			Reason:    MovementAdjustment,
+			ActorID:   actorID,
+			Reference: "opening balance",
+			value:     &value,
+		}
+		if locationID != "" {
+			movement.LocationID = &locationID
+		}
+		if err := r.WithTx(tx).ApplyMovement(movement); err != nil {
+			return err
+		}
+		amenity.Stock, amenity.StockValue = movement.BalanceAfter, movement.ValueAfter
+		return nil
+	})
+}
+
+// CreateWithOpeningStocks creates an amenity with zero stock and records the
+// stock it opens with as one opening adjustment movement per location, which
+// brings in that location's lots. amenity.StockValue is shared out over the
+// locations by quantity.
+func (r *Repository) CreateWithOpeningStocks(amenity *Amenity, openings []OpeningStock, actorID *string) error {
+	value, total := amenity.StockValue, 0
+	for _, opening := range openings {
+		total += opening.Quantity
+	}
+	amenity.Stock, amenity.StockValue = 0, 0
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(amenity).Error; err != nil {
+			return err
+		}
+
+		repo := r.WithTx(tx)
+		for _, opening := range openings {
+			locationID := opening.LocationID
+			if opening.Quantity > 0 {
+				// The last location takes whatever value is left, so
+				// rounding never loses any
+				share := ShareOf(value, opening.Quantity, total)
+				movement := &StockMovement{
+					AmenityID:  amenity.ID,
+					LocationID: &locationID,
+					Delta:      opening.Quantity,
+					Reason:     MovementAdjustment,
+					ActorID:    actorID,
+					Reference:  "opening balance",
+					value:      &share,
+				}
+				for _, lot := range opening.Lots {
+					movement.lots = append(movement.lots, lotShare{lot: lot, quantity: lot.Quantity})
+				}
+				if err := repo.ApplyMovement(movement); err != nil {
+					return err
+				}
+				value, total = value-share, total-opening.Quantity
+				amenity.Stock, amenity.StockValue = movement.BalanceAfter, movement.ValueAfter
+				locationID = *movement.LocationID
+			}
+			if opening.MinimumStock > 0 {
+				if locationID == "" {
+					location, err := repo.resolveLocation(amenity.TenantID, nil)
+					if err != nil {
+						return err
+					}
+					locationID = location.ID
+				}
+				if _, err := repo.SetLocationMinimum(amenity, locationID, opening.MinimumStock); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// FindStocksByTenantID retrieves the location rows of a tenant's live
+// amenities that hold stock or a low-stock threshold
+func (r *Repository) FindStocksByTenantID(tenantID string) ([]AmenityStock, error) {
+	var stocks []AmenityStock
+	err := r.db.Model(&AmenityStock{}).
+		Joins("JOIN amenities ON amenities.id = amenity_stocks.amenity_id AND amenities.deleted_at IS NULL").
+		Where("amenity_stocks.tenant_id = ?", tenantID).
+		Where("amenity_stocks.quantity > 0 OR amenity_stocks.minimum_stock > 0").
+		Order("amenity_stocks.amenity_id ASC, amenity_stocks.location_id ASC").
+		Find(&stocks).Error
+	return stocks, err
+}
+
+// ApplyMovement locks the amenity row, applies the movement's delta to the
+// stock at its location and to the amenity's total, and appends the movement
+// to the ledger, all in one transaction. The amenity's stock value moves
+// with it. A movement without a location applies to the tenant's default
+// location. TenantID, LocationID, the balances and the value are filled in
+// on the movement.
+func (r *Repository) ApplyMovement(movement *StockMovement) error {
+	return r.applyMovement(movement, nil)
+}
+
+// SetStock records the adjustment needed to bring an amenity's stock at the
+// movement's location to quantity. A movement without a location sets the
+// amenity's total instead, which is only possible while all of it is at the
+// default location. The delta is computed under the row lock, so concurrent
+// writers cannot make it stale. No movement is recorded if the stock
+// already matches.
+func (r *Repository) SetStock(movement *StockMovement, quantity int) error {
+	return r.applyMovement(movement, &quantity)
+}
+
+// Transfer moves stock between two locations as a pair of transfer
+// movements sharing a TransferID. Either both sides are recorded or neither.
+// The lots the stock was taken from are recreated at the destination.
+func (r *Repository) Transfer(out, in *StockMovement) error {
+	transferID := uuid.New().String()
+	out.TransferID = &transferID
+	in.TransferID = &transferID
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		repo := r.WithTx(tx)
+		if err := repo.ApplyMovement(out); err != nil {
+			return err
+		}
+		// The lots taken at the source arrive as lots at the destination
+		in.lots = out.lots
+		return repo.ApplyMovement(in)
+	})
+}
+
+func (r *Repository) applyMovement(movement *StockMovement, target *int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var amenity Amenity
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "tenant_id", "kind", "stock", "stock_value").
+			Where("id = ?", movement.AmenityID).
+			First(&amenity).Error
+		if err != nil {
+			return err
+		}
+		if amenity.IsKit() {
+			return errors.New("kits hold no stock of their own")
+		}
+
+		settingTotal := target != nil && (movement.LocationID == nil || *movement.LocationID == "")
+		location, err := r.WithTx(tx).resolveLocation(amenity.TenantID, movement.LocationID)
+		if err != nil {
+			return err
+		}
+
+		// The amenity row lock serialises every writer of this amenity's
+		// location rows, so reading or creating the row here is safe
+		stock := AmenityStock{AmenityID: amenity.ID, LocationID: location.ID, TenantID: amenity.TenantID}
+		if err := tx.Where("amenity_id = ? AND location_id = ?", amenity.ID, location.ID).FirstOrCreate(&stock).Error; err != nil {
+			return err
+		}
+
+		if settingTotal && stock.Quantity != amenity.Stock {
+			return errors.New("stock is split across locations; locationId is required")
+		}
+		if target != nil {
+			movement.Delta = *target - stock.Quantity
+			if movement.Delta == 0 {
+				movement.TenantID = amenity.TenantID
+				movement.LocationID = &location.ID
+				movement.BalanceAfter = amenity.Stock
+				movement.ValueAfter = amenity.StockValue
+				movement.LocationBalanceAfter = &stock.Quantity
+				return nil
+			}
+		}
+
+		locationBalance := stock.Quantity + movement.Delta
+		if locationBalance < 0 {
+			return errors.New("stock quantity cannot be negative")
+		}
+		balance := amenity.Stock + movement.Delta
+		value, err := valueMovement(&amenity, movement)
+		if err != nil {
+			return err
+		}
+		if value > 0 && amenity.StockValue > math.MaxInt64-value {
+			return errors.New("stock value is too large")
+		}
+
+		err = tx.Model(&AmenityStock{}).
+			Where("amenity_id = ? AND location_id = ?", amenity.ID, location.ID).
+			Update("quantity", locationBalance).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&Amenity{}).Where("id = ?", amenity.ID).
+			Updates(map[string]interface{}{"stock": balance, "stock_value": amenity.StockValue + value}).Error
+		if err != nil {
+			return err
+		}
+
+		if movement.ID == "" {
+			movement.ID = uuid.New().String()
+		}
+		movement.TenantID = amenity.TenantID
+		movement.LocationID = &location.ID
+		movement.BalanceAfter = balance
+		movement.LocationBalanceAfter = &locationBalance
+		movement.Value = value
+		movement.ValueAfter = amenity.StockValue + value
+		if err := tx.Create(movement).Error; err != nil {
+			return err
+		}
+		return r.moveLots(tx, movement)
+	})
+}
+
+// resolveLocation returns the location a movement applies to, checking that
+// it belongs to the tenant. A nil ID means the tenant's default location.
+func (r *Repository) resolveLocation(tenantID string, locationID *string) (*locations.Location, error) {
+	if locationID == nil || *locationID == "" {
+		return r.locations.EnsureDefault(tenantID)
+	}
+	location, err := r.locations.GetByID(*locationID)
+	if err != nil {
+		return nil, err
+	}
+	if location.TenantID != tenantID {
+		return nil, errors.New("location not found")
+	}
+	return location, nil
+}
+
+// GetStockByLocation retrieves the amenities held at a location. With
+// lowOnly set, only those below the location's own minimum are returned.
+func (r *Repository) GetStockByLocation(locationID string, lowOnly bool) ([]AmenityStock, error) {
+	var stocks []AmenityStock
+	query := r.db.Preload("Amenity").
+		Joins("JOIN amenities ON amenities.id = amenity_stocks.amenity_id AND amenities.deleted_at IS NULL").
+		Where("amenity_stocks.location_id = ?", locationID)
+	if lowOnly {
+		query = query.Where("amenity_stocks.quantity < amenity_stocks.minimum_stock")
+	}
+	err := query.Order("amenities.item_name ASC").Find(&stocks).Error
+	return stocks, err
+}
+
+// GetLowStockAtLocations retrieves, for a tenant, every amenity and location
+// pair whose quantity is below that location's minimum
+func (r *Repository) GetLowStockAtLocations(tenantID string) ([]AmenityStock, error) {
+	var stocks []AmenityStock
+	err := r.db.Preload("Amenity").Preload("Location").
+		Joins("JOIN amenities ON amenities.id = amenity_stocks.amenity_id AND amenities.deleted_at IS NULL").
+		Where("amenity_stocks.tenant_id = ? AND amenity_stocks.quantity < amenity_stocks.minimum_stock", tenantID).
+		Order("amenities.item_name ASC").
+		Find(&stocks).Error
+	return stocks, err
+}
+
+// GetAllLowStockAtLocations retrieves every amenity and location pair below
+// the location's minimum in every tenant
+func (r *Repository) GetAllLowStockAtLocations() ([]AmenityStock, error) {
+	var stocks []AmenityStock
+	err := r.db.Preload("Amenity").Preload("Location").
+		Joins("JOIN amenities ON amenities.id = amenity_stocks.amenity_id AND amenities.deleted_at IS NULL").
+		Where("amenity_stocks.quantity < amenity_stocks.minimum_stock").
+		Find(&stocks).Error
+	return stocks, err
+}
+
+// SetLocationMinimum sets the low-stock threshold for an amenity at a
+// location. It takes the amenity row lock so it cannot race a movement
+// creating the same location row.
+func (r *Repository) SetLocationMinimum(amenity *Amenity, locationID string, minimum int) (*AmenityStock, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var locked Amenity
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("id = ?", amenity.ID).
+			First(&locked).Error
+		if err != nil {
+			return err
+		}
+
+		stock := AmenityStock{
+			AmenityID:    amenity.ID,
+			LocationID:   locationID,
+			TenantID:     amenity.TenantID,
+			MinimumStock: minimum,
+		}
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"minimum_stock", "updated_at"}),
+		}).Create(&stock).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var saved AmenityStock
+	err = r.db.Preload("Location").
+		Where("amenity_id = ? AND location_id = ?", amenity.ID, locationID).
+		First(&saved).Error
+	return &saved, err
+}
+
+// BackfillLocationStock places stock recorded before locations existed at
+// each tenant's default location. Amenities that already have location rows
+// are left alone, so running it again is harmless.
+func (r *Repository) BackfillLocationStock() (int, error) {
+	var pending []Amenity
+	err := r.db.Select("id", "tenant_id", "stock").
+		Where("stock > 0").
+		Where("NOT EXISTS (SELECT 1 FROM amenity_stocks WHERE amenity_stocks.amenity_id = amenities.id)").
+		Find(&pending).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for _, amenity := range pending {
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			location, err := r.WithTx(tx).locations.EnsureDefault(amenity.TenantID)
+			if err != nil {
+				return err
+			}
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&AmenityStock{
+				AmenityID:  amenity.ID,
+				LocationID: location.ID,
+				TenantID:   amenity.TenantID,
+				Quantity:   amenity.Stock,
+			}).Error
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(pending), nil
 }
 
+// GetMovements retrieves the stock movements for an amenity, newest first
+func (r *Repository) GetMovements(amenityID string, filter MovementFilter, page, pageSize int) ([]StockMovement, int64, error) {
+	var movements []StockMovement
+	var total int64
+
+	db := r.db.Model(&StockMovement{}).Where("amenity_id = ?", amenityID)
+	if filter.From != nil {
+		db = db.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		db = db.Where("created_at < ?", *filter.To)
+	}
+	if filter.Reason != "" {
+		db = db.Where("reason = ?", filter.Reason)
+	}
+	if filter.LocationID != "" {
+		db = db.Where("location_id = ?", filter.LocationID)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("created_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&movements).Error
+
+	return movements, total, err
+}
@@ -0,0 +1,209 @@
+package amenities
+
+import (
+	"sync"
+	"testing"
+
+	"concierge-be/database/dbtest"
+	"concierge-be/internal/locations"
+
+	"gorm.io/gorm"
+)
+
+// newStockTest sets up an amenity with no stock in a database holding the
+// stock ledger
+func newStockTest(t *testing.T) (*gorm.DB, *Repository, *Amenity) {
+	t.Helper()
+	return newStockTestOn(t, dbtest.Open)
+}
+
+// newStockTestOn is newStockTest on a database opened by open
+func newStockTestOn(t *testing.T, open func(testing.TB, ...interface{}) *gorm.DB) (*gorm.DB, *Repository, *Amenity) {
+	t.Helper()
+	db := open(t, &Amenity{}, &AmenityStock{}, &StockMovement{},
+		&StockLot{}, &StockMovementLot{}, &locations.Location{})
+
+	amenity := &Amenity{ID: "amenity-1", TenantID: "tenant-1", CategoryID: "category-1", ItemName: "Shampoo", Kind: KindItem}
+	if err := db.Create(amenity).Error; err != nil {
+		t.Fatalf("create amenity: %v", err)
+	}
+	return db, NewRepository(), amenity
+}
+
+func TestApplyMovementConcurrently(t *testing.T) {
+	// SQLite's single connection checks the bookkeeping; only MySQL's pool
+	// lets the writers interleave and so shows the row lock holds
+	t.Run("sqlite", func(t *testing.T) {
+		testApplyMovementConcurrently(t, dbtest.Open)
+	})
+	t.Run("mysql", func(t *testing.T) {
+		testApplyMovementConcurrently(t, dbtest.OpenMySQL)
+	})
+}
+
+func testApplyMovementConcurrently(t *testing.T, open func(testing.TB, ...interface{}) *gorm.DB) {
+	db, repo, amenity := newStockTestOn(t, open)
+
+	// Receipts and removals race each other; removals that would take the
+	// stock below zero must fail rather than go through
+	const writers = 60
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		applied int
+		refused int
+	)
+	for i := 0; i < writers; i++ {
+		delta := -4
+		if i%3 == 0 {
+			delta = 5
+		}
+		var lot *LotSpec
+		if i%6 == 0 {
+			lot = &LotSpec{LotNumber: "L1"}
+		}
+
+		wg.Add(1)
+		go func(delta int, lot *LotSpec) {
+			defer wg.Done()
+			reason := MovementConsumption
+			if delta > 0 {
+				reason = MovementRestock
+			}
+			err := repo.ApplyMovement(&StockMovement{AmenityID: amenity.ID, Delta: delta, Reason: reason, Lot: lot})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				applied += delta
+			case err.Error() == "stock quantity cannot be negative":
+				refused++
+			default:
+				t.Errorf("ApplyMovement(%d): %v", delta, err)
+			}
+		}(delta, lot)
+	}
+	wg.Wait()
+
+	var stock Amenity
+	if err := db.First(&stock, "id = ?", amenity.ID).Error; err != nil {
+		t.Fatalf("load amenity: %v", err)
+	}
+	if stock.Stock != applied {
+		t.Errorf("stock = %d, want %d, the sum of the movements applied", stock.Stock, applied)
+	}
+	if stock.Stock < 0 {
+		t.Errorf("stock went negative: %d", stock.Stock)
+	}
+
+	var movements []StockMovement
+	if err := db.Where("amenity_id = ?", amenity.ID).Find(&movements).Error; err != nil {
+		t.Fatalf("load movements: %v", err)
+	}
+	if len(movements)+refused != writers {
+		t.Errorf("%d movements recorded and %d refused, want %d in all", len(movements), refused, writers)
+	}
+	ledger := 0
+	for _, movement := range movements {
+		ledger += movement.Delta
+		if movement.BalanceAfter < 0 || movement.LocationBalanceAfter == nil || *movement.LocationBalanceAfter < 0 {
+			t.Errorf("movement %s left a negative balance: %d", movement.ID, movement.BalanceAfter)
+		}
+	}
+	if ledger != stock.Stock {
+		t.Errorf("ledger sums to %d, stock is %d", ledger, stock.Stock)
+	}
+
+	var located, lotted int64
+	db.Model(&AmenityStock{}).Where("amenity_id = ?", amenity.ID).Select("COALESCE(SUM(quantity), 0)").Scan(&located)
+	db.Model(&StockLot{}).Where("amenity_id = ?", amenity.ID).Select("COALESCE(SUM(quantity), 0)").Scan(&lotted)
+	if int(located) != stock.Stock {
+		t.Errorf("location stock sums to %d, stock is %d", located, stock.Stock)
+	}
+	if lotted > located {
+		t.Errorf("lots hold %d, more than the %d at the location", lotted, located)
+	}
+}
+
+func TestSetStock(t *testing.T) {
+	db, repo, amenity := newStockTest(t)
+	cart := &locations.Location{ID: "cart-1", TenantID: "tenant-1", Name: "Cart 1"}
+	if err := db.Create(cart).Error; err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	set := func(locationID string, quantity int) error {
+		movement := &StockMovement{AmenityID: amenity.ID, Reason: MovementAdjustment}
+		if locationID != "" {
+			movement.LocationID = &locationID
+		}
+		return repo.SetStock(movement, quantity)
+	}
+	total := func() int {
+		var stock Amenity
+		if err := db.First(&stock, "id = ?", amenity.ID).Error; err != nil {
+			t.Fatalf("load amenity: %v", err)
+		}
+		return stock.Stock
+	}
+
+	// With all of it at the default location, the total can be set
+	if err := set("", 30); err != nil {
+		t.Fatalf("set total: %v", err)
+	}
+	if err := set("", 25); err != nil {
+		t.Fatalf("set total again: %v", err)
+	}
+	if got := total(); got != 25 {
+		t.Errorf("stock = %d, want 25", got)
+	}
+
+	// Once some is elsewhere, only a location's stock can be set
+	if err := set(cart.ID, 5); err != nil {
+		t.Fatalf("set cart stock: %v", err)
+	}
+	if err := set("", 40); err == nil || err.Error() != "stock is split across locations; locationId is required" {
+		t.Errorf("set total of split stock: err = %v", err)
+	}
+	if got := total(); got != 30 {
+		t.Errorf("stock = %d, want 30", got)
+	}
+}
+
+func TestUpdateDetailsWithStockIsAtomic(t *testing.T) {
+	db, repo, amenity := newStockTest(t)
+	cart := &locations.Location{ID: "cart-1", TenantID: "tenant-1", Name: "Cart 1"}
+	if err := db.Create(cart).Error; err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	if err := repo.ApplyMovement(&StockMovement{AmenityID: amenity.ID, LocationID: &cart.ID, Delta: 5, Reason: MovementRestock}); err != nil {
+		t.Fatalf("stock cart: %v", err)
+	}
+
+	// The stock cannot be set, so the rename is rolled back with it
+	amenity.ItemName = "Conditioner"
+	movement := &StockMovement{AmenityID: amenity.ID, Reason: MovementAdjustment}
+	if err := repo.UpdateDetails(amenity, nil, nil, nil, movement, 12); err == nil {
+		t.Fatal("UpdateDetails set the total of split stock")
+	}
+	var stored Amenity
+	if err := db.First(&stored, "id = ?", amenity.ID).Error; err != nil {
+		t.Fatalf("load amenity: %v", err)
+	}
+	if stored.ItemName != "Shampoo" || stored.Version != 1 || stored.Stock != 5 {
+		t.Fatalf("amenity = %s v%d stock %d, want the update rolled back", stored.ItemName, stored.Version, stored.Stock)
+	}
+
+	// Both land together
+	stored.ItemName = "Conditioner"
+	movement = &StockMovement{AmenityID: amenity.ID, LocationID: &cart.ID, Reason: MovementAdjustment}
+	if err := repo.UpdateDetails(&stored, nil, nil, nil, movement, 12); err != nil {
+		t.Fatalf("UpdateDetails: %v", err)
+	}
+	if err := db.First(&stored, "id = ?", amenity.ID).Error; err != nil {
+		t.Fatalf("load amenity: %v", err)
+	}
+	if stored.ItemName != "Conditioner" || stored.Stock != 12 {
+		t.Errorf("amenity = %s stock %d, want Conditioner with 12", stored.ItemName, stored.Stock)
+	}
+}
@@ -1,24 +1,54 @@
 package amenities
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/barcodes"
+	"concierge-be/internal/locations"
+	"concierge-be/internal/quotas"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Limits on amenity identifiers and label printing
+const (
+	maxSKULength   = 64
+	maxBarcodes    = 20
+	maxLabelCopies = 100
+	maxLabels      = 1000
 )
 
 type Service struct {
-	repo *Repository
+	repo      *Repository
+	locations *locations.Repository
+	quotas    *quotas.Service
 }
 
 func NewService() *Service {
 	return &Service{
-		repo: NewRepository(),
+		repo:      NewRepository(),
+		locations: locations.NewRepository(),
+		quotas:    quotas.NewService(),
 	}
 }
 
-// CreateAmenity creates a new amenity
-func (s *Service) CreateAmenity(req *CreateAmenityRequest) (*Amenity, error) {
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	copied := *s
+	copied.repo = s.repo.WithTx(s.repo.db.WithContext(ctx))
+	return &copied
+}
+
+// CreateAmenity creates a new amenity. Initial stock is recorded as an
+// opening adjustment movement at the requested location.
+func (s *Service) CreateAmenity(req *CreateAmenityRequest, actorID *string) (*Amenity, error) {
 	// Check if item name already exists for this tenant
 	exists, err := s.repo.CheckItemNameExists(req.TenantID, req.ItemName, "")
 	if err != nil {
@@ -33,18 +63,91 @@ func (s *Service) CreateAmenity(req *CreateAmenityRequest) (*Amenity, error) {
 		available = *req.Available
 	}
 
+	id := uuid.New().String()
+	sku := strings.TrimSpace(req.SKU)
+	codes, err := s.checkIdentifiers(req.TenantID, id, sku, req.Barcodes)
+	if err != nil {
+		return nil, err
+	}
+	baseUnit := strings.TrimSpace(req.BaseUnit)
+	if baseUnit == "" {
+		baseUnit = DefaultBaseUnit
+	}
+	preferredUnit := strings.TrimSpace(req.PreferredUnit)
+	units, err := checkUnits(req.TenantID, id, baseUnit, preferredUnit, req.Units)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := req.Kind
+	if kind == "" {
+		kind = KindItem
+	}
+	var components []KitComponent
+	switch kind {
+	case KindItem:
+		if len(req.Components) > 0 {
+			return nil, errors.New("invalid kit: components only apply to kits")
+		}
+	case KindKit:
+		if req.Stock != 0 || req.UnitCost != nil {
+			return nil, errors.New("invalid kit: kits hold no stock of their own; stock their components")
+		}
+		if req.MinimumStock != 0 {
+			return nil, errors.New("invalid kit: kits have no minimum stock")
+		}
+		if components, err = s.checkComponents(req.TenantID, id, req.Components); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("invalid kit: kind must be item or kit")
+	}
+
 	amenity := &Amenity{
-		ID:           uuid.New().String(),
-		TenantID:     req.TenantID,
-		CategoryID:   req.CategoryID,
-		ItemName:     req.ItemName,
-		Description:  req.Description,
-		Stock:        req.Stock,
-		MinimumStock: req.MinimumStock,
-		Available:    available,
+		ID:            id,
+		TenantID:      req.TenantID,
+		CategoryID:    req.CategoryID,
+		ItemName:      req.ItemName,
+		SKU:           sku,
+		Kind:          kind,
+		Barcodes:      codes,
+		Units:         units,
+		Components:    components,
+		Description:   req.Description,
+		MinimumStock:  req.MinimumStock,
+		BaseUnit:      baseUnit,
+		PreferredUnit: preferredUnit,
+		Available:     available,
 	}
 
-	if err := s.repo.Create(amenity); err != nil {
+	if req.Stock < 0 {
+		return nil, errors.New("stock quantity cannot be negative")
+	}
+	if amenity.Stock, err = amenity.ToBase(req.Stock, req.StockUnit); err != nil {
+		return nil, err
+	}
+	if err := checkUnitCost(req.UnitCost, true); err != nil {
+		return nil, err
+	}
+	if req.UnitCost != nil {
+		if amenity.StockValue, err = costOf(req.Stock, *req.UnitCost); err != nil {
+			return nil, err
+		}
+	}
+
+	// The quota is checked under the tenant's row lock in the creating
+	// transaction, so concurrent creates cannot both take the last slot
+	err = s.repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.quotas.WithTx(tx).CheckLimit(req.TenantID, quotas.ResourceAmenities); err != nil {
+			return err
+		}
+		return s.repo.WithTx(tx).CreateWithOpeningStock(amenity, req.LocationID, actorID)
+	})
+	if err != nil {
+		if errors.Is(err, quotas.ErrQuotaExceeded) || err.Error() == "tenant not found" ||
+			err.Error() == "location not found" || err.Error() == "stock value is too large" {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to create amenity: %w", err)
 	}
 
@@ -57,32 +160,82 @@ func (s *Service) GetAmenityByID(id string) (*Amenity, error) {
 	return s.repo.GetByID(id)
 }
 
-// GetAmenitiesByTenantID retrieves all amenities for a tenant
-func (s *Service) GetAmenitiesByTenantID(tenantID string) ([]Amenity, error) {
-	return s.repo.GetByTenantID(tenantID, true)
+// ListAmenities retrieves a page of a tenant's amenities
+func (s *Service) ListAmenities(filter ListFilter, sort ListSort, page, pageSize int) ([]Amenity, int64, error) {
+	if _, ok := sortColumns[sort.Field]; !ok {
+		return nil, 0, errors.New("invalid sort field")
+	}
+	return s.repo.List(filter, sort, page, pageSize)
 }
 
-// GetAmenitiesByCategoryID retrieves all amenities for a category
-func (s *Service) GetAmenitiesByCategoryID(categoryID string) ([]Amenity, error) {
-	return s.repo.GetByCategoryID(categoryID, true)
+// ListAmenitiesAfter retrieves the page of a tenant's amenities following
+// cursor, or the first page if cursor is empty. The returned cursor leads to
+// the next page and is empty on the last one.
+func (s *Service) ListAmenitiesAfter(filter ListFilter, sort ListSort, cursor string, pageSize int) ([]Amenity, int64, string, error) {
+	if _, ok := sortColumns[sort.Field]; !ok {
+		return nil, 0, "", errors.New("invalid sort field")
+	}
+
+	var after interface{}
+	afterID := ""
+	if cursor != "" {
+		decoded, err := database.DecodeCursor(cursor, sort.Field, sort.Desc)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		if after, err = cursorValue(sort.Field, decoded.Value); err != nil {
+			return nil, 0, "", database.ErrInvalidCursor
+		}
+		afterID = decoded.ID
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	list, total, err := s.repo.ListAfter(filter, sort, after, afterID, pageSize+1)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if len(list) <= pageSize {
+		return list, total, "", nil
+	}
+
+	list = list[:pageSize]
+	last := list[pageSize-1]
+	next := database.Cursor{Sort: sort.Field, Desc: sort.Desc, Value: sortValue(sort.Field, &last), ID: last.ID}
+	return list, total, next.Encode(), nil
 }
 
-// GetAllAmenities retrieves all amenities
-func (s *Service) GetAllAmenities() ([]Amenity, error) {
-	return s.repo.GetAll(true)
+// sortValue renders an amenity's value of a sort field for a cursor
+func sortValue(field string, amenity *Amenity) string {
+	switch field {
+	case SortStock:
+		return strconv.Itoa(amenity.Stock)
+	case SortUpdatedAt:
+		return amenity.UpdatedAt.Format(time.RFC3339Nano)
+	}
+	return amenity.ItemName
 }
 
-// GetLowStockAmenities retrieves amenities with stock below minimum
-func (s *Service) GetLowStockAmenities(tenantID string) ([]Amenity, error) {
-	return s.repo.GetLowStock(tenantID)
+// cursorValue parses a cursor's sort value back into a column value
+func cursorValue(field, value string) (interface{}, error) {
+	switch field {
+	case SortStock:
+		return strconv.Atoi(value)
+	case SortUpdatedAt:
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return value, nil
 }
 
-// UpdateAmenity updates an existing amenity
-func (s *Service) UpdateAmenity(id string, req *UpdateAmenityRequest) (*Amenity, error) {
+// UpdateAmenity updates an existing amenity the caller last read at version.
+// A changed stock level is recorded as an adjustment movement.
+func (s *Service) UpdateAmenity(id string, req *UpdateAmenityRequest, version int64, actorID *string) (*Amenity, error) {
 	amenity, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, fmt.Errorf("amenity not found: %w", err)
 	}
+	if amenity.Version != version {
+		return nil, database.ErrVersionConflict
+	}
 
 	// If item name is being updated, check for duplicates
 	if req.ItemName != "" && req.ItemName != amenity.ItemName {
@@ -100,12 +253,74 @@ func (s *Service) UpdateAmenity(id string, req *UpdateAmenityRequest) (*Amenity,
 		amenity.CategoryID = req.CategoryID
 	}
 
-	if req.Description != "" {
-		amenity.Description = req.Description
+	if req.SKU != nil {
+		amenity.SKU = strings.TrimSpace(*req.SKU)
+	}
+	var codes *[]AmenityBarcode
+	if req.SKU != nil || req.Barcodes != nil {
+		inputs := make([]BarcodeInput, 0, len(amenity.Barcodes))
+		if req.Barcodes != nil {
+			inputs = *req.Barcodes
+		} else {
+			for _, barcode := range amenity.Barcodes {
+				inputs = append(inputs, BarcodeInput{Code: barcode.Code, Type: barcode.Symbology})
+			}
+		}
+		checked, err := s.checkIdentifiers(amenity.TenantID, id, amenity.SKU, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if req.Barcodes != nil {
+			codes = &checked
+		}
 	}
 
-	if req.Stock != nil {
-		amenity.Stock = *req.Stock
+	var units *[]AmenityUnit
+	if req.BaseUnit != nil || req.PreferredUnit != nil || req.Units != nil {
+		if req.BaseUnit != nil {
+			amenity.BaseUnit = strings.TrimSpace(*req.BaseUnit)
+		}
+		if req.PreferredUnit != nil {
+			amenity.PreferredUnit = strings.TrimSpace(*req.PreferredUnit)
+		}
+		inputs := make([]UnitInput, 0, len(amenity.Units))
+		if req.Units != nil {
+			inputs = *req.Units
+		} else {
+			for _, unit := range amenity.Units {
+				inputs = append(inputs, UnitInput{Name: unit.Name, Factor: unit.Factor})
+			}
+		}
+		checked, err := checkUnits(amenity.TenantID, id, amenity.BaseUnit, amenity.PreferredUnit, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if req.Units != nil {
+			units = &checked
+			amenity.Units = checked
+		}
+	}
+
+	var components *[]KitComponent
+	if req.Components != nil {
+		if !amenity.IsKit() {
+			return nil, errors.New("invalid kit: components only apply to kits")
+		}
+		checked, err := s.checkComponents(amenity.TenantID, id, *req.Components)
+		if err != nil {
+			return nil, err
+		}
+		components = &checked
+	}
+	if amenity.IsKit() && req.Stock != nil {
+		return nil, errors.New("invalid kit: kits hold no stock of their own; stock their components")
+	}
+	if amenity.IsKit() && req.MinimumStock != nil && *req.MinimumStock != 0 {
+		return nil, errors.New("invalid kit: kits have no minimum stock")
+	}
+
+	if req.Description != "" {
+		amenity.Description = req.Description
 	}
 
 	if req.MinimumStock != nil {
@@ -116,7 +331,27 @@ func (s *Service) UpdateAmenity(id string, req *UpdateAmenityRequest) (*Amenity,
 		amenity.Available = *req.Available
 	}
 
-	if err := s.repo.Update(amenity); err != nil {
+	if req.Stock != nil && *req.Stock < 0 {
+		return nil, errors.New("stock quantity cannot be negative")
+	}
+	var stock int
+	if req.Stock != nil {
+		if stock, err = amenity.ToBase(*req.Stock, req.StockUnit); err != nil {
+			return nil, err
+		}
+	}
+
+	var movement *StockMovement
+	if req.Stock != nil {
+		movement = &StockMovement{AmenityID: id, Reason: MovementAdjustment, ActorID: actorID}
+		if req.LocationID != "" {
+			movement.LocationID = &req.LocationID
+		}
+	}
+	if err := s.repo.UpdateDetails(amenity, codes, units, components, movement, stock); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) || err.Error() == "location not found" || err.Error() == "stock is split across locations; locationId is required" {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to update amenity: %w", err)
 	}
 
@@ -124,19 +359,248 @@ func (s *Service) UpdateAmenity(id string, req *UpdateAmenityRequest) (*Amenity,
 	return s.repo.GetByID(id)
 }
 
-// UpdateStock updates the stock quantity for an amenity
-func (s *Service) UpdateStock(id string, quantity int) (*Amenity, error) {
+// checkIdentifiers validates an amenity's SKU and barcodes and checks that
+// none of them already identifies another of the tenant's amenities. The
+// barcodes are returned ready to store, in the order given. A barcode
+// without a type gets the symbology its code looks like.
+func (s *Service) checkIdentifiers(tenantID, amenityID, sku string, inputs []BarcodeInput) ([]AmenityBarcode, error) {
+	if len(sku) > maxSKULength {
+		return nil, fmt.Errorf("invalid sku: must be at most %d characters", maxSKULength)
+	}
+	if len(inputs) > maxBarcodes {
+		return nil, fmt.Errorf("invalid barcode: an amenity can have at most %d barcodes", maxBarcodes)
+	}
+
+	seen := map[string]bool{}
+	if sku != "" {
+		seen[sku] = true
+	}
+	codes := make([]AmenityBarcode, 0, len(inputs))
+	for i, input := range inputs {
+		code := strings.TrimSpace(input.Code)
+		symbology := strings.ToLower(strings.TrimSpace(input.Type))
+		if symbology == "" {
+			symbology = barcodes.Detect(code)
+		}
+		if err := barcodes.Validate(symbology, code); err != nil {
+			return nil, fmt.Errorf("invalid barcode: %w", err)
+		}
+		if seen[code] {
+			return nil, fmt.Errorf("invalid barcode: %q is listed twice or repeats the sku", code)
+		}
+		seen[code] = true
+
+		codes = append(codes, AmenityBarcode{
+			ID:        uuid.New().String(),
+			TenantID:  tenantID,
+			AmenityID: amenityID,
+			Code:      code,
+			Symbology: symbology,
+			SortOrder: i,
+		})
+	}
+
+	if sku != "" {
+		taken, err := s.repo.CheckCodeExists(tenantID, sku, amenityID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check sku: %w", err)
+		}
+		if taken {
+			return nil, fmt.Errorf("sku %q is already used by another amenity", sku)
+		}
+	}
+	for _, barcode := range codes {
+		taken, err := s.repo.CheckCodeExists(tenantID, barcode.Code, amenityID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check barcode: %w", err)
+		}
+		if taken {
+			return nil, fmt.Errorf("barcode %q is already used by another amenity", barcode.Code)
+		}
+	}
+	return codes, nil
+}
+
+// GetAmenityByCode retrieves the amenity a scanned barcode or SKU belongs to
+func (s *Service) GetAmenityByCode(tenantID, code string) (*Amenity, error) {
+	amenity, err := s.repo.GetByCode(tenantID, strings.TrimSpace(code))
+	if err != nil {
+		return nil, fmt.Errorf("amenity not found: %w", err)
+	}
+	return amenity, nil
+}
+
+// ApplyScans records each scan event of a batch as a stock movement of
+// the amenity its code belongs to. Events are applied one by one in the
+// order given, so a failed event, such as an unknown code or a shelf
+// without enough stock, leaves the others in place.
+func (s *Service) ApplyScans(req *ScanRequest, actorID *string) (*ScanBatchResult, error) {
+	result := &ScanBatchResult{Results: make([]ScanResult, 0, len(req.Events))}
+	found := map[string]*Amenity{}
+
+	for i, event := range req.Events {
+		scan := ScanResult{Index: i, Code: event.Code}
+		movement, err := s.applyScan(req, event, found, actorID, &scan)
+		if err != nil {
+			scan.Status = ScanFailed
+			scan.Error = err.Error()
+			result.Failed++
+		} else {
+			scan.Status = ScanApplied
+			scan.Movement = movement
+			result.Applied++
+		}
+		result.Results = append(result.Results, scan)
+	}
+	return result, nil
+}
+
+// applyScan applies one scan event, caching the amenities codes resolve to
+func (s *Service) applyScan(req *ScanRequest, event ScanEvent, found map[string]*Amenity, actorID *string, scan *ScanResult) (*StockAdjustmentResult, error) {
+	code := strings.TrimSpace(event.Code)
+	amenity, ok := found[code]
+	if !ok {
+		var err error
+		if amenity, err = s.repo.GetByCode(req.TenantID, code); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("unknown barcode")
+			}
+			return nil, err
+		}
+		found[code] = amenity
+	}
+	scan.AmenityID = amenity.ID
+	scan.ItemName = amenity.ItemName
+
+	quantity := event.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity < 0 {
+		return nil, errors.New("quantity must be greater than zero")
+	}
+	reason := event.Reason
+	if reason == "" {
+		reason = MovementConsumption
+	}
+	direction := -1
+	switch reason {
+	case MovementRestock:
+		direction = 1
+	case MovementConsumption, MovementDamage:
+	default:
+		return nil, errors.New("scan reason must be restock, consumption or damage")
+	}
+	locationID := event.LocationID
+	if locationID == "" {
+		locationID = req.LocationID
+	}
+	reference := event.Reference
+	if reference == "" {
+		reference = "scan"
+	}
+
+	return s.adjustStock(amenity.ID, direction, &StockAdjustmentRequest{
+		Quantity:   quantity,
+		Unit:       event.Unit,
+		LocationID: locationID,
+		Reason:     reason,
+		Reference:  reference,
+		Note:       event.Note,
+	}, actorID)
+}
+
+// LabelFor builds the label of an amenity. code picks which of its
+// barcodes, or its SKU, to print; by default the first barcode is printed,
+// or the SKU as Code 128 when it has none.
+func (s *Service) LabelFor(amenity *Amenity, code string) (*barcodes.Label, error) {
+	label := &barcodes.Label{Title: amenity.ItemName}
+	if amenity.SKU != "" {
+		label.Subtitle = "SKU " + amenity.SKU
+	}
+
+	for _, barcode := range amenity.Barcodes {
+		if code == "" || barcode.Code == code {
+			label.Code, label.Symbology = barcode.Code, barcode.Symbology
+			return label, nil
+		}
+	}
+	if amenity.SKU != "" && (code == "" || code == amenity.SKU) {
+		label.Code, label.Symbology = amenity.SKU, barcodes.SymbologyCode128
+		if err := barcodes.Validate(label.Symbology, label.Code); err != nil {
+			label.Symbology = barcodes.SymbologyQR
+		}
+		return label, nil
+	}
+	if code != "" {
+		return nil, fmt.Errorf("code %q is not a barcode or the sku of this amenity", code)
+	}
+	return nil, errors.New("amenity has no sku or barcode to print")
+}
+
+// Labels builds the labels of several of a tenant's amenities, each
+// repeated as many times as asked
+func (s *Service) Labels(req *LabelRequest) ([]barcodes.Label, error) {
+	var labels []barcodes.Label
+	for _, item := range req.Items {
+		copies := item.Copies
+		if copies == 0 {
+			copies = 1
+		}
+		if copies < 0 || copies > maxLabelCopies {
+			return nil, fmt.Errorf("copies must be between 1 and %d", maxLabelCopies)
+		}
+
+		amenity, err := s.repo.GetByID(item.AmenityID)
+		if err != nil || amenity.TenantID != req.TenantID {
+			return nil, fmt.Errorf("amenity not found: %s", item.AmenityID)
+		}
+		label, err := s.LabelFor(amenity, item.Code)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", amenity.ItemName, err)
+		}
+		for i := 0; i < copies; i++ {
+			labels = append(labels, *label)
+		}
+	}
+	if len(labels) > maxLabels {
+		return nil, fmt.Errorf("at most %d labels can be printed at once", maxLabels)
+	}
+	return labels, nil
+}
+
+// UpdateStock sets the stock quantity for an amenity at a location, or its
+// total if empty, by recording the movement that brings it to quantity,
+// given in unit or the base unit if empty. Setting the total fails once the
+// stock is split across locations.
+func (s *Service) UpdateStock(id string, quantity int, unit, locationID, reason, reference string, actorID *string) (*Amenity, error) {
 	if quantity < 0 {
 		return nil, errors.New("stock quantity cannot be negative")
 	}
+	if reason == "" {
+		reason = MovementAdjustment
+	}
+	if !ValidMovementReason(reason) {
+		return nil, errors.New("invalid movement reason")
+	}
 
 	// Check if amenity exists
-	_, err := s.repo.GetByID(id)
+	amenity, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, fmt.Errorf("amenity not found: %w", err)
 	}
+	if quantity, err = amenity.ToBase(quantity, unit); err != nil {
+		return nil, err
+	}
 
-	if err := s.repo.UpdateStock(id, quantity); err != nil {
+	movement := &StockMovement{AmenityID: id, Reason: reason, Reference: reference, ActorID: actorID}
+	if locationID != "" {
+		movement.LocationID = &locationID
+	}
+	if err := s.repo.SetStock(movement, quantity); err != nil {
+		if errors.Is(err, ErrInsufficientUnexpiredStock) || err.Error() == "stock quantity cannot be negative" || err.Error() == "location not found" || err.Error() == "stock is split across locations; locationId is required" {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to update stock: %w", err)
 	}
 
@@ -144,6 +608,345 @@ func (s *Service) UpdateStock(id string, quantity int) (*Amenity, error) {
 	return s.repo.GetByID(id)
 }
 
+// IncrementStock atomically adds quantity to an amenity's stock.
+// The reason defaults to restock.
+func (s *Service) IncrementStock(id string, req *StockAdjustmentRequest, actorID *string) (*StockAdjustmentResult, error) {
+	if req.Reason == "" {
+		req.Reason = MovementRestock
+	}
+	return s.adjustStock(id, 1, req, actorID)
+}
+
+// DecrementStock atomically removes quantity from an amenity's stock and
+// fails without changing anything if that would take it below zero.
+// The reason defaults to consumption.
+func (s *Service) DecrementStock(id string, req *StockAdjustmentRequest, actorID *string) (*StockAdjustmentResult, error) {
+	if req.Reason == "" {
+		req.Reason = MovementConsumption
+	}
+	return s.adjustStock(id, -1, req, actorID)
+}
+
+// adjustStock applies a relative stock change under the amenity's row lock,
+// so concurrent adjustments never lose updates. direction is 1 to add
+// req.Quantity and -1 to remove it.
+func (s *Service) adjustStock(id string, direction int, req *StockAdjustmentRequest, actorID *string) (*StockAdjustmentResult, error) {
+	if req.Quantity <= 0 {
+		return nil, errors.New("quantity must be greater than zero")
+	}
+	if !ValidMovementReason(req.Reason) {
+		return nil, errors.New("invalid movement reason")
+	}
+	quantity, err := s.toBase(id, req.Quantity, req.Unit)
+	if err != nil {
+		return nil, err
+	}
+	lot, err := NewLotSpec(direction > 0, req.LotID, req.LotNumber, req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnitCost(req.UnitCost, direction > 0); err != nil {
+		return nil, err
+	}
+
+	movement := &StockMovement{
+		AmenityID: id,
+		Lot:       lot,
+		UnitCost:  req.UnitCost,
+		Delta:     direction * quantity,
+		Reason:    req.Reason,
+		Reference: req.Reference,
+		Note:      req.Note,
+		ActorID:   actorID,
+	}
+	if req.Unit != "" {
+		movement.Unit, movement.UnitQuantity = req.Unit, req.Quantity
+	}
+	if req.LocationID != "" {
+		movement.LocationID = &req.LocationID
+	}
+	if err := s.repo.ApplyMovement(movement); err != nil {
+		return nil, stockError(err)
+	}
+
+	return movementResult(movement), nil
+}
+
+// TransferStock moves quantity of an amenity from one location to another,
+// recording a transfer movement on each side
+func (s *Service) TransferStock(id string, req *TransferRequest, actorID *string) (*TransferResult, error) {
+	if req.Quantity <= 0 {
+		return nil, errors.New("quantity must be greater than zero")
+	}
+	if req.FromLocationID == req.ToLocationID {
+		return nil, errors.New("source and destination locations must differ")
+	}
+	quantity, err := s.toBase(id, req.Quantity, req.Unit)
+	if err != nil {
+		return nil, err
+	}
+	lot, err := NewLotSpec(false, req.LotID, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	out := &StockMovement{
+		AmenityID:  id,
+		Lot:        lot,
+		LocationID: &req.FromLocationID,
+		Delta:      -quantity,
+		Reason:     MovementTransfer,
+		Reference:  req.Reference,
+		Note:       req.Note,
+		ActorID:    actorID,
+	}
+	in := &StockMovement{
+		AmenityID:  id,
+		LocationID: &req.ToLocationID,
+		Delta:      quantity,
+		Reason:     MovementTransfer,
+		Reference:  req.Reference,
+		Note:       req.Note,
+		ActorID:    actorID,
+	}
+	if req.Unit != "" {
+		out.Unit, out.UnitQuantity = req.Unit, req.Quantity
+		in.Unit, in.UnitQuantity = req.Unit, req.Quantity
+	}
+	if err := s.repo.Transfer(out, in); err != nil {
+		return nil, stockError(err)
+	}
+
+	return &TransferResult{
+		TransferID: *out.TransferID,
+		AmenityID:  id,
+		Quantity:   quantity,
+		From:       *movementResult(out),
+		To:         *movementResult(in),
+	}, nil
+}
+
+// toBase converts a quantity of an amenity entered in unit to its base
+// unit. An empty unit is the base unit.
+func (s *Service) toBase(id string, quantity int, unit string) (int, error) {
+	if unit == "" {
+		return quantity, nil
+	}
+	amenity, err := s.repo.GetByID(id)
+	if err != nil {
+		return 0, fmt.Errorf("amenity not found: %w", err)
+	}
+	return amenity.ToBase(quantity, unit)
+}
+
+// ConvertQuantity converts a quantity of an amenity between two of its
+// units, failing rather than rounding when the result is not whole
+func (s *Service) ConvertQuantity(id string, quantity int, from, to string) (*ConversionResult, error) {
+	amenity, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("amenity not found: %w", err)
+	}
+	if from == "" {
+		from = amenity.baseUnit()
+	}
+	if to == "" {
+		to = amenity.baseUnit()
+	}
+	base, err := amenity.ToBase(quantity, from)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := amenity.FromBase(base, to)
+	if err != nil {
+		return nil, err
+	}
+	return &ConversionResult{
+		AmenityID:    id,
+		From:         from,
+		To:           to,
+		Quantity:     quantity,
+		Converted:    converted,
+		BaseQuantity: base,
+	}, nil
+}
+
+// stockError maps repository errors from a stock movement to the messages
+// handlers report
+func stockError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("amenity not found: %w", err)
+	}
+	if errors.Is(err, ErrInsufficientUnexpiredStock) {
+		return err
+	}
+	switch err.Error() {
+	case "stock quantity cannot be negative":
+		return errors.New("insufficient stock")
+	case "location not found", "insufficient stock in lot", "lot not found at this location", "stock value is too large",
+		"kits hold no stock of their own":
+		return err
+	}
+	return fmt.Errorf("failed to adjust stock: %w", err)
+}
+
+// movementResult summarizes an applied movement
+func movementResult(movement *StockMovement) *StockAdjustmentResult {
+	result := &StockAdjustmentResult{
+		AmenityID:  movement.AmenityID,
+		MovementID: movement.ID,
+		Delta:      movement.Delta,
+		Balance:    movement.BalanceAfter,
+		Value:      movement.Value,
+		StockValue: movement.ValueAfter,
+	}
+	if movement.LocationID != nil {
+		result.LocationID = *movement.LocationID
+	}
+	if movement.LocationBalanceAfter != nil {
+		result.LocationBalance = *movement.LocationBalanceAfter
+	}
+	return result
+}
+
+// GetLocationStock retrieves the amenities held at a location, optionally
+// only those below the location's own minimum
+func (s *Service) GetLocationStock(locationID string, lowOnly bool) ([]AmenityStock, error) {
+	if _, err := s.locations.GetByID(locationID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStockByLocation(locationID, lowOnly)
+}
+
+// GetLowStockAtLocations retrieves every amenity below a per-location
+// minimum anywhere in the tenant
+func (s *Service) GetLowStockAtLocations(tenantID string) ([]AmenityStock, error) {
+	return s.repo.GetLowStockAtLocations(tenantID)
+}
+
+// SetLocationMinimum sets the low-stock threshold for an amenity at one
+// location. A threshold of zero disables the rule.
+func (s *Service) SetLocationMinimum(id, locationID string, minimum int) (*AmenityStock, error) {
+	if minimum < 0 {
+		return nil, errors.New("minimum stock cannot be negative")
+	}
+
+	amenity, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("amenity not found: %w", err)
+	}
+	location, err := s.locations.GetByID(locationID)
+	if err != nil {
+		return nil, err
+	}
+	if location.TenantID != amenity.TenantID {
+		return nil, errors.New("location not found")
+	}
+
+	return s.repo.SetLocationMinimum(amenity, locationID, minimum)
+}
+
+// BackfillLocationStock moves stock recorded before locations existed to
+// each tenant's default location
+func (s *Service) BackfillLocationStock() (int, error) {
+	return s.repo.BackfillLocationStock()
+}
+
+// GetMovements retrieves the stock movement ledger for an amenity
+func (s *Service) GetMovements(id string, filter MovementFilter, page, pageSize int) ([]StockMovement, int64, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, 0, fmt.Errorf("amenity not found: %w", err)
+	}
+	if filter.Reason != "" && !ValidMovementReason(filter.Reason) {
+		return nil, 0, errors.New("invalid movement reason")
+	}
+	return s.repo.GetMovements(id, filter, page, pageSize)
+}
+
+// GetLots retrieves an amenity's lots, first to expire first, optionally
+// with those already used up
+func (s *Service) GetLots(id string, includeEmpty bool) ([]StockLot, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, fmt.Errorf("amenity not found: %w", err)
+	}
+	return s.repo.GetLots(LotFilter{AmenityID: id, IncludeEmpty: includeEmpty})
+}
+
+// GetExpiringLots reports a tenant's lots that expire within days, or
+// have expired, first to expire first
+func (s *Service) GetExpiringLots(tenantID, locationID string, days int) ([]StockLot, error) {
+	before := today().AddDate(0, 0, days)
+	return s.repo.GetLots(LotFilter{TenantID: tenantID, LocationID: locationID, ExpiresBefore: &before})
+}
+
+// WriteOffLot removes what is left of an expired lot as an expired movement
+func (s *Service) WriteOffLot(id, lotID string, req *WriteOffRequest, actorID *string) (*StockAdjustmentResult, error) {
+	lot, err := s.repo.GetLot(lotID)
+	if err != nil || lot.AmenityID != id {
+		return nil, errors.New("lot not found")
+	}
+	if !lot.Expired {
+		return nil, errors.New("lot has not expired")
+	}
+	if lot.Quantity == 0 {
+		return nil, errors.New("lot is already used up")
+	}
+
+	movement := writeOffMovement(lot, req, actorID)
+	if err := s.repo.ApplyMovement(movement); err != nil {
+		return nil, stockError(err)
+	}
+	return movementResult(movement), nil
+}
+
+// WriteOffExpired removes every expired lot of a tenant, or of one of its
+// locations, each as its own expired movement. Either all are written off
+// or none.
+func (s *Service) WriteOffExpired(req *WriteOffRequest, actorID *string) (*WriteOffResult, error) {
+	before := today().AddDate(0, 0, -1)
+	lots, err := s.repo.GetLots(LotFilter{TenantID: req.TenantID, LocationID: req.LocationID, ExpiresBefore: &before})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expired lots: %w", err)
+	}
+
+	result := &WriteOffResult{Movements: []StockAdjustmentResult{}}
+	err = s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		for i := range lots {
+			movement := writeOffMovement(&lots[i], req, actorID)
+			if err := repo.ApplyMovement(movement); err != nil {
+				return err
+			}
+			result.WrittenOff++
+			result.Quantity += lots[i].Quantity
+			result.Movements = append(result.Movements, *movementResult(movement))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, stockError(err)
+	}
+	return result, nil
+}
+
+// writeOffMovement builds the movement that removes what is left of a lot
+func writeOffMovement(lot *StockLot, req *WriteOffRequest, actorID *string) *StockMovement {
+	locationID := lot.LocationID
+	reference := req.Reference
+	if reference == "" && lot.LotNumber != "" {
+		reference = "lot " + lot.LotNumber
+	}
+	return &StockMovement{
+		AmenityID:  lot.AmenityID,
+		LocationID: &locationID,
+		Lot:        &LotSpec{LotID: lot.ID},
+		Delta:      -lot.Quantity,
+		Reason:     MovementExpired,
+		Reference:  reference,
+		Note:       req.Note,
+		ActorID:    actorID,
+	}
+}
+
 // DeleteAmenity deletes an amenity
 func (s *Service) DeleteAmenity(id string) error {
 	// Check if amenity exists
@@ -152,6 +955,15 @@ func (s *Service) DeleteAmenity(id string) error {
 		return fmt.Errorf("amenity not found: %w", err)
 	}
 
+	// Kits would be left short of a component
+	inKit, err := s.repo.IsKitComponent(id)
+	if err != nil {
+		return fmt.Errorf("failed to check kits: %w", err)
+	}
+	if inKit {
+		return errors.New("amenity is in use by kits")
+	}
+
 	if err := s.repo.Delete(id); err != nil {
 		return fmt.Errorf("failed to delete amenity: %w", err)
 	}
@@ -0,0 +1,223 @@
+package amenities
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultBaseUnit is the base unit of amenities that do not name one
+const DefaultBaseUnit = "unit"
+
+// Limits on an amenity's units of measure
+const (
+	maxUnitNameLength = 32
+	maxUnits          = 10
+)
+
+// AmenityUnit is a unit of measure an amenity is handled in, such as a box
+// or a case. Factor is how many base units one of it holds, so a case of
+// 48 bottles has factor 48 when the base unit is the bottle.
+type AmenityUnit struct {
+	ID        string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID  string    `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	AmenityID string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_unit_amenity_name" json:"amenityId"`
+	Name      string    `gorm:"type:varchar(32);not null;uniqueIndex:idx_unit_amenity_name" json:"name"`
+	Factor    int       `gorm:"not null" json:"factor"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (AmenityUnit) TableName() string {
+	return "amenity_units"
+}
+
+// UnitInput is a unit of measure given for an amenity
+type UnitInput struct {
+	Name   string `json:"name" binding:"required"`
+	Factor int    `json:"factor" binding:"required,gt=0"`
+}
+
+// UnitQuantity is a quantity of base units expressed in a larger unit:
+// Quantity whole units and the Remainder base units that do not fill one
+type UnitQuantity struct {
+	Unit      string `json:"unit"`
+	Quantity  int    `json:"quantity"`
+	Remainder int    `json:"remainder"`
+	Text      string `json:"text"` // e.g. "2 box + 6 bottle"
+}
+
+// ConversionResult reports an exact conversion between two of an
+// amenity's units
+type ConversionResult struct {
+	AmenityID    string `json:"amenityId"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+	Quantity     int    `json:"quantity"`
+	Converted    int    `json:"converted"`
+	BaseQuantity int    `json:"baseQuantity"`
+}
+
+// orderedUnits preloads an amenity's units from the smallest up
+func orderedUnits(db *gorm.DB) *gorm.DB {
+	return db.Order("factor ASC, name ASC")
+}
+
+// baseUnit returns the name of the amenity's base unit
+func (a *Amenity) baseUnit() string {
+	if a.BaseUnit == "" {
+		return DefaultBaseUnit
+	}
+	return a.BaseUnit
+}
+
+// UnitFactor returns how many base units one of the named unit holds.
+// Names are matched case-insensitively; the base unit has factor 1.
+func (a *Amenity) UnitFactor(name string) (int, bool) {
+	if strings.EqualFold(name, a.baseUnit()) {
+		return 1, true
+	}
+	for _, unit := range a.Units {
+		if strings.EqualFold(unit.Name, name) {
+			return unit.Factor, true
+		}
+	}
+	return 0, false
+}
+
+// ToBase converts quantity in unit to base units. An empty unit is the
+// base unit.
+func (a *Amenity) ToBase(quantity int, unit string) (int, error) {
+	if unit == "" {
+		return quantity, nil
+	}
+	factor, ok := a.UnitFactor(unit)
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", unit)
+	}
+	if quantity > math.MaxInt32/factor || quantity < math.MinInt32/factor {
+		return 0, errors.New("quantity is too large")
+	}
+	return quantity * factor, nil
+}
+
+// FromBase converts a quantity of base units to unit, refusing quantities
+// that are not a whole number of it
+func (a *Amenity) FromBase(quantity int, unit string) (int, error) {
+	factor, ok := a.UnitFactor(unit)
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", unit)
+	}
+	if quantity%factor != 0 {
+		return 0, fmt.Errorf("%d %s is not a whole number of %s", quantity, a.baseUnit(), unit)
+	}
+	return quantity / factor, nil
+}
+
+// InUnit breaks a quantity of base units down into whole units of unit
+// and the base units left over
+func (a *Amenity) InUnit(quantity int, unit string) (*UnitQuantity, error) {
+	factor, ok := a.UnitFactor(unit)
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", unit)
+	}
+	result := &UnitQuantity{Unit: unit, Quantity: quantity / factor, Remainder: quantity % factor}
+	result.Text = fmt.Sprintf("%d %s", result.Quantity, unit)
+	if result.Remainder != 0 {
+		result.Text += fmt.Sprintf(" + %d %s", result.Remainder, a.baseUnit())
+	}
+	return result, nil
+}
+
+// AfterFind works out the average cost of the stock, how many kits the
+// components of a kit make up when they were loaded, and expresses the
+// stock in the amenity's preferred unit, when it has one other than the
+// base unit and its units were loaded
+func (a *Amenity) AfterFind(tx *gorm.DB) error {
+	a.AverageCost = a.averageCost()
+	a.KitsAvailable = nil
+	if a.IsKit() && len(a.Components) > 0 {
+		available := a.kitsAvailable()
+		a.KitsAvailable = &available
+	}
+	a.StockDisplay = nil
+	if a.PreferredUnit == "" || strings.EqualFold(a.PreferredUnit, a.baseUnit()) {
+		return nil
+	}
+	display, err := a.InUnit(a.Stock, a.PreferredUnit)
+	if err == nil {
+		a.StockDisplay = display
+	}
+	return nil
+}
+
+// checkUnits validates an amenity's base unit, its other units and the
+// preferred unit among them, and returns the units ready to store
+func checkUnits(tenantID, amenityID, baseUnit, preferredUnit string, inputs []UnitInput) ([]AmenityUnit, error) {
+	if baseUnit == "" || len(baseUnit) > maxUnitNameLength {
+		return nil, fmt.Errorf("invalid unit: base unit must be 1 to %d characters", maxUnitNameLength)
+	}
+	if len(inputs) > maxUnits {
+		return nil, fmt.Errorf("invalid unit: an amenity can have at most %d units", maxUnits)
+	}
+
+	seen := map[string]bool{strings.ToLower(baseUnit): true}
+	units := make([]AmenityUnit, 0, len(inputs))
+	for _, input := range inputs {
+		name := strings.TrimSpace(input.Name)
+		if name == "" || len(name) > maxUnitNameLength {
+			return nil, fmt.Errorf("invalid unit: name must be 1 to %d characters", maxUnitNameLength)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("invalid unit: %q is defined twice", name)
+		}
+		if input.Factor < 1 || input.Factor > math.MaxInt32 {
+			return nil, fmt.Errorf("invalid unit: factor of %q must be a whole number of %s", name, baseUnit)
+		}
+		seen[strings.ToLower(name)] = true
+
+		units = append(units, AmenityUnit{
+			ID:        uuid.New().String(),
+			TenantID:  tenantID,
+			AmenityID: amenityID,
+			Name:      name,
+			Factor:    input.Factor,
+		})
+	}
+
+	if preferredUnit != "" && !seen[strings.ToLower(preferredUnit)] {
+		return nil, fmt.Errorf("invalid unit: preferred unit %q is not defined", preferredUnit)
+	}
+	return units, nil
+}
+
+// CopyUnits copies an amenity's units, with new IDs, for an amenity of
+// another tenant, as when copying or importing a catalog
+func CopyUnits(tenantID, amenityID string, units []AmenityUnit) []AmenityUnit {
+	copies := make([]AmenityUnit, 0, len(units))
+	for _, unit := range units {
+		copies = append(copies, AmenityUnit{
+			ID:        uuid.New().String(),
+			TenantID:  tenantID,
+			AmenityID: amenityID,
+			Name:      unit.Name,
+			Factor:    unit.Factor,
+		})
+	}
+	return copies
+}
+
+// ValidateUnits checks the units of an amenity read from outside, such as
+// an archive, the way they are checked when entered
+func ValidateUnits(amenity *Amenity) error {
+	inputs := make([]UnitInput, 0, len(amenity.Units))
+	for _, unit := range amenity.Units {
+		inputs = append(inputs, UnitInput{Name: unit.Name, Factor: unit.Factor})
+	}
+	_, err := checkUnits(amenity.TenantID, amenity.ID, amenity.baseUnit(), amenity.PreferredUnit, inputs)
+	return err
+}
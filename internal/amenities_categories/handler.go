@@ -1,8 +1,13 @@
 package amenities_categories
 
 import (
+	"concierge-be/database"
+	"concierge-be/internal/quotas"
 	"concierge-be/utils"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,6 +22,11 @@ func NewHandler() *Handler {
 	}
 }
 
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
 // CreateCategory handles POST /api/v1/amenities-categories
 func (h *Handler) CreateCategory(c *gin.Context) {
 	var req CreateAmenityCategoryRequest
@@ -25,13 +35,13 @@ func (h *Handler) CreateCategory(c *gin.Context) {
 		return
 	}
 
-	category, err := h.service.CreateCategory(&req)
+	category, err := h.serviceFor(c).CreateCategory(&req)
 	if err != nil {
-		if err.Error() == "category name already exists for this tenant" {
-			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		if errors.Is(err, quotas.ErrQuotaExceeded) {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
 			return
 		}
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		categoryErrorResponse(c, err)
 		return
 	}
 
@@ -46,69 +56,259 @@ func (h *Handler) CreateCategory(c *gin.Context) {
 func (h *Handler) GetCategory(c *gin.Context) {
 	id := c.Param("id")
 
-	category, err := h.service.GetCategoryByID(id)
+	category, err := h.serviceFor(c).GetCategoryByID(id)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Category not found")
 		return
 	}
 
+	utils.SetETag(c, category.Version)
 	utils.SuccessResponse(c, category)
 }
 
-// GetAllCategories handles GET /api/v1/amenities-categories
-// Optionally filtered by tenantId query parameter
+// GetAllCategories handles GET /api/v1/amenities-categories?tenantId=
+// Supports updatedSince (RFC3339 or YYYY-MM-DD), sort=name|updatedAt with
+// order=asc|desc, and page/pageSize pagination. Passing cursor (empty for the
+// first page) switches to cursor pagination.
 func (h *Handler) GetAllCategories(c *gin.Context) {
-	tenantID := c.Query("tenantId")
+	filter := ListFilter{TenantID: c.Query("tenantId")}
+	if filter.TenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+	if value := c.Query("updatedSince"); value != "" {
+		t, _, err := utils.ParseDateParam(value)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "updatedSince must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filter.UpdatedSince = &t
+	}
 
-	var categories []AmenityCategory
-	var err error
+	sort := ListSort{Field: c.DefaultQuery("sort", SortName)}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		sort.Desc = true
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "order must be asc or desc")
+		return
+	}
 
-	if tenantID != "" {
-		categories, err = h.service.GetCategoriesByTenantID(tenantID)
-	} else {
-		categories, err = h.service.GetAllCategories()
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
 	}
 
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		categories, total, next, err := h.serviceFor(c).ListCategoriesAfter(filter, sort, cursor, pageSize)
+		if err != nil {
+			listErrorResponse(c, err)
+			return
+		}
+		utils.SuccessResponseWithCursor(c, categories, pageSize, int(total), next)
+		return
+	}
+
+	categories, total, err := h.serviceFor(c).ListCategories(filter, sort, page, pageSize)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		listErrorResponse(c, err)
 		return
 	}
 
-	utils.SuccessResponse(c, categories)
+	utils.SuccessResponseWithPagination(c, categories, page, pageSize, int(total))
+}
+
+// listErrorResponse maps category listing errors to HTTP responses
+func listErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, database.ErrInvalidCursor) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "cursor is invalid or was issued for a different sort")
+		return
+	}
+	if err.Error() == "invalid sort field" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "sort must be name or updatedAt")
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 }
 
 // UpdateCategory handles PUT /api/v1/amenities-categories/:id
+// Requires If-Match with the version from the category's ETag
 func (h *Handler) UpdateCategory(c *gin.Context) {
 	id := c.Param("id")
 
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	var req UpdateAmenityCategoryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	category, err := h.service.UpdateCategory(id, &req)
+	category, err := h.serviceFor(c).UpdateCategory(id, &req, version)
 	if err != nil {
-		if err.Error() == "category name already exists for this tenant" {
-			utils.ErrorResponse(c, http.StatusConflict, err.Error())
-			return
-		}
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		categoryErrorResponse(c, err)
 		return
 	}
 
+	utils.SetETag(c, category.Version)
 	utils.SuccessResponse(c, category)
 }
 
 // DeleteCategory handles DELETE /api/v1/amenities-categories/:id
+// A category amenities still use is only deleted with ?reassignTo=<categoryId>,
+// which moves them to that category first.
 func (h *Handler) DeleteCategory(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.service.DeleteCategory(id); err != nil {
+	result, err := h.serviceFor(c).DeleteCategory(id, c.Query("reassignTo"))
+	if err != nil {
+		categoryErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Category deleted successfully", "reassigned": result.Reassigned})
+}
+
+// MergeCategory handles POST /api/v1/amenities-categories/:id/merge
+func (h *Handler) MergeCategory(c *gin.Context) {
+	var req MergeCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	merge, err := h.serviceFor(c).MergeCategory(c.Param("id"), &req, utils.ActorID(c))
+	if err != nil {
+		categoryErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, merge)
+}
+
+// GetMerges handles GET /api/v1/amenities-categories/merges?tenantId=
+func (h *Handler) GetMerges(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	merges, total, err := h.serviceFor(c).GetMergesByTenantID(tenantID, page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithPagination(c, merges, page, pageSize, int(total))
+}
+
+// GetMerge handles GET /api/v1/amenities-categories/merges/:mergeId
+func (h *Handler) GetMerge(c *gin.Context) {
+	merge, err := h.serviceFor(c).GetMergeByID(c.Param("mergeId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, merge)
+}
+
+// GetCategoryTree handles GET /api/v1/amenities-categories/tree?tenantId=
+func (h *Handler) GetCategoryTree(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+
+	tree, err := h.serviceFor(c).GetCategoryTree(tenantID)
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.SuccessResponse(c, gin.H{"message": "Category deleted successfully"})
+	utils.SuccessResponse(c, tree)
+}
+
+// GetSubtree handles GET /api/v1/amenities-categories/:id/subtree
+func (h *Handler) GetSubtree(c *gin.Context) {
+	subtree, err := h.serviceFor(c).GetSubtree(c.Param("id"))
+	if err != nil {
+		categoryErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, subtree)
+}
+
+// MoveCategory handles POST /api/v1/amenities-categories/:id/move
+// Requires If-Match with the version from the category's ETag
+func (h *Handler) MoveCategory(c *gin.Context) {
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req MoveCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	category, err := h.serviceFor(c).MoveCategory(c.Param("id"), &req, version)
+	if err != nil {
+		categoryErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, category.Version)
+	utils.SuccessResponse(c, category)
+}
+
+// categoryErrorResponse maps category errors to HTTP responses
+func categoryErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, database.ErrVersionConflict) {
+		utils.ErrorResponse(c, http.StatusPreconditionFailed, "Category has been modified; reload and retry")
+		return
+	}
+	if errors.Is(err, errTooDeep) {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if strings.HasPrefix(err.Error(), "merge target already has a subcategory named") {
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	switch err.Error() {
+	case "category not found", "tenant not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case "parent category not found", "category cannot be moved under itself or its descendants",
+		"reassignment category not found", "amenities cannot be reassigned to the category being deleted",
+		"merge target category not found", "a category cannot be merged into itself",
+		"a category cannot be merged into its own subcategory":
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case "category name already exists under this parent", "category has subcategories", "category is in use by amenities":
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
 }
 
@@ -6,12 +6,17 @@ import (
 	"gorm.io/gorm"
 )
 
-// AmenityCategory represents a category for amenities (tenant-scoped)
+// AmenityCategory represents a category for amenities (tenant-scoped).
+// Categories form a tree per tenant, at most MaxDepth levels deep; names are
+// unique among siblings and SortOrder orders siblings for display.
 type AmenityCategory struct {
 	ID          string    `gorm:"type:varchar(36);primaryKey" json:"id"`
 	TenantID    string    `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	ParentID    *string   `gorm:"type:varchar(36);index" json:"parentId"`
 	Name        string    `gorm:"type:varchar(100);not null" json:"name"`
+	SortOrder   int       `gorm:"not null;default:0" json:"sortOrder"`
 	Description string    `gorm:"type:text" json:"description"`
+	Version     int64     `gorm:"not null;default:1" json:"version"`
 	CreatedAt   time.Time `json:"createdAt"`
 	UpdatedAt   time.Time `json:"updatedAt"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -21,11 +26,37 @@ func (AmenityCategory) TableName() string {
 	return "amenities_categories"
 }
 
-// CreateAmenityCategoryRequest represents the request body for creating a category
+// ListFilter narrows a category listing to one tenant
+type ListFilter struct {
+	TenantID     string
+	UpdatedSince *time.Time
+}
+
+// Sort fields for category listings
+const (
+	SortName      = "name"
+	SortUpdatedAt = "updatedAt"
+)
+
+// sortColumns maps each sort field to its column
+var sortColumns = map[string]string{
+	SortName:      "name",
+	SortUpdatedAt: "updated_at",
+}
+
+// ListSort orders a category listing. Ties are broken by ID.
+type ListSort struct {
+	Field string
+	Desc  bool
+}
+
+// CreateAmenityCategoryRequest represents the request body for creating a
+// category. It is placed last among its siblings.
 type CreateAmenityCategoryRequest struct {
-	TenantID    string `json:"tenantId" binding:"required"`
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
+	TenantID    string  `json:"tenantId" binding:"required"`
+	ParentID    *string `json:"parentId"`
+	Name        string  `json:"name" binding:"required"`
+	Description string  `json:"description"`
 }
 
 // UpdateAmenityCategoryRequest represents the request body for updating a category
@@ -34,3 +65,65 @@ type UpdateAmenityCategoryRequest struct {
 	Description string `json:"description"`
 }
 
+// MoveCategoryRequest represents the request body for moving a category. An
+// empty parentId moves it to the top level. Position is its zero-based place
+// among its new siblings; it goes last if omitted.
+type MoveCategoryRequest struct {
+	ParentID *string `json:"parentId"`
+	Position *int    `json:"position"`
+}
+
+// Merge item kinds
+const (
+	MergeItemAmenity     = "amenity"
+	MergeItemSubcategory = "subcategory"
+)
+
+// CategoryMerge records a category folded into another: its amenities and
+// subcategories moved to the target and it was deleted. SourceName and
+// SourcePath keep the deleted category's name as it was.
+type CategoryMerge struct {
+	ID                 string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID           string    `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	SourceID           string    `gorm:"type:varchar(36);not null;index" json:"sourceId"`
+	SourceName         string    `gorm:"type:varchar(100);not null" json:"sourceName"`
+	SourcePath         string    `gorm:"type:varchar(500);not null" json:"sourcePath"`
+	TargetID           string    `gorm:"type:varchar(36);not null;index" json:"targetId"`
+	AmenitiesMoved     int       `gorm:"not null;default:0" json:"amenitiesMoved"`
+	SubcategoriesMoved int       `gorm:"not null;default:0" json:"subcategoriesMoved"`
+	ActorID            *string   `gorm:"type:varchar(36)" json:"actorId"`
+	CreatedAt          time.Time `json:"createdAt"`
+
+	// Relationships
+	Target *AmenityCategory    `gorm:"foreignKey:TargetID;references:ID" json:"target,omitempty"`
+	Items  []CategoryMergeItem `gorm:"foreignKey:MergeID;references:ID" json:"items,omitempty"`
+}
+
+func (CategoryMerge) TableName() string {
+	return "category_merges"
+}
+
+// CategoryMergeItem is one amenity or subcategory a merge moved
+type CategoryMergeItem struct {
+	ID       string `gorm:"type:varchar(36);primaryKey" json:"id"`
+	MergeID  string `gorm:"type:varchar(36);not null;index" json:"mergeId"`
+	Kind     string `gorm:"type:varchar(20);not null" json:"kind"`
+	RecordID string `gorm:"type:varchar(36);not null;index" json:"recordId"`
+	Name     string `gorm:"type:varchar(100);not null" json:"name"`
+}
+
+func (CategoryMergeItem) TableName() string {
+	return "category_merge_items"
+}
+
+// MergeCategoryRequest represents the request body for merging a category
+// into TargetID
+type MergeCategoryRequest struct {
+	TargetID string `json:"targetId" binding:"required"`
+}
+
+// DeleteResult reports a category deletion and how many amenities were
+// reassigned to another category first
+type DeleteResult struct {
+	Reassigned int64 `json:"reassigned"`
+}
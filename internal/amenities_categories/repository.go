@@ -1,6 +1,8 @@
 package amenities_categories
 
 import (
+	"time"
+
 	"concierge-be/database"
 
 	"gorm.io/gorm"
@@ -16,6 +18,16 @@ func NewRepository() *Repository {
 	}
 }
 
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// Transaction runs fn in a database transaction
+func (r *Repository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
 // Create creates a new amenity category
 func (r *Repository) Create(category *AmenityCategory) error {
 	return r.db.Create(category).Error
@@ -31,6 +43,15 @@ func (r *Repository) GetByID(id string) (*AmenityCategory, error) {
 	return &category, nil
 }
 
+// TenantOf returns the tenant of category id, "" if it does not exist
+func (r *Repository) TenantOf(id string) (string, error) {
+	var tenantIDs []string
+	if err := r.db.Model(&AmenityCategory{}).Where("id = ?", id).Pluck("tenant_id", &tenantIDs).Error; err != nil || len(tenantIDs) == 0 {
+		return "", err
+	}
+	return tenantIDs[0], nil
+}
+
 // GetByTenantID retrieves all amenity categories for a specific tenant
 func (r *Repository) GetByTenantID(tenantID string) ([]AmenityCategory, error) {
 	var categories []AmenityCategory
@@ -41,19 +62,60 @@ func (r *Repository) GetByTenantID(tenantID string) ([]AmenityCategory, error) {
 	return categories, nil
 }
 
-// GetAll retrieves all amenity categories
-func (r *Repository) GetAll() ([]AmenityCategory, error) {
+// filtered returns a reusable query for the categories matching filter
+func (r *Repository) filtered(filter ListFilter) *gorm.DB {
+	query := r.db.Model(&AmenityCategory{}).Where("tenant_id = ?", filter.TenantID)
+	if filter.UpdatedSince != nil {
+		query = query.Where("updated_at >= ?", *filter.UpdatedSince)
+	}
+	return query.Session(&gorm.Session{})
+}
+
+// List retrieves a page of the categories matching filter
+func (r *Repository) List(filter ListFilter, sort ListSort, page, pageSize int) ([]AmenityCategory, int64, error) {
 	var categories []AmenityCategory
-	err := r.db.Order("name ASC").Find(&categories).Error
-	if err != nil {
-		return nil, err
+	var total int64
+
+	query := r.filtered(filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
-	return categories, nil
+
+	offset := (page - 1) * pageSize
+	err := database.OrderBy(query, sortColumns[sort.Field], "id", sort.Desc).
+		Offset(offset).Limit(pageSize).
+		Find(&categories).Error
+
+	return categories, total, err
+}
+
+// ListAfter retrieves up to limit categories matching filter that follow
+// after in sort order, or the first ones if after is nil. total counts
+// every match, not just those after the cursor.
+func (r *Repository) ListAfter(filter ListFilter, sort ListSort, after interface{}, afterID string, limit int) ([]AmenityCategory, int64, error) {
+	var categories []AmenityCategory
+	var total int64
+
+	query := r.filtered(filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column := sortColumns[sort.Field]
+	if after != nil {
+		query = database.SeekAfter(query, column, "id", sort.Desc, after, afterID)
+	} else {
+		query = database.OrderBy(query, column, "id", sort.Desc)
+	}
+	err := query.Limit(limit).Find(&categories).Error
+
+	return categories, total, err
 }
 
-// Update updates an existing amenity category
+// Update updates an existing amenity category if it is still at
+// category.Version, returning database.ErrVersionConflict otherwise
 func (r *Repository) Update(category *AmenityCategory) error {
-	return r.db.Save(category).Error
+	return database.UpdateVersioned(r.db, category, &category.Version)
 }
 
 // Delete soft deletes an amenity category
@@ -61,20 +123,127 @@ func (r *Repository) Delete(id string) error {
 	return r.db.Delete(&AmenityCategory{}, "id = ?", id).Error
 }
 
-// CheckNameExists checks if a category name already exists for a tenant
-func (r *Repository) CheckNameExists(tenantID, name, excludeID string) (bool, error) {
+// CheckNameExists checks if a sibling under parentID already uses name
+func (r *Repository) CheckNameExists(tenantID string, parentID *string, name, excludeID string) (bool, error) {
 	var count int64
 	query := r.db.Model(&AmenityCategory{}).Where("tenant_id = ? AND name = ?", tenantID, name)
-	
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
 	if excludeID != "" {
 		query = query.Where("id != ?", excludeID)
 	}
-	
 	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// CountChildren counts the categories directly under id
+func (r *Repository) CountChildren(id string) (int64, error) {
+	var count int64
+	err := r.db.Model(&AmenityCategory{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// GetChildren retrieves the categories directly under parentID, or the top
+// level if it is nil, in display order
+func (r *Repository) GetChildren(tenantID string, parentID *string) ([]AmenityCategory, error) {
+	var categories []AmenityCategory
+	query := r.db.Where("tenant_id = ?", tenantID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	err := query.Order("sort_order ASC, name ASC").Find(&categories).Error
+	return categories, err
+}
+
+// SetSortOrder changes a category's display order, advancing its version
+func (r *Repository) SetSortOrder(id string, sortOrder int) error {
+	return r.db.Model(&AmenityCategory{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"sort_order": sortOrder,
+		"version":    gorm.Expr("version + 1"),
+	}).Error
+}
+
+// amenityRef is the part of an amenity a category needs to move it
+type amenityRef struct {
+	ID       string
+	ItemName string
+}
+
+// GetAmenities retrieves the amenities in a category by name
+func (r *Repository) GetAmenities(categoryID string) ([]amenityRef, error) {
+	var refs []amenityRef
+	err := r.db.Table("amenities").Select("id", "item_name").
+		Where("category_id = ? AND deleted_at IS NULL", categoryID).
+		Order("item_name ASC").
+		Find(&refs).Error
+	return refs, err
+}
+
+// CountAmenities counts the amenities in a category
+func (r *Repository) CountAmenities(categoryID string) (int64, error) {
+	var count int64
+	err := r.db.Table("amenities").Where("category_id = ? AND deleted_at IS NULL", categoryID).Count(&count).Error
+	return count, err
+}
+
+// ReassignAmenities moves every amenity in fromID to toID, advancing their
+// versions, and returns how many moved
+func (r *Repository) ReassignAmenities(fromID, toID string) (int64, error) {
+	result := r.db.Table("amenities").
+		Where("category_id = ? AND deleted_at IS NULL", fromID).
+		Updates(map[string]interface{}{
+			"category_id": toID,
+			"version":     gorm.Expr("version + 1"),
+			"updated_at":  time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// SetParent moves a category under parentID at sortOrder, advancing its version
+func (r *Repository) SetParent(id string, parentID *string, sortOrder int) error {
+	return r.db.Model(&AmenityCategory{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"parent_id":  parentID,
+		"sort_order": sortOrder,
+		"version":    gorm.Expr("version + 1"),
+	}).Error
+}
+
+// CreateMerge records a merge with its items
+func (r *Repository) CreateMerge(merge *CategoryMerge) error {
+	return r.db.Omit("Target").Create(merge).Error
+}
+
+// GetMerge retrieves a merge with its target and items
+func (r *Repository) GetMerge(id string) (*CategoryMerge, error) {
+	var merge CategoryMerge
+	err := r.db.Preload("Target").
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("kind ASC, name ASC") }).
+		Where("id = ?", id).First(&merge).Error
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	
-	return count > 0, nil
+	return &merge, nil
 }
 
+// GetMerges retrieves a page of a tenant's merges, newest first
+func (r *Repository) GetMerges(tenantID string, page, pageSize int) ([]CategoryMerge, int64, error) {
+	var merges []CategoryMerge
+	var total int64
+
+	query := r.db.Model(&CategoryMerge{}).Where("tenant_id = ?", tenantID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("Target").Order("created_at DESC, id DESC").
+		Offset(offset).Limit(pageSize).
+		Find(&merges).Error
+
+	return merges, total, err
+}
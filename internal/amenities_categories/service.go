@@ -1,42 +1,90 @@
 package amenities_categories
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/quotas"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Service struct {
-	repo *Repository
+	repo   *Repository
+	quotas *quotas.Service
 }
 
 func NewService() *Service {
 	return &Service{
-		repo: NewRepository(),
+		repo:   NewRepository(),
+		quotas: quotas.NewService(),
 	}
 }
 
-// CreateCategory creates a new amenity category
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	copied := *s
+	copied.repo = s.repo.WithTx(s.repo.db.WithContext(ctx))
+	return &copied
+}
+
+// CreateCategory creates a new amenity category, optionally under a
+// parent, placed last among its siblings
 func (s *Service) CreateCategory(req *CreateAmenityCategoryRequest) (*AmenityCategory, error) {
-	// Check if category name already exists for this tenant
-	exists, err := s.repo.CheckNameExists(req.TenantID, req.Name, "")
+	parentID := normalizeParentID(req.ParentID)
+	if parentID != nil {
+		all, err := s.repo.GetByTenantID(req.TenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load categories: %w", err)
+		}
+		if !containsCategory(all, *parentID) {
+			return nil, errors.New("parent category not found")
+		}
+		if Depth(all, *parentID)+1 > MaxDepth {
+			return nil, errTooDeep
+		}
+	}
+
+	// Check if category name already exists under the parent
+	exists, err := s.repo.CheckNameExists(req.TenantID, parentID, req.Name, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to check category name: %w", err)
 	}
 	if exists {
-		return nil, errors.New("category name already exists for this tenant")
+		return nil, errors.New("category name already exists under this parent")
+	}
+
+	siblings, err := s.repo.GetChildren(req.TenantID, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sibling categories: %w", err)
 	}
 
 	category := &AmenityCategory{
 		ID:          uuid.New().String(),
 		TenantID:    req.TenantID,
+		ParentID:    parentID,
 		Name:        req.Name,
+		SortOrder:   nextSortOrder(siblings),
 		Description: req.Description,
 	}
 
-	if err := s.repo.Create(category); err != nil {
-		return nil, fmt.Errorf("failed to create category: %w", err)
+	// The quota is checked under the tenant's row lock in the creating
+	// transaction, so concurrent creates cannot both take the last slot
+	err = s.repo.Transaction(func(tx *gorm.DB) error {
+		if err := s.quotas.WithTx(tx).CheckLimit(req.TenantID, quotas.ResourceCategories); err != nil {
+			return err
+		}
+		if err := s.repo.WithTx(tx).Create(category); err != nil {
+			return fmt.Errorf("failed to create category: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return category, nil
@@ -47,31 +95,74 @@ func (s *Service) GetCategoryByID(id string) (*AmenityCategory, error) {
 	return s.repo.GetByID(id)
 }
 
-// GetCategoriesByTenantID retrieves all categories for a tenant
-func (s *Service) GetCategoriesByTenantID(tenantID string) ([]AmenityCategory, error) {
-	return s.repo.GetByTenantID(tenantID)
+// ListCategories retrieves a page of a tenant's categories
+func (s *Service) ListCategories(filter ListFilter, sort ListSort, page, pageSize int) ([]AmenityCategory, int64, error) {
+	if _, ok := sortColumns[sort.Field]; !ok {
+		return nil, 0, errors.New("invalid sort field")
+	}
+	return s.repo.List(filter, sort, page, pageSize)
 }
 
-// GetAllCategories retrieves all categories
-func (s *Service) GetAllCategories() ([]AmenityCategory, error) {
-	return s.repo.GetAll()
+// ListCategoriesAfter retrieves the page of a tenant's categories following
+// cursor, or the first page if cursor is empty. The returned cursor leads to
+// the next page and is empty on the last one.
+func (s *Service) ListCategoriesAfter(filter ListFilter, sort ListSort, cursor string, pageSize int) ([]AmenityCategory, int64, string, error) {
+	if _, ok := sortColumns[sort.Field]; !ok {
+		return nil, 0, "", errors.New("invalid sort field")
+	}
+
+	var after interface{}
+	afterID := ""
+	if cursor != "" {
+		decoded, err := database.DecodeCursor(cursor, sort.Field, sort.Desc)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		after = decoded.Value
+		if sort.Field == SortUpdatedAt {
+			if after, err = time.Parse(time.RFC3339Nano, decoded.Value); err != nil {
+				return nil, 0, "", database.ErrInvalidCursor
+			}
+		}
+		afterID = decoded.ID
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	list, total, err := s.repo.ListAfter(filter, sort, after, afterID, pageSize+1)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if len(list) <= pageSize {
+		return list, total, "", nil
+	}
+
+	list = list[:pageSize]
+	last := list[pageSize-1]
+	next := database.Cursor{Sort: sort.Field, Desc: sort.Desc, Value: last.Name, ID: last.ID}
+	if sort.Field == SortUpdatedAt {
+		next.Value = last.UpdatedAt.Format(time.RFC3339Nano)
+	}
+	return list, total, next.Encode(), nil
 }
 
-// UpdateCategory updates an existing category
-func (s *Service) UpdateCategory(id string, req *UpdateAmenityCategoryRequest) (*AmenityCategory, error) {
+// UpdateCategory updates an existing category the caller last read at version
+func (s *Service) UpdateCategory(id string, req *UpdateAmenityCategoryRequest, version int64) (*AmenityCategory, error) {
 	category, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, fmt.Errorf("category not found: %w", err)
 	}
+	if category.Version != version {
+		return nil, database.ErrVersionConflict
+	}
 
-	// If name is being updated, check for duplicates
+	// If name is being updated, check for duplicates among its siblings
 	if req.Name != "" && req.Name != category.Name {
-		exists, err := s.repo.CheckNameExists(category.TenantID, req.Name, id)
+		exists, err := s.repo.CheckNameExists(category.TenantID, category.ParentID, req.Name, id)
 		if err != nil {
 			return nil, fmt.Errorf("failed to check category name: %w", err)
 		}
 		if exists {
-			return nil, errors.New("category name already exists for this tenant")
+			return nil, errors.New("category name already exists under this parent")
 		}
 		category.Name = req.Name
 	}
@@ -87,18 +178,327 @@ func (s *Service) UpdateCategory(id string, req *UpdateAmenityCategoryRequest) (
 	return category, nil
 }
 
-// DeleteCategory deletes a category
-func (s *Service) DeleteCategory(id string) error {
-	// Check if category exists
-	_, err := s.repo.GetByID(id)
+// GetCategoryTree retrieves a tenant's categories arranged as a tree
+func (s *Service) GetCategoryTree(tenantID string) ([]*CategoryNode, error) {
+	all, err := s.repo.GetByTenantID(tenantID)
 	if err != nil {
-		return fmt.Errorf("category not found: %w", err)
+		return nil, err
 	}
+	return BuildTree(all), nil
+}
 
-	if err := s.repo.Delete(id); err != nil {
-		return fmt.Errorf("failed to delete category: %w", err)
+// GetSubtree retrieves a category with every category below it
+func (s *Service) GetSubtree(id string) (*CategoryNode, error) {
+	category, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("category not found")
+	}
+	all, err := s.repo.GetByTenantID(category.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
 	}
 
-	return nil
+	inSubtree := make(map[string]bool)
+	for _, descendant := range Descendants(all, id) {
+		inSubtree[descendant] = true
+	}
+	subtree := make([]AmenityCategory, 0, len(inSubtree))
+	for _, c := range all {
+		if inSubtree[c.ID] {
+			subtree = append(subtree, c)
+		}
+	}
+
+	// The category's parent is outside the subtree, so it is the only root
+	for _, root := range BuildTree(subtree) {
+		if root.ID == id {
+			return root, nil
+		}
+	}
+	return nil, errors.New("category not found")
 }
 
+// MoveCategory moves a category the caller last read at version under a
+// new parent, or to the top level, and places it among its new siblings.
+// Its subcategories move with it. Moves that would create a cycle or nest
+// deeper than MaxDepth are rejected.
+func (s *Service) MoveCategory(id string, req *MoveCategoryRequest, version int64) (*AmenityCategory, error) {
+	var moved *AmenityCategory
+	err := s.repo.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		category, err := repo.GetByID(id)
+		if err != nil {
+			return errors.New("category not found")
+		}
+		if category.Version != version {
+			return database.ErrVersionConflict
+		}
+
+		all, err := repo.GetByTenantID(category.TenantID)
+		if err != nil {
+			return fmt.Errorf("failed to load categories: %w", err)
+		}
+
+		parentID := normalizeParentID(req.ParentID)
+		depth := 1
+		if parentID != nil {
+			if !containsCategory(all, *parentID) {
+				return errors.New("parent category not found")
+			}
+			if IsDescendant(all, *parentID, id) {
+				return errors.New("category cannot be moved under itself or its descendants")
+			}
+			depth = Depth(all, *parentID) + 1
+		}
+		if depth+Height(all, id)-1 > MaxDepth {
+			return errTooDeep
+		}
+
+		exists, err := repo.CheckNameExists(category.TenantID, parentID, category.Name, id)
+		if err != nil {
+			return fmt.Errorf("failed to check category name: %w", err)
+		}
+		if exists {
+			return errors.New("category name already exists under this parent")
+		}
+
+		children, err := repo.GetChildren(category.TenantID, parentID)
+		if err != nil {
+			return fmt.Errorf("failed to load sibling categories: %w", err)
+		}
+		siblings := make([]AmenityCategory, 0, len(children))
+		for _, child := range children {
+			if child.ID != id {
+				siblings = append(siblings, child)
+			}
+		}
+
+		position := len(siblings)
+		if req.Position != nil && *req.Position >= 0 && *req.Position < position {
+			position = *req.Position
+		}
+
+		// Renumber the siblings around the moved category so the order is dense
+		for i, sibling := range siblings {
+			order := i
+			if i >= position {
+				order = i + 1
+			}
+			if sibling.SortOrder != order {
+				if err := repo.SetSortOrder(sibling.ID, order); err != nil {
+					return fmt.Errorf("failed to reorder categories: %w", err)
+				}
+			}
+		}
+
+		category.ParentID = parentID
+		category.SortOrder = position
+		if err := repo.Update(category); err != nil {
+			return err
+		}
+		moved = category
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// DeleteCategory deletes a category without subcategories. A category
+// that amenities still use is only deleted when reassignTo names another
+// category of the tenant; its amenities move there in the same transaction.
+func (s *Service) DeleteCategory(id, reassignTo string) (*DeleteResult, error) {
+	result := &DeleteResult{}
+	err := s.repo.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		category, err := repo.GetByID(id)
+		if err != nil {
+			return errors.New("category not found")
+		}
+
+		children, err := repo.CountChildren(id)
+		if err != nil {
+			return fmt.Errorf("failed to check subcategories: %w", err)
+		}
+		if children > 0 {
+			return errors.New("category has subcategories")
+		}
+
+		if reassignTo != "" {
+			if reassignTo == id {
+				return errors.New("amenities cannot be reassigned to the category being deleted")
+			}
+			target, err := repo.GetByID(reassignTo)
+			if err != nil || target.TenantID != category.TenantID {
+				return errors.New("reassignment category not found")
+			}
+			if result.Reassigned, err = repo.ReassignAmenities(id, reassignTo); err != nil {
+				return fmt.Errorf("failed to reassign amenities: %w", err)
+			}
+		} else {
+			inUse, err := repo.CountAmenities(id)
+			if err != nil {
+				return fmt.Errorf("failed to check amenities: %w", err)
+			}
+			if inUse > 0 {
+				return errors.New("category is in use by amenities")
+			}
+		}
+
+		if err := repo.Delete(id); err != nil {
+			return fmt.Errorf("failed to delete category: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// MergeCategory folds a category into req.TargetID: its amenities and
+// subcategories move to the target, it is deleted, and what moved is
+// recorded. Moved subcategories keep their order, after the target's own.
+func (s *Service) MergeCategory(id string, req *MergeCategoryRequest, actorID *string) (*CategoryMerge, error) {
+	var merge *CategoryMerge
+	err := s.repo.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		source, err := repo.GetByID(id)
+		if err != nil {
+			return errors.New("category not found")
+		}
+		if req.TargetID == id {
+			return errors.New("a category cannot be merged into itself")
+		}
+		target, err := repo.GetByID(req.TargetID)
+		if err != nil || target.TenantID != source.TenantID {
+			return errors.New("merge target category not found")
+		}
+
+		all, err := repo.GetByTenantID(source.TenantID)
+		if err != nil {
+			return fmt.Errorf("failed to load categories: %w", err)
+		}
+		if IsDescendant(all, target.ID, source.ID) {
+			return errors.New("a category cannot be merged into its own subcategory")
+		}
+		if Depth(all, target.ID)+Height(all, source.ID)-1 > MaxDepth {
+			return errTooDeep
+		}
+
+		children, err := repo.GetChildren(source.TenantID, &source.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load subcategories: %w", err)
+		}
+		targetChildren, err := repo.GetChildren(target.TenantID, &target.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load subcategories: %w", err)
+		}
+		for _, child := range children {
+			for _, existing := range targetChildren {
+				if existing.Name == child.Name {
+					return fmt.Errorf("merge target already has a subcategory named %q", child.Name)
+				}
+			}
+		}
+
+		amenities, err := repo.GetAmenities(source.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load amenities: %w", err)
+		}
+
+		merge = &CategoryMerge{
+			ID:                 uuid.New().String(),
+			TenantID:           source.TenantID,
+			SourceID:           source.ID,
+			SourceName:         source.Name,
+			SourcePath:         Paths(all)[source.ID],
+			TargetID:           target.ID,
+			AmenitiesMoved:     len(amenities),
+			SubcategoriesMoved: len(children),
+			ActorID:            actorID,
+		}
+		for _, amenity := range amenities {
+			merge.Items = append(merge.Items, CategoryMergeItem{
+				ID: uuid.New().String(), MergeID: merge.ID, Kind: MergeItemAmenity, RecordID: amenity.ID, Name: amenity.ItemName,
+			})
+		}
+
+		order := nextSortOrder(targetChildren)
+		for _, child := range children {
+			if err := repo.SetParent(child.ID, &target.ID, order); err != nil {
+				return fmt.Errorf("failed to move subcategory %q: %w", child.Name, err)
+			}
+			order++
+			merge.Items = append(merge.Items, CategoryMergeItem{
+				ID: uuid.New().String(), MergeID: merge.ID, Kind: MergeItemSubcategory, RecordID: child.ID, Name: child.Name,
+			})
+		}
+
+		if _, err := repo.ReassignAmenities(source.ID, target.ID); err != nil {
+			return fmt.Errorf("failed to move amenities: %w", err)
+		}
+		if err := repo.Delete(source.ID); err != nil {
+			return fmt.Errorf("failed to delete category: %w", err)
+		}
+		if err := repo.CreateMerge(merge); err != nil {
+			return fmt.Errorf("failed to record merge: %w", err)
+		}
+		merge.Target = target
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+// GetMergesByTenantID retrieves a page of a tenant's merges, newest first
+func (s *Service) GetMergesByTenantID(tenantID string, page, pageSize int) ([]CategoryMerge, int64, error) {
+	return s.repo.GetMerges(tenantID, page, pageSize)
+}
+
+// GetMergeByID retrieves a merge with everything it moved
+func (s *Service) GetMergeByID(id string) (*CategoryMerge, error) {
+	merge, err := s.repo.GetMerge(id)
+	if err != nil {
+		return nil, errors.New("merge not found")
+	}
+	return merge, nil
+}
+
+// errTooDeep rejects categories nested deeper than MaxDepth
+var errTooDeep = fmt.Errorf("categories cannot nest more than %d levels deep", MaxDepth)
+
+// normalizeParentID treats an empty parent ID as the top level
+func normalizeParentID(parentID *string) *string {
+	if parentID == nil || *parentID == "" {
+		return nil
+	}
+	return parentID
+}
+
+// containsCategory reports whether id is one of categories
+func containsCategory(categories []AmenityCategory, id string) bool {
+	for _, category := range categories {
+		if category.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// nextSortOrder returns the display order that places a category after siblings
+func nextSortOrder(siblings []AmenityCategory) int {
+	next := 0
+	for _, sibling := range siblings {
+		if sibling.SortOrder >= next {
+			next = sibling.SortOrder + 1
+		}
+	}
+	return next
+}
@@ -0,0 +1,175 @@
+package amenities_categories
+
+import (
+	"sort"
+	"strings"
+)
+
+// MaxDepth is how many levels deep categories may nest, counting the top level
+const MaxDepth = 4
+
+// PathSeparator joins category names into a path such as
+// "Bathroom > Toiletries > Dental"
+const PathSeparator = " > "
+
+// CategoryNode is a category together with its subcategories
+type CategoryNode struct {
+	AmenityCategory
+	Children []*CategoryNode `json:"children"`
+}
+
+// parentsOf maps each category ID to its parent ID
+func parentsOf(categories []AmenityCategory) map[string]*string {
+	parents := make(map[string]*string, len(categories))
+	for i := range categories {
+		parents[categories[i].ID] = categories[i].ParentID
+	}
+	return parents
+}
+
+// IsDescendant reports whether id is ancestorID itself or lies below it in
+// the tree formed by categories
+func IsDescendant(categories []AmenityCategory, id, ancestorID string) bool {
+	parents := parentsOf(categories)
+
+	// The walk is bounded by the number of categories so corrupt data
+	// cannot loop forever
+	current := &id
+	for steps := 0; current != nil && steps <= len(categories); steps++ {
+		if *current == ancestorID {
+			return true
+		}
+		current = parents[*current]
+	}
+	return false
+}
+
+// Depth returns the level of a category, 1 for a top-level category
+func Depth(categories []AmenityCategory, id string) int {
+	parents := parentsOf(categories)
+	depth := 0
+	current := &id
+	for ; current != nil && depth <= len(categories); depth++ {
+		current = parents[*current]
+	}
+	return depth
+}
+
+// Height returns how many levels the subtree under a category spans,
+// 1 for a category without subcategories
+func Height(categories []AmenityCategory, id string) int {
+	height := 0
+	for _, descendant := range Descendants(categories, id) {
+		if d := Depth(categories, descendant); d > height {
+			height = d
+		}
+	}
+	return height - Depth(categories, id) + 1
+}
+
+// Descendants returns the IDs of a category and every category below it
+func Descendants(categories []AmenityCategory, id string) []string {
+	children := make(map[string][]string)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	ids := []string{id}
+	seen := map[string]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// Paths returns the full path of every category, keyed by ID
+func Paths(categories []AmenityCategory) map[string]string {
+	byID := make(map[string]*AmenityCategory, len(categories))
+	for i := range categories {
+		byID[categories[i].ID] = &categories[i]
+	}
+
+	paths := make(map[string]string, len(categories))
+	for _, category := range categories {
+		names := []string{category.Name}
+		current := category.ParentID
+		for steps := 0; current != nil && steps < len(categories); steps++ {
+			parent, ok := byID[*current]
+			if !ok {
+				break
+			}
+			names = append([]string{parent.Name}, names...)
+			current = parent.ParentID
+		}
+		paths[category.ID] = strings.Join(names, PathSeparator)
+	}
+	return paths
+}
+
+// SplitPath splits a category path into its trimmed names
+func SplitPath(path string) []string {
+	names := strings.Split(path, strings.TrimSpace(PathSeparator))
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return names
+}
+
+// SortByDepth orders categories so every parent comes before its children,
+// siblings by display order then name
+func SortByDepth(categories []AmenityCategory) []AmenityCategory {
+	sorted := make([]AmenityCategory, len(categories))
+	copy(sorted, categories)
+	depths := make(map[string]int, len(categories))
+	for _, category := range categories {
+		depths[category.ID] = Depth(categories, category.ID)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if depths[sorted[i].ID] != depths[sorted[j].ID] {
+			return depths[sorted[i].ID] < depths[sorted[j].ID]
+		}
+		return siblingLess(&sorted[i], &sorted[j])
+	})
+	return sorted
+}
+
+// BuildTree arranges categories into a tree with siblings in display
+// order. Categories whose parent is missing are treated as top level.
+func BuildTree(categories []AmenityCategory) []*CategoryNode {
+	sorted := make([]AmenityCategory, len(categories))
+	copy(sorted, categories)
+	sort.SliceStable(sorted, func(i, j int) bool { return siblingLess(&sorted[i], &sorted[j]) })
+
+	nodes := make(map[string]*CategoryNode, len(sorted))
+	for _, category := range sorted {
+		nodes[category.ID] = &CategoryNode{AmenityCategory: category, Children: []*CategoryNode{}}
+	}
+
+	roots := []*CategoryNode{}
+	for _, category := range sorted {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// siblingLess orders siblings by display order, then name
+func siblingLess(a, b *AmenityCategory) bool {
+	if a.SortOrder != b.SortOrder {
+		return a.SortOrder < b.SortOrder
+	}
+	return a.Name < b.Name
+}
@@ -0,0 +1,36 @@
+// Package audit logs every create, update and delete made through the
+// database. A change is attributed to the API request whose context its
+// statement runs with, so handlers reach their services through a copy
+// bound to the request (serviceFor, which calls the service's WithContext)
+// rather than the shared one, whose statements carry no request.
+package audit
+
+import (
+	"context"
+)
+
+// Request identifies the API request a change is made in. Actor is asked
+// for the acting user when a change is recorded, so it sees the user set by
+// authentication that runs after the request is tagged.
+type Request struct {
+	ID    string
+	IP    string
+	Actor func() *string
+}
+
+type requestKey struct{}
+
+// WithRequest returns a copy of ctx carrying req. Database statements run
+// with that context are logged against the request.
+func WithRequest(ctx context.Context, req *Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, req)
+}
+
+// RequestFrom returns the request ctx carries, nil outside of one
+func RequestFrom(ctx context.Context) *Request {
+	if ctx == nil {
+		return nil
+	}
+	req, _ := ctx.Value(requestKey{}).(*Request)
+	return req
+}
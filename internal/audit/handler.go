@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"concierge-be/database"
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// GetAuditLogs handles GET /api/v1/audit-logs
+// Filters: tenantId, entity (table name, e.g. amenities), entityId, actorId,
+// action (create|update|delete|restore), requestId, and from/to (RFC3339 or
+// YYYY-MM-DD; a date-only to includes that whole day). Entries come newest
+// first with cursor pagination: pass the returned nextCursor as cursor.
+// Tenant admins only see their tenant's entries.
+func (h *Handler) GetAuditLogs(c *gin.Context) {
+	filter := ListFilter{
+		TenantID:  tenantScope(c),
+		Entity:    c.Query("entity"),
+		EntityID:  c.Query("entityId"),
+		ActorID:   c.Query("actorId"),
+		Action:    c.Query("action"),
+		RequestID: c.Query("requestId"),
+	}
+	switch filter.Action {
+	case "", ActionCreate, ActionUpdate, ActionDelete, ActionRestore:
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "action must be create, update, delete or restore")
+		return
+	}
+	if from := c.Query("from"); from != "" {
+		t, _, err := utils.ParseDateParam(from)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "from must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filter.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, dateOnly, err := utils.ParseDateParam(to)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "to must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = &t
+	}
+
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	logs, total, next, err := h.service.ListLogs(filter, c.Query("cursor"), pageSize)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidCursor) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "cursor is invalid")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithCursor(c, logs, pageSize, int(total), next)
+}
+
+// GetAuditLog handles GET /api/v1/audit-logs/:id
+func (h *Handler) GetAuditLog(c *gin.Context) {
+	entry, err := h.service.GetLog(c.Param("id"))
+	// Entries of other tenants are hidden rather than forbidden
+	tenantID := tenantScope(c)
+	if err != nil || (tenantID != "" && (entry.TenantID == nil || *entry.TenantID != tenantID)) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Audit log entry not found")
+		return
+	}
+
+	utils.SuccessResponse(c, entry)
+}
+
+// tenantScope returns the tenant a request is held to: a tenant admin's own
+// tenant, or the optional tenantId of a platform admin
+func tenantScope(c *gin.Context) string {
+	if utils.IsPlatformAdmin(c) {
+		return c.Query("tenantId")
+	}
+	return c.GetString("tenant_id")
+}
@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RetentionJob periodically deletes audit log entries older than the
+// configured retention
+type RetentionJob struct {
+	service  *Service
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewRetentionJob creates a job that runs every interval
+func NewRetentionJob(interval time.Duration) *RetentionJob {
+	return &RetentionJob{
+		service:  NewService(),
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Start runs the job in the background until ctx is cancelled
+func (j *RetentionJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			j.Run()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Run deletes what has expired once, logging what it did
+func (j *RetentionJob) Run() {
+	if j.service.Retention() <= 0 {
+		return
+	}
+	deleted, err := j.service.DeleteExpired(j.now().Add(-j.service.Retention()))
+	if err != nil {
+		log.Printf("audit retention: %v", err)
+	}
+	if deleted > 0 {
+		log.Printf("audit retention: deleted %d entries", deleted)
+	}
+}
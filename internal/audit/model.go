@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore" // a soft-deleted record brought back
+)
+
+// Change is one field's value before and after a change. Before is nil for
+// created records and After for deleted ones.
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditLog records one create, update or delete of a record: who made it,
+// in which request, and how each changed field moved. Entity is the table
+// of the record and EntityID its primary key, colon-joined when composite.
+type AuditLog struct {
+	ID        string            `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID  *string           `gorm:"type:varchar(36);index:idx_audit_logs_tenant_created,priority:1" json:"tenantId"`
+	Entity    string            `gorm:"type:varchar(64);not null;index:idx_audit_logs_entity,priority:1" json:"entity"`
+	EntityID  string            `gorm:"type:varchar(110);not null;index:idx_audit_logs_entity,priority:2" json:"entityId"`
+	Action    string            `gorm:"type:varchar(20);not null" json:"action"`
+	ActorID   *string           `gorm:"type:varchar(36);index" json:"actorId"` // nil for anonymous requests and background jobs
+	RequestID string            `gorm:"type:varchar(64);index" json:"requestId,omitempty"`
+	IP        string            `gorm:"type:varchar(45)" json:"ip,omitempty"`
+	Changes   map[string]Change `gorm:"type:mediumtext;serializer:json" json:"changes"` // keyed by field name, e.g. "itemName"
+	CreatedAt time.Time         `gorm:"index:idx_audit_logs_tenant_created,priority:2;index" json:"createdAt"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// ListFilter narrows an audit log listing. Empty fields match everything;
+// From is inclusive and To exclusive.
+type ListFilter struct {
+	TenantID  string
+	Entity    string
+	EntityID  string
+	ActorID   string
+	Action    string
+	RequestID string
+	From      *time.Time
+	To        *time.Time
+}
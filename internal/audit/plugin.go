@@ -0,0 +1,445 @@
+package audit
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// skippedTables are not audited: the log itself, request metering that
+// changes on every API call, and the stock movement ledgers, which already
+// record who moved what
+var skippedTables = map[string]bool{
+	"audit_logs":          true,
+	"usage_counters":      true,
+	"stock_movements":     true,
+	"stock_movement_lots": true,
+}
+
+// ignoredColumns change on every write and are left out of diffs
+var ignoredColumns = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"version":    true,
+}
+
+// redactedColumns hold secrets; the log only notes that they changed
+var redactedColumns = map[string]bool{
+	"password":       true,
+	"webhook_secret": true,
+	"token":          true,
+}
+
+const redacted = "[redacted]"
+
+// commit is gorm's callback ending the transaction around a statement
+const commit = "gorm:commit_or_rollback_transaction"
+
+// snapshotKey holds the rows a statement is about to change
+const snapshotKey = "audit:snapshot"
+
+// Plugin records every create, update and delete gorm runs in the audit
+// log. Updates and deletes read the rows they match before running and,
+// for updates, again after, so diffs hold the stored values whether the
+// statement was given a struct, a map or expressions. Raw SQL is not seen.
+// Entries are written in the statement's transaction and roll back with it.
+type Plugin struct{}
+
+// NewPlugin creates the audit plugin, installed with db.Use
+func NewPlugin() *Plugin {
+	return &Plugin{}
+}
+
+func (p *Plugin) Name() string {
+	return "audit"
+}
+
+// Initialize registers the plugin's callbacks. Those that record changes run
+// before gorm commits its transaction, so entries commit with the change.
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Before(commit).Register("audit:after_create", afterCreate); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("audit:before_update", snapshot); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Before(commit).Register("audit:after_update", afterUpdate); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("audit:before_delete", snapshot); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Before(commit).Register("audit:after_delete", afterDelete)
+}
+
+// audited reports whether the statement's changes are logged
+func audited(db *gorm.DB) bool {
+	stmt := db.Statement
+	return db.Error == nil && !db.DryRun && stmt.Table != "" && !skippedTables[stmt.Table]
+}
+
+// primaryKeys returns the primary key columns of the statement's table,
+// "id" for statements on a bare table
+func primaryKeys(stmt *gorm.Statement) []string {
+	if stmt.Schema != nil && len(stmt.Schema.PrimaryFieldDBNames) > 0 {
+		return stmt.Schema.PrimaryFieldDBNames
+	}
+	return []string{"id"}
+}
+
+func afterCreate(db *gorm.DB) {
+	if !audited(db) || db.Statement.Schema == nil || db.Statement.RowsAffected == 0 {
+		return
+	}
+	stmt := db.Statement
+
+	var entries []AuditLog
+	add := func(rv reflect.Value) {
+		row := map[string]interface{}{}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			value, _ := field.ValueOf(stmt.Context, rv)
+			row[field.DBName] = normalize(value)
+		}
+		if changes := diff(nil, row); len(changes) > 0 {
+			entries = append(entries, newEntry(db, ActionCreate, row, changes))
+		}
+	}
+
+	switch rv := reflect.Indirect(stmt.ReflectValue); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+				add(elem)
+			}
+		}
+	case reflect.Struct:
+		add(rv)
+	}
+	write(db, entries)
+}
+
+// snapshot reads the rows an update or delete is about to change
+func snapshot(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+	stmt := db.Statement
+
+	var conditions []clause.Expression
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			conditions = append(conditions, where.Exprs...)
+		}
+	}
+	// gorm adds the primary key of the model passed in to the WHERE
+	// clause only as it builds the statement
+	if keys := modelKeys(stmt); len(keys) > 0 {
+		column, values := schema.ToQueryValues("", stmt.Schema.PrimaryFieldDBNames, keys)
+		conditions = append(conditions, clause.IN{Column: column, Values: values})
+	}
+	// Without conditions gorm refuses the statement anyway
+	if len(conditions) == 0 {
+		return
+	}
+
+	if !stmt.Unscoped && softDeleted(stmt) {
+		conditions = append(conditions, clause.Eq{Column: clause.Column{Table: stmt.Table, Name: "deleted_at"}, Value: nil})
+	}
+	rows, err := find(db, conditions)
+	if err != nil {
+		db.AddError(fmt.Errorf("audit: failed to read %s before change: %w", stmt.Table, err))
+		return
+	}
+	db.InstanceSet(snapshotKey, rows)
+}
+
+func afterUpdate(db *gorm.DB) {
+	before := snapshotted(db)
+	if len(before) == 0 {
+		return
+	}
+	stmt := db.Statement
+	columns := primaryKeys(stmt)
+
+	keys := make([][]interface{}, 0, len(before))
+	for _, row := range before {
+		keys = append(keys, keyValues(columns, row))
+	}
+	column, values := schema.ToQueryValues(stmt.Table, columns, keys)
+	rows, err := find(db, []clause.Expression{clause.IN{Column: column, Values: values}})
+	if err != nil {
+		db.AddError(fmt.Errorf("audit: failed to read %s after change: %w", stmt.Table, err))
+		return
+	}
+	after := make(map[string]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		after[entityID(columns, row)] = row
+	}
+
+	var entries []AuditLog
+	for _, old := range before {
+		row, ok := after[entityID(columns, old)]
+		if !ok {
+			continue
+		}
+		changes := diff(old, row)
+		if len(changes) == 0 {
+			continue
+		}
+		action := ActionUpdate
+		if change, ok := changes["deletedAt"]; ok {
+			if change.Before == nil {
+				action = ActionDelete
+			} else if change.After == nil {
+				action = ActionRestore
+			}
+		}
+		entries = append(entries, newEntry(db, action, row, changes))
+	}
+	write(db, entries)
+}
+
+func afterDelete(db *gorm.DB) {
+	before := snapshotted(db)
+	if len(before) == 0 {
+		return
+	}
+
+	entries := make([]AuditLog, 0, len(before))
+	for _, row := range before {
+		entries = append(entries, newEntry(db, ActionDelete, row, diff(row, nil)))
+	}
+	write(db, entries)
+}
+
+// snapshotted returns the rows snapshot read, nil if the statement failed
+// or changed nothing
+func snapshotted(db *gorm.DB) []map[string]interface{} {
+	if !audited(db) || db.Statement.RowsAffected == 0 {
+		return nil
+	}
+	value, ok := db.InstanceGet(snapshotKey)
+	if !ok {
+		return nil
+	}
+	rows, _ := value.([]map[string]interface{})
+	return rows
+}
+
+// softDeleted reports whether the statement's model is soft-deleted, so
+// gorm only touches its rows that are not deleted yet
+func softDeleted(stmt *gorm.Statement) bool {
+	if stmt.Schema == nil {
+		return false
+	}
+	field := stmt.Schema.LookUpField("deleted_at")
+	return field != nil && field.FieldType == reflect.TypeOf(gorm.DeletedAt{})
+}
+
+// modelKeys returns the primary keys of the model or models the statement
+// was given, skipping those without one
+func modelKeys(stmt *gorm.Statement) [][]interface{} {
+	if stmt.Schema == nil || len(stmt.Schema.PrimaryFields) == 0 || !stmt.ReflectValue.IsValid() {
+		return nil
+	}
+
+	var keys [][]interface{}
+	add := func(rv reflect.Value) {
+		key := make([]interface{}, 0, len(stmt.Schema.PrimaryFields))
+		for _, field := range stmt.Schema.PrimaryFields {
+			value, zero := field.ValueOf(stmt.Context, rv)
+			if zero {
+				return
+			}
+			key = append(key, value)
+		}
+		keys = append(keys, key)
+	}
+
+	switch rv := reflect.Indirect(stmt.ReflectValue); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+				add(elem)
+			}
+		}
+	case reflect.Struct:
+		add(rv)
+	}
+	return keys
+}
+
+// find reads the rows of the statement's table matching conditions, in
+// the statement's transaction. Rows are read as plain column values, the
+// same whether the statement had a model or only a table.
+func find(db *gorm.DB, conditions []clause.Expression) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	err := db.Session(&gorm.Session{NewDB: true}).
+		Table(db.Statement.Table).
+		Clauses(clause.Where{Exprs: conditions}).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		for column, value := range row {
+			row[column] = normalize(value)
+		}
+	}
+	return rows, nil
+}
+
+// normalize turns a field or column value into what is stored: pointers
+// are followed, valuers such as gorm.DeletedAt asked for their value and
+// bytes read as text
+func normalize(value interface{}) interface{} {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(valuer); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil
+		}
+		stored, err := valuer.Value()
+		if err != nil {
+			return nil
+		}
+		value = stored
+	}
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+		value = rv.Interface()
+	}
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
+
+// diff lists the fields whose values differ between two versions of a row.
+// A nil row stands for one that does not exist; fields empty on both sides
+// are left out.
+func diff(before, after map[string]interface{}) map[string]Change {
+	columns := map[string]bool{}
+	for column := range before {
+		columns[column] = true
+	}
+	for column := range after {
+		columns[column] = true
+	}
+
+	changes := map[string]Change{}
+	for column := range columns {
+		if ignoredColumns[column] {
+			continue
+		}
+		old, current := before[column], after[column]
+		if equal(old, current) {
+			continue
+		}
+		if redactedColumns[column] {
+			old, current = redact(old), redact(current)
+		}
+		changes[fieldName(column)] = Change{Before: old, After: current}
+	}
+	return changes
+}
+
+// equal compares two stored values
+func equal(a, b interface{}) bool {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func redact(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return redacted
+}
+
+// fieldName turns a column name into the field name the API uses for it,
+// e.g. item_name into itemName
+func fieldName(column string) string {
+	parts := strings.Split(column, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// keyValues returns a row's primary key values
+func keyValues(columns []string, row map[string]interface{}) []interface{} {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = row[column]
+	}
+	return values
+}
+
+// entityID renders a row's primary key, colon-joining composite keys
+func entityID(columns []string, row map[string]interface{}) string {
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		parts[i] = fmt.Sprint(row[column])
+	}
+	return strings.Join(parts, ":")
+}
+
+// newEntry builds the log entry of a change to row, tagged with the
+// request the statement runs in
+func newEntry(db *gorm.DB, action string, row map[string]interface{}, changes map[string]Change) AuditLog {
+	stmt := db.Statement
+	entry := AuditLog{
+		ID:        uuid.New().String(),
+		Entity:    stmt.Table,
+		EntityID:  entityID(primaryKeys(stmt), row),
+		Action:    action,
+		Changes:   changes,
+		CreatedAt: time.Now(),
+	}
+
+	if stmt.Table == "tenants" {
+		tenantID := entry.EntityID
+		entry.TenantID = &tenantID
+	} else if tenantID, ok := row["tenant_id"].(string); ok && tenantID != "" {
+		entry.TenantID = &tenantID
+	}
+
+	if req := RequestFrom(stmt.Context); req != nil {
+		entry.RequestID = req.ID
+		entry.IP = req.IP
+		if req.Actor != nil {
+			entry.ActorID = req.Actor()
+		}
+	}
+	return entry
+}
+
+// write saves entries in the statement's transaction, failing the
+// statement if they cannot be saved
+func write(db *gorm.DB, entries []AuditLog) {
+	if len(entries) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&entries).Error; err != nil {
+		db.AddError(fmt.Errorf("audit: failed to record changes to %s: %w", db.Statement.Table, err))
+	}
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"time"
+
+	"concierge-be/database"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		db: database.GetDB(),
+	}
+}
+
+// filtered returns a query over the entries matching filter
+func (r *Repository) filtered(filter ListFilter) *gorm.DB {
+	query := r.db.Model(&AuditLog{})
+	if filter.TenantID != "" {
+		query = query.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.Entity != "" {
+		query = query.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
+
+// ListAfter retrieves up to limit entries matching filter, newest first,
+// that follow the entry at (after, afterID), or the newest ones if after is
+// nil. total counts every match, not just those after the cursor.
+func (r *Repository) ListAfter(filter ListFilter, after *time.Time, afterID string, limit int) ([]AuditLog, int64, error) {
+	var logs []AuditLog
+	var total int64
+
+	query := r.filtered(filter)
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := query
+	if after != nil {
+		page = database.SeekAfter(page, "created_at", "id", true, *after, afterID)
+	} else {
+		page = database.OrderBy(page, "created_at", "id", true)
+	}
+	err := page.Limit(limit).Find(&logs).Error
+
+	return logs, total, err
+}
+
+// GetByID retrieves an audit log entry by ID
+func (r *Repository) GetByID(id string) (*AuditLog, error) {
+	var entry AuditLog
+	err := r.db.Where("id = ?", id).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// DeleteBefore deletes up to limit entries recorded before cutoff, oldest
+// first, returning how many went
+func (r *Repository) DeleteBefore(cutoff time.Time, limit int) (int64, error) {
+	result := r.db.Exec("DELETE FROM audit_logs WHERE created_at < ? ORDER BY created_at LIMIT ?", cutoff, limit)
+	return result.RowsAffected, result.Error
+}
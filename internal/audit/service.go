@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"time"
+
+	"concierge-be/config"
+	"concierge-be/database"
+)
+
+// deleteBatchSize is how many expired entries a retention run deletes at a time
+const deleteBatchSize = 1000
+
+type Service struct {
+	repo      *Repository
+	retention time.Duration
+}
+
+func NewService() *Service {
+	retention := time.Duration(0)
+	if config.AppConfig != nil {
+		retention = time.Duration(config.AppConfig.Audit.RetentionDays) * 24 * time.Hour
+	}
+	return &Service{
+		repo:      NewRepository(),
+		retention: retention,
+	}
+}
+
+// Retention returns how long entries are kept, 0 for forever
+func (s *Service) Retention() time.Duration {
+	return s.retention
+}
+
+// ListLogs retrieves the page of entries matching filter, newest first,
+// following cursor, or the first page if cursor is empty. The returned
+// cursor leads to the next page and is empty on the last one.
+func (s *Service) ListLogs(filter ListFilter, cursor string, pageSize int) ([]AuditLog, int64, string, error) {
+	var after *time.Time
+	afterID := ""
+	if cursor != "" {
+		decoded, err := database.DecodeCursor(cursor, "createdAt", true)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		t, err := time.Parse(time.RFC3339Nano, decoded.Value)
+		if err != nil {
+			return nil, 0, "", database.ErrInvalidCursor
+		}
+		after, afterID = &t, decoded.ID
+	}
+
+	// Fetch one extra entry to learn whether another page follows
+	logs, total, err := s.repo.ListAfter(filter, after, afterID, pageSize+1)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if len(logs) <= pageSize {
+		return logs, total, "", nil
+	}
+
+	logs = logs[:pageSize]
+	last := logs[pageSize-1]
+	next := database.Cursor{Sort: "createdAt", Desc: true, Value: last.CreatedAt.Format(time.RFC3339Nano), ID: last.ID}
+	return logs, total, next.Encode(), nil
+}
+
+// GetLog retrieves one entry
+func (s *Service) GetLog(id string) (*AuditLog, error) {
+	return s.repo.GetByID(id)
+}
+
+// DeleteExpired deletes every entry recorded before cutoff, in batches so
+// a large backlog does not hold one long lock, returning how many went
+func (s *Service) DeleteExpired(cutoff time.Time) (int64, error) {
+	var deleted int64
+	for {
+		n, err := s.repo.DeleteBefore(cutoff, deleteBatchSize)
+		deleted += n
+		if err != nil || n < deleteBatchSize {
+			return deleted, err
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package barcodes
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+)
+
+// Rendering sizes, in pixels per module (the narrowest bar, or one QR cell)
+const (
+	linearModule = 3
+	linearHeight = 90
+	qrModule     = 8
+)
+
+// Label is one printed label: a barcode with the lines of text under it
+type Label struct {
+	Title     string // e.g. the item name
+	Subtitle  string // e.g. the SKU
+	Code      string
+	Symbology string
+}
+
+// encode builds the barcode of code in symbology at print size, with the
+// quiet zone scanners need around it
+func encode(symbology, code string) (image.Image, error) {
+	if err := Validate(symbology, code); err != nil {
+		return nil, err
+	}
+
+	var bc barcode.Barcode
+	var err error
+	switch symbology {
+	case SymbologyEAN13:
+		bc, err = ean.Encode(code)
+	case SymbologyUPC:
+		// UPC-A is EAN-13 with a leading zero
+		bc, err = ean.Encode("0" + code)
+	case SymbologyCode128:
+		bc, err = code128.Encode(code)
+	case SymbologyQR:
+		bc, err = qr.Encode(code, qr.M, qr.Auto)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode barcode: %w", err)
+	}
+
+	bounds := bc.Bounds()
+	module, height, quiet := linearModule, linearHeight, 10*linearModule
+	if symbology == SymbologyQR {
+		module, height, quiet = qrModule, bounds.Dy()*qrModule, 4*qrModule
+	}
+	scaled, err := barcode.Scale(bc, bounds.Dx()*module, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scale barcode: %w", err)
+	}
+
+	size := scaled.Bounds()
+	canvas := image.NewGray(image.Rect(0, 0, size.Dx()+2*quiet, size.Dy()+2*quiet))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(canvas, size.Add(image.Pt(quiet, quiet)), scaled, size.Min, draw.Src)
+	return canvas, nil
+}
+
+// RenderPNG renders the barcode of code in symbology as a PNG image
+func RenderPNG(symbology, code string) ([]byte, error) {
+	img, err := encode(symbology, code)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to write PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Layout places labels on PDF pages. Sizes are in millimetres.
+type Layout struct {
+	PageWidth, PageHeight float64
+	Columns, Rows         int
+	LabelWidth            float64
+	LabelHeight           float64
+	MarginLeft, MarginTop float64
+}
+
+// Layouts labels can be printed in
+var (
+	// LayoutSingle prints one 70x37 mm label per page, for label printers
+	LayoutSingle = Layout{PageWidth: 70, PageHeight: 37, Columns: 1, Rows: 1, LabelWidth: 70, LabelHeight: 37}
+	// LayoutSheet prints 3x8 labels of 70x37 mm on A4 sheets
+	LayoutSheet = Layout{PageWidth: 210, PageHeight: 297, Columns: 3, Rows: 8, LabelWidth: 70, LabelHeight: 37, MarginTop: 0.5}
+)
+
+// labelPadding is the blank border kept inside each label, in millimetres
+const labelPadding = 2.5
+
+// RenderPDF renders labels as a PDF, filling pages in layout's grid row by row
+func RenderPDF(labels []Label, layout Layout) ([]byte, error) {
+	orientation := "P"
+	if layout.PageWidth > layout.PageHeight {
+		orientation = "L"
+	}
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: orientation,
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: layout.PageWidth, Ht: layout.PageHeight},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	// Core fonts only cover Latin-1
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+
+	perPage := layout.Columns * layout.Rows
+	for i, label := range labels {
+		if i%perPage == 0 {
+			pdf.AddPage()
+		}
+		slot := i % perPage
+		x := layout.MarginLeft + float64(slot%layout.Columns)*layout.LabelWidth
+		y := layout.MarginTop + float64(slot/layout.Columns)*layout.LabelHeight
+
+		if err := drawLabel(pdf, translate, label, fmt.Sprintf("barcode-%d", i), x, y, layout); err != nil {
+			return nil, err
+		}
+	}
+	if len(labels) == 0 {
+		pdf.AddPage()
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// drawLabel draws one label with its top left corner at (x, y): the title
+// and subtitle at the top, the barcode below them and the code underneath
+func drawLabel(pdf *fpdf.Fpdf, translate func(string) string, label Label, name string, x, y float64, layout Layout) error {
+	img, err := encode(label.Symbology, label.Code)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return fmt.Errorf("failed to write barcode image: %w", err)
+	}
+	pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, &buf)
+
+	width := layout.LabelWidth - 2*labelPadding
+	left, top := x+labelPadding, y+labelPadding
+
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetXY(left, top)
+	pdf.CellFormat(width, 4, fit(pdf, translate(label.Title), width), "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 7)
+	pdf.CellFormat(width, 3.5, fit(pdf, translate(label.Subtitle), width), "", 2, "L", false, 0, "")
+
+	// The barcode fills the space left above the code line, keeping its
+	// aspect ratio
+	imageTop := top + 8
+	maxWidth, maxHeight := width, layout.LabelHeight-labelPadding-(imageTop-y)-3.5
+	bounds := img.Bounds()
+	w, h := maxWidth, maxWidth*float64(bounds.Dy())/float64(bounds.Dx())
+	if h > maxHeight {
+		w, h = maxHeight*float64(bounds.Dx())/float64(bounds.Dy()), maxHeight
+	}
+	pdf.ImageOptions(name, left+(width-w)/2, imageTop, w, h, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+	pdf.SetFont("Courier", "", 7)
+	pdf.SetXY(left, imageTop+h)
+	pdf.CellFormat(width, 3.5, fit(pdf, translate(label.Code), width), "", 0, "C", false, 0, "")
+
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("failed to draw label: %w", err)
+	}
+	return nil
+}
+
+// fit shortens translated text, one byte per character, with an ellipsis
+// until it fits width in the current font
+func fit(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	text = strings.TrimSpace(text)
+	for text != "" && pdf.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
@@ -0,0 +1,113 @@
+package barcodes
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Symbologies a barcode can be printed and scanned in
+const (
+	SymbologyEAN13   = "ean13"
+	SymbologyUPC     = "upc" // UPC-A
+	SymbologyCode128 = "code128"
+	SymbologyQR      = "qr"
+)
+
+// Length limits of codes, so they stay scannable from a shelf label
+const (
+	maxCode128Length = 80
+	maxQRLength      = 255
+)
+
+// ValidSymbology reports whether symbology is a known barcode type
+func ValidSymbology(symbology string) bool {
+	switch symbology {
+	case SymbologyEAN13, SymbologyUPC, SymbologyCode128, SymbologyQR:
+		return true
+	}
+	return false
+}
+
+// Detect picks the symbology a code is most likely printed in: EAN-13 or
+// UPC-A for digit strings with a valid check digit, Code 128 for other
+// short printable text, QR for anything else
+func Detect(code string) string {
+	switch {
+	case len(code) == 13 && validCheckDigit(code):
+		return SymbologyEAN13
+	case len(code) == 12 && validCheckDigit(code):
+		return SymbologyUPC
+	case len(code) <= maxCode128Length && printableASCII(code):
+		return SymbologyCode128
+	}
+	return SymbologyQR
+}
+
+// Validate checks that code can be encoded in symbology
+func Validate(symbology, code string) error {
+	if code == "" {
+		return errors.New("barcode cannot be empty")
+	}
+	switch symbology {
+	case SymbologyEAN13:
+		if len(code) != 13 || !digits(code) {
+			return fmt.Errorf("EAN-13 barcode %q must be 13 digits", code)
+		}
+		if !validCheckDigit(code) {
+			return fmt.Errorf("EAN-13 barcode %q has a wrong check digit", code)
+		}
+	case SymbologyUPC:
+		if len(code) != 12 || !digits(code) {
+			return fmt.Errorf("UPC barcode %q must be 12 digits", code)
+		}
+		if !validCheckDigit(code) {
+			return fmt.Errorf("UPC barcode %q has a wrong check digit", code)
+		}
+	case SymbologyCode128:
+		if len(code) > maxCode128Length || !printableASCII(code) {
+			return fmt.Errorf("Code 128 barcode %q must be at most %d printable ASCII characters", code, maxCode128Length)
+		}
+	case SymbologyQR:
+		if len(code) > maxQRLength {
+			return fmt.Errorf("QR code must be at most %d bytes", maxQRLength)
+		}
+	default:
+		return fmt.Errorf("barcode type must be %s, %s, %s or %s", SymbologyEAN13, SymbologyUPC, SymbologyCode128, SymbologyQR)
+	}
+	return nil
+}
+
+func digits(code string) bool {
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return code != ""
+}
+
+func printableASCII(code string) bool {
+	for _, r := range code {
+		if r < ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// validCheckDigit verifies the GS1 mod-10 check digit that ends EAN and
+// UPC codes: digits are weighted 3 and 1 alternately from the right
+func validCheckDigit(code string) bool {
+	if !digits(code) {
+		return false
+	}
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		weight := 1
+		if (len(code)-2-i)%2 == 0 {
+			weight = 3
+		}
+		sum += int(code[i]-'0') * weight
+	}
+	return (10-sum%10)%10 == int(code[len(code)-1]-'0')
+}
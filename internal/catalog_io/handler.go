@@ -0,0 +1,177 @@
+package catalog_io
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/quotas"
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
+// ImportAmenities handles POST /api/v1/amenities/import
+// Accepts a CSV or XLSX file as multipart field "file". Supports ?tenantId=,
+// ?dryRun=true, ?upsert=true and ?format= when the file name has no extension.
+func (h *Handler) ImportAmenities(c *gin.Context) {
+	h.importFile(c, (*Service).ImportAmenities)
+}
+
+// ImportCategories handles POST /api/v1/amenities-categories/import
+// Accepts the same file and query parameters as the amenity import
+func (h *Handler) ImportCategories(c *gin.Context) {
+	h.importFile(c, (*Service).ImportCategories)
+}
+
+func (h *Handler) importFile(c *gin.Context, run func(*Service, [][]string, ImportOptions) (*ImportResult, error)) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+	service := h.serviceFor(c)
+	if _, err := service.GetTenant(tenantID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	if fileHeader.Size > MaxImportSize {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file must not exceed %d bytes", MaxImportSize))
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = FormatFromFilename(fileHeader.Filename)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	rows, err := ReadTable(file, format)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := run(service, rows, ImportOptions{
+		TenantID: tenantID,
+		DryRun:   c.Query("dryRun") == "true",
+		Upsert:   c.Query("upsert") == "true",
+		ActorID:  utils.ActorID(c),
+	})
+	if err != nil {
+		var fileErr *FileError
+		switch {
+		case errors.As(err, &fileErr):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, quotas.ErrQuotaExceeded):
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		case err.Error() == "tenant not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, database.ErrVersionConflict):
+			utils.ErrorResponse(c, http.StatusConflict, "Records were modified during the import; retry")
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	if len(result.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, utils.Response{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "invalid rows",
+			Data:    result,
+		})
+		return
+	}
+
+	utils.SuccessResponse(c, result)
+}
+
+// ExportAmenities handles GET /api/v1/amenities/export
+// Streams CSV by default; ?format=xlsx returns a workbook. Supports the
+// filters of the amenity listing.
+func (h *Handler) ExportAmenities(c *gin.Context) {
+	filter, err := amenities.ParseListFilter(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.exportFile(c, filter.TenantID, "amenities", func(w TableWriter) error {
+		return h.service.ExportAmenities(filter, w)
+	})
+}
+
+// ExportCategories handles GET /api/v1/amenities-categories/export
+// Streams CSV by default; ?format=xlsx returns a workbook
+func (h *Handler) ExportCategories(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	h.exportFile(c, tenantID, "categories", func(w TableWriter) error {
+		return h.service.ExportCategories(tenantID, w)
+	})
+}
+
+func (h *Handler) exportFile(c *gin.Context, tenantID, name string, export func(TableWriter) error) {
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+	format := c.DefaultQuery("format", FormatCSV)
+	if format != FormatCSV && format != FormatXLSX {
+		utils.ErrorResponse(c, http.StatusBadRequest, "format must be csv or xlsx")
+		return
+	}
+
+	// Resolve the tenant before any bytes are written so a 404 is still possible
+	if _, err := h.service.GetTenant(tenantID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writer, err := NewTableWriter(c.Writer, format)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102"), format)
+	c.Header("Content-Type", ContentType(format))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only be logged and the stream cut short
+	if err := export(writer); err != nil {
+		log.Printf("%s export for tenant %s failed: %v", name, tenantID, err)
+		c.Abort()
+	}
+}
@@ -0,0 +1,88 @@
+package catalog_io
+
+import (
+	"strings"
+)
+
+// File formats accepted for import and produced by export
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Import limits: the largest file accepted and the most data rows it may contain
+const (
+	MaxImportSize = 10 << 20
+	MaxImportRows = 10000
+)
+
+// Column names. Exports write every column of their kind; imports read the
+// writable ones and ignore the rest, so an export can be edited and imported back.
+const (
+	ColumnID           = "id"
+	ColumnItemName     = "itemName"
+	ColumnCategoryID   = "categoryId"
+	ColumnCategory     = "category"
+	ColumnDescription  = "description"
+	ColumnStock        = "stock"
+	ColumnMinimumStock = "minimumStock"
+	ColumnAvailable    = "available"
+	ColumnName         = "name"
+	ColumnParent       = "parent"
+	ColumnUpdatedAt    = "updatedAt"
+)
+
+// AmenityColumns is the header row of an amenity export
+var AmenityColumns = []string{
+	ColumnID, ColumnItemName, ColumnCategoryID, ColumnCategory, ColumnDescription,
+	ColumnStock, ColumnMinimumStock, ColumnAvailable, ColumnUpdatedAt,
+}
+
+// CategoryColumns is the header row of a category export
+var CategoryColumns = []string{ColumnID, ColumnName, ColumnParent, ColumnDescription, ColumnUpdatedAt}
+
+// ImportOptions controls how a file is imported
+type ImportOptions struct {
+	TenantID string
+	// DryRun validates and applies the file inside a transaction, then rolls it back
+	DryRun bool
+	// Upsert updates rows whose name already exists instead of rejecting them
+	Upsert  bool
+	ActorID *string
+}
+
+// RowError is a problem with one row of an import. Row is the spreadsheet
+// row number, counting the header as row 1.
+type RowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportResult summarizes an import or dry run. Nothing is written unless
+// every row is valid, in which case Applied is set (never on a dry run).
+type ImportResult struct {
+	DryRun    bool       `json:"dryRun"`
+	Applied   bool       `json:"applied"`
+	Rows      int        `json:"rows"`
+	Created   int        `json:"created"`
+	Updated   int        `json:"updated"`
+	Unchanged int        `json:"unchanged"`
+	Errors    []RowError `json:"errors"`
+}
+
+// FileError reports a file that cannot be read as a table at all
+type FileError struct {
+	Message string
+}
+
+func (e *FileError) Error() string {
+	return "invalid file: " + e.Message
+}
+
+// normalizeColumn folds a header cell so "Item Name", "item_name" and
+// "itemName" all match
+func normalizeColumn(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name)
+}
@@ -0,0 +1,608 @@
+package catalog_io
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/amenities_categories"
+	"concierge-be/internal/quotas"
+	"concierge-be/internal/tenants"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many amenities are loaded per query while streaming
+const exportBatchSize = 500
+
+// errRollback undoes an import that was a dry run or had invalid rows
+var errRollback = errors.New("rollback")
+
+// importReference is written on the stock movements an import records
+const importReference = "import"
+
+type Service struct {
+	db           *gorm.DB
+	amenityRepo  *amenities.Repository
+	categoryRepo *amenities_categories.Repository
+	tenantRepo   *tenants.Repository
+	quotas       *quotas.Service
+}
+
+func NewService() *Service {
+	return &Service{
+		db:           database.GetDB(),
+		amenityRepo:  amenities.NewRepository(),
+		categoryRepo: amenities_categories.NewRepository(),
+		tenantRepo:   tenants.NewRepository(),
+		quotas:       quotas.NewService(),
+	}
+}
+
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	copied := *s
+	copied.db = s.db.WithContext(ctx)
+	copied.amenityRepo = s.amenityRepo.WithTx(copied.db)
+	copied.categoryRepo = s.categoryRepo.WithTx(copied.db)
+	copied.tenantRepo = s.tenantRepo.WithTx(copied.db)
+	copied.quotas = s.quotas.WithContext(ctx)
+	return &copied
+}
+
+// GetTenant retrieves the tenant being imported into or exported
+func (s *Service) GetTenant(tenantID string) (*tenants.Tenant, error) {
+	return s.tenantRepo.GetTenantByID(tenantID)
+}
+
+// amenityRow is a parsed amenity import row. Nil fields were left empty
+// and keep their current value on update.
+type amenityRow struct {
+	row          int
+	itemName     string
+	categoryID   string
+	description  *string
+	stock        *int
+	minimumStock *int
+	available    *bool
+	existing     *amenities.Amenity
+}
+
+// ImportAmenities validates amenity rows against the same rules as creating
+// an amenity: item names are unique per tenant, the category must exist in
+// the tenant and stock cannot be negative. Categories are matched by
+// categoryId, by full path ("Bathroom > Toiletries") or by a name only one
+// category uses. With Upsert, rows naming an existing item update
+// it, and a stock value is reached by an adjustment at the default location.
+func (s *Service) ImportAmenities(rows [][]string, opts ImportOptions) (*ImportResult, error) {
+	h := newHeader(rows[0], AmenityColumns)
+	if !h.has(ColumnItemName) {
+		return nil, &FileError{Message: "missing itemName column"}
+	}
+	if !h.has(ColumnCategoryID) && !h.has(ColumnCategory) {
+		return nil, &FileError{Message: "missing category or categoryId column"}
+	}
+
+	result := &ImportResult{DryRun: opts.DryRun, Errors: []RowError{}}
+	addError := func(row int, column, message string) {
+		result.Errors = append(result.Errors, RowError{Row: row, Column: column, Message: message})
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		amenityRepo := s.amenityRepo.WithTx(tx)
+
+		categories, err := s.categoryRepo.WithTx(tx).GetByTenantID(opts.TenantID)
+		if err != nil {
+			return fmt.Errorf("failed to load categories: %w", err)
+		}
+		resolver := newCategoryResolver(categories)
+
+		current, err := amenityRepo.GetByTenantID(opts.TenantID, false)
+		if err != nil {
+			return fmt.Errorf("failed to load amenities: %w", err)
+		}
+		existing := make(map[string]*amenities.Amenity, len(current))
+		for i := range current {
+			existing[strings.ToLower(current[i].ItemName)] = &current[i]
+		}
+
+		var parsed []amenityRow
+		seen := make(map[string]int)
+		for i, cells := range rows[1:] {
+			if blankRow(cells) {
+				continue
+			}
+			rowNo := i + 2
+			result.Rows++
+			errorsBefore := len(result.Errors)
+
+			item := amenityRow{row: rowNo, itemName: h.get(cells, ColumnItemName)}
+			key := strings.ToLower(item.itemName)
+			switch {
+			case item.itemName == "":
+				addError(rowNo, ColumnItemName, "itemName is required")
+			case len(item.itemName) > 100:
+				addError(rowNo, ColumnItemName, "itemName must not exceed 100 characters")
+			case seen[key] != 0:
+				addError(rowNo, ColumnItemName, fmt.Sprintf("item name repeats row %d", seen[key]))
+			default:
+				seen[key] = rowNo
+				item.existing = existing[key]
+				if item.existing != nil && !opts.Upsert {
+					addError(rowNo, ColumnItemName, "item name already exists for this tenant")
+				}
+			}
+
+			if id := h.get(cells, ColumnCategoryID); id != "" {
+				if !resolver.ids[id] {
+					addError(rowNo, ColumnCategoryID, "category not found")
+				}
+				item.categoryID = id
+			} else if name := h.get(cells, ColumnCategory); name != "" {
+				id, err := resolver.resolve(name)
+				if err != nil {
+					addError(rowNo, ColumnCategory, err.Error())
+				}
+				item.categoryID = id
+			} else if item.existing == nil {
+				addError(rowNo, ColumnCategory, "category is required")
+			}
+
+			if h.has(ColumnDescription) {
+				description := h.get(cells, ColumnDescription)
+				if description != "" {
+					item.description = &description
+				}
+			}
+			item.stock = parseQuantity(h, cells, ColumnStock, func(message string) { addError(rowNo, ColumnStock, message) })
+			item.minimumStock = parseQuantity(h, cells, ColumnMinimumStock, func(message string) { addError(rowNo, ColumnMinimumStock, message) })
+			if value := h.get(cells, ColumnAvailable); value != "" {
+				if available, ok := parseBool(value); ok {
+					item.available = &available
+				} else {
+					addError(rowNo, ColumnAvailable, "available must be true or false")
+				}
+			}
+			// Kits hold nothing themselves; their components carry stock
+			if item.existing != nil && item.existing.IsKit() {
+				if item.stock != nil && *item.stock != 0 {
+					addError(rowNo, ColumnStock, "kits hold no stock of their own; stock their components")
+				}
+				if item.minimumStock != nil && *item.minimumStock != 0 {
+					addError(rowNo, ColumnMinimumStock, "kits have no minimum stock")
+				}
+			}
+
+			if len(result.Errors) == errorsBefore {
+				parsed = append(parsed, item)
+			}
+		}
+		if len(result.Errors) > 0 {
+			return errRollback
+		}
+
+		creating := 0
+		for _, item := range parsed {
+			if item.existing == nil {
+				creating++
+			}
+		}
+		if err := s.quotas.WithTx(tx).CheckCapacity(opts.TenantID, quotas.ResourceAmenities, creating); err != nil {
+			return err
+		}
+
+		for _, item := range parsed {
+			if item.existing == nil {
+				if err := s.createAmenity(amenityRepo, item, opts); err != nil {
+					return err
+				}
+				result.Created++
+				continue
+			}
+
+			changed, err := s.updateAmenity(amenityRepo, item, opts)
+			if err != nil {
+				if err.Error() == "stock quantity cannot be negative" {
+					addError(item.row, ColumnStock, "not enough stock at the default location to reduce stock to this value")
+					continue
+				}
+				return err
+			}
+			if changed {
+				result.Updated++
+			} else {
+				result.Unchanged++
+			}
+		}
+
+		if len(result.Errors) > 0 || opts.DryRun {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		return nil, err
+	}
+
+	result.Applied = err == nil
+	return result, nil
+}
+
+// createAmenity creates an imported amenity, placing its stock at the
+// default location as an opening balance
+func (s *Service) createAmenity(repo *amenities.Repository, item amenityRow, opts ImportOptions) error {
+	amenity := &amenities.Amenity{
+		ID:         uuid.New().String(),
+		TenantID:   opts.TenantID,
+		CategoryID: item.categoryID,
+		ItemName:   item.itemName,
+		Available:  true,
+	}
+	if item.description != nil {
+		amenity.Description = *item.description
+	}
+	if item.stock != nil {
+		amenity.Stock = *item.stock
+	}
+	if item.minimumStock != nil {
+		amenity.MinimumStock = *item.minimumStock
+	}
+	if item.available != nil {
+		amenity.Available = *item.available
+	}
+
+	if err := repo.CreateWithOpeningStock(amenity, "", opts.ActorID); err != nil {
+		return fmt.Errorf("failed to create amenity %q: %w", item.itemName, err)
+	}
+	return nil
+}
+
+// updateAmenity applies an imported row to an existing amenity, reporting
+// whether anything changed
+func (s *Service) updateAmenity(repo *amenities.Repository, item amenityRow, opts ImportOptions) (bool, error) {
+	amenity := item.existing
+	changed := false
+
+	if item.categoryID != "" && item.categoryID != amenity.CategoryID {
+		amenity.CategoryID = item.categoryID
+		changed = true
+	}
+	if item.description != nil && *item.description != amenity.Description {
+		amenity.Description = *item.description
+		changed = true
+	}
+	if item.minimumStock != nil && *item.minimumStock != amenity.MinimumStock {
+		amenity.MinimumStock = *item.minimumStock
+		changed = true
+	}
+	if item.available != nil && *item.available != amenity.Available {
+		amenity.Available = *item.available
+		changed = true
+	}
+	if changed {
+		if err := repo.Update(amenity); err != nil {
+			return false, fmt.Errorf("failed to update amenity %q: %w", item.itemName, err)
+		}
+	}
+
+	if item.stock != nil && *item.stock != amenity.Stock {
+		movement := &amenities.StockMovement{
+			AmenityID: amenity.ID,
+			Delta:     *item.stock - amenity.Stock,
+			Reason:    amenities.MovementAdjustment,
+			Reference: importReference,
+			ActorID:   opts.ActorID,
+		}
+		if err := repo.ApplyMovement(movement); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// ImportCategories validates category rows against the same rules as
+// creating a category: names are required and unique among siblings, and
+// nesting stops at MaxDepth. The parent column holds the full path of the
+// parent, which may be an existing category or one created by an earlier
+// row. With Upsert, rows naming an existing category update its description.
+func (s *Service) ImportCategories(rows [][]string, opts ImportOptions) (*ImportResult, error) {
+	h := newHeader(rows[0], CategoryColumns)
+	if !h.has(ColumnName) {
+		return nil, &FileError{Message: "missing name column"}
+	}
+
+	result := &ImportResult{DryRun: opts.DryRun, Errors: []RowError{}}
+	addError := func(row int, column, message string) {
+		result.Errors = append(result.Errors, RowError{Row: row, Column: column, Message: message})
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		categoryRepo := s.categoryRepo.WithTx(tx)
+
+		current, err := categoryRepo.GetByTenantID(opts.TenantID)
+		if err != nil {
+			return fmt.Errorf("failed to load categories: %w", err)
+		}
+
+		// Categories by lowercased full path, extended with each new row so
+		// later rows can nest under it
+		paths := amenities_categories.Paths(current)
+		byPath := make(map[string]*amenities_categories.AmenityCategory, len(current))
+		for i := range current {
+			byPath[strings.ToLower(paths[current[i].ID])] = &current[i]
+		}
+		nextOrder := make(map[string]int)
+		for _, category := range current {
+			key := parentKey(category.ParentID)
+			if category.SortOrder >= nextOrder[key] {
+				nextOrder[key] = category.SortOrder + 1
+			}
+		}
+
+		type categoryRow struct {
+			category *amenities_categories.AmenityCategory
+			existing bool
+			changed  bool
+		}
+		var parsed []categoryRow
+		seen := make(map[string]int)
+		for i, cells := range rows[1:] {
+			if blankRow(cells) {
+				continue
+			}
+			rowNo := i + 2
+			result.Rows++
+
+			name := h.get(cells, ColumnName)
+			description := h.get(cells, ColumnDescription)
+			parentPath := strings.Join(amenities_categories.SplitPath(h.get(cells, ColumnParent)), amenities_categories.PathSeparator)
+
+			var parent *amenities_categories.AmenityCategory
+			depth := 1
+			if parentPath != "" {
+				parent = byPath[strings.ToLower(parentPath)]
+				if parent == nil {
+					addError(rowNo, ColumnParent, fmt.Sprintf("parent category %q not found", parentPath))
+					continue
+				}
+				depth = len(amenities_categories.SplitPath(parentPath)) + 1
+			}
+
+			path := name
+			if parentPath != "" {
+				path = parentPath + amenities_categories.PathSeparator + name
+			}
+			key := strings.ToLower(path)
+			switch {
+			case name == "":
+				addError(rowNo, ColumnName, "name is required")
+			case len(name) > 100:
+				addError(rowNo, ColumnName, "name must not exceed 100 characters")
+			case depth > amenities_categories.MaxDepth:
+				addError(rowNo, ColumnParent, fmt.Sprintf("categories cannot nest more than %d levels deep", amenities_categories.MaxDepth))
+			case seen[key] != 0:
+				addError(rowNo, ColumnName, fmt.Sprintf("category repeats row %d", seen[key]))
+			case byPath[key] != nil && !opts.Upsert:
+				seen[key] = rowNo
+				addError(rowNo, ColumnName, "category name already exists under this parent")
+			case byPath[key] != nil:
+				seen[key] = rowNo
+				item := categoryRow{category: byPath[key], existing: true}
+				if description != "" && description != item.category.Description {
+					item.category.Description = description
+					item.changed = true
+				}
+				parsed = append(parsed, item)
+			default:
+				seen[key] = rowNo
+				category := &amenities_categories.AmenityCategory{
+					ID:          uuid.New().String(),
+					TenantID:    opts.TenantID,
+					Name:        name,
+					Description: description,
+				}
+				if parent != nil {
+					category.ParentID = &parent.ID
+				}
+				orderKey := parentKey(category.ParentID)
+				category.SortOrder = nextOrder[orderKey]
+				nextOrder[orderKey]++
+				byPath[key] = category
+				parsed = append(parsed, categoryRow{category: category})
+			}
+		}
+		if len(result.Errors) > 0 {
+			return errRollback
+		}
+
+		creating := 0
+		for _, item := range parsed {
+			if !item.existing {
+				creating++
+			}
+		}
+		if err := s.quotas.WithTx(tx).CheckCapacity(opts.TenantID, quotas.ResourceCategories, creating); err != nil {
+			return err
+		}
+
+		// Rows come parent first, so every parent exists before its children
+		for _, item := range parsed {
+			switch {
+			case !item.existing:
+				if err := categoryRepo.Create(item.category); err != nil {
+					return fmt.Errorf("failed to create category %q: %w", item.category.Name, err)
+				}
+				result.Created++
+			case !item.changed:
+				result.Unchanged++
+			default:
+				if err := categoryRepo.Update(item.category); err != nil {
+					return fmt.Errorf("failed to update category %q: %w", item.category.Name, err)
+				}
+				result.Updated++
+			}
+		}
+
+		if opts.DryRun {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		return nil, err
+	}
+
+	result.Applied = err == nil
+	return result, nil
+}
+
+// ExportAmenities streams the amenities matching filter to w
+func (s *Service) ExportAmenities(filter amenities.ListFilter, w TableWriter) error {
+	if err := w.WriteRow(columnValues(AmenityColumns)); err != nil {
+		return err
+	}
+
+	categories, err := s.categoryRepo.GetByTenantID(filter.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load categories: %w", err)
+	}
+	paths := amenities_categories.Paths(categories)
+
+	err = s.amenityRepo.FindInBatches(filter, exportBatchSize, func(batch []amenities.Amenity) error {
+		for _, amenity := range batch {
+			err := w.WriteRow([]interface{}{
+				amenity.ID, amenity.ItemName, amenity.CategoryID, paths[amenity.CategoryID], amenity.Description,
+				amenity.Stock, amenity.MinimumStock, amenity.Available, amenity.UpdatedAt.Format(time.RFC3339),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export amenities: %w", err)
+	}
+	return w.Close()
+}
+
+// ExportCategories writes a tenant's categories to w, parents before their
+// children, so the file imports back as the same tree
+func (s *Service) ExportCategories(tenantID string, w TableWriter) error {
+	categories, err := s.categoryRepo.GetByTenantID(tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load categories: %w", err)
+	}
+	paths := amenities_categories.Paths(categories)
+
+	if err := w.WriteRow(columnValues(CategoryColumns)); err != nil {
+		return err
+	}
+	for _, category := range amenities_categories.SortByDepth(categories) {
+		parent := ""
+		if category.ParentID != nil {
+			parent = paths[*category.ParentID]
+		}
+		err := w.WriteRow([]interface{}{
+			category.ID, category.Name, parent, category.Description, category.UpdatedAt.Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// parseQuantity reads an optional non-negative whole number, reporting
+// anything else through fail
+func parseQuantity(h header, cells []string, column string, fail func(string)) *int {
+	value := h.get(cells, column)
+	if value == "" {
+		return nil
+	}
+	quantity, err := strconv.Atoi(value)
+	if err != nil {
+		fail(column + " must be a whole number")
+		return nil
+	}
+	if quantity < 0 {
+		fail(column + " cannot be negative")
+		return nil
+	}
+	return &quantity
+}
+
+// parseBool reads the yes/no spellings spreadsheets commonly use
+func parseBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "y", "1":
+		return true, true
+	case "false", "no", "n", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// columnValues turns a header row into cell values
+func columnValues(columns []string) []interface{} {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = column
+	}
+	return values
+}
+
+// categoryResolver finds categories named in an import by ID, full path or
+// unique name
+type categoryResolver struct {
+	ids   map[string]bool
+	paths map[string]string
+	names map[string][]string
+}
+
+func newCategoryResolver(categories []amenities_categories.AmenityCategory) *categoryResolver {
+	r := &categoryResolver{
+		ids:   make(map[string]bool, len(categories)),
+		paths: make(map[string]string, len(categories)),
+		names: make(map[string][]string),
+	}
+	for id, path := range amenities_categories.Paths(categories) {
+		r.paths[strings.ToLower(path)] = id
+	}
+	for _, category := range categories {
+		r.ids[category.ID] = true
+		key := strings.ToLower(category.Name)
+		r.names[key] = append(r.names[key], category.ID)
+	}
+	return r
+}
+
+// resolve returns the ID of the category at a full path or with a name
+func (r *categoryResolver) resolve(value string) (string, error) {
+	path := strings.Join(amenities_categories.SplitPath(value), amenities_categories.PathSeparator)
+	if id, ok := r.paths[strings.ToLower(path)]; ok {
+		return id, nil
+	}
+	switch ids := r.names[strings.ToLower(value)]; len(ids) {
+	case 0:
+		return "", fmt.Errorf("category %q not found", value)
+	case 1:
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("category name %q is ambiguous; use its full path", value)
+}
+
+// parentKey keys a parent ID for maps, "" standing for the top level
+func parentKey(parentID *string) string {
+	if parentID == nil {
+		return ""
+	}
+	return *parentID
+}
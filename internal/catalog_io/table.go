@@ -0,0 +1,255 @@
+package catalog_io
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxUnzipLimit caps how far an uploaded workbook may expand when unzipped
+const xlsxUnzipLimit = 64 << 20
+
+// FormatFromFilename infers a file format from its extension
+func FormatFromFilename(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".xlsx":
+		return FormatXLSX
+	}
+	return ""
+}
+
+// ContentType returns the MIME type of a file format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ReadTable reads a CSV file, or the first sheet of an XLSX workbook, into
+// rows of cells. The first row is the header.
+func ReadTable(r io.Reader, format string) ([][]string, error) {
+	var rows [][]string
+	var err error
+	switch format {
+	case FormatCSV:
+		rows, err = readCSV(r)
+	case FormatXLSX:
+		rows, err = readXLSX(r)
+	default:
+		return nil, &FileError{Message: "format must be csv or xlsx"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, &FileError{Message: "file has no header row"}
+	}
+	return rows, nil
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+	// Spreadsheet programs often prefix UTF-8 CSV with a byte order mark
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, &FileError{Message: err.Error()}
+		}
+		if len(rows) > MaxImportRows {
+			return nil, &FileError{Message: fmt.Sprintf("file must not contain more than %d rows", MaxImportRows)}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func readXLSX(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r, excelize.Options{UnzipSizeLimit: xlsxUnzipLimit, UnzipXMLSizeLimit: xlsxUnzipLimit})
+	if err != nil {
+		return nil, &FileError{Message: err.Error()}
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, &FileError{Message: "workbook has no sheets"}
+	}
+	iter, err := f.Rows(sheets[0])
+	if err != nil {
+		return nil, &FileError{Message: err.Error()}
+	}
+	defer iter.Close()
+
+	var rows [][]string
+	for iter.Next() {
+		row, err := iter.Columns()
+		if err != nil {
+			return nil, &FileError{Message: err.Error()}
+		}
+		if len(rows) > MaxImportRows {
+			return nil, &FileError{Message: fmt.Sprintf("file must not contain more than %d rows", MaxImportRows)}
+		}
+		rows = append(rows, row)
+	}
+	return rows, iter.Error()
+}
+
+// header maps normalized column names to their position in a row
+type header map[string]int
+
+// newHeader indexes a header row, keeping only the known columns
+func newHeader(row []string, known []string) header {
+	names := make(map[string]string, len(known))
+	for _, column := range known {
+		names[normalizeColumn(column)] = column
+	}
+
+	h := header{}
+	for i, cell := range row {
+		if column, ok := names[normalizeColumn(cell)]; ok {
+			if _, seen := h[column]; !seen {
+				h[column] = i
+			}
+		}
+	}
+	return h
+}
+
+// has reports whether the file has a column
+func (h header) has(column string) bool {
+	_, ok := h[column]
+	return ok
+}
+
+// get returns a row's trimmed value for a column, empty if the column or
+// cell is missing
+func (h header) get(row []string, column string) string {
+	i, ok := h[column]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// blankRow reports whether every cell of a row is empty
+func blankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// TableWriter writes an export one row at a time
+type TableWriter interface {
+	WriteRow(values []interface{}) error
+	// Flush pushes what has been written so far towards the client
+	Flush() error
+	// Close finishes the file
+	Close() error
+}
+
+// NewTableWriter returns a writer producing format on w
+func NewTableWriter(w io.Writer, format string) (TableWriter, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: w, csv: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		f := excelize.NewFile()
+		stream, err := f.NewStreamWriter("Sheet1")
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &xlsxWriter{w: w, file: f, stream: stream}, nil
+	}
+	return nil, errors.New("format must be csv or xlsx")
+}
+
+type csvWriter struct {
+	w   io.Writer
+	csv *csv.Writer
+}
+
+func (cw *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = fmt.Sprint(value)
+	}
+	return cw.csv.Write(record)
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.csv.Flush()
+	if err := cw.csv.Error(); err != nil {
+		return err
+	}
+	flush(cw.w)
+	return nil
+}
+
+func (cw *csvWriter) Close() error {
+	return cw.Flush()
+}
+
+// xlsxWriter streams rows into a temporary sheet; a workbook is a zip
+// archive, so nothing reaches the client until Close
+type xlsxWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func (xw *xlsxWriter) WriteRow(values []interface{}) error {
+	xw.row++
+	cell, err := excelize.CoordinatesToCellName(1, xw.row)
+	if err != nil {
+		return err
+	}
+	return xw.stream.SetRow(cell, values)
+}
+
+func (xw *xlsxWriter) Flush() error {
+	return nil
+}
+
+func (xw *xlsxWriter) Close() error {
+	defer xw.file.Close()
+	if err := xw.stream.Flush(); err != nil {
+		return err
+	}
+	if _, err := xw.file.WriteTo(xw.w); err != nil {
+		return err
+	}
+	flush(xw.w)
+	return nil
+}
+
+// flush pushes buffered output to the client when the writer supports it
+func flush(w io.Writer) {
+	if f, ok := w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,148 @@
+package locations
+
+import (
+	"errors"
+	"net/http"
+
+	"concierge-be/database"
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
+// CreateLocation handles POST /api/v1/locations
+func (h *Handler) CreateLocation(c *gin.Context) {
+	var req CreateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	location, err := h.serviceFor(c).CreateLocation(&req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid location kind", "parent location not found":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case "location name already exists at this level":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "Success",
+		Data:    location,
+	})
+}
+
+// GetLocation handles GET /api/v1/locations/:id
+func (h *Handler) GetLocation(c *gin.Context) {
+	location, err := h.serviceFor(c).GetLocationByID(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SetETag(c, location.Version)
+	utils.SuccessResponse(c, location)
+}
+
+// GetLocations handles GET /api/v1/locations?tenantId=
+// Returns a flat list, or a nested tree with tree=true
+func (h *Handler) GetLocations(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+
+	if c.Query("tree") == "true" {
+		tree, err := h.serviceFor(c).GetLocationTree(tenantID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.SuccessResponse(c, tree)
+		return
+	}
+
+	locations, err := h.serviceFor(c).GetLocationsByTenantID(tenantID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, locations)
+}
+
+// UpdateLocation handles PUT /api/v1/locations/:id
+// Requires If-Match with the version from the location's ETag
+func (h *Handler) UpdateLocation(c *gin.Context) {
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	location, err := h.serviceFor(c).UpdateLocation(c.Param("id"), &req, version)
+	if err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			utils.ErrorResponse(c, http.StatusPreconditionFailed, "Location has been modified; reload and retry")
+			return
+		}
+		switch err.Error() {
+		case "location not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case "invalid location kind", "parent location not found", "location cannot be moved under itself or its descendants":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case "location name already exists at this level":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SetETag(c, location.Version)
+	utils.SuccessResponse(c, location)
+}
+
+// DeleteLocation handles DELETE /api/v1/locations/:id
+func (h *Handler) DeleteLocation(c *gin.Context) {
+	if err := h.serviceFor(c).DeleteLocation(c.Param("id")); err != nil {
+		switch err.Error() {
+		case "location not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case "default location cannot be deleted", "location has child locations", "location still holds stock":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Location deleted successfully"})
+}
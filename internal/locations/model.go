@@ -0,0 +1,71 @@
+package locations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Location kinds
+const (
+	KindStoreroom = "storeroom"
+	KindFloor     = "floor"
+	KindCloset    = "closet"
+	KindCart      = "cart"
+	KindOther     = "other"
+)
+
+// ValidKind reports whether kind is a known location kind
+func ValidKind(kind string) bool {
+	switch kind {
+	case KindStoreroom, KindFloor, KindCloset, KindCart, KindOther:
+		return true
+	}
+	return false
+}
+
+// Location is a place where a tenant keeps stock, such as the basement
+// store, a floor closet or a housekeeping cart. Locations form a tree per
+// tenant. Every tenant has one default location that receives stock
+// movements which do not name a location.
+type Location struct {
+	ID          string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID    string         `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	ParentID    *string        `gorm:"type:varchar(36);index" json:"parentId"`
+	Name        string         `gorm:"type:varchar(100);not null" json:"name"`
+	Kind        string         `gorm:"type:varchar(20);not null;default:'other'" json:"kind"`
+	Description string         `gorm:"type:text" json:"description"`
+	IsDefault   bool           `gorm:"default:false" json:"isDefault"`
+	Version     int64          `gorm:"not null;default:1" json:"version"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Location) TableName() string {
+	return "locations"
+}
+
+// LocationNode is a location together with its children
+type LocationNode struct {
+	Location
+	Children []*LocationNode `json:"children"`
+}
+
+// CreateLocationRequest represents the request body for creating a location
+type CreateLocationRequest struct {
+	TenantID    string  `json:"tenantId" binding:"required"`
+	ParentID    *string `json:"parentId"`
+	Name        string  `json:"name" binding:"required"`
+	Kind        string  `json:"kind"`
+	Description string  `json:"description"`
+}
+
+// UpdateLocationRequest represents the request body for updating a location.
+// An empty parentId moves the location to the top level.
+type UpdateLocationRequest struct {
+	ParentID    *string `json:"parentId"`
+	Name        string  `json:"name"`
+	Kind        string  `json:"kind"`
+	Description string  `json:"description"`
+}
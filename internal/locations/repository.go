@@ -0,0 +1,130 @@
+package locations
+
+import (
+	"errors"
+
+	"concierge-be/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Name and kind of the location created for every tenant on first use
+const (
+	defaultLocationName = "Main Store"
+	defaultLocationKind = KindStoreroom
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		db: database.GetDB(),
+	}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// Create creates a new location
+func (r *Repository) Create(location *Location) error {
+	return r.db.Create(location).Error
+}
+
+// GetByID retrieves a location by ID
+func (r *Repository) GetByID(id string) (*Location, error) {
+	var location Location
+	err := r.db.Where("id = ?", id).First(&location).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("location not found")
+		}
+		return nil, err
+	}
+	return &location, nil
+}
+
+// TenantOf returns the tenant of location id, "" if it does not exist
+func (r *Repository) TenantOf(id string) (string, error) {
+	var tenantIDs []string
+	if err := r.db.Model(&Location{}).Where("id = ?", id).Pluck("tenant_id", &tenantIDs).Error; err != nil || len(tenantIDs) == 0 {
+		return "", err
+	}
+	return tenantIDs[0], nil
+}
+
+// GetByTenantID retrieves all locations for a tenant
+func (r *Repository) GetByTenantID(tenantID string) ([]Location, error) {
+	var locations []Location
+	err := r.db.Where("tenant_id = ?", tenantID).Order("name ASC").Find(&locations).Error
+	return locations, err
+}
+
+// Update updates an existing location if it is still at location.Version,
+// returning database.ErrVersionConflict otherwise
+func (r *Repository) Update(location *Location) error {
+	return database.UpdateVersioned(r.db, location, &location.Version)
+}
+
+// Delete soft deletes a location
+func (r *Repository) Delete(id string) error {
+	return r.db.Delete(&Location{}, "id = ?", id).Error
+}
+
+// CheckNameExists checks if a sibling under parentID already uses name
+func (r *Repository) CheckNameExists(tenantID string, parentID *string, name, excludeID string) (bool, error) {
+	var count int64
+	query := r.db.Model(&Location{}).Where("tenant_id = ? AND name = ?", tenantID, name)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	if excludeID != "" {
+		query = query.Where("id != ?", excludeID)
+	}
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// CountChildren counts the locations directly under id
+func (r *Repository) CountChildren(id string) (int64, error) {
+	var count int64
+	err := r.db.Model(&Location{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// HoldsStock reports whether any amenity has stock at the location
+func (r *Repository) HoldsStock(id string) (bool, error) {
+	var count int64
+	err := r.db.Table("amenity_stocks").Where("location_id = ? AND quantity > 0", id).Count(&count).Error
+	return count > 0, err
+}
+
+// EnsureDefault returns the tenant's default location, creating it if it
+// does not exist yet. The ID is derived from the tenant ID, so concurrent
+// callers cannot create two.
+func (r *Repository) EnsureDefault(tenantID string) (*Location, error) {
+	location := &Location{
+		ID:        defaultLocationID(tenantID),
+		TenantID:  tenantID,
+		Name:      defaultLocationName,
+		Kind:      defaultLocationKind,
+		IsDefault: true,
+	}
+	err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(location).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(location.ID)
+}
+
+// defaultLocationID returns the fixed ID of a tenant's default location
+func defaultLocationID(tenantID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte("concierge-default-location:"+tenantID)).String()
+}
@@ -0,0 +1,252 @@
+package locations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"concierge-be/database"
+
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService() *Service {
+	return &Service{
+		repo: NewRepository(),
+	}
+}
+
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	copied := *s
+	copied.repo = s.repo.WithTx(s.repo.db.WithContext(ctx))
+	return &copied
+}
+
+// CreateLocation creates a new location, optionally under a parent
+func (s *Service) CreateLocation(req *CreateLocationRequest) (*Location, error) {
+	kind := req.Kind
+	if kind == "" {
+		kind = KindOther
+	}
+	if !ValidKind(kind) {
+		return nil, errors.New("invalid location kind")
+	}
+
+	parentID := normalizeParentID(req.ParentID)
+	if parentID != nil {
+		if _, err := s.getTenantLocation(req.TenantID, *parentID); err != nil {
+			return nil, errors.New("parent location not found")
+		}
+	}
+
+	exists, err := s.repo.CheckNameExists(req.TenantID, parentID, req.Name, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check location name: %w", err)
+	}
+	if exists {
+		return nil, errors.New("location name already exists at this level")
+	}
+
+	location := &Location{
+		ID:          uuid.New().String(),
+		TenantID:    req.TenantID,
+		ParentID:    parentID,
+		Name:        req.Name,
+		Kind:        kind,
+		Description: req.Description,
+	}
+	if err := s.repo.Create(location); err != nil {
+		return nil, fmt.Errorf("failed to create location: %w", err)
+	}
+
+	return location, nil
+}
+
+// GetLocationByID retrieves a location by ID
+func (s *Service) GetLocationByID(id string) (*Location, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetLocationsByTenantID retrieves all locations for a tenant, creating the
+// default location if the tenant has none yet
+func (s *Service) GetLocationsByTenantID(tenantID string) ([]Location, error) {
+	if _, err := s.repo.EnsureDefault(tenantID); err != nil {
+		return nil, fmt.Errorf("failed to create default location: %w", err)
+	}
+	return s.repo.GetByTenantID(tenantID)
+}
+
+// GetLocationTree retrieves a tenant's locations arranged as a tree
+func (s *Service) GetLocationTree(tenantID string) ([]*LocationNode, error) {
+	locations, err := s.GetLocationsByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTree(locations), nil
+}
+
+// UpdateLocation updates a location the caller last read at version.
+// Moving a location under itself or one of its descendants is rejected.
+func (s *Service) UpdateLocation(id string, req *UpdateLocationRequest, version int64) (*Location, error) {
+	location, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if location.Version != version {
+		return nil, database.ErrVersionConflict
+	}
+
+	if req.Kind != "" {
+		if !ValidKind(req.Kind) {
+			return nil, errors.New("invalid location kind")
+		}
+		location.Kind = req.Kind
+	}
+
+	if req.ParentID != nil {
+		parentID := normalizeParentID(req.ParentID)
+		if parentID != nil {
+			if err := s.checkMove(location, *parentID); err != nil {
+				return nil, err
+			}
+		}
+		location.ParentID = parentID
+	}
+
+	if req.Name != "" {
+		location.Name = req.Name
+	}
+
+	if req.ParentID != nil || req.Name != "" {
+		exists, err := s.repo.CheckNameExists(location.TenantID, location.ParentID, location.Name, location.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check location name: %w", err)
+		}
+		if exists {
+			return nil, errors.New("location name already exists at this level")
+		}
+	}
+
+	if req.Description != "" {
+		location.Description = req.Description
+	}
+
+	if err := s.repo.Update(location); err != nil {
+		return nil, fmt.Errorf("failed to update location: %w", err)
+	}
+
+	return location, nil
+}
+
+// DeleteLocation deletes an empty leaf location. The default location
+// cannot be deleted.
+func (s *Service) DeleteLocation(id string) error {
+	location, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if location.IsDefault {
+		return errors.New("default location cannot be deleted")
+	}
+
+	children, err := s.repo.CountChildren(id)
+	if err != nil {
+		return fmt.Errorf("failed to check child locations: %w", err)
+	}
+	if children > 0 {
+		return errors.New("location has child locations")
+	}
+
+	holdsStock, err := s.repo.HoldsStock(id)
+	if err != nil {
+		return fmt.Errorf("failed to check location stock: %w", err)
+	}
+	if holdsStock {
+		return errors.New("location still holds stock")
+	}
+
+	return s.repo.Delete(id)
+}
+
+// getTenantLocation loads a location and checks it belongs to tenantID
+func (s *Service) getTenantLocation(tenantID, id string) (*Location, error) {
+	location, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if location.TenantID != tenantID {
+		return nil, errors.New("location not found")
+	}
+	return location, nil
+}
+
+// checkMove verifies that location can be placed under parentID
+func (s *Service) checkMove(location *Location, parentID string) error {
+	if _, err := s.getTenantLocation(location.TenantID, parentID); err != nil {
+		return errors.New("parent location not found")
+	}
+
+	all, err := s.repo.GetByTenantID(location.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+	if IsDescendant(all, parentID, location.ID) {
+		return errors.New("location cannot be moved under itself or its descendants")
+	}
+	return nil
+}
+
+// normalizeParentID treats an empty parent ID as the top level
+func normalizeParentID(parentID *string) *string {
+	if parentID == nil || *parentID == "" {
+		return nil
+	}
+	return parentID
+}
+
+// IsDescendant reports whether id is ancestorID itself or lies below it in
+// the tree formed by locations
+func IsDescendant(locations []Location, id, ancestorID string) bool {
+	parents := make(map[string]*string, len(locations))
+	for i := range locations {
+		parents[locations[i].ID] = locations[i].ParentID
+	}
+
+	// The walk is bounded by the number of locations so corrupt data
+	// cannot loop forever
+	current := &id
+	for steps := 0; current != nil && steps <= len(locations); steps++ {
+		if *current == ancestorID {
+			return true
+		}
+		current = parents[*current]
+	}
+	return false
+}
+
+// BuildTree arranges locations into a tree. Locations whose parent is
+// missing are treated as top level.
+func BuildTree(locations []Location) []*LocationNode {
+	nodes := make(map[string]*LocationNode, len(locations))
+	for _, location := range locations {
+		nodes[location.ID] = &LocationNode{Location: location, Children: []*LocationNode{}}
+	}
+
+	roots := []*LocationNode{}
+	for _, location := range locations {
+		node := nodes[location.ID]
+		if location.ParentID != nil {
+			if parent, ok := nodes[*location.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
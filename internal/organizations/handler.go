@@ -0,0 +1,293 @@
+package organizations
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"concierge-be/database"
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantRoleResolver resolves the role a user holds in a tenant, directly
+// or through its organization
+type TenantRoleResolver interface {
+	GetUserRoleInTenant(userID, tenantID string) (string, error)
+}
+
+type Handler struct {
+	service     *Service
+	tenantRoles TenantRoleResolver
+}
+
+func NewHandler(tenantRoles TenantRoleResolver) *Handler {
+	return &Handler{
+		service:     NewService(),
+		tenantRoles: tenantRoles,
+	}
+}
+
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
+// CreateOrganization handles POST /api/v1/organizations
+func (h *Handler) CreateOrganization(c *gin.Context) {
+	var req CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	org, err := h.serviceFor(c).CreateOrganization(&req, c.GetString("user_id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "Success",
+		Data:    org,
+	})
+}
+
+// GetOrganization handles GET /api/v1/organizations/:id
+func (h *Handler) GetOrganization(c *gin.Context) {
+	org, err := h.serviceFor(c).GetOrganizationByID(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SetETag(c, org.Version)
+	utils.SuccessResponse(c, org)
+}
+
+// GetAllOrganizations handles GET /api/v1/organizations with pagination
+func (h *Handler) GetAllOrganizations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	orgs, total, err := h.serviceFor(c).GetAllOrganizations(page, pageSize)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithPagination(c, orgs, page, pageSize, int(total))
+}
+
+// UpdateOrganization handles PUT /api/v1/organizations/:id
+func (h *Handler) UpdateOrganization(c *gin.Context) {
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	org, err := h.serviceFor(c).UpdateOrganization(c.Param("id"), &req, version)
+	if err != nil {
+		if err.Error() == "organization not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, database.ErrVersionConflict) {
+			utils.ErrorResponse(c, http.StatusPreconditionFailed, "Organization has been modified; reload and retry")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SetETag(c, org.Version)
+	utils.SuccessResponse(c, org)
+}
+
+// DeleteOrganization handles DELETE /api/v1/organizations/:id
+func (h *Handler) DeleteOrganization(c *gin.Context) {
+	if err := h.serviceFor(c).DeleteOrganization(c.Param("id")); err != nil {
+		if err.Error() == "organization not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Organization deleted successfully"})
+}
+
+// GetTenants handles GET /api/v1/organizations/:id/tenants
+func (h *Handler) GetTenants(c *gin.Context) {
+	list, err := h.serviceFor(c).GetTenants(c.Param("id"))
+	if err != nil {
+		if err.Error() == "organization not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, list)
+}
+
+// AddTenant handles POST /api/v1/organizations/:id/tenants
+// The caller must also be an admin of the tenant, since its members gain
+// the organization's roles over it
+func (h *Handler) AddTenant(c *gin.Context) {
+	var req AddTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	role, err := h.tenantRoles.GetUserRoleInTenant(c.GetString("user_id"), req.TenantID)
+	if err != nil && err.Error() != "user-tenant relationship not found" {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if role != RoleAdmin {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only an admin of the tenant can add it to an organization")
+		return
+	}
+
+	if err := h.serviceFor(c).AddTenant(c.Param("id"), req.TenantID); err != nil {
+		if err.Error() == "organization not found" || err.Error() == "tenant not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Tenant added to organization successfully"})
+}
+
+// RemoveTenant handles DELETE /api/v1/organizations/:id/tenants/:tenantId
+func (h *Handler) RemoveTenant(c *gin.Context) {
+	if err := h.serviceFor(c).RemoveTenant(c.Param("id"), c.Param("tenantId")); err != nil {
+		if err.Error() == "organization not found" || err.Error() == "tenant does not belong to this organization" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Tenant removed from organization successfully"})
+}
+
+// AddMember handles POST /api/v1/organizations/:id/members
+func (h *Handler) AddMember(c *gin.Context) {
+	var req AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = RoleMember
+	}
+
+	member, err := h.serviceFor(c).AddMember(c.Param("id"), req.UserID, req.Role)
+	if err != nil {
+		if err.Error() == "organization not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		if err.Error() == "role must be one of admin, manager, member or viewer" {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, member)
+}
+
+// GetMembers handles GET /api/v1/organizations/:id/members
+func (h *Handler) GetMembers(c *gin.Context) {
+	members, err := h.serviceFor(c).GetMembers(c.Param("id"))
+	if err != nil {
+		if err.Error() == "organization not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, members)
+}
+
+// RemoveMember handles DELETE /api/v1/organizations/:id/members/:userId
+func (h *Handler) RemoveMember(c *gin.Context) {
+	if err := h.serviceFor(c).RemoveMember(c.Param("id"), c.Param("userId")); err != nil {
+		if err.Error() == "organization member not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Member removed from organization successfully"})
+}
+
+// GetLowStock handles GET /api/v1/organizations/:id/low-stock
+// Returns low stock amenities across every property in the organization
+func (h *Handler) GetLowStock(c *gin.Context) {
+	list, err := h.serviceFor(c).GetLowStockAmenities(c.Param("id"))
+	if err != nil {
+		if err.Error() == "organization not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, list)
+}
+
+// CopyCatalog handles POST /api/v1/organizations/:id/catalog-copy
+func (h *Handler) CopyCatalog(c *gin.Context) {
+	var req CopyCatalogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.serviceFor(c).CopyCatalog(c.Param("id"), &req)
+	if err != nil {
+		switch err.Error() {
+		case "organization not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case "source and target tenant must differ", "tenant does not belong to this organization":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, result)
+}
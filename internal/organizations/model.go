@@ -0,0 +1,96 @@
+package organizations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Organization represents a hotel group that owns multiple tenant properties
+type Organization struct {
+	ID          string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Name        string         `gorm:"type:varchar(100);not null" json:"name"`
+	Description string         `gorm:"type:text" json:"description"`
+	IsActive    bool           `gorm:"default:true" json:"isActive"`
+	Version     int64          `gorm:"not null;default:1" json:"version"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// Roles a user can hold in an organization or, directly, in a tenant
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+	RoleMember  = "member"
+	RoleViewer  = "viewer"
+)
+
+// ValidRole reports whether role is one of the roles above
+func ValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleManager, RoleMember, RoleViewer:
+		return true
+	}
+	return false
+}
+
+// OrganizationMember grants a user an org-level role that is inherited by
+// every tenant property in the organization
+type OrganizationMember struct {
+	ID             string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	OrganizationID string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_org_member" json:"organizationId"`
+	UserID         string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_org_member;index" json:"userId"`
+	Role           string    `gorm:"type:varchar(50);default:'member'" json:"role"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func (OrganizationMember) TableName() string {
+	return "organization_members"
+}
+
+// CreateOrganizationRequest represents the request body for creating an organization
+type CreateOrganizationRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	IsActive    *bool  `json:"isActive"`
+}
+
+// UpdateOrganizationRequest represents the request body for updating an organization
+type UpdateOrganizationRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsActive    *bool  `json:"isActive"`
+}
+
+// AddTenantRequest represents the request body for attaching a tenant to an organization
+type AddTenantRequest struct {
+	TenantID string `json:"tenantId" binding:"required"`
+}
+
+// AddMemberRequest represents the request body for granting an org-level role
+type AddMemberRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	Role   string `json:"role"`
+}
+
+// CopyCatalogRequest represents the request body for copying a category and
+// amenity catalog from one property to another
+type CopyCatalogRequest struct {
+	SourceTenantID string `json:"sourceTenantId" binding:"required"`
+	TargetTenantID string `json:"targetTenantId" binding:"required"`
+	IncludeStock   bool   `json:"includeStock"`
+}
+
+// CopyCatalogResult summarizes what a catalog copy created and skipped
+type CopyCatalogResult struct {
+	CategoriesCopied  int `json:"categoriesCopied"`
+	CategoriesSkipped int `json:"categoriesSkipped"`
+	AmenitiesCopied   int `json:"amenitiesCopied"`
+	AmenitiesSkipped  int `json:"amenitiesSkipped"`
+}
@@ -0,0 +1,170 @@
+package organizations
+
+import (
+	"errors"
+
+	"concierge-be/database"
+	"concierge-be/internal/tenants"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		db: database.GetDB(),
+	}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction
+func (r *Repository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+// Create creates a new organization
+func (r *Repository) Create(org *Organization) error {
+	return r.db.Create(org).Error
+}
+
+// GetByID retrieves an organization by ID
+func (r *Repository) GetByID(id string) (*Organization, error) {
+	var org Organization
+	err := r.db.Where("id = ?", id).First(&org).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("organization not found")
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// GetAll retrieves organizations with pagination
+func (r *Repository) GetAll(page, pageSize int) ([]Organization, int64, error) {
+	var orgs []Organization
+	var total int64
+
+	db := r.db.Model(&Organization{})
+
+	// Get total count
+	db.Count(&total)
+
+	// Paginated query
+	offset := (page - 1) * pageSize
+	err := db.Order("name ASC").Offset(offset).Limit(pageSize).Find(&orgs).Error
+
+	return orgs, total, err
+}
+
+// Update updates an existing organization if it is still at org.Version,
+// returning database.ErrVersionConflict otherwise
+func (r *Repository) Update(org *Organization) error {
+	return database.UpdateVersioned(r.db, org, &org.Version)
+}
+
+// Delete soft deletes an organization
+func (r *Repository) Delete(id string) error {
+	return r.db.Delete(&Organization{}, "id = ?", id).Error
+}
+
+// GetTenants retrieves all tenant properties owned by an organization
+func (r *Repository) GetTenants(orgID string) ([]tenants.Tenant, error) {
+	var list []tenants.Tenant
+	err := r.db.Where("organization_id = ?", orgID).Order("name ASC").Find(&list).Error
+	return list, err
+}
+
+// GetTenantIDs retrieves the IDs of all tenant properties owned by an organization
+func (r *Repository) GetTenantIDs(orgID string) ([]string, error) {
+	var ids []string
+	err := r.db.Model(&tenants.Tenant{}).Where("organization_id = ?", orgID).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// SameCurrency reports whether two tenants value their stock in the same currency
+func (r *Repository) SameCurrency(tenantID, otherID string) (bool, error) {
+	var currencies []string
+	err := r.db.Model(&tenants.Tenant{}).Where("id IN ?", []string{tenantID, otherID}).
+		Distinct().Pluck("currency", &currencies).Error
+	return len(currencies) == 1, err
+}
+
+// SetTenantOrganization attaches a tenant to an organization, or detaches it when orgID is nil
+func (r *Repository) SetTenantOrganization(tenantID string, orgID *string) error {
+	result := r.db.Model(&tenants.Tenant{}).Where("id = ?", tenantID).Update("organization_id", orgID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("tenant not found")
+	}
+	return nil
+}
+
+// CreateMember creates a new org-level membership
+func (r *Repository) CreateMember(member *OrganizationMember) error {
+	return r.db.Create(member).Error
+}
+
+// GetMembers retrieves all members of an organization
+func (r *Repository) GetMembers(orgID string) ([]OrganizationMember, error) {
+	var members []OrganizationMember
+	err := r.db.Where("organization_id = ?", orgID).Find(&members).Error
+	return members, err
+}
+
+// GetMember retrieves a user's membership in an organization
+func (r *Repository) GetMember(orgID, userID string) (*OrganizationMember, error) {
+	var member OrganizationMember
+	err := r.db.Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("organization member not found")
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetMembershipsByUserID retrieves all org-level memberships for a user
+func (r *Repository) GetMembershipsByUserID(userID string) ([]OrganizationMember, error) {
+	var members []OrganizationMember
+	err := r.db.Where("user_id = ?", userID).Find(&members).Error
+	return members, err
+}
+
+// UpdateMember updates an org-level membership
+func (r *Repository) UpdateMember(member *OrganizationMember) error {
+	return r.db.Save(member).Error
+}
+
+// DeleteMember removes a user from an organization
+func (r *Repository) DeleteMember(orgID, userID string) error {
+	return r.db.Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&OrganizationMember{}).Error
+}
+
+// GetInheritedRole resolves the org-level role a user holds over a tenant
+// through the organization that owns it
+func (r *Repository) GetInheritedRole(userID, tenantID string) (*OrganizationMember, error) {
+	var member OrganizationMember
+	err := r.db.Table("organization_members AS om").
+		Select("om.*").
+		Joins("JOIN tenants t ON t.organization_id = om.organization_id AND t.deleted_at IS NULL").
+		Where("om.user_id = ? AND t.id = ?", userID, tenantID).
+		First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("organization member not found")
+		}
+		return nil, err
+	}
+	return &member, nil
+}
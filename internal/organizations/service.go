@@ -0,0 +1,393 @@
+package organizations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"concierge-be/database"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/amenities_categories"
+	"concierge-be/internal/tenants"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	repo         *Repository
+	amenityRepo  *amenities.Repository
+	categoryRepo *amenities_categories.Repository
+}
+
+func NewService() *Service {
+	return &Service{
+		repo:         NewRepository(),
+		amenityRepo:  amenities.NewRepository(),
+		categoryRepo: amenities_categories.NewRepository(),
+	}
+}
+
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	db := s.repo.db.WithContext(ctx)
+	copied := *s
+	copied.repo = s.repo.WithTx(db)
+	copied.amenityRepo = s.amenityRepo.WithTx(db)
+	copied.categoryRepo = s.categoryRepo.WithTx(db)
+	return &copied
+}
+
+// CreateOrganization creates a new organization with its creator as admin
+func (s *Service) CreateOrganization(req *CreateOrganizationRequest, creatorID string) (*Organization, error) {
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	org := &Organization{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Description: req.Description,
+		IsActive:    isActive,
+	}
+
+	err := s.repo.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		if err := repo.Create(org); err != nil {
+			return err
+		}
+		return repo.CreateMember(&OrganizationMember{
+			ID:             uuid.New().String(),
+			OrganizationID: org.ID,
+			UserID:         creatorID,
+			Role:           RoleAdmin,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return org, nil
+}
+
+// GetOrganizationByID retrieves an organization by ID
+func (s *Service) GetOrganizationByID(id string) (*Organization, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetAllOrganizations retrieves organizations with pagination
+func (s *Service) GetAllOrganizations(page, pageSize int) ([]Organization, int64, error) {
+	return s.repo.GetAll(page, pageSize)
+}
+
+// UpdateOrganization updates an existing organization the caller last read at version
+func (s *Service) UpdateOrganization(id string, req *UpdateOrganizationRequest, version int64) (*Organization, error) {
+	org, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if org.Version != version {
+		return nil, database.ErrVersionConflict
+	}
+
+	if req.Name != "" {
+		org.Name = req.Name
+	}
+
+	if req.Description != "" {
+		org.Description = req.Description
+	}
+
+	if req.IsActive != nil {
+		org.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.Update(org); err != nil {
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+
+	return org, nil
+}
+
+// DeleteOrganization deletes an organization and detaches its properties
+func (s *Service) DeleteOrganization(id string) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return err
+	}
+
+	return s.repo.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&tenants.Tenant{}).Where("organization_id = ?", id).
+			Update("organization_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", id).Delete(&OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Organization{}, "id = ?", id).Error
+	})
+}
+
+// GetTenants retrieves all tenant properties in an organization
+func (s *Service) GetTenants(orgID string) ([]tenants.Tenant, error) {
+	if _, err := s.repo.GetByID(orgID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTenants(orgID)
+}
+
+// AddTenant attaches a tenant property to an organization
+func (s *Service) AddTenant(orgID, tenantID string) error {
+	if _, err := s.repo.GetByID(orgID); err != nil {
+		return err
+	}
+	return s.repo.SetTenantOrganization(tenantID, &orgID)
+}
+
+// RemoveTenant detaches a tenant property from an organization
+func (s *Service) RemoveTenant(orgID, tenantID string) error {
+	if err := s.ensureTenantInOrganization(orgID, tenantID); err != nil {
+		return err
+	}
+	return s.repo.SetTenantOrganization(tenantID, nil)
+}
+
+// AddMember grants a user an org-level role, or updates the existing one
+func (s *Service) AddMember(orgID, userID, role string) (*OrganizationMember, error) {
+	if !ValidRole(role) {
+		return nil, errors.New("role must be one of admin, manager, member or viewer")
+	}
+	if _, err := s.repo.GetByID(orgID); err != nil {
+		return nil, err
+	}
+
+	if existing, err := s.repo.GetMember(orgID, userID); err == nil {
+		existing.Role = role
+		if err := s.repo.UpdateMember(existing); err != nil {
+			return nil, fmt.Errorf("failed to update organization member: %w", err)
+		}
+		return existing, nil
+	}
+
+	member := &OrganizationMember{
+		ID:             uuid.New().String(),
+		OrganizationID: orgID,
+		UserID:         userID,
+		Role:           role,
+	}
+	if err := s.repo.CreateMember(member); err != nil {
+		return nil, fmt.Errorf("failed to add organization member: %w", err)
+	}
+	return member, nil
+}
+
+// GetMemberRole returns the role a user holds in an organization
+func (s *Service) GetMemberRole(orgID, userID string) (string, error) {
+	member, err := s.repo.GetMember(orgID, userID)
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// GetMembers retrieves all members of an organization
+func (s *Service) GetMembers(orgID string) ([]OrganizationMember, error) {
+	if _, err := s.repo.GetByID(orgID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMembers(orgID)
+}
+
+// RemoveMember revokes a user's org-level role
+func (s *Service) RemoveMember(orgID, userID string) error {
+	if _, err := s.repo.GetMember(orgID, userID); err != nil {
+		return err
+	}
+	return s.repo.DeleteMember(orgID, userID)
+}
+
+// GetLowStockAmenities retrieves low stock amenities across every property in the organization
+func (s *Service) GetLowStockAmenities(orgID string) ([]amenities.Amenity, error) {
+	if _, err := s.repo.GetByID(orgID); err != nil {
+		return nil, err
+	}
+
+	tenantIDs, err := s.repo.GetTenantIDs(orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load organization tenants: %w", err)
+	}
+
+	return s.amenityRepo.GetLowStockByTenantIDs(tenantIDs)
+}
+
+// CopyCatalog copies categories and amenities from one property to another
+// within the same organization. Entries whose names already exist in the
+// target are skipped; categories are matched by full path, so the tree is
+// copied level by level, and copied amenities are linked to the target's
+// category at the same path. A kit is skipped when one of its components
+// was neither copied nor already an item of the target.
+func (s *Service) CopyCatalog(orgID string, req *CopyCatalogRequest) (*CopyCatalogResult, error) {
+	if req.SourceTenantID == req.TargetTenantID {
+		return nil, errors.New("source and target tenant must differ")
+	}
+	if err := s.ensureTenantInOrganization(orgID, req.SourceTenantID); err != nil {
+		return nil, err
+	}
+	if err := s.ensureTenantInOrganization(orgID, req.TargetTenantID); err != nil {
+		return nil, err
+	}
+
+	result := &CopyCatalogResult{}
+
+	err := s.repo.Transaction(func(tx *gorm.DB) error {
+		categoryRepo := s.categoryRepo.WithTx(tx)
+		amenityRepo := s.amenityRepo.WithTx(tx)
+
+		sourceCategories, err := categoryRepo.GetByTenantID(req.SourceTenantID)
+		if err != nil {
+			return err
+		}
+		targetCategories, err := categoryRepo.GetByTenantID(req.TargetTenantID)
+		if err != nil {
+			return err
+		}
+
+		// Map category paths in the target so existing ones are reused
+		targetByPath := make(map[string]string, len(targetCategories))
+		for id, path := range amenities_categories.Paths(targetCategories) {
+			targetByPath[path] = id
+		}
+
+		// Parents come first, so a copied child can point at its parent's copy
+		sourcePaths := amenities_categories.Paths(sourceCategories)
+		categoryMap := make(map[string]string, len(sourceCategories))
+		for _, category := range amenities_categories.SortByDepth(sourceCategories) {
+			path := sourcePaths[category.ID]
+			if id, ok := targetByPath[path]; ok {
+				categoryMap[category.ID] = id
+				result.CategoriesSkipped++
+				continue
+			}
+
+			copied := &amenities_categories.AmenityCategory{
+				ID:          uuid.New().String(),
+				TenantID:    req.TargetTenantID,
+				Name:        category.Name,
+				SortOrder:   category.SortOrder,
+				Description: category.Description,
+			}
+			if category.ParentID != nil {
+				parentID := categoryMap[*category.ParentID]
+				copied.ParentID = &parentID
+			}
+			if err := categoryRepo.Create(copied); err != nil {
+				return err
+			}
+			categoryMap[category.ID] = copied.ID
+			targetByPath[path] = copied.ID
+			result.CategoriesCopied++
+		}
+
+		sourceAmenities, err := amenityRepo.GetByTenantID(req.SourceTenantID, false)
+		if err != nil {
+			return err
+		}
+		targetAmenities, err := amenityRepo.GetByTenantID(req.TargetTenantID, false)
+		if err != nil {
+			return err
+		}
+		// Kits are copied after the items they are made of, and may use
+		// items the target already has under the same name
+		amenityMap := make(map[string]string, len(sourceAmenities))
+		targetItems := make(map[string]string, len(targetAmenities))
+		for _, amenity := range targetAmenities {
+			if !amenity.IsKit() {
+				targetItems[amenity.ItemName] = amenity.ID
+			}
+		}
+		sort.SliceStable(sourceAmenities, func(i, j int) bool {
+			return !sourceAmenities[i].IsKit() && sourceAmenities[j].IsKit()
+		})
+		// Stock keeps its value only between properties with one currency
+		sameCurrency, err := s.repo.SameCurrency(req.SourceTenantID, req.TargetTenantID)
+		if err != nil {
+			return err
+		}
+
+		for _, amenity := range sourceAmenities {
+			exists, err := amenityRepo.CheckItemNameExists(req.TargetTenantID, amenity.ItemName, "")
+			if err != nil {
+				return err
+			}
+			categoryID, ok := categoryMap[amenity.CategoryID]
+			if exists || !ok {
+				if id, ok := targetItems[amenity.ItemName]; ok && !amenity.IsKit() {
+					amenityMap[amenity.ID] = id
+				}
+				result.AmenitiesSkipped++
+				continue
+			}
+
+			stock, value := 0, int64(0)
+			if req.IncludeStock {
+				stock = amenity.Stock
+				if sameCurrency {
+					value = amenity.StockValue
+				}
+			}
+
+			copied := &amenities.Amenity{
+				ID:            uuid.New().String(),
+				TenantID:      req.TargetTenantID,
+				CategoryID:    categoryID,
+				ItemName:      amenity.ItemName,
+				Kind:          amenity.Kind,
+				Description:   amenity.Description,
+				Stock:         stock,
+				StockValue:    value,
+				MinimumStock:  amenity.MinimumStock,
+				BaseUnit:      amenity.BaseUnit,
+				PreferredUnit: amenity.PreferredUnit,
+				Available:     amenity.Available,
+			}
+			copied.Units = amenities.CopyUnits(copied.TenantID, copied.ID, amenity.Units)
+			if amenity.IsKit() {
+				if copied.Components, ok = amenities.CopyComponents(copied.TenantID, copied.ID, amenity.Components, amenityMap); !ok {
+					result.AmenitiesSkipped++
+					continue
+				}
+			}
+			if err := amenityRepo.CreateWithOpeningStock(copied, "", nil); err != nil {
+				return err
+			}
+			amenityMap[amenity.ID] = copied.ID
+			result.AmenitiesCopied++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy catalog: %w", err)
+	}
+
+	return result, nil
+}
+
+// ensureTenantInOrganization checks that a tenant belongs to the organization
+func (s *Service) ensureTenantInOrganization(orgID, tenantID string) error {
+	if _, err := s.repo.GetByID(orgID); err != nil {
+		return err
+	}
+
+	tenantIDs, err := s.repo.GetTenantIDs(orgID)
+	if err != nil {
+		return err
+	}
+	for _, id := range tenantIDs {
+		if id == tenantID {
+			return nil
+		}
+	}
+	return errors.New("tenant does not belong to this organization")
+}
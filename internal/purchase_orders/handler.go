@@ -0,0 +1,218 @@
+package purchase_orders
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"concierge-be/database"
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
+// CreateOrder handles POST /api/v1/purchase-orders
+func (h *Handler) CreateOrder(c *gin.Context) {
+	var req CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order, err := h.serviceFor(c).CreateOrder(&req, utils.ActorID(c))
+	if err != nil {
+		orderErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, order.Version)
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "Success",
+		Data:    order,
+	})
+}
+
+// GetOrder handles GET /api/v1/purchase-orders/:id
+func (h *Handler) GetOrder(c *gin.Context) {
+	order, err := h.serviceFor(c).GetOrderByID(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SetETag(c, order.Version)
+	utils.SuccessResponse(c, order)
+}
+
+// GetOrders handles GET /api/v1/purchase-orders?tenantId=&status=&supplierId=
+func (h *Handler) GetOrders(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := OrderFilter{
+		Status:     c.Query("status"),
+		SupplierID: c.Query("supplierId"),
+	}
+	orders, total, err := h.serviceFor(c).GetOrdersByTenantID(tenantID, filter, page, pageSize)
+	if err != nil {
+		if err.Error() == "invalid purchase order status" {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithPagination(c, orders, page, pageSize, int(total))
+}
+
+// UpdateOrder handles PUT /api/v1/purchase-orders/:id
+// Only drafts can be edited. Requires If-Match with the order's version.
+func (h *Handler) UpdateOrder(c *gin.Context) {
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order, err := h.serviceFor(c).UpdateOrder(c.Param("id"), &req, version)
+	if err != nil {
+		orderErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, order.Version)
+	utils.SuccessResponse(c, order)
+}
+
+// DeleteOrder handles DELETE /api/v1/purchase-orders/:id
+func (h *Handler) DeleteOrder(c *gin.Context) {
+	if err := h.serviceFor(c).DeleteOrder(c.Param("id")); err != nil {
+		orderErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Purchase order deleted successfully"})
+}
+
+// SendOrder handles POST /api/v1/purchase-orders/:id/send
+func (h *Handler) SendOrder(c *gin.Context) {
+	order, err := h.serviceFor(c).SendOrder(c.Param("id"))
+	if err != nil {
+		orderErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, order.Version)
+	utils.SuccessResponse(c, order)
+}
+
+// ReceiveOrder handles POST /api/v1/purchase-orders/:id/receive
+func (h *Handler) ReceiveOrder(c *gin.Context) {
+	var req ReceiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.serviceFor(c).ReceiveOrder(c.Param("id"), &req, utils.ActorID(c))
+	if err != nil {
+		orderErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, result.Order.Version)
+	utils.SuccessResponse(c, result)
+}
+
+// CloseOrder handles POST /api/v1/purchase-orders/:id/close
+func (h *Handler) CloseOrder(c *gin.Context) {
+	order, err := h.serviceFor(c).CloseOrder(c.Param("id"))
+	if err != nil {
+		orderErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, order.Version)
+	utils.SuccessResponse(c, order)
+}
+
+// GenerateDrafts handles POST /api/v1/purchase-orders/generate
+func (h *Handler) GenerateDrafts(c *gin.Context) {
+	var req GenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.serviceFor(c).GenerateDrafts(&req, utils.ActorID(c))
+	if err != nil {
+		orderErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "Success",
+		Data:    result,
+	})
+}
+
+// orderErrorResponse maps purchase order errors to HTTP responses
+func orderErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, database.ErrVersionConflict) {
+		utils.ErrorResponse(c, http.StatusPreconditionFailed, "Purchase order has been modified; reload and retry")
+		return
+	}
+	switch err.Error() {
+	case "purchase order not found", "purchase order line not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case "supplier not found", "supplier is inactive", "location not found", "amenity not found",
+		"packs must be greater than zero", "amenity appears on more than one line", "supplier does not list this amenity",
+		"no lines to receive", "quantity must be greater than zero", "quantity exceeds the outstanding amount on the line":
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case "only draft orders can be edited", "only draft orders can be deleted", "only draft orders can be sent",
+		"purchase order has no lines", "purchase order is not awaiting receipt", "only received orders can be closed":
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	default:
+		if strings.HasPrefix(err.Error(), "invalid lot") {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
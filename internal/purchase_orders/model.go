@@ -0,0 +1,167 @@
+package purchase_orders
+
+import (
+	"time"
+
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/suppliers"
+)
+
+// Purchase order statuses. An order moves draft -> sent -> partially
+// received -> received -> closed; an order still partially received can
+// also be closed when the rest will never arrive.
+const (
+	StatusDraft             = "draft"
+	StatusSent              = "sent"
+	StatusPartiallyReceived = "partially_received"
+	StatusReceived          = "received"
+	StatusClosed            = "closed"
+)
+
+// ValidStatus reports whether status is a known purchase order status
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusDraft, StatusSent, StatusPartiallyReceived, StatusReceived, StatusClosed:
+		return true
+	}
+	return false
+}
+
+// PurchaseOrder is an order for amenities placed with one supplier.
+// LocationID is where received stock goes unless a receipt says otherwise;
+// nil means the tenant's default location.
+type PurchaseOrder struct {
+	ID         string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID   string     `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	SupplierID string     `gorm:"type:varchar(36);not null;index" json:"supplierId"`
+	Number     string     `gorm:"type:varchar(32);not null;uniqueIndex" json:"number"`
+	Status     string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	LocationID *string    `gorm:"type:varchar(36)" json:"locationId"`
+	Notes      string     `gorm:"type:text" json:"notes"`
+	CreatedBy  *string    `gorm:"type:varchar(36)" json:"createdBy"`
+	SentAt     *time.Time `json:"sentAt"`
+	ReceivedAt *time.Time `json:"receivedAt"`
+	ClosedAt   *time.Time `json:"closedAt"`
+	Version    int64      `gorm:"not null;default:1" json:"version"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+
+	// Total is the order value in minor currency units, computed from the lines
+	Total int64 `gorm:"-" json:"total"`
+
+	// Relationships
+	Supplier *suppliers.Supplier `gorm:"foreignKey:SupplierID;references:ID" json:"supplier,omitempty"`
+	Lines    []PurchaseOrderLine `gorm:"foreignKey:PurchaseOrderID;references:ID" json:"lines"`
+}
+
+func (PurchaseOrder) TableName() string {
+	return "purchase_orders"
+}
+
+// PurchaseOrderLine orders whole packs of one amenity. SKU, pack size and
+// unit price are copied from the supplier's catalogue when the line is
+// written, so later catalogue changes do not alter a placed order.
+// Quantities are in units, not packs.
+type PurchaseOrderLine struct {
+	ID               string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	PurchaseOrderID  string    `gorm:"type:varchar(36);not null;index" json:"purchaseOrderId"`
+	LineNo           int       `gorm:"not null" json:"lineNo"`
+	AmenityID        string    `gorm:"type:varchar(36);not null;index" json:"amenityId"`
+	SupplierItemID   *string   `gorm:"type:varchar(36)" json:"supplierItemId"`
+	SKU              string    `gorm:"type:varchar(64);not null" json:"sku"`
+	PackSize         int       `gorm:"not null" json:"packSize"`
+	Packs            int       `gorm:"not null" json:"packs"`
+	UnitPrice        int64     `gorm:"not null" json:"unitPrice"`
+	QuantityOrdered  int       `gorm:"not null" json:"quantityOrdered"`
+	QuantityReceived int       `gorm:"not null;default:0" json:"quantityReceived"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+
+	// Relationships
+	Amenity *amenities.Amenity `gorm:"foreignKey:AmenityID;references:ID" json:"amenity,omitempty"`
+}
+
+func (PurchaseOrderLine) TableName() string {
+	return "purchase_order_lines"
+}
+
+// Outstanding is the quantity still to be received
+func (l *PurchaseOrderLine) Outstanding() int {
+	if l.QuantityReceived >= l.QuantityOrdered {
+		return 0
+	}
+	return l.QuantityOrdered - l.QuantityReceived
+}
+
+// LineRequest orders a number of packs of an amenity from the supplier's catalogue
+type LineRequest struct {
+	AmenityID string `json:"amenityId" binding:"required"`
+	Packs     int    `json:"packs" binding:"required"`
+}
+
+// CreateOrderRequest represents the request body for creating a draft order
+type CreateOrderRequest struct {
+	TenantID   string        `json:"tenantId" binding:"required"`
+	SupplierID string        `json:"supplierId" binding:"required"`
+	LocationID *string       `json:"locationId"`
+	Notes      string        `json:"notes"`
+	Lines      []LineRequest `json:"lines"`
+}
+
+// UpdateOrderRequest represents the request body for editing a draft order.
+// Lines, when present, replace the order's lines.
+type UpdateOrderRequest struct {
+	LocationID *string       `json:"locationId"`
+	Notes      *string       `json:"notes"`
+	Lines      []LineRequest `json:"lines"`
+}
+
+// ReceiveLineRequest records units of one line that arrived. Perishable
+// goods can be received as a lot with its number and expiry date (YYYY-MM-DD).
+type ReceiveLineRequest struct {
+	LineID    string `json:"lineId" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required"`
+	LotNumber string `json:"lotNumber"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// ReceiveRequest represents the request body for receiving goods against an
+// order. LocationID overrides the order's receiving location.
+type ReceiveRequest struct {
+	LocationID string               `json:"locationId"`
+	Note       string               `json:"note"`
+	Lines      []ReceiveLineRequest `json:"lines" binding:"required"`
+}
+
+// ReceiveResult is the order after a receipt and the restock movements it wrote
+type ReceiveResult struct {
+	Order     *PurchaseOrder            `json:"order"`
+	Movements []amenities.StockMovement `json:"movements"`
+}
+
+// GenerateRequest represents the request body for generating draft orders
+// from a tenant's low-stock amenities
+type GenerateRequest struct {
+	TenantID   string  `json:"tenantId" binding:"required"`
+	LocationID *string `json:"locationId"`
+}
+
+// UnsourcedAmenity is a low-stock amenity no active supplier lists
+type UnsourcedAmenity struct {
+	AmenityID string `json:"amenityId"`
+	ItemName  string `json:"itemName"`
+	Shortfall int    `json:"shortfall"`
+}
+
+// GenerateResult lists the draft orders created and the amenities that
+// could not be ordered
+type GenerateResult struct {
+	Orders    []PurchaseOrder    `json:"orders"`
+	Unsourced []UnsourcedAmenity `json:"unsourced"`
+}
+
+// OrderFilter narrows a purchase order listing
+type OrderFilter struct {
+	Status     string
+	SupplierID string
+}
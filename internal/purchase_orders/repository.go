@@ -0,0 +1,162 @@
+package purchase_orders
+
+import (
+	"errors"
+
+	"concierge-be/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		db: database.GetDB(),
+	}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// Create creates an order together with its lines
+func (r *Repository) Create(order *PurchaseOrder) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(order).Error; err != nil {
+			return err
+		}
+		return r.WithTx(tx).ReplaceLines(order.ID, order.Lines)
+	})
+}
+
+// GetByID retrieves an order with its supplier and lines
+func (r *Repository) GetByID(id string) (*PurchaseOrder, error) {
+	var order PurchaseOrder
+	err := r.db.Preload("Supplier").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("line_no ASC") }).
+		Preload("Lines.Amenity").
+		Where("id = ?", id).
+		First(&order).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("purchase order not found")
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// TenantOf returns the tenant of purchase order id, "" if it does not exist
+func (r *Repository) TenantOf(id string) (string, error) {
+	var tenantIDs []string
+	if err := r.db.Model(&PurchaseOrder{}).Where("id = ?", id).Pluck("tenant_id", &tenantIDs).Error; err != nil || len(tenantIDs) == 0 {
+		return "", err
+	}
+	return tenantIDs[0], nil
+}
+
+// GetForUpdate locks an order row and loads its lines. Must be called in a
+// transaction; it serialises every change to the order.
+func (r *Repository) GetForUpdate(id string) (*PurchaseOrder, error) {
+	var order PurchaseOrder
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&order).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("purchase order not found")
+		}
+		return nil, err
+	}
+	if err := r.db.Where("purchase_order_id = ?", id).Order("line_no ASC").Find(&order.Lines).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetByTenantID retrieves a tenant's orders, newest first
+func (r *Repository) GetByTenantID(tenantID string, filter OrderFilter, page, pageSize int) ([]PurchaseOrder, int64, error) {
+	var orders []PurchaseOrder
+	var total int64
+
+	db := r.db.Model(&PurchaseOrder{}).Where("tenant_id = ?", tenantID)
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	if filter.SupplierID != "" {
+		db = db.Where("supplier_id = ?", filter.SupplierID)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Preload("Supplier").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("line_no ASC") }).
+		Order("created_at DESC, id DESC").
+		Offset(offset).Limit(pageSize).
+		Find(&orders).Error
+
+	return orders, total, err
+}
+
+// Update saves an order's own columns if it is still at order.Version,
+// returning database.ErrVersionConflict otherwise
+func (r *Repository) Update(order *PurchaseOrder) error {
+	return database.UpdateVersioned(r.db, order, &order.Version)
+}
+
+// ReplaceLines deletes an order's lines and writes lines in their place
+func (r *Repository) ReplaceLines(orderID string, lines []PurchaseOrderLine) error {
+	if err := r.db.Where("purchase_order_id = ?", orderID).Delete(&PurchaseOrderLine{}).Error; err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return r.db.Omit(clause.Associations).Create(&lines).Error
+}
+
+// UpdateReceived records the quantity received on a line
+func (r *Repository) UpdateReceived(lineID string, quantity int) error {
+	return r.db.Model(&PurchaseOrderLine{}).Where("id = ?", lineID).Update("quantity_received", quantity).Error
+}
+
+// Delete removes an order and its lines
+func (r *Repository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("purchase_order_id = ?", id).Delete(&PurchaseOrderLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&PurchaseOrder{}, "id = ?", id).Error
+	})
+}
+
+// GetOutstandingByAmenity sums, per amenity, the units a tenant has on
+// order but not yet received across drafts and orders awaiting receipt
+func (r *Repository) GetOutstandingByAmenity(tenantID string) (map[string]int, error) {
+	var rows []struct {
+		AmenityID   string
+		Outstanding int
+	}
+	err := r.db.Model(&PurchaseOrderLine{}).
+		Select("purchase_order_lines.amenity_id, SUM(purchase_order_lines.quantity_ordered - purchase_order_lines.quantity_received) AS outstanding").
+		Joins("JOIN purchase_orders ON purchase_orders.id = purchase_order_lines.purchase_order_id").
+		Where("purchase_orders.tenant_id = ? AND purchase_orders.status IN ?", tenantID,
+			[]string{StatusDraft, StatusSent, StatusPartiallyReceived}).
+		Group("purchase_order_lines.amenity_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	outstanding := make(map[string]int, len(rows))
+	for _, row := range rows {
+		outstanding[row.AmenityID] = row.Outstanding
+	}
+	return outstanding, nil
+}
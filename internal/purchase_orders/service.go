@@ -0,0 +1,571 @@
+package purchase_orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/locations"
+	"concierge-be/internal/suppliers"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	repo          *Repository
+	supplierRepo  *suppliers.Repository
+	amenityRepo   *amenities.Repository
+	locationsRepo *locations.Repository
+}
+
+func NewService() *Service {
+	return &Service{
+		repo:          NewRepository(),
+		supplierRepo:  suppliers.NewRepository(),
+		amenityRepo:   amenities.NewRepository(),
+		locationsRepo: locations.NewRepository(),
+	}
+}
+
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	db := s.repo.db.WithContext(ctx)
+	copied := *s
+	copied.repo = s.repo.WithTx(db)
+	copied.supplierRepo = s.supplierRepo.WithTx(db)
+	copied.amenityRepo = s.amenityRepo.WithTx(db)
+	copied.locationsRepo = s.locationsRepo.WithTx(db)
+	return &copied
+}
+
+// CreateOrder creates a draft order with lines priced from the supplier's catalogue
+func (s *Service) CreateOrder(req *CreateOrderRequest, actorID *string) (*PurchaseOrder, error) {
+	supplier, err := s.getTenantSupplier(req.TenantID, req.SupplierID)
+	if err != nil {
+		return nil, err
+	}
+	locationID, err := s.checkLocation(req.TenantID, req.LocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &PurchaseOrder{
+		ID:         uuid.New().String(),
+		TenantID:   req.TenantID,
+		SupplierID: supplier.ID,
+		Number:     newOrderNumber(time.Now()),
+		Status:     StatusDraft,
+		LocationID: locationID,
+		Notes:      req.Notes,
+		CreatedBy:  actorID,
+	}
+	order.Lines, err = s.buildLines(order.ID, supplier.ID, req.Lines)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(order); err != nil {
+		return nil, fmt.Errorf("failed to create purchase order: %w", err)
+	}
+
+	return s.GetOrderByID(order.ID)
+}
+
+// GetOrderByID retrieves an order with its supplier and lines
+func (s *Service) GetOrderByID(id string) (*PurchaseOrder, error) {
+	order, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	setTotal(order)
+	return order, nil
+}
+
+// GetOrdersByTenantID retrieves a tenant's orders, optionally by status or supplier
+func (s *Service) GetOrdersByTenantID(tenantID string, filter OrderFilter, page, pageSize int) ([]PurchaseOrder, int64, error) {
+	if filter.Status != "" && !ValidStatus(filter.Status) {
+		return nil, 0, errors.New("invalid purchase order status")
+	}
+	orders, total, err := s.repo.GetByTenantID(tenantID, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range orders {
+		setTotal(&orders[i])
+	}
+	return orders, total, nil
+}
+
+// UpdateOrder edits a draft order the caller last read at version
+func (s *Service) UpdateOrder(id string, req *UpdateOrderRequest, version int64) (*PurchaseOrder, error) {
+	err := s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		order, err := repo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if order.Version != version {
+			return database.ErrVersionConflict
+		}
+		if order.Status != StatusDraft {
+			return errors.New("only draft orders can be edited")
+		}
+
+		if req.LocationID != nil {
+			locationID, err := s.checkLocation(order.TenantID, req.LocationID)
+			if err != nil {
+				return err
+			}
+			order.LocationID = locationID
+		}
+		if req.Notes != nil {
+			order.Notes = *req.Notes
+		}
+		if req.Lines != nil {
+			lines, err := s.buildLines(order.ID, order.SupplierID, req.Lines)
+			if err != nil {
+				return err
+			}
+			if err := repo.ReplaceLines(order.ID, lines); err != nil {
+				return err
+			}
+		}
+
+		return repo.Update(order)
+	})
+	if err != nil {
+		return nil, orderError(err, "failed to update purchase order")
+	}
+
+	return s.GetOrderByID(id)
+}
+
+// DeleteOrder deletes a draft order
+func (s *Service) DeleteOrder(id string) error {
+	err := s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		order, err := repo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if order.Status != StatusDraft {
+			return errors.New("only draft orders can be deleted")
+		}
+		return repo.Delete(order.ID)
+	})
+	if err != nil {
+		return orderError(err, "failed to delete purchase order")
+	}
+	return nil
+}
+
+// SendOrder marks a draft order as sent to the supplier
+func (s *Service) SendOrder(id string) (*PurchaseOrder, error) {
+	err := s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		order, err := repo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if order.Status != StatusDraft {
+			return errors.New("only draft orders can be sent")
+		}
+		if len(order.Lines) == 0 {
+			return errors.New("purchase order has no lines")
+		}
+
+		now := time.Now()
+		order.Status = StatusSent
+		order.SentAt = &now
+		return repo.Update(order)
+	})
+	if err != nil {
+		return nil, orderError(err, "failed to send purchase order")
+	}
+
+	return s.GetOrderByID(id)
+}
+
+// ReceiveOrder records goods that arrived against a sent order. Each
+// received line writes a restock movement referencing the order number,
+// and the order becomes partially received or, once every line is in full,
+// received. Receiving more than is outstanding on a line is rejected.
+func (s *Service) ReceiveOrder(id string, req *ReceiveRequest, actorID *string) (*ReceiveResult, error) {
+	if len(req.Lines) == 0 {
+		return nil, errors.New("no lines to receive")
+	}
+
+	var movements []amenities.StockMovement
+	err := s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		amenityRepo := s.amenityRepo.WithTx(tx)
+
+		order, err := repo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if order.Status != StatusSent && order.Status != StatusPartiallyReceived {
+			return errors.New("purchase order is not awaiting receipt")
+		}
+
+		locationID := order.LocationID
+		if req.LocationID != "" {
+			locationID = &req.LocationID
+		}
+
+		lines := make(map[string]*PurchaseOrderLine, len(order.Lines))
+		for i := range order.Lines {
+			lines[order.Lines[i].ID] = &order.Lines[i]
+		}
+
+		for _, received := range req.Lines {
+			line, ok := lines[received.LineID]
+			if !ok {
+				return errors.New("purchase order line not found")
+			}
+			if received.Quantity <= 0 {
+				return errors.New("quantity must be greater than zero")
+			}
+			if received.Quantity > line.Outstanding() {
+				return errors.New("quantity exceeds the outstanding amount on the line")
+			}
+			lot, err := amenities.NewLotSpec(true, "", received.LotNumber, received.ExpiresAt)
+			if err != nil {
+				return err
+			}
+
+			movement := amenities.StockMovement{
+				AmenityID:  line.AmenityID,
+				LocationID: locationID,
+				Lot:        lot,
+				Delta:      received.Quantity,
+				UnitCost:   &line.UnitPrice,
+				Reason:     amenities.MovementRestock,
+				Reference:  order.Number,
+				Note:       req.Note,
+				ActorID:    actorID,
+			}
+			if err := amenityRepo.ApplyMovement(&movement); err != nil {
+				return err
+			}
+			movements = append(movements, movement)
+
+			line.QuantityReceived += received.Quantity
+			if err := repo.UpdateReceived(line.ID, line.QuantityReceived); err != nil {
+				return err
+			}
+		}
+
+		order.Status = StatusReceived
+		for _, line := range order.Lines {
+			if line.Outstanding() > 0 {
+				order.Status = StatusPartiallyReceived
+				break
+			}
+		}
+		if order.Status == StatusReceived {
+			now := time.Now()
+			order.ReceivedAt = &now
+		}
+		return repo.Update(order)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("amenity not found")
+		}
+		return nil, orderError(err, "failed to receive purchase order")
+	}
+
+	order, err := s.GetOrderByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return &ReceiveResult{Order: order, Movements: movements}, nil
+}
+
+// CloseOrder closes a received order, or a partially received one whose
+// remaining quantities will not arrive
+func (s *Service) CloseOrder(id string) (*PurchaseOrder, error) {
+	err := s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		order, err := repo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if order.Status != StatusReceived && order.Status != StatusPartiallyReceived {
+			return errors.New("only received orders can be closed")
+		}
+
+		now := time.Now()
+		order.Status = StatusClosed
+		order.ClosedAt = &now
+		return repo.Update(order)
+	})
+	if err != nil {
+		return nil, orderError(err, "failed to close purchase order")
+	}
+
+	return s.GetOrderByID(id)
+}
+
+// GenerateDrafts creates one draft order per supplier covering every
+// low-stock amenity of a tenant. Each amenity is ordered from its preferred
+// supplier, or the cheapest active one, in enough whole packs to bring it
+// back to its minimum once everything already on order arrives.
+func (s *Service) GenerateDrafts(req *GenerateRequest, actorID *string) (*GenerateResult, error) {
+	locationID, err := s.checkLocation(req.TenantID, req.LocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	low, err := s.amenityRepo.GetLowStock(req.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load low-stock amenities: %w", err)
+	}
+	outstanding, err := s.repo.GetOutstandingByAmenity(req.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load outstanding orders: %w", err)
+	}
+	amenityIDs := make([]string, 0, len(low))
+	for _, amenity := range low {
+		amenityIDs = append(amenityIDs, amenity.ID)
+	}
+	items, err := s.supplierRepo.GetItemsByAmenityIDs(amenityIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load supplier catalogues: %w", err)
+	}
+
+	plans, unsourced := PlanReorder(low, outstanding, items)
+
+	result := &GenerateResult{Orders: []PurchaseOrder{}, Unsourced: unsourced}
+	now := time.Now()
+	err = s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		for _, plan := range plans {
+			order := PurchaseOrder{
+				ID:         uuid.New().String(),
+				TenantID:   req.TenantID,
+				SupplierID: plan.SupplierID,
+				Number:     newOrderNumber(now),
+				Status:     StatusDraft,
+				LocationID: locationID,
+				Notes:      "Generated from low-stock amenities",
+				CreatedBy:  actorID,
+			}
+			for i, planned := range plan.Lines {
+				item := planned.Item
+				order.Lines = append(order.Lines, PurchaseOrderLine{
+					ID:              uuid.New().String(),
+					PurchaseOrderID: order.ID,
+					LineNo:          i + 1,
+					AmenityID:       item.AmenityID,
+					SupplierItemID:  &item.ID,
+					SKU:             item.SKU,
+					PackSize:        item.PackSize,
+					Packs:           planned.Packs,
+					UnitPrice:       item.UnitPrice,
+					QuantityOrdered: planned.Packs * item.PackSize,
+				})
+			}
+			if err := repo.Create(&order); err != nil {
+				return err
+			}
+			result.Orders = append(result.Orders, order)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate purchase orders: %w", err)
+	}
+
+	for i := range result.Orders {
+		order, err := s.GetOrderByID(result.Orders[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		result.Orders[i] = *order
+	}
+	return result, nil
+}
+
+// PlannedLine is a number of packs of one catalogue entry to order
+type PlannedLine struct {
+	Item  suppliers.SupplierItem
+	Packs int
+}
+
+// SupplierPlan is the lines to order from one supplier
+type SupplierPlan struct {
+	SupplierID string
+	Lines      []PlannedLine
+}
+
+// PlanReorder works out what to order for low-stock amenities. The shortfall
+// is the minimum less current stock and anything already on order; it is
+// rounded up to whole packs of the first catalogue entry listed for the
+// amenity, so items should come preferred first, then cheapest. Amenities
+// whose shortfall is already covered are skipped and those no supplier lists
+// are returned as unsourced. Plans are ordered by supplier name.
+func PlanReorder(low []amenities.Amenity, outstanding map[string]int, items []suppliers.SupplierItem) ([]SupplierPlan, []UnsourcedAmenity) {
+	best := make(map[string]suppliers.SupplierItem)
+	for _, item := range items {
+		if _, ok := best[item.AmenityID]; !ok {
+			best[item.AmenityID] = item
+		}
+	}
+
+	bySupplier := make(map[string]*SupplierPlan)
+	supplierNames := make(map[string]string)
+	unsourced := []UnsourcedAmenity{}
+	for _, amenity := range low {
+		shortfall := amenity.MinimumStock - amenity.Stock - outstanding[amenity.ID]
+		if shortfall <= 0 {
+			continue
+		}
+		item, ok := best[amenity.ID]
+		if !ok {
+			unsourced = append(unsourced, UnsourcedAmenity{
+				AmenityID: amenity.ID,
+				ItemName:  amenity.ItemName,
+				Shortfall: shortfall,
+			})
+			continue
+		}
+
+		packSize := item.PackSize
+		if packSize < 1 {
+			packSize = 1
+		}
+		plan, ok := bySupplier[item.SupplierID]
+		if !ok {
+			plan = &SupplierPlan{SupplierID: item.SupplierID}
+			bySupplier[item.SupplierID] = plan
+			if item.Supplier != nil {
+				supplierNames[item.SupplierID] = item.Supplier.Name
+			}
+		}
+		plan.Lines = append(plan.Lines, PlannedLine{
+			Item:  item,
+			Packs: (shortfall + packSize - 1) / packSize,
+		})
+	}
+
+	plans := make([]SupplierPlan, 0, len(bySupplier))
+	for _, plan := range bySupplier {
+		plans = append(plans, *plan)
+	}
+	sort.Slice(plans, func(i, j int) bool {
+		a, b := supplierNames[plans[i].SupplierID], supplierNames[plans[j].SupplierID]
+		if a != b {
+			return a < b
+		}
+		return plans[i].SupplierID < plans[j].SupplierID
+	})
+	return plans, unsourced
+}
+
+// buildLines turns requested lines into order lines priced from the
+// supplier's catalogue
+func (s *Service) buildLines(orderID, supplierID string, requested []LineRequest) ([]PurchaseOrderLine, error) {
+	items, err := s.supplierRepo.GetItemsBySupplierID(supplierID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load supplier catalogue: %w", err)
+	}
+	catalogue := make(map[string]suppliers.SupplierItem, len(items))
+	for _, item := range items {
+		catalogue[item.AmenityID] = item
+	}
+
+	lines := make([]PurchaseOrderLine, 0, len(requested))
+	seen := make(map[string]bool, len(requested))
+	for i, req := range requested {
+		if req.Packs <= 0 {
+			return nil, errors.New("packs must be greater than zero")
+		}
+		if seen[req.AmenityID] {
+			return nil, errors.New("amenity appears on more than one line")
+		}
+		seen[req.AmenityID] = true
+
+		item, ok := catalogue[req.AmenityID]
+		if !ok {
+			return nil, errors.New("supplier does not list this amenity")
+		}
+		itemID := item.ID
+		lines = append(lines, PurchaseOrderLine{
+			ID:              uuid.New().String(),
+			PurchaseOrderID: orderID,
+			LineNo:          i + 1,
+			AmenityID:       item.AmenityID,
+			SupplierItemID:  &itemID,
+			SKU:             item.SKU,
+			PackSize:        item.PackSize,
+			Packs:           req.Packs,
+			UnitPrice:       item.UnitPrice,
+			QuantityOrdered: req.Packs * item.PackSize,
+		})
+	}
+	return lines, nil
+}
+
+// getTenantSupplier returns an active supplier of the tenant
+func (s *Service) getTenantSupplier(tenantID, supplierID string) (*suppliers.Supplier, error) {
+	supplier, err := s.supplierRepo.GetByID(supplierID)
+	if err != nil || supplier.TenantID != tenantID {
+		return nil, errors.New("supplier not found")
+	}
+	if !supplier.IsActive {
+		return nil, errors.New("supplier is inactive")
+	}
+	return supplier, nil
+}
+
+// checkLocation verifies a receiving location belongs to the tenant. An
+// empty ID means the default location and is returned as nil.
+func (s *Service) checkLocation(tenantID string, locationID *string) (*string, error) {
+	if locationID == nil || *locationID == "" {
+		return nil, nil
+	}
+	location, err := s.locationsRepo.GetByID(*locationID)
+	if err != nil || location.TenantID != tenantID {
+		return nil, errors.New("location not found")
+	}
+	return &location.ID, nil
+}
+
+// orderError passes known order errors through and wraps the rest
+func orderError(err error, action string) error {
+	if errors.Is(err, database.ErrVersionConflict) || strings.HasPrefix(err.Error(), "invalid lot") {
+		return err
+	}
+	switch err.Error() {
+	case "purchase order not found", "location not found", "stock quantity cannot be negative",
+		"only draft orders can be edited", "only draft orders can be deleted", "only draft orders can be sent",
+		"purchase order has no lines", "purchase order is not awaiting receipt", "only received orders can be closed",
+		"purchase order line not found", "quantity must be greater than zero",
+		"quantity exceeds the outstanding amount on the line",
+		"packs must be greater than zero", "amenity appears on more than one line", "supplier does not list this amenity":
+		return err
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
+
+// setTotal computes an order's value from its lines
+func setTotal(order *PurchaseOrder) {
+	order.Total = 0
+	for _, line := range order.Lines {
+		order.Total += int64(line.QuantityOrdered) * line.UnitPrice
+	}
+}
+
+// newOrderNumber returns a human-readable order number such as PO-20260301-1A2B3C4D
+func newOrderNumber(now time.Time) string {
+	suffix := strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:8])
+	return fmt.Sprintf("PO-%s-%s", now.Format("20060102"), suffix)
+}
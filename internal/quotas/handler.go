@@ -0,0 +1,157 @@
+package quotas
+
+import (
+	"errors"
+	"net/http"
+
+	"concierge-be/database"
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
+// CreatePlan handles POST /api/v1/plans
+func (h *Handler) CreatePlan(c *gin.Context) {
+	var req CreatePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	plan, err := h.serviceFor(c).CreatePlan(&req)
+	if err != nil {
+		if err.Error() == "plan code already exists" {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "Success",
+		Data:    plan,
+	})
+}
+
+// GetPlan handles GET /api/v1/plans/:id
+func (h *Handler) GetPlan(c *gin.Context) {
+	plan, err := h.serviceFor(c).GetPlanByID(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SetETag(c, plan.Version)
+	utils.SuccessResponse(c, plan)
+}
+
+// GetAllPlans handles GET /api/v1/plans
+func (h *Handler) GetAllPlans(c *gin.Context) {
+	plans, err := h.serviceFor(c).GetAllPlans()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, plans)
+}
+
+// UpdatePlan handles PUT /api/v1/plans/:id
+func (h *Handler) UpdatePlan(c *gin.Context) {
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req UpdatePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	plan, err := h.serviceFor(c).UpdatePlan(c.Param("id"), &req, version)
+	if err != nil {
+		if err.Error() == "plan not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, database.ErrVersionConflict) {
+			utils.ErrorResponse(c, http.StatusPreconditionFailed, "Plan has been modified; reload and retry")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SetETag(c, plan.Version)
+	utils.SuccessResponse(c, plan)
+}
+
+// DeletePlan handles DELETE /api/v1/plans/:id
+func (h *Handler) DeletePlan(c *gin.Context) {
+	if err := h.serviceFor(c).DeletePlan(c.Param("id")); err != nil {
+		switch err.Error() {
+		case "plan not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case "plan is assigned to tenants":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Plan deleted successfully"})
+}
+
+// AssignPlan handles PUT /api/v1/tenants/:id/plan
+func (h *Handler) AssignPlan(c *gin.Context) {
+	var req AssignPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.serviceFor(c).AssignPlan(c.Param("id"), req.PlanID); err != nil {
+		if err.Error() == "plan not found" || err.Error() == "tenant not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Plan assigned successfully"})
+}
+
+// GetUsage handles GET /api/v1/tenants/:id/usage
+func (h *Handler) GetUsage(c *gin.Context) {
+	usage, err := h.serviceFor(c).GetUsage(c.Param("id"))
+	if err != nil {
+		if err.Error() == "tenant not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, usage)
+}
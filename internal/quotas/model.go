@@ -0,0 +1,102 @@
+package quotas
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Resources limited by a plan
+const (
+	ResourceAmenities      = "amenities"
+	ResourceCategories     = "categories"
+	ResourceMembers        = "members"
+	ResourceRequestsPerDay = "requestsPerDay"
+)
+
+// Plan is a sellable tier with per-tenant limits. A limit of 0 means unlimited.
+type Plan struct {
+	ID                string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Code              string         `gorm:"type:varchar(50);uniqueIndex;not null" json:"code"`
+	Name              string         `gorm:"type:varchar(100);not null" json:"name"`
+	MaxAmenities      int            `gorm:"default:0;not null" json:"maxAmenities"`
+	MaxCategories     int            `gorm:"default:0;not null" json:"maxCategories"`
+	MaxMembers        int            `gorm:"default:0;not null" json:"maxMembers"`
+	MaxRequestsPerDay int            `gorm:"default:0;not null" json:"maxRequestsPerDay"`
+	Version           int64          `gorm:"not null;default:1" json:"version"`
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Plan) TableName() string {
+	return "plans"
+}
+
+// Limit returns the plan's limit for a resource
+func (p *Plan) Limit(resource string) int {
+	switch resource {
+	case ResourceAmenities:
+		return p.MaxAmenities
+	case ResourceCategories:
+		return p.MaxCategories
+	case ResourceMembers:
+		return p.MaxMembers
+	case ResourceRequestsPerDay:
+		return p.MaxRequestsPerDay
+	}
+	return 0
+}
+
+// UsageCounter records metered usage for a tenant over a period, such as
+// requests per calendar day
+type UsageCounter struct {
+	TenantID  string    `gorm:"type:varchar(36);primaryKey" json:"tenantId"`
+	Metric    string    `gorm:"type:varchar(50);primaryKey" json:"metric"`
+	Period    string    `gorm:"type:varchar(20);primaryKey" json:"period"`
+	Value     int64     `gorm:"default:0;not null" json:"value"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (UsageCounter) TableName() string {
+	return "usage_counters"
+}
+
+// CreatePlanRequest represents the request body for creating a plan
+type CreatePlanRequest struct {
+	Code              string `json:"code" binding:"required"`
+	Name              string `json:"name" binding:"required"`
+	MaxAmenities      int    `json:"maxAmenities" binding:"min=0"`
+	MaxCategories     int    `json:"maxCategories" binding:"min=0"`
+	MaxMembers        int    `json:"maxMembers" binding:"min=0"`
+	MaxRequestsPerDay int    `json:"maxRequestsPerDay" binding:"min=0"`
+}
+
+// UpdatePlanRequest represents the request body for updating a plan
+type UpdatePlanRequest struct {
+	Name              string `json:"name"`
+	MaxAmenities      *int   `json:"maxAmenities" binding:"omitempty,min=0"`
+	MaxCategories     *int   `json:"maxCategories" binding:"omitempty,min=0"`
+	MaxMembers        *int   `json:"maxMembers" binding:"omitempty,min=0"`
+	MaxRequestsPerDay *int   `json:"maxRequestsPerDay" binding:"omitempty,min=0"`
+}
+
+// AssignPlanRequest represents the request body for assigning a plan to a tenant
+type AssignPlanRequest struct {
+	PlanID string `json:"planId" binding:"required"`
+}
+
+// ResourceUsage reports how much of one limit a tenant has used
+type ResourceUsage struct {
+	Resource  string `json:"resource"`
+	Used      int64  `json:"used"`
+	Limit     int    `json:"limit"`
+	Remaining *int64 `json:"remaining"` // nil when unlimited
+}
+
+// TenantUsage reports a tenant's usage against its plan
+type TenantUsage struct {
+	TenantID string          `json:"tenantId"`
+	Plan     *Plan           `json:"plan"`
+	Usage    []ResourceUsage `json:"usage"`
+}
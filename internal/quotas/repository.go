@@ -0,0 +1,177 @@
+package quotas
+
+import (
+	"errors"
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/tenants"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		db: database.GetDB(),
+	}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// CreatePlan creates a new plan
+func (r *Repository) CreatePlan(plan *Plan) error {
+	return r.db.Create(plan).Error
+}
+
+// GetPlanByID retrieves a plan by ID
+func (r *Repository) GetPlanByID(id string) (*Plan, error) {
+	var plan Plan
+	err := r.db.Where("id = ?", id).First(&plan).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("plan not found")
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// GetAllPlans retrieves all plans
+func (r *Repository) GetAllPlans() ([]Plan, error) {
+	var plans []Plan
+	err := r.db.Order("name ASC").Find(&plans).Error
+	return plans, err
+}
+
+// CheckPlanCodeExists checks if a plan code is already taken. Deleted plans
+// count: the unique index still covers them until purged.
+func (r *Repository) CheckPlanCodeExists(code string) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&Plan{}).Where("code = ?", code).Count(&count).Error
+	return count > 0, err
+}
+
+// UpdatePlan updates an existing plan if it is still at plan.Version,
+// returning database.ErrVersionConflict otherwise
+func (r *Repository) UpdatePlan(plan *Plan) error {
+	return database.UpdateVersioned(r.db, plan, &plan.Version)
+}
+
+// DeletePlan soft deletes a plan
+func (r *Repository) DeletePlan(id string) error {
+	return r.db.Delete(&Plan{}, "id = ?", id).Error
+}
+
+// CountTenantsOnPlan counts tenants assigned to a plan
+func (r *Repository) CountTenantsOnPlan(planID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&tenants.Tenant{}).Where("plan_id = ?", planID).Count(&count).Error
+	return count, err
+}
+
+// GetTenantPlan retrieves the plan assigned to a tenant, or nil if none
+func (r *Repository) GetTenantPlan(tenantID string) (*Plan, error) {
+	var tenant tenants.Tenant
+	err := r.db.Select("id", "plan_id").Where("id = ?", tenantID).First(&tenant).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tenant not found")
+		}
+		return nil, err
+	}
+	if tenant.PlanID == nil {
+		return nil, nil
+	}
+	return r.GetPlanByID(*tenant.PlanID)
+}
+
+// LockTenantPlan retrieves the plan assigned to a tenant like GetTenantPlan,
+// locking the tenant row until the surrounding transaction ends so that
+// concurrent quota checks for the tenant run one at a time
+func (r *Repository) LockTenantPlan(tenantID string) (*Plan, error) {
+	var tenant tenants.Tenant
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "plan_id").Where("id = ?", tenantID).First(&tenant).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tenant not found")
+		}
+		return nil, err
+	}
+	if tenant.PlanID == nil {
+		return nil, nil
+	}
+	return r.GetPlanByID(*tenant.PlanID)
+}
+
+// SetTenantPlan assigns a plan to a tenant
+func (r *Repository) SetTenantPlan(tenantID, planID string) error {
+	result := r.db.Model(&tenants.Tenant{}).Where("id = ?", tenantID).Update("plan_id", planID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("tenant not found")
+	}
+	return nil
+}
+
+// CountResource counts how many of a limited resource a tenant currently holds
+func (r *Repository) CountResource(tenantID, resource string) (int64, error) {
+	var count int64
+	var err error
+
+	switch resource {
+	case ResourceAmenities:
+		err = r.db.Table("amenities").Where("tenant_id = ? AND deleted_at IS NULL", tenantID).Count(&count).Error
+	case ResourceCategories:
+		err = r.db.Table("amenities_categories").Where("tenant_id = ? AND deleted_at IS NULL", tenantID).Count(&count).Error
+	case ResourceMembers:
+		err = r.db.Table("user_tenants").Where("tenant_id = ?", tenantID).Count(&count).Error
+	case ResourceRequestsPerDay:
+		return r.GetCounter(tenantID, ResourceRequestsPerDay, dayPeriod(time.Now()))
+	}
+
+	return count, err
+}
+
+// IncrementCounter atomically adds delta to a usage counter and returns the new value
+func (r *Repository) IncrementCounter(tenantID, metric, period string, delta int64) (int64, error) {
+	counter := UsageCounter{TenantID: tenantID, Metric: metric, Period: period, Value: delta}
+	err := r.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"value":      gorm.Expr("value + ?", delta),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&counter).Error
+	if err != nil {
+		return 0, err
+	}
+	return r.GetCounter(tenantID, metric, period)
+}
+
+// GetCounter reads a usage counter, returning 0 if it has not been recorded
+func (r *Repository) GetCounter(tenantID, metric, period string) (int64, error) {
+	var counter UsageCounter
+	err := r.db.Where("tenant_id = ? AND metric = ? AND period = ?", tenantID, metric, period).First(&counter).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return counter.Value, nil
+}
+
+// dayPeriod formats the counter period for a calendar day
+func dayPeriod(t time.Time) string {
+	return t.Format("2006-01-02")
+}
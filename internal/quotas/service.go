@@ -0,0 +1,229 @@
+package quotas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"concierge-be/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrQuotaExceeded is returned when an action would take a tenant past a plan limit
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService() *Service {
+	return &Service{
+		repo: NewRepository(),
+	}
+}
+
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	copied := *s
+	copied.repo = s.repo.WithTx(s.repo.db.WithContext(ctx))
+	return &copied
+}
+
+// WithTx returns a copy of the service whose statements run in tx. Quota
+// checks must run in the transaction that creates the resource they guard.
+func (s *Service) WithTx(tx *gorm.DB) *Service {
+	copied := *s
+	copied.repo = s.repo.WithTx(tx)
+	return &copied
+}
+
+// CheckLimit returns an error wrapping ErrQuotaExceeded if the tenant cannot
+// create another unit of resource under its plan
+func (s *Service) CheckLimit(tenantID, resource string) error {
+	return s.CheckCapacity(tenantID, resource, 1)
+}
+
+// CheckCapacity returns an error wrapping ErrQuotaExceeded if the tenant
+// cannot create n more units of resource under its plan. It locks the tenant
+// row, so run it through WithTx in the transaction that creates the units.
+func (s *Service) CheckCapacity(tenantID, resource string, n int) error {
+	plan, err := s.repo.LockTenantPlan(tenantID)
+	if err != nil {
+		if err.Error() == "tenant not found" {
+			return err
+		}
+		return fmt.Errorf("failed to load tenant plan: %w", err)
+	}
+	if plan == nil {
+		return nil
+	}
+
+	limit := plan.Limit(resource)
+	if limit == 0 {
+		return nil
+	}
+
+	used, err := s.repo.CountResource(tenantID, resource)
+	if err != nil {
+		return fmt.Errorf("failed to count %s: %w", resource, err)
+	}
+	if used+int64(n) > int64(limit) {
+		return fmt.Errorf("%w: %s limit of %d reached for plan %s", ErrQuotaExceeded, resource, limit, plan.Code)
+	}
+	return nil
+}
+
+// RecordRequest counts an API request against the tenant's daily limit.
+// The request is counted even when it is rejected; requests naming a tenant
+// that does not exist are not counted.
+func (s *Service) RecordRequest(tenantID string) error {
+	plan, err := s.repo.GetTenantPlan(tenantID)
+	if err != nil {
+		if err.Error() == "tenant not found" {
+			return nil
+		}
+		return fmt.Errorf("failed to load tenant plan: %w", err)
+	}
+
+	used, err := s.repo.IncrementCounter(tenantID, ResourceRequestsPerDay, dayPeriod(time.Now()), 1)
+	if err != nil {
+		return fmt.Errorf("failed to record request: %w", err)
+	}
+	if plan == nil {
+		return nil
+	}
+	if limit := plan.MaxRequestsPerDay; limit > 0 && used > int64(limit) {
+		return fmt.Errorf("%w: %s limit of %d reached for plan %s", ErrQuotaExceeded, ResourceRequestsPerDay, limit, plan.Code)
+	}
+	return nil
+}
+
+// GetUsage reports a tenant's usage against every limit in its plan
+func (s *Service) GetUsage(tenantID string) (*TenantUsage, error) {
+	plan, err := s.repo.GetTenantPlan(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &TenantUsage{TenantID: tenantID, Plan: plan}
+	resources := []string{ResourceAmenities, ResourceCategories, ResourceMembers, ResourceRequestsPerDay}
+	for _, resource := range resources {
+		used, err := s.repo.CountResource(tenantID, resource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", resource, err)
+		}
+
+		item := ResourceUsage{Resource: resource, Used: used}
+		if plan != nil {
+			item.Limit = plan.Limit(resource)
+		}
+		if item.Limit > 0 {
+			remaining := int64(item.Limit) - used
+			if remaining < 0 {
+				remaining = 0
+			}
+			item.Remaining = &remaining
+		}
+		usage.Usage = append(usage.Usage, item)
+	}
+
+	return usage, nil
+}
+
+// AssignPlan assigns a plan to a tenant
+func (s *Service) AssignPlan(tenantID, planID string) error {
+	if _, err := s.repo.GetPlanByID(planID); err != nil {
+		return err
+	}
+	return s.repo.SetTenantPlan(tenantID, planID)
+}
+
+// CreatePlan creates a new plan
+func (s *Service) CreatePlan(req *CreatePlanRequest) (*Plan, error) {
+	exists, err := s.repo.CheckPlanCodeExists(req.Code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check plan code: %w", err)
+	}
+	if exists {
+		return nil, errors.New("plan code already exists")
+	}
+
+	plan := &Plan{
+		ID:                uuid.New().String(),
+		Code:              req.Code,
+		Name:              req.Name,
+		MaxAmenities:      req.MaxAmenities,
+		MaxCategories:     req.MaxCategories,
+		MaxMembers:        req.MaxMembers,
+		MaxRequestsPerDay: req.MaxRequestsPerDay,
+	}
+
+	if err := s.repo.CreatePlan(plan); err != nil {
+		return nil, fmt.Errorf("failed to create plan: %w", err)
+	}
+
+	return plan, nil
+}
+
+// GetPlanByID retrieves a plan by ID
+func (s *Service) GetPlanByID(id string) (*Plan, error) {
+	return s.repo.GetPlanByID(id)
+}
+
+// GetAllPlans retrieves all plans
+func (s *Service) GetAllPlans() ([]Plan, error) {
+	return s.repo.GetAllPlans()
+}
+
+// UpdatePlan updates an existing plan the caller last read at version
+func (s *Service) UpdatePlan(id string, req *UpdatePlanRequest, version int64) (*Plan, error) {
+	plan, err := s.repo.GetPlanByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Version != version {
+		return nil, database.ErrVersionConflict
+	}
+
+	if req.Name != "" {
+		plan.Name = req.Name
+	}
+	if req.MaxAmenities != nil {
+		plan.MaxAmenities = *req.MaxAmenities
+	}
+	if req.MaxCategories != nil {
+		plan.MaxCategories = *req.MaxCategories
+	}
+	if req.MaxMembers != nil {
+		plan.MaxMembers = *req.MaxMembers
+	}
+	if req.MaxRequestsPerDay != nil {
+		plan.MaxRequestsPerDay = *req.MaxRequestsPerDay
+	}
+
+	if err := s.repo.UpdatePlan(plan); err != nil {
+		return nil, fmt.Errorf("failed to update plan: %w", err)
+	}
+
+	return plan, nil
+}
+
+// DeletePlan deletes a plan that no tenant is using
+func (s *Service) DeletePlan(id string) error {
+	if _, err := s.repo.GetPlanByID(id); err != nil {
+		return err
+	}
+
+	count, err := s.repo.CountTenantsOnPlan(id)
+	if err != nil {
+		return fmt.Errorf("failed to check plan usage: %w", err)
+	}
+	if count > 0 {
+		return errors.New("plan is assigned to tenants")
+	}
+
+	return s.repo.DeletePlan(id)
+}
@@ -0,0 +1,209 @@
+package replenishment
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Calculation defaults
+const (
+	DefaultLookbackDays = 90
+	DefaultReviewDays   = 7
+	DefaultServiceLevel = 0.95
+	DefaultLeadTimeDays = 7
+
+	// MinHistoryDays is how much history a recommendation needs before it
+	// can be accepted
+	MinHistoryDays = 14
+
+	// leadTimeSamples is how many recent deliveries the observed lead time averages
+	leadTimeSamples = 5
+)
+
+// serviceLevels maps the supported service levels (the chance of not running
+// out while waiting for a delivery) to their standard normal z-scores
+var serviceLevels = map[float64]float64{
+	0.90: 1.28,
+	0.95: 1.65,
+	0.98: 2.05,
+	0.99: 2.33,
+}
+
+// ServiceLevelZ returns the z-score for a supported service level
+func ServiceLevelZ(level float64) (float64, bool) {
+	z, ok := serviceLevels[level]
+	return z, ok
+}
+
+// SupportedServiceLevels lists the service levels ServiceLevelZ accepts
+func SupportedServiceLevels() []float64 {
+	levels := make([]float64, 0, len(serviceLevels))
+	for level := range serviceLevels {
+		levels = append(levels, level)
+	}
+	sort.Float64s(levels)
+	return levels
+}
+
+// UsageStats summarises a daily consumption series
+type UsageStats struct {
+	Days   int
+	Total  int
+	Mean   float64
+	StdDev float64
+}
+
+// Usage computes the mean and population standard deviation of daily
+// consumption. Days with no consumption must be present as zeros.
+func Usage(daily []int) UsageStats {
+	stats := UsageStats{Days: len(daily)}
+	if stats.Days == 0 {
+		return stats
+	}
+	for _, used := range daily {
+		stats.Total += used
+	}
+	stats.Mean = float64(stats.Total) / float64(stats.Days)
+
+	var squares float64
+	for _, used := range daily {
+		diff := float64(used) - stats.Mean
+		squares += diff * diff
+	}
+	stats.StdDev = math.Sqrt(squares / float64(stats.Days))
+	return stats
+}
+
+// Inputs are everything a recommendation depends on. The same inputs always
+// produce the same recommendation.
+type Inputs struct {
+	Usage        UsageStats
+	LeadTimeDays float64
+	ReviewDays   int
+	Z            float64
+	Stock        int
+	PackSize     int
+}
+
+// Levels are the recommended stock levels for one amenity, in units
+type Levels struct {
+	SafetyStock   int
+	ReorderPoint  int
+	ParLevel      int
+	OrderQuantity int
+}
+
+// Compute derives stock levels from consumption and lead time:
+//
+//	safety stock  = z × σ × √L
+//	reorder point = μ × L + safety stock
+//	par level     = reorder point + μ × R
+//	order qty     = par level − stock, rounded up to whole packs
+//
+// where μ and σ are the mean and standard deviation of daily usage, L the
+// lead time and R the review period in days. Each level is rounded up to
+// whole units.
+func Compute(in Inputs) Levels {
+	mean := in.Usage.Mean
+	leadTime := math.Max(in.LeadTimeDays, 0)
+
+	levels := Levels{
+		SafetyStock: ceil(in.Z * in.Usage.StdDev * math.Sqrt(leadTime)),
+	}
+	levels.ReorderPoint = ceil(mean*leadTime) + levels.SafetyStock
+	levels.ParLevel = levels.ReorderPoint + ceil(mean*float64(in.ReviewDays))
+
+	shortfall := levels.ParLevel - in.Stock
+	if shortfall > 0 {
+		packSize := in.PackSize
+		if packSize < 1 {
+			packSize = 1
+		}
+		levels.OrderQuantity = (shortfall + packSize - 1) / packSize * packSize
+	}
+	return levels
+}
+
+// ceil rounds up, ignoring floating point noise just above a whole number
+func ceil(value float64) int {
+	if value <= 0 {
+		return 0
+	}
+	return int(math.Ceil(value - 1e-9))
+}
+
+// round2 rounds to two decimal places for display
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// DailySeries buckets consumption into units used per day for the days
+// starting at start, which should be a midnight. Consumption falls on its
+// calendar date in start's location, so days of 23 or 25 hours across a
+// daylight saving change count as one day each. Days without consumption
+// are zero; consumption outside the range is ignored.
+func DailySeries(consumption []Consumption, start time.Time, days int) []int {
+	if days <= 0 {
+		return []int{}
+	}
+	series := make([]int, days)
+	for _, used := range consumption {
+		day := daysBetween(start, used.At.In(start.Location()))
+		if day >= 0 && day < days {
+			series[day] += used.Quantity
+		}
+	}
+	return series
+}
+
+// daysBetween counts the calendar days from from's date to to's date, each
+// taken in its own location
+func daysBetween(from, to time.Time) int {
+	fromYear, fromMonth, fromDay := from.Date()
+	toYear, toMonth, toDay := to.Date()
+	// Whole days are exactly 24 hours apart in UTC
+	start := time.Date(fromYear, fromMonth, fromDay, 0, 0, 0, 0, time.UTC)
+	end := time.Date(toYear, toMonth, toDay, 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start) / (24 * time.Hour))
+}
+
+// ObservedLeadTime averages the delivery time in days of up to limit
+// deliveries, which should be most recent first
+func ObservedLeadTime(deliveries []Delivery, limit int) (float64, bool) {
+	var total time.Duration
+	count := 0
+	for _, delivery := range deliveries {
+		if count == limit {
+			break
+		}
+		if delivery.ReceivedAt.Before(delivery.SentAt) {
+			continue
+		}
+		total += delivery.ReceivedAt.Sub(delivery.SentAt)
+		count++
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return round2(total.Hours() / 24 / float64(count)), true
+}
+
+// LeadTime picks the lead time a recommendation uses and where it came
+// from: the average of the most recent deliveries, else the supplier's
+// quoted lead time (none if zero), else DefaultLeadTimeDays
+func LeadTime(deliveries []Delivery, supplierLeadTimeDays int) (float64, string) {
+	if observed, ok := ObservedLeadTime(deliveries, leadTimeSamples); ok {
+		return observed, LeadTimeObserved
+	}
+	if supplierLeadTimeDays > 0 {
+		return float64(supplierLeadTimeDays), LeadTimeSupplier
+	}
+	return DefaultLeadTimeDays, LeadTimeDefault
+}
+
+// startOfDay returns the midnight that begins t's day in t's location
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
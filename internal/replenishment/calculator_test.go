@@ -0,0 +1,176 @@
+package replenishment
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestRecommendationLevels(t *testing.T) {
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(day, hour, quantity int) Consumption {
+		return Consumption{AmenityID: "amenity-1", Quantity: quantity, At: start.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)}
+	}
+	sent := func(day, leadDays int) Delivery {
+		sentAt := start.AddDate(0, 0, day)
+		return Delivery{AmenityID: "amenity-1", SentAt: sentAt, ReceivedAt: sentAt.AddDate(0, 0, leadDays)}
+	}
+	daily := func(quantities ...int) []Consumption {
+		consumption := make([]Consumption, 0, len(quantities))
+		for day, quantity := range quantities {
+			consumption = append(consumption, at(day, 10, quantity))
+		}
+		return consumption
+	}
+
+	tests := []struct {
+		name             string
+		consumption      []Consumption
+		days             int
+		deliveries       []Delivery
+		supplierLeadTime int
+		stock            int
+		packSize         int
+
+		wantSeries   []int
+		wantMean     float64
+		wantStdDev   float64
+		wantLeadTime float64
+		wantSource   string
+		want         Levels
+	}{
+		{
+			name:         "flat history",
+			consumption:  daily(10, 10, 10, 10, 10, 10, 10),
+			days:         7,
+			deliveries:   []Delivery{sent(20, 2), sent(10, 4)},
+			packSize:     1,
+			wantSeries:   []int{10, 10, 10, 10, 10, 10, 10},
+			wantMean:     10,
+			wantLeadTime: 3,
+			wantSource:   LeadTimeObserved,
+			want:         Levels{SafetyStock: 0, ReorderPoint: 30, ParLevel: 100, OrderQuantity: 100},
+		},
+		{
+			name:             "trending history",
+			consumption:      daily(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14),
+			days:             14,
+			supplierLeadTime: 7,
+			stock:            20,
+			packSize:         12,
+			wantSeries:       []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14},
+			wantMean:         7.5,
+			wantStdDev:       math.Sqrt(16.25),
+			wantLeadTime:     7,
+			wantSource:       LeadTimeSupplier,
+			// σ√L×z = 4.03×2.65×1.65 = 17.6; shortfall 104 rounds up to 9 packs
+			want: Levels{SafetyStock: 18, ReorderPoint: 71, ParLevel: 124, OrderQuantity: 108},
+		},
+		{
+			name:         "zero usage",
+			days:         14,
+			stock:        5,
+			packSize:     1,
+			wantSeries:   make([]int, 14),
+			wantLeadTime: DefaultLeadTimeDays,
+			wantSource:   LeadTimeDefault,
+			want:         Levels{},
+		},
+		{
+			name: "history with gaps",
+			consumption: []Consumption{
+				at(0, 9, 6), at(3, 8, 4), at(3, 22, 2), at(9, 23, 8),
+				at(-1, 12, 50), at(10, 0, 50), // outside the window
+			},
+			days:         10,
+			stock:        10,
+			packSize:     1,
+			wantSeries:   []int{6, 0, 0, 6, 0, 0, 0, 0, 0, 8},
+			wantMean:     2,
+			wantStdDev:   math.Sqrt(9.6),
+			wantLeadTime: DefaultLeadTimeDays,
+			wantSource:   LeadTimeDefault,
+			want:         Levels{SafetyStock: 14, ReorderPoint: 28, ParLevel: 42, OrderQuantity: 32},
+		},
+		{
+			name:         "lead time with no receipts",
+			consumption:  daily(4, 4, 4, 4),
+			days:         4,
+			deliveries:   []Delivery{{AmenityID: "amenity-1", SentAt: start, ReceivedAt: start.Add(-time.Hour)}},
+			stock:        100,
+			packSize:     1,
+			wantSeries:   []int{4, 4, 4, 4},
+			wantMean:     4,
+			wantLeadTime: DefaultLeadTimeDays,
+			wantSource:   LeadTimeDefault,
+			want:         Levels{SafetyStock: 0, ReorderPoint: 28, ParLevel: 56, OrderQuantity: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := DailySeries(tt.consumption, start, tt.days)
+			if !reflect.DeepEqual(series, tt.wantSeries) {
+				t.Fatalf("series = %v, want %v", series, tt.wantSeries)
+			}
+
+			stats := Usage(series)
+			if math.Abs(stats.Mean-tt.wantMean) > 1e-9 || math.Abs(stats.StdDev-tt.wantStdDev) > 1e-9 {
+				t.Errorf("usage = %.4f ± %.4f, want %.4f ± %.4f", stats.Mean, stats.StdDev, tt.wantMean, tt.wantStdDev)
+			}
+
+			leadTime, source := LeadTime(tt.deliveries, tt.supplierLeadTime)
+			if leadTime != tt.wantLeadTime || source != tt.wantSource {
+				t.Errorf("lead time = %v (%s), want %v (%s)", leadTime, source, tt.wantLeadTime, tt.wantSource)
+			}
+
+			z, _ := ServiceLevelZ(DefaultServiceLevel)
+			levels := Compute(Inputs{
+				Usage:        stats,
+				LeadTimeDays: leadTime,
+				ReviewDays:   DefaultReviewDays,
+				Z:            z,
+				Stock:        tt.stock,
+				PackSize:     tt.packSize,
+			})
+			if levels != tt.want {
+				t.Errorf("levels = %+v, want %+v", levels, tt.want)
+			}
+		})
+	}
+}
+
+func TestDailySeriesAcrossDaylightSaving(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	local := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, berlin)
+	}
+
+	tests := []struct {
+		name  string
+		start time.Time
+		at    time.Time
+		want  []int
+	}{
+		// 29 March has 23 hours; 00:30 the next day is 47.5 hours in
+		{"spring forward, just after midnight", local(time.March, 28, 0, 0), local(time.March, 30, 0, 30), []int{0, 0, 1}},
+		// 25 October has 25 hours; 23:30 that day is 48.5 hours in
+		{"fall back, late in the long day", local(time.October, 24, 0, 0), local(time.October, 25, 23, 30), []int{0, 1, 0}},
+		// Consumption recorded in UTC falls on its date in start's location
+		{"recorded in another zone", local(time.March, 28, 0, 0), time.Date(2026, 3, 29, 22, 30, 0, 0, time.UTC), []int{0, 0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := DailySeries([]Consumption{{Quantity: 1, At: tt.at}}, tt.start, 3)
+			if !reflect.DeepEqual(series, tt.want) {
+				t.Errorf("series = %v, want %v", series, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package replenishment
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"concierge-be/database"
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
+// GetRecommendations handles GET /api/v1/amenities/recommendations?tenantId=
+// Optional: lookbackDays, reviewDays, serviceLevel and changedOnly=true
+func (h *Handler) GetRecommendations(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+	params, ok := queryParams(c)
+	if !ok {
+		return
+	}
+
+	recommendations, err := h.serviceFor(c).GetRecommendations(tenantID, params, c.Query("changedOnly") == "true")
+	if err != nil {
+		recommendationErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, recommendations)
+}
+
+// GetRecommendation handles GET /api/v1/amenities/:id/recommendation
+func (h *Handler) GetRecommendation(c *gin.Context) {
+	params, ok := queryParams(c)
+	if !ok {
+		return
+	}
+
+	recommendation, err := h.serviceFor(c).GetRecommendation(c.Param("id"), params)
+	if err != nil {
+		recommendationErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, recommendation)
+}
+
+// AcceptRecommendation handles POST /api/v1/amenities/:id/recommendation/accept
+// Recomputes the recommendation with the same query parameters and sets the
+// amenity's minimum stock to its reorder point. Requires If-Match with the
+// amenity's version.
+func (h *Handler) AcceptRecommendation(c *gin.Context) {
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+	params, ok := queryParams(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.serviceFor(c).AcceptRecommendation(c.Param("id"), params, version)
+	if err != nil {
+		recommendationErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, result.Version)
+	utils.SuccessResponse(c, result)
+}
+
+// queryParams reads the optional calculation parameters, writing a 400 if
+// any is not a number
+func queryParams(c *gin.Context) (Params, bool) {
+	var params Params
+	var err error
+	if value := c.Query("lookbackDays"); value != "" {
+		if params.LookbackDays, err = strconv.Atoi(value); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "lookbackDays must be a whole number")
+			return params, false
+		}
+	}
+	if value := c.Query("reviewDays"); value != "" {
+		if params.ReviewDays, err = strconv.Atoi(value); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "reviewDays must be a whole number")
+			return params, false
+		}
+	}
+	if value := c.Query("serviceLevel"); value != "" {
+		if params.ServiceLevel, err = strconv.ParseFloat(value, 64); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "serviceLevel must be a number")
+			return params, false
+		}
+	}
+	return params, true
+}
+
+// recommendationErrorResponse maps recommendation errors to HTTP responses
+func recommendationErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, database.ErrVersionConflict) {
+		utils.ErrorResponse(c, http.StatusPreconditionFailed, "Amenity has been modified; reload and retry")
+		return
+	}
+	message := err.Error()
+	switch {
+	case message == "amenity not found":
+		utils.ErrorResponse(c, http.StatusNotFound, message)
+	case message == "not enough consumption history to recommend a minimum", message == "kits are replenished through their components":
+		utils.ErrorResponse(c, http.StatusConflict, message)
+	case strings.HasPrefix(message, "lookbackDays"), strings.HasPrefix(message, "reviewDays"), strings.HasPrefix(message, "serviceLevel"):
+		utils.ErrorResponse(c, http.StatusBadRequest, message)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, message)
+	}
+}
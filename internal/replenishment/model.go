@@ -0,0 +1,47 @@
+package replenishment
+
+// Lead time sources, in order of preference
+const (
+	LeadTimeObserved = "observed" // average sent-to-received time of past orders
+	LeadTimeSupplier = "supplier" // the preferred supplier's quoted lead time
+	LeadTimeDefault  = "default"
+)
+
+// Params tune a recommendation run
+type Params struct {
+	LookbackDays int
+	ReviewDays   int
+	ServiceLevel float64
+}
+
+// Recommendation is the suggested reorder point, par level and order
+// quantity for one amenity, with the figures they were derived from.
+// Accepting it sets the amenity's minimum stock to ReorderPoint.
+type Recommendation struct {
+	AmenityID           string  `json:"amenityId"`
+	ItemName            string  `json:"itemName"`
+	Stock               int     `json:"stock"`
+	MinimumStock        int     `json:"minimumStock"`
+	DaysOfHistory       int     `json:"daysOfHistory"`
+	TotalConsumed       int     `json:"totalConsumed"`
+	AverageDailyUsage   float64 `json:"averageDailyUsage"`
+	DailyUsageStdDev    float64 `json:"dailyUsageStdDev"`
+	LeadTimeDays        float64 `json:"leadTimeDays"`
+	LeadTimeSource      string  `json:"leadTimeSource"`
+	PackSize            int     `json:"packSize"`
+	SafetyStock         int     `json:"safetyStock"`
+	ReorderPoint        int     `json:"reorderPoint"`
+	ParLevel            int     `json:"parLevel"`
+	OrderQuantity       int     `json:"orderQuantity"`
+	HasHistory          bool    `json:"hasHistory"`
+	ChangesMinimumStock bool    `json:"changesMinimumStock"`
+}
+
+// AcceptResult reports a minimum stock change made by accepting a recommendation
+type AcceptResult struct {
+	AmenityID            string          `json:"amenityId"`
+	PreviousMinimumStock int             `json:"previousMinimumStock"`
+	MinimumStock         int             `json:"minimumStock"`
+	Version              int64           `json:"version"`
+	Recommendation       *Recommendation `json:"recommendation"`
+}
@@ -0,0 +1,85 @@
+package replenishment
+
+import (
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/purchase_orders"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		db: database.GetDB(),
+	}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// Consumption is one consumption movement: Quantity units used at At
+type Consumption struct {
+	AmenityID string
+	Quantity  int
+	At        time.Time
+}
+
+// GetConsumption retrieves a tenant's consumption movements in [from, to),
+// optionally for a single amenity
+func (r *Repository) GetConsumption(tenantID, amenityID string, from, to time.Time) ([]Consumption, error) {
+	var rows []struct {
+		AmenityID string
+		Delta     int
+		CreatedAt time.Time
+	}
+	query := r.db.Model(&amenities.StockMovement{}).
+		Select("amenity_id, delta, created_at").
+		Where("tenant_id = ? AND reason = ? AND created_at >= ? AND created_at < ?",
+			tenantID, amenities.MovementConsumption, from, to)
+	if amenityID != "" {
+		query = query.Where("amenity_id = ?", amenityID)
+	}
+	if err := query.Order("created_at ASC").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	consumption := make([]Consumption, 0, len(rows))
+	for _, row := range rows {
+		consumption = append(consumption, Consumption{
+			AmenityID: row.AmenityID,
+			Quantity:  -row.Delta,
+			At:        row.CreatedAt,
+		})
+	}
+	return consumption, nil
+}
+
+// Delivery is how long one received order took to arrive
+type Delivery struct {
+	AmenityID  string
+	SentAt     time.Time
+	ReceivedAt time.Time
+}
+
+// GetDeliveries retrieves the send and receipt times of a tenant's fully
+// received orders per amenity, most recently received first
+func (r *Repository) GetDeliveries(tenantID, amenityID string) ([]Delivery, error) {
+	var deliveries []Delivery
+	query := r.db.Model(&purchase_orders.PurchaseOrderLine{}).
+		Select("purchase_order_lines.amenity_id, purchase_orders.sent_at, purchase_orders.received_at").
+		Joins("JOIN purchase_orders ON purchase_orders.id = purchase_order_lines.purchase_order_id").
+		Where("purchase_orders.tenant_id = ? AND purchase_orders.sent_at IS NOT NULL AND purchase_orders.received_at IS NOT NULL", tenantID)
+	if amenityID != "" {
+		query = query.Where("purchase_order_lines.amenity_id = ?", amenityID)
+	}
+	err := query.Order("purchase_orders.received_at DESC").Scan(&deliveries).Error
+	return deliveries, err
+}
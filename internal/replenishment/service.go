@@ -0,0 +1,272 @@
+package replenishment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/suppliers"
+
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	repo         *Repository
+	amenityRepo  *amenities.Repository
+	supplierRepo *suppliers.Repository
+	now          func() time.Time
+}
+
+func NewService() *Service {
+	return &Service{
+		repo:         NewRepository(),
+		amenityRepo:  amenities.NewRepository(),
+		supplierRepo: suppliers.NewRepository(),
+		now:          time.Now,
+	}
+}
+
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	db := s.repo.db.WithContext(ctx)
+	copied := *s
+	copied.repo = s.repo.WithTx(db)
+	copied.amenityRepo = s.amenityRepo.WithTx(db)
+	copied.supplierRepo = s.supplierRepo.WithTx(db)
+	return &copied
+}
+
+// NormalizeParams fills in defaults and validates a recommendation run's parameters
+func NormalizeParams(params Params) (Params, error) {
+	if params.LookbackDays == 0 {
+		params.LookbackDays = DefaultLookbackDays
+	}
+	if params.ReviewDays == 0 {
+		params.ReviewDays = DefaultReviewDays
+	}
+	if params.ServiceLevel == 0 {
+		params.ServiceLevel = DefaultServiceLevel
+	}
+	if params.LookbackDays < MinHistoryDays || params.LookbackDays > 365 {
+		return params, fmt.Errorf("lookbackDays must be between %d and 365", MinHistoryDays)
+	}
+	if params.ReviewDays < 1 || params.ReviewDays > 90 {
+		return params, errors.New("reviewDays must be between 1 and 90")
+	}
+	if _, ok := ServiceLevelZ(params.ServiceLevel); !ok {
+		return params, fmt.Errorf("serviceLevel must be one of %v", SupportedServiceLevels())
+	}
+	return params, nil
+}
+
+// GetRecommendations recommends stock levels for every amenity of a tenant.
+// Kits are left out: they are replenished through their components.
+func (s *Service) GetRecommendations(tenantID string, params Params, changedOnly bool) ([]Recommendation, error) {
+	params, err := NormalizeParams(params)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.amenityRepo.GetByTenantID(tenantID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load amenities: %w", err)
+	}
+	items := list[:0]
+	for _, amenity := range list {
+		if !amenity.IsKit() {
+			items = append(items, amenity)
+		}
+	}
+
+	recommendations, err := s.recommend(tenantID, "", items, params)
+	if err != nil {
+		return nil, err
+	}
+	if !changedOnly {
+		return recommendations, nil
+	}
+
+	changed := make([]Recommendation, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		if recommendation.ChangesMinimumStock {
+			changed = append(changed, recommendation)
+		}
+	}
+	return changed, nil
+}
+
+// GetRecommendation recommends stock levels for one amenity
+func (s *Service) GetRecommendation(amenityID string, params Params) (*Recommendation, error) {
+	params, err := NormalizeParams(params)
+	if err != nil {
+		return nil, err
+	}
+	amenity, err := s.getAmenity(amenityID)
+	if err != nil {
+		return nil, err
+	}
+	return s.recommendOne(amenity, params)
+}
+
+// AcceptRecommendation sets an amenity's minimum stock to its recommended
+// reorder point. version is the amenity version the caller last read.
+func (s *Service) AcceptRecommendation(amenityID string, params Params, version int64) (*AcceptResult, error) {
+	params, err := NormalizeParams(params)
+	if err != nil {
+		return nil, err
+	}
+	amenity, err := s.getAmenity(amenityID)
+	if err != nil {
+		return nil, err
+	}
+	if amenity.Version != version {
+		return nil, database.ErrVersionConflict
+	}
+
+	recommendation, err := s.recommendOne(amenity, params)
+	if err != nil {
+		return nil, err
+	}
+	if !recommendation.HasHistory {
+		return nil, errors.New("not enough consumption history to recommend a minimum")
+	}
+
+	previous := amenity.MinimumStock
+	amenity.MinimumStock = recommendation.ReorderPoint
+	if err := s.amenityRepo.Update(amenity); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update minimum stock: %w", err)
+	}
+
+	recommendation.MinimumStock = amenity.MinimumStock
+	recommendation.ChangesMinimumStock = false
+	return &AcceptResult{
+		AmenityID:            amenity.ID,
+		PreviousMinimumStock: previous,
+		MinimumStock:         amenity.MinimumStock,
+		Version:              amenity.Version,
+		Recommendation:       recommendation,
+	}, nil
+}
+
+// getAmenity loads an amenity to recommend for, reporting a missing one as
+// not found. Kits hold no stock, so there is nothing to recommend.
+func (s *Service) getAmenity(id string) (*amenities.Amenity, error) {
+	amenity, err := s.amenityRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("amenity not found")
+		}
+		return nil, err
+	}
+	if amenity.IsKit() {
+		return nil, errors.New("kits are replenished through their components")
+	}
+	return amenity, nil
+}
+
+// recommendOne recommends stock levels for a single loaded amenity
+func (s *Service) recommendOne(amenity *amenities.Amenity, params Params) (*Recommendation, error) {
+	recommendations, err := s.recommend(amenity.TenantID, amenity.ID, []amenities.Amenity{*amenity}, params)
+	if err != nil {
+		return nil, err
+	}
+	return &recommendations[0], nil
+}
+
+// recommend loads consumption, deliveries and catalogues for the given
+// amenities and computes their recommendations. amenityID narrows the
+// queries when only one amenity is wanted.
+func (s *Service) recommend(tenantID, amenityID string, list []amenities.Amenity, params Params) ([]Recommendation, error) {
+	today := startOfDay(s.now())
+	windowStart := today.AddDate(0, 0, -params.LookbackDays)
+
+	consumption, err := s.repo.GetConsumption(tenantID, amenityID, windowStart, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load consumption: %w", err)
+	}
+	byAmenity := make(map[string][]Consumption)
+	for _, used := range consumption {
+		byAmenity[used.AmenityID] = append(byAmenity[used.AmenityID], used)
+	}
+
+	deliveries, err := s.repo.GetDeliveries(tenantID, amenityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deliveries: %w", err)
+	}
+	deliveriesByAmenity := make(map[string][]Delivery)
+	for _, delivery := range deliveries {
+		deliveriesByAmenity[delivery.AmenityID] = append(deliveriesByAmenity[delivery.AmenityID], delivery)
+	}
+
+	amenityIDs := make([]string, 0, len(list))
+	for _, amenity := range list {
+		amenityIDs = append(amenityIDs, amenity.ID)
+	}
+	items, err := s.supplierRepo.GetItemsByAmenityIDs(amenityIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load supplier catalogues: %w", err)
+	}
+	preferred := make(map[string]suppliers.SupplierItem)
+	for _, item := range items {
+		if _, ok := preferred[item.AmenityID]; !ok {
+			preferred[item.AmenityID] = item
+		}
+	}
+
+	z, _ := ServiceLevelZ(params.ServiceLevel)
+	recommendations := make([]Recommendation, 0, len(list))
+	for _, amenity := range list {
+		// Only count whole days the amenity existed for
+		start := windowStart
+		if first := startOfDay(amenity.CreatedAt.In(today.Location())).AddDate(0, 0, 1); first.After(start) {
+			start = first
+		}
+		days := daysBetween(start, today)
+		stats := Usage(DailySeries(byAmenity[amenity.ID], start, days))
+
+		packSize, supplierLeadTime := 1, 0
+		if item, listed := preferred[amenity.ID]; listed {
+			packSize = item.PackSize
+			if item.Supplier != nil {
+				supplierLeadTime = item.Supplier.LeadTimeDays
+			}
+		}
+		leadTime, source := LeadTime(deliveriesByAmenity[amenity.ID], supplierLeadTime)
+
+		levels := Compute(Inputs{
+			Usage:        stats,
+			LeadTimeDays: leadTime,
+			ReviewDays:   params.ReviewDays,
+			Z:            z,
+			Stock:        amenity.Stock,
+			PackSize:     packSize,
+		})
+
+		hasHistory := stats.Days >= MinHistoryDays && stats.Total > 0
+		recommendations = append(recommendations, Recommendation{
+			AmenityID:           amenity.ID,
+			ItemName:            amenity.ItemName,
+			Stock:               amenity.Stock,
+			MinimumStock:        amenity.MinimumStock,
+			DaysOfHistory:       stats.Days,
+			TotalConsumed:       stats.Total,
+			AverageDailyUsage:   round2(stats.Mean),
+			DailyUsageStdDev:    round2(stats.StdDev),
+			LeadTimeDays:        leadTime,
+			LeadTimeSource:      source,
+			PackSize:            packSize,
+			SafetyStock:         levels.SafetyStock,
+			ReorderPoint:        levels.ReorderPoint,
+			ParLevel:            levels.ParLevel,
+			OrderQuantity:       levels.OrderQuantity,
+			HasHistory:          hasHistory,
+			ChangesMinimumStock: hasHistory && levels.ReorderPoint != amenity.MinimumStock,
+		})
+	}
+	return recommendations, nil
+}
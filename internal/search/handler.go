@@ -0,0 +1,50 @@
+package search
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// Search handles GET /api/v1/search?tenantId=&q=
+// Supports type=amenity|category (comma-separated) and limit (default 20, max 50)
+func (h *Handler) Search(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+
+	query := Query{TenantID: tenantID, Text: c.Query("q")}
+	query.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultLimit)))
+	if kinds := c.Query("type"); kinds != "" {
+		query.Kinds = strings.Split(kinds, ",")
+	}
+
+	result, err := h.service.Search(query)
+	if err != nil {
+		switch err.Error() {
+		case "search text is required", "type must be amenity or category":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, result)
+}
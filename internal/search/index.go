@@ -0,0 +1,212 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"concierge-be/config"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/amenities_categories"
+
+	"gorm.io/gorm"
+)
+
+// Index finds the candidate documents for a query. Backends only narrow the
+// candidates; ranking and highlighting are shared, so every backend orders
+// the same documents the same way.
+type Index interface {
+	Backend() string
+	Candidates(query Query, terms []string) ([]Document, error)
+}
+
+// NewIndex returns the configured index. Without configuration a MySQL
+// database gets the FULLTEXT index and anything else the in-process one.
+func NewIndex(db *gorm.DB) Index {
+	backend := ""
+	if config.AppConfig != nil {
+		backend = config.AppConfig.Search.Backend
+	}
+	if backend == "" && db.Dialector.Name() == "mysql" {
+		backend = BackendMySQL
+	}
+	if backend == BackendMySQL {
+		return NewMySQLIndex(db)
+	}
+	return NewMemoryIndex(db)
+}
+
+// MemoryIndex loads a tenant's whole catalogue and leaves all matching to
+// the shared scorer. It needs no database support, so it works on any
+// database gorm does and suits tests and small catalogues. A limit caps the
+// rows of each kind it loads, most recently updated first.
+type MemoryIndex struct {
+	db    *gorm.DB
+	limit int // zero for the whole catalogue
+}
+
+func NewMemoryIndex(db *gorm.DB) *MemoryIndex {
+	return &MemoryIndex{db: db}
+}
+
+func (m *MemoryIndex) Backend() string {
+	return BackendMemory
+}
+
+// Candidates returns every amenity and category of the tenant, up to the
+// index's limit
+func (m *MemoryIndex) Candidates(query Query, terms []string) ([]Document, error) {
+	var docs []Document
+
+	if query.wants(KindAmenity) {
+		var list []amenities.Amenity
+		if err := m.capped(m.db.Preload("Category").Where("tenant_id = ?", query.TenantID)).Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for i := range list {
+			docs = append(docs, Document{Kind: KindAmenity, Amenity: &list[i]})
+		}
+	}
+
+	if query.wants(KindCategory) {
+		var categories []amenities_categories.AmenityCategory
+		if err := m.capped(m.db.Where("tenant_id = ?", query.TenantID)).Find(&categories).Error; err != nil {
+			return nil, err
+		}
+		for i := range categories {
+			docs = append(docs, Document{Kind: KindCategory, Category: &categories[i]})
+		}
+	}
+
+	return docs, nil
+}
+
+// capped applies the index's limit to a catalogue query
+func (m *MemoryIndex) capped(query *gorm.DB) *gorm.DB {
+	if m.limit <= 0 {
+		return query
+	}
+	return query.Order("updated_at DESC, id ASC").Limit(m.limit)
+}
+
+const (
+	// mysqlCandidateLimit caps the rows each FULLTEXT query returns for ranking
+	mysqlCandidateLimit = 200
+	// mysqlFallbackBelow is how few FULLTEXT candidates suggest a typo
+	mysqlFallbackBelow = 3
+	// mysqlFallbackLimit caps the rows of each kind the typo fallback loads
+	mysqlFallbackLimit = 2000
+)
+
+// MySQLIndex finds candidates with MySQL FULLTEXT indexes, matching whole
+// words and prefixes. FULLTEXT cannot match typos or words shorter than
+// innodb_ft_min_token_size, so when it finds no or next to no candidates
+// it also scans the most recently updated part of the catalogue in process.
+type MySQLIndex struct {
+	db       *gorm.DB
+	fallback *MemoryIndex
+}
+
+func NewMySQLIndex(db *gorm.DB) *MySQLIndex {
+	return &MySQLIndex{db: db, fallback: &MemoryIndex{db: db, limit: mysqlFallbackLimit}}
+}
+
+func (m *MySQLIndex) Backend() string {
+	return BackendMySQL
+}
+
+// Candidates returns the FULLTEXT matches, joined by the fallback's
+// candidates if there are too few of them to rule out a typo
+func (m *MySQLIndex) Candidates(query Query, terms []string) ([]Document, error) {
+	against := booleanQuery(terms)
+	var docs []Document
+
+	if query.wants(KindAmenity) {
+		var list []amenities.Amenity
+		err := m.db.Select("amenities.*").Preload("Category").
+			Joins("LEFT JOIN amenities_categories ON amenities_categories.id = amenities.category_id AND amenities_categories.deleted_at IS NULL").
+			Where("amenities.tenant_id = ?", query.TenantID).
+			Where("MATCH(amenities.item_name, amenities.description) AGAINST (? IN BOOLEAN MODE) OR MATCH(amenities_categories.name, amenities_categories.description) AGAINST (? IN BOOLEAN MODE)", against, against).
+			Limit(mysqlCandidateLimit).
+			Find(&list).Error
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			docs = append(docs, Document{Kind: KindAmenity, Amenity: &list[i]})
+		}
+	}
+
+	if query.wants(KindCategory) {
+		var categories []amenities_categories.AmenityCategory
+		err := m.db.Where("tenant_id = ?", query.TenantID).
+			Where("MATCH(name, description) AGAINST (? IN BOOLEAN MODE)", against).
+			Limit(mysqlCandidateLimit).
+			Find(&categories).Error
+		if err != nil {
+			return nil, err
+		}
+		for i := range categories {
+			docs = append(docs, Document{Kind: KindCategory, Category: &categories[i]})
+		}
+	}
+
+	if len(docs) >= mysqlFallbackBelow {
+		return docs, nil
+	}
+	fallback, err := m.fallback.Candidates(query, terms)
+	if err != nil {
+		return nil, err
+	}
+	return mergeDocuments(docs, fallback), nil
+}
+
+// mergeDocuments appends the documents of more not already in docs
+func mergeDocuments(docs, more []Document) []Document {
+	seen := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		seen[doc.Kind+":"+doc.ID()] = true
+	}
+	for _, doc := range more {
+		if !seen[doc.Kind+":"+doc.ID()] {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// booleanQuery turns terms into a boolean-mode query matching any of them as
+// a word or word prefix. Terms are already letters and digits only.
+func booleanQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + "*"
+	}
+	return strings.Join(parts, " ")
+}
+
+// fulltextIndexes are the indexes MATCH queries rely on; their column lists
+// must equal those in the MATCH clauses above
+var fulltextIndexes = []struct {
+	table, name, columns string
+}{
+	{"amenities", "ft_amenities_search", "item_name, description"},
+	{"amenities_categories", "ft_amenities_categories_search", "name, description"},
+}
+
+// EnsureIndexes creates the FULLTEXT indexes when the MySQL backend is in
+// use. It is safe to run on every start.
+func EnsureIndexes(db *gorm.DB) error {
+	if NewIndex(db).Backend() != BackendMySQL {
+		return nil
+	}
+	for _, index := range fulltextIndexes {
+		if db.Migrator().HasIndex(index.table, index.name) {
+			continue
+		}
+		sql := fmt.Sprintf("ALTER TABLE %s ADD FULLTEXT INDEX %s (%s)", index.table, index.name, index.columns)
+		if err := db.Exec(sql).Error; err != nil {
+			return fmt.Errorf("failed to create %s: %w", index.name, err)
+		}
+	}
+	return nil
+}
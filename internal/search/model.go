@@ -0,0 +1,106 @@
+package search
+
+import (
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/amenities_categories"
+)
+
+// Result kinds
+const (
+	KindAmenity  = "amenity"
+	KindCategory = "category"
+)
+
+// Index backends
+const (
+	BackendMySQL  = "mysql"
+	BackendMemory = "memory"
+)
+
+// Search limits
+const (
+	DefaultLimit = 20
+	MaxLimit     = 50
+)
+
+// Document is a searchable amenity or category
+type Document struct {
+	Kind     string
+	Amenity  *amenities.Amenity
+	Category *amenities_categories.AmenityCategory
+}
+
+// ID returns the ID of the amenity or category
+func (d Document) ID() string {
+	if d.Amenity != nil {
+		return d.Amenity.ID
+	}
+	return d.Category.ID
+}
+
+// Title returns the name a document is listed under
+func (d Document) Title() string {
+	if d.Amenity != nil {
+		return d.Amenity.ItemName
+	}
+	return d.Category.Name
+}
+
+// Fields returns a document's searchable fields and their weights. An
+// amenity is also found by its category's name.
+func (d Document) Fields() []Field {
+	if d.Amenity != nil {
+		fields := []Field{
+			{Name: "itemName", Text: d.Amenity.ItemName, Weight: 3},
+			{Name: "description", Text: d.Amenity.Description, Weight: 1},
+		}
+		if d.Amenity.Category != nil {
+			fields = append(fields, Field{Name: "category", Text: d.Amenity.Category.Name, Weight: 1.5})
+		}
+		return fields
+	}
+	return []Field{
+		{Name: "name", Text: d.Category.Name, Weight: 3},
+		{Name: "description", Text: d.Category.Description, Weight: 1},
+	}
+}
+
+// Query is a search request. Kinds limits the result kinds; empty means both.
+type Query struct {
+	TenantID string
+	Text     string
+	Kinds    []string
+	Limit    int
+}
+
+// wants reports whether the query asks for a kind of result
+func (q Query) wants(kind string) bool {
+	if len(q.Kinds) == 0 {
+		return true
+	}
+	for _, k := range q.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Hit is one ranked search result. Highlights holds each matching field
+// HTML-escaped, with the matched words wrapped in <mark>.
+type Hit struct {
+	Kind       string                                `json:"kind"`
+	ID         string                                `json:"id"`
+	Title      string                                `json:"title"`
+	Score      float64                               `json:"score"`
+	Highlights map[string]string                     `json:"highlights"`
+	Amenity    *amenities.Amenity                    `json:"amenity,omitempty"`
+	Category   *amenities_categories.AmenityCategory `json:"category,omitempty"`
+}
+
+// Result is the response to a search
+type Result struct {
+	Query   string `json:"query"`
+	Backend string `json:"backend"`
+	Hits    []Hit  `json:"hits"`
+}
@@ -0,0 +1,186 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Match qualities. A term matching a word exactly beats a prefix of it,
+// which beats a match within the typo allowance.
+const (
+	qualityExact  = 1.0
+	qualityPrefix = 0.8
+	qualityFuzzy  = 0.6
+)
+
+// Tokenize lowercases text and splits it into words, dropping repeats
+func Tokenize(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range words(text) {
+		if !seen[word.text] {
+			seen[word.text] = true
+			terms = append(terms, word.text)
+		}
+	}
+	return terms
+}
+
+// word is a lowercased word of a field and its rune span in the original text
+type word struct {
+	text       string
+	start, end int
+}
+
+// words splits text into lowercased words of letters and digits
+func words(text string) []word {
+	var out []word
+	runes := []rune(text)
+	start := -1
+	for i := 0; i <= len(runes); i++ {
+		inWord := i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]))
+		if inWord && start < 0 {
+			start = i
+		}
+		if !inWord && start >= 0 {
+			out = append(out, word{text: strings.ToLower(string(runes[start:i])), start: start, end: i})
+			start = -1
+		}
+	}
+	return out
+}
+
+// allowedEdits is how many typos a term of n runes tolerates
+func allowedEdits(n int) int {
+	switch {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	}
+	return 2
+}
+
+// matchQuality rates how well a query term matches one word, 0 for no match
+func matchQuality(term, token string) float64 {
+	if term == token {
+		return qualityExact
+	}
+	if len([]rune(term)) >= 2 && strings.HasPrefix(token, term) {
+		return qualityPrefix
+	}
+
+	allowed := allowedEdits(len([]rune(term)))
+	if allowed == 0 {
+		return 0
+	}
+	distance := editDistance(term, token)
+	// Also compare against the start of a longer word, so "shampo" finds "shampoos"
+	if tokenRunes := []rune(token); len(tokenRunes) > len([]rune(term)) {
+		if d := editDistance(term, string(tokenRunes[:len([]rune(term))])); d < distance {
+			distance = d
+		}
+	}
+	if distance > allowed {
+		return 0
+	}
+	return qualityFuzzy - 0.1*float64(distance-1)
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and adjacent transpositions
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev2 := make([]int, len(br)+1)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ar[i-1] == br[j-2] && ar[i-2] == br[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(br)]
+}
+
+// Field is one searchable field of a document with its ranking weight
+type Field struct {
+	Name   string
+	Text   string
+	Weight float64
+}
+
+// Score ranks a document against query terms. Each term counts its best
+// match across the fields, weighted by field; the total is scaled by the
+// share of terms that matched, so documents matching every term rank first.
+// A score of zero means nothing matched.
+func Score(fields []Field, terms []string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+
+	tokenized := make([][]word, len(fields))
+	for i, field := range fields {
+		tokenized[i] = words(field.Text)
+	}
+
+	total, matched := 0.0, 0
+	for _, term := range terms {
+		best := 0.0
+		for i, field := range fields {
+			for _, w := range tokenized[i] {
+				if q := matchQuality(term, w.text) * field.Weight; q > best {
+					best = q
+				}
+			}
+		}
+		if best > 0 {
+			matched++
+			total += best
+		}
+	}
+	return total * float64(matched) / float64(len(terms))
+}
+
+// Highlight HTML-escapes text and wraps the words matching any term in
+// <mark> tags. It returns "" if nothing in text matches.
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	last, found := 0, false
+	for _, w := range words(text) {
+		hit := false
+		for _, term := range terms {
+			if matchQuality(term, w.text) > 0 {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			continue
+		}
+		found = true
+		b.WriteString(html.EscapeString(string(runes[last:w.start])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[w.start:w.end])))
+		b.WriteString("</mark>")
+		last = w.end
+	}
+	if !found {
+		return ""
+	}
+	b.WriteString(html.EscapeString(string(runes[last:])))
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"concierge-be/database"
+)
+
+type Service struct {
+	index Index
+}
+
+func NewService() *Service {
+	return &Service{
+		index: NewIndex(database.GetDB()),
+	}
+}
+
+// Search ranks a tenant's amenities and categories against the query text,
+// tolerating small typos, and highlights the matching words
+func (s *Service) Search(query Query) (*Result, error) {
+	terms := Tokenize(query.Text)
+	if len(terms) == 0 {
+		return nil, errors.New("search text is required")
+	}
+	for _, kind := range query.Kinds {
+		if kind != KindAmenity && kind != KindCategory {
+			return nil, errors.New("type must be amenity or category")
+		}
+	}
+	if query.Limit < 1 || query.Limit > MaxLimit {
+		query.Limit = DefaultLimit
+	}
+
+	docs, err := s.index.Candidates(query, terms)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, 0, len(docs))
+	for _, doc := range docs {
+		fields := doc.Fields()
+		score := Score(fields, terms)
+		if score == 0 {
+			continue
+		}
+
+		highlights := make(map[string]string)
+		for _, field := range fields {
+			if marked := Highlight(field.Text, terms); marked != "" {
+				highlights[field.Name] = marked
+			}
+		}
+		hits = append(hits, Hit{
+			Kind:       doc.Kind,
+			ID:         doc.ID(),
+			Title:      doc.Title(),
+			Score:      math.Round(score*1000) / 1000,
+			Highlights: highlights,
+			Amenity:    doc.Amenity,
+			Category:   doc.Category,
+		})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Title != hits[j].Title {
+			return hits[i].Title < hits[j].Title
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+
+	return &Result{Query: query.Text, Backend: s.index.Backend(), Hits: hits}, nil
+}
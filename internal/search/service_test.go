@@ -0,0 +1,157 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"concierge-be/database/dbtest"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/amenities_categories"
+
+	"gorm.io/gorm"
+)
+
+// newSearchTest sets up two tenants' catalogues and a service searching
+// them through the in-process index
+func newSearchTest(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := dbtest.Open(t, &amenities.Amenity{}, &amenities_categories.AmenityCategory{})
+
+	category := func(id, tenantID, name, description string) *amenities_categories.AmenityCategory {
+		return &amenities_categories.AmenityCategory{ID: id, TenantID: tenantID, Name: name, Description: description}
+	}
+	amenity := func(id, tenantID, categoryID, name, description string) *amenities.Amenity {
+		return &amenities.Amenity{ID: id, TenantID: tenantID, CategoryID: categoryID, ItemName: name, Description: description}
+	}
+	fixtures := []interface{}{
+		category("bathroom", "tenant-1", "Bathroom", "Towels and toiletries"),
+		category("kitchen", "tenant-1", "Kitchen", "Kettles and mugs"),
+		amenity("bath-towel", "tenant-1", "bathroom", "Bath Towel", "Large cotton towel"),
+		amenity("towels-deluxe", "tenant-1", "bathroom", "Towels Deluxe", "Set of three"),
+		amenity("bathrobe", "tenant-1", "bathroom", "Bathrobe", "Soft cotton, matching towel included"),
+		amenity("shampoo", "tenant-1", "bathroom", "Shampoo", "Herbal, 30 ml"),
+		amenity("toothbrush", "tenant-1", "bathroom", "Toothbrush", "Soft bristles"),
+		amenity("kettle", "tenant-1", "kitchen", "Kettle", "1.7 litre"),
+		category("other-bathroom", "tenant-2", "Bathroom", "Towels"),
+		amenity("other-towel", "tenant-2", "other-bathroom", "Bath Towel", "Large cotton towel"),
+		amenity("other-shampoo", "tenant-2", "other-bathroom", "Shampoo", "Herbal"),
+	}
+	for _, fixture := range fixtures {
+		if err := db.Create(fixture).Error; err != nil {
+			t.Fatalf("create fixture: %v", err)
+		}
+	}
+	return db, &Service{index: NewMemoryIndex(db)}
+}
+
+// hitIDs lists the IDs of a result's hits in order
+func hitIDs(result *Result) []string {
+	ids := []string{}
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	_, service := newSearchTest(t)
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		// Item name beats description; exact beats prefix
+		{"ranks by field and match quality", Query{TenantID: "tenant-1", Text: "towel"},
+			[]string{"bath-towel", "towels-deluxe", "bathrobe", "bathroom"}},
+		{"documents matching every term first", Query{TenantID: "tenant-1", Text: "cotton towel"},
+			[]string{"bath-towel", "bathrobe", "towels-deluxe", "bathroom"}},
+		{"limited to a kind", Query{TenantID: "tenant-1", Text: "towel", Kinds: []string{KindCategory}},
+			[]string{"bathroom"}},
+		{"limited in number", Query{TenantID: "tenant-1", Text: "towel", Limit: 2},
+			[]string{"bath-towel", "towels-deluxe"}},
+		{"transposed letters", Query{TenantID: "tenant-1", Text: "shmapoo"}, []string{"shampoo"}},
+		{"missing letter", Query{TenantID: "tenant-1", Text: "toothbrsh"}, []string{"toothbrush"}},
+		{"wrong letter", Query{TenantID: "tenant-1", Text: "kettel"}, []string{"kettle", "kitchen"}},
+		{"short words tolerate no typos", Query{TenantID: "tenant-1", Text: "mgu"}, []string{}},
+		{"other tenant's catalogue", Query{TenantID: "tenant-2", Text: "towel"},
+			[]string{"other-towel", "other-bathroom"}},
+		{"unknown tenant", Query{TenantID: "tenant-3", Text: "towel"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.Search(tt.query)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if got := hitIDs(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	_, service := newSearchTest(t)
+
+	result, err := service.Search(Query{TenantID: "tenant-1", Text: "towl", Kinds: []string{KindAmenity}, Limit: 1})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(result.Hits) != 1 {
+		t.Fatalf("hits = %v, want one", hitIDs(result))
+	}
+	want := map[string]string{
+		"itemName":    "Bath <mark>Towel</mark>",
+		"description": "Large cotton <mark>towel</mark>",
+	}
+	if !reflect.DeepEqual(result.Hits[0].Highlights, want) {
+		t.Errorf("highlights = %v, want %v", result.Hits[0].Highlights, want)
+	}
+}
+
+func TestMemoryIndexLimit(t *testing.T) {
+	db, _ := newSearchTest(t)
+	// Touch two amenities so they are the most recently updated
+	for i, id := range []string{"kettle", "shampoo"} {
+		at := time.Now().Add(time.Duration(i+1) * time.Hour)
+		if err := db.Model(&amenities.Amenity{}).Where("id = ?", id).UpdateColumn("updated_at", at).Error; err != nil {
+			t.Fatalf("touch amenity: %v", err)
+		}
+	}
+
+	index := &MemoryIndex{db: db, limit: 2}
+	docs, err := index.Candidates(Query{TenantID: "tenant-1", Kinds: []string{KindAmenity}}, []string{"anything"})
+	if err != nil {
+		t.Fatalf("Candidates: %v", err)
+	}
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ID())
+	}
+	if want := []string{"shampoo", "kettle"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("candidates = %v, want %v", ids, want)
+	}
+}
+
+func TestMergeDocuments(t *testing.T) {
+	doc := func(kind, id string) Document {
+		if kind == KindCategory {
+			return Document{Kind: kind, Category: &amenities_categories.AmenityCategory{ID: id}}
+		}
+		return Document{Kind: kind, Amenity: &amenities.Amenity{ID: id}}
+	}
+	merged := mergeDocuments(
+		[]Document{doc(KindAmenity, "a")},
+		[]Document{doc(KindAmenity, "b"), doc(KindAmenity, "a"), doc(KindCategory, "a")},
+	)
+	got := make([]string, 0, len(merged))
+	for _, d := range merged {
+		got = append(got, d.Kind+":"+d.ID())
+	}
+	if want := []string{"amenity:a", "amenity:b", "category:a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("merged = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,190 @@
+package stock_counts
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"concierge-be/database"
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
+// CreateSession handles POST /api/v1/stock-counts
+func (h *Handler) CreateSession(c *gin.Context) {
+	var req CreateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session, err := h.serviceFor(c).CreateSession(&req, utils.ActorID(c))
+	if err != nil {
+		countErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, session.Version)
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "Success",
+		Data:    session,
+	})
+}
+
+// GetSession handles GET /api/v1/stock-counts/:id
+func (h *Handler) GetSession(c *gin.Context) {
+	session, err := h.serviceFor(c).GetSession(c.Param("id"))
+	if err != nil {
+		countErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, session.Version)
+	utils.SuccessResponse(c, session)
+}
+
+// GetSessions handles GET /api/v1/stock-counts?tenantId=&status=
+func (h *Handler) GetSessions(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	sessions, total, err := h.serviceFor(c).GetSessionsByTenantID(tenantID, c.Query("status"), page, pageSize)
+	if err != nil {
+		if err.Error() == "invalid count session status" {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithPagination(c, sessions, page, pageSize, int(total))
+}
+
+// SubmitCounts handles POST /api/v1/stock-counts/:id/counts
+func (h *Handler) SubmitCounts(c *gin.Context) {
+	var req SubmitCountsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session, err := h.serviceFor(c).SubmitCounts(c.Param("id"), &req, utils.ActorID(c))
+	if err != nil {
+		countErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, session.Version)
+	utils.SuccessResponse(c, session)
+}
+
+// GetEntries handles GET /api/v1/stock-counts/:id/entries
+func (h *Handler) GetEntries(c *gin.Context) {
+	entries, err := h.serviceFor(c).GetEntries(c.Param("id"))
+	if err != nil {
+		countErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, entries)
+}
+
+// GetVarianceReport handles GET /api/v1/stock-counts/:id/variance
+func (h *Handler) GetVarianceReport(c *gin.Context) {
+	report, err := h.serviceFor(c).GetVarianceReport(c.Param("id"))
+	if err != nil {
+		countErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, report.Version)
+	utils.SuccessResponse(c, report)
+}
+
+// ApproveSession handles POST /api/v1/stock-counts/:id/approve
+// Requires If-Match with the version of the variance report being approved
+func (h *Handler) ApproveSession(c *gin.Context) {
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req ApproveRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	result, err := h.serviceFor(c).ApproveSession(c.Param("id"), &req, version, utils.ActorID(c))
+	if err != nil {
+		countErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, result.Session.Version)
+	utils.SuccessResponse(c, result)
+}
+
+// CancelSession handles POST /api/v1/stock-counts/:id/cancel
+func (h *Handler) CancelSession(c *gin.Context) {
+	session, err := h.serviceFor(c).CancelSession(c.Param("id"))
+	if err != nil {
+		countErrorResponse(c, err)
+		return
+	}
+
+	utils.SetETag(c, session.Version)
+	utils.SuccessResponse(c, session)
+}
+
+// countErrorResponse maps count session errors to HTTP responses
+func countErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, database.ErrVersionConflict) {
+		utils.ErrorResponse(c, http.StatusPreconditionFailed, "Counts have changed since the variance report was read; reload and retry")
+		return
+	}
+	switch err.Error() {
+	case "count session not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case "count session name is required", "no counts to submit", "mode must be set or add", "quantity cannot be negative",
+		"location not found", "location is not part of this count", "amenity not found", "kits are counted through their components":
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case "count session is not open",
+		"adjustment would take stock below zero; recount the items that moved during the count":
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,170 @@
+package stock_counts
+
+import (
+	"time"
+
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/locations"
+)
+
+// Count session statuses. A session is open while staff count, and is then
+// either approved, which posts the variances as adjustments, or cancelled.
+const (
+	StatusOpen      = "open"
+	StatusApproved  = "approved"
+	StatusCancelled = "cancelled"
+)
+
+// Count entry modes. Set replaces a line's counted quantity; add adds to it,
+// for several people counting the same item in different places.
+const (
+	ModeSet = "set"
+	ModeAdd = "add"
+)
+
+// CountSession is a physical stock count of a tenant, or of one location.
+// Expected quantities are frozen on its lines when it starts. Reference is
+// written on the adjustment movements it posts.
+type CountSession struct {
+	ID          string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID    string     `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	LocationID  *string    `gorm:"type:varchar(36);index" json:"locationId"`
+	Reference   string     `gorm:"type:varchar(32);not null;uniqueIndex" json:"reference"`
+	Name        string     `gorm:"type:varchar(100);not null" json:"name"`
+	Note        string     `gorm:"type:text" json:"note"`
+	Status      string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	StartedBy   *string    `gorm:"type:varchar(36)" json:"startedBy"`
+	StartedAt   time.Time  `gorm:"not null" json:"startedAt"`
+	ApprovedBy  *string    `gorm:"type:varchar(36)" json:"approvedBy"`
+	ApprovedAt  *time.Time `json:"approvedAt"`
+	CancelledAt *time.Time `json:"cancelledAt"`
+	Version     int64      `gorm:"not null;default:1" json:"version"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+
+	// Relationships
+	Location *locations.Location `gorm:"foreignKey:LocationID;references:ID" json:"location,omitempty"`
+	Lines    []CountLine         `gorm:"foreignKey:SessionID;references:ID" json:"lines,omitempty"`
+}
+
+func (CountSession) TableName() string {
+	return "count_sessions"
+}
+
+// CountLine is one amenity at one location in a count. ExpectedQuantity is
+// the stock when the session started and UnitCost the value of one unit
+// then, in minor currency units. CountedQuantity is nil until counted.
+// PostedValue is the change in stock value the line's adjustment made on
+// approval, at the average cost then; it is nil until one is posted.
+type CountLine struct {
+	ID               string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	SessionID        string     `gorm:"type:varchar(36);not null;uniqueIndex:idx_count_line" json:"sessionId"`
+	AmenityID        string     `gorm:"type:varchar(36);not null;uniqueIndex:idx_count_line" json:"amenityId"`
+	LocationID       string     `gorm:"type:varchar(36);not null;uniqueIndex:idx_count_line" json:"locationId"`
+	ExpectedQuantity int        `gorm:"not null" json:"expectedQuantity"`
+	CountedQuantity  *int       `json:"countedQuantity"`
+	UnitCost         int64      `gorm:"not null;default:0" json:"unitCost"`
+	PostedValue      *int64     `json:"postedValue"`
+	CountedBy        *string    `gorm:"type:varchar(36)" json:"countedBy"`
+	CountedAt        *time.Time `json:"countedAt"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+
+	// Relationships
+	Amenity  *amenities.Amenity  `gorm:"foreignKey:AmenityID;references:ID" json:"amenity,omitempty"`
+	Location *locations.Location `gorm:"foreignKey:LocationID;references:ID" json:"location,omitempty"`
+}
+
+func (CountLine) TableName() string {
+	return "count_lines"
+}
+
+// CountEntry records every count submitted, by whom and from which device
+type CountEntry struct {
+	ID        string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	SessionID string    `gorm:"type:varchar(36);not null;index" json:"sessionId"`
+	LineID    string    `gorm:"type:varchar(36);not null;index" json:"lineId"`
+	Mode      string    `gorm:"type:varchar(10);not null" json:"mode"`
+	Quantity  int       `gorm:"not null" json:"quantity"`
+	DeviceID  string    `gorm:"type:varchar(100)" json:"deviceId"`
+	ActorID   *string   `gorm:"type:varchar(36)" json:"actorId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (CountEntry) TableName() string {
+	return "count_entries"
+}
+
+// CreateSessionRequest represents the request body for starting a count
+type CreateSessionRequest struct {
+	TenantID   string  `json:"tenantId" binding:"required"`
+	LocationID *string `json:"locationId"`
+	Name       string  `json:"name" binding:"required"`
+	Note       string  `json:"note"`
+}
+
+// CountEntryRequest is one counted quantity. LocationID defaults to the
+// session's location, or the tenant's default location.
+type CountEntryRequest struct {
+	AmenityID  string `json:"amenityId" binding:"required"`
+	LocationID string `json:"locationId"`
+	Quantity   int    `json:"quantity" binding:"gte=0"`
+	Mode       string `json:"mode"`
+}
+
+// SubmitCountsRequest represents the request body for submitting counts
+type SubmitCountsRequest struct {
+	DeviceID string              `json:"deviceId"`
+	Entries  []CountEntryRequest `json:"entries" binding:"required"`
+}
+
+// ApproveRequest represents the request body for approving a count. With
+// TreatUncountedAsZero, lines nobody counted are posted as counted zero;
+// otherwise they are left unchanged.
+type ApproveRequest struct {
+	TreatUncountedAsZero bool   `json:"treatUncountedAsZero"`
+	Note                 string `json:"note"`
+}
+
+// VarianceLine is one line of a variance report. Variance is counted less
+// expected. ValueImpact estimates it at the line's unit cost while the
+// session is open, and is the value actually posted once it is approved.
+type VarianceLine struct {
+	LineID                 string `json:"lineId"`
+	AmenityID              string `json:"amenityId"`
+	ItemName               string `json:"itemName"`
+	LocationID             string `json:"locationId"`
+	LocationName           string `json:"locationName"`
+	ExpectedQuantity       int    `json:"expectedQuantity"`
+	CountedQuantity        *int   `json:"countedQuantity"`
+	Variance               int    `json:"variance"`
+	UnitCost               int64  `json:"unitCost"`
+	ValueImpact            int64  `json:"valueImpact"`
+	MovementsDuringCount   int    `json:"movementsDuringCount"`
+	NetMovementDuringCount int    `json:"netMovementDuringCount"`
+}
+
+// VarianceReport summarises a count against the frozen expected quantities
+type VarianceReport struct {
+	SessionID         string         `json:"sessionId"`
+	Status            string         `json:"status"`
+	Version           int64          `json:"version"`
+	LinesTotal        int            `json:"linesTotal"`
+	LinesCounted      int            `json:"linesCounted"`
+	LinesWithVariance int            `json:"linesWithVariance"`
+	UnitsOver         int            `json:"unitsOver"`
+	UnitsShort        int            `json:"unitsShort"`
+	ValueOver         int64          `json:"valueOver"`
+	ValueShort        int64          `json:"valueShort"`
+	NetValueImpact    int64          `json:"netValueImpact"`
+	Warnings          []string       `json:"warnings"`
+	Lines             []VarianceLine `json:"lines"`
+}
+
+// ApproveResult is the approved session, its final variance report and the
+// adjustment movements posted
+type ApproveResult struct {
+	Session   *CountSession             `json:"session"`
+	Report    *VarianceReport           `json:"report"`
+	Movements []amenities.StockMovement `json:"movements"`
+}
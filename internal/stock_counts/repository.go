@@ -0,0 +1,202 @@
+package stock_counts
+
+import (
+	"errors"
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/amenities"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		db: database.GetDB(),
+	}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// Create creates a session together with its lines
+func (r *Repository) Create(session *CountSession) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(session).Error; err != nil {
+			return err
+		}
+		if len(session.Lines) == 0 {
+			return nil
+		}
+		return tx.Omit(clause.Associations).CreateInBatches(session.Lines, 500).Error
+	})
+}
+
+// GetByID retrieves a session, with its lines if withLines is set
+func (r *Repository) GetByID(id string, withLines bool) (*CountSession, error) {
+	var session CountSession
+	query := r.db.Preload("Location")
+	if withLines {
+		query = query.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+			return db.Joins("JOIN amenities ON amenities.id = count_lines.amenity_id").
+				Order("amenities.item_name ASC, count_lines.location_id ASC")
+		}).Preload("Lines.Amenity", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).Preload("Lines.Location")
+	}
+	err := query.Where("id = ?", id).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("count session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// TenantOf returns the tenant of count session id, "" if it does not exist
+func (r *Repository) TenantOf(id string) (string, error) {
+	var tenantIDs []string
+	if err := r.db.Model(&CountSession{}).Where("id = ?", id).Pluck("tenant_id", &tenantIDs).Error; err != nil || len(tenantIDs) == 0 {
+		return "", err
+	}
+	return tenantIDs[0], nil
+}
+
+// GetForUpdate locks a session row. Must be called in a transaction; it
+// serialises count submissions and approval.
+func (r *Repository) GetForUpdate(id string) (*CountSession, error) {
+	var session CountSession
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("count session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByTenantID retrieves a tenant's sessions, newest first
+func (r *Repository) GetByTenantID(tenantID, status string, page, pageSize int) ([]CountSession, int64, error) {
+	var sessions []CountSession
+	var total int64
+
+	db := r.db.Model(&CountSession{}).Where("tenant_id = ?", tenantID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Preload("Location").
+		Order("started_at DESC, id DESC").
+		Offset(offset).Limit(pageSize).
+		Find(&sessions).Error
+
+	return sessions, total, err
+}
+
+// Update saves a session's own columns if it is still at session.Version,
+// returning database.ErrVersionConflict otherwise
+func (r *Repository) Update(session *CountSession) error {
+	return database.UpdateVersioned(r.db, session, &session.Version)
+}
+
+// GetLines retrieves a session's lines
+func (r *Repository) GetLines(sessionID string) ([]CountLine, error) {
+	var lines []CountLine
+	err := r.db.Where("session_id = ?", sessionID).Order("amenity_id ASC, location_id ASC").Find(&lines).Error
+	return lines, err
+}
+
+// GetLine retrieves the line for an amenity at a location in a session
+func (r *Repository) GetLine(sessionID, amenityID, locationID string) (*CountLine, error) {
+	var line CountLine
+	err := r.db.Where("session_id = ? AND amenity_id = ? AND location_id = ?", sessionID, amenityID, locationID).
+		First(&line).Error
+	if err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+// CreateLine adds a line to a session
+func (r *Repository) CreateLine(line *CountLine) error {
+	return r.db.Omit(clause.Associations).Create(line).Error
+}
+
+// RecordCount sets a line's counted quantity or, with ModeAdd, adds to it
+func (r *Repository) RecordCount(lineID, mode string, quantity int, actorID *string, at time.Time) error {
+	counted := interface{}(quantity)
+	if mode == ModeAdd {
+		counted = gorm.Expr("COALESCE(counted_quantity, 0) + ?", quantity)
+	}
+	return r.db.Model(&CountLine{}).Where("id = ?", lineID).Updates(map[string]interface{}{
+		"counted_quantity": counted,
+		"counted_by":       actorID,
+		"counted_at":       at,
+	}).Error
+}
+
+// SetPostedValue records the value a line's adjustment posted
+func (r *Repository) SetPostedValue(lineID string, value int64) error {
+	return r.db.Model(&CountLine{}).Where("id = ?", lineID).Update("posted_value", value).Error
+}
+
+// CreateEntry appends a count entry
+func (r *Repository) CreateEntry(entry *CountEntry) error {
+	return r.db.Create(entry).Error
+}
+
+// GetEntries retrieves a session's count entries, oldest first
+func (r *Repository) GetEntries(sessionID string) ([]CountEntry, error) {
+	var entries []CountEntry
+	err := r.db.Where("session_id = ?", sessionID).Order("created_at ASC, id ASC").Find(&entries).Error
+	return entries, err
+}
+
+// MovementActivity is the stock movements recorded on one amenity at one
+// location over a period
+type MovementActivity struct {
+	AmenityID  string
+	LocationID string
+	Movements  int
+	NetDelta   int
+}
+
+// GetMovementsSince summarises a tenant's stock movements per amenity and
+// location since a time, leaving out those carrying excludeReference
+func (r *Repository) GetMovementsSince(tenantID string, since time.Time, excludeReference string) ([]MovementActivity, error) {
+	var activity []MovementActivity
+	err := r.db.Model(&amenities.StockMovement{}).
+		Select("amenity_id, location_id, COUNT(*) AS movements, SUM(delta) AS net_delta").
+		Where("tenant_id = ? AND created_at >= ? AND location_id IS NOT NULL AND reference != ?", tenantID, since, excludeReference).
+		Group("amenity_id, location_id").
+		Scan(&activity).Error
+	return activity, err
+}
+
+// GetStockSnapshot retrieves the current per-location stock rows of a
+// tenant's amenities, optionally at one location
+func (r *Repository) GetStockSnapshot(tenantID string, locationID *string) ([]amenities.AmenityStock, error) {
+	var stocks []amenities.AmenityStock
+	query := r.db.Model(&amenities.AmenityStock{}).
+		Joins("JOIN amenities ON amenities.id = amenity_stocks.amenity_id AND amenities.deleted_at IS NULL").
+		Where("amenity_stocks.tenant_id = ?", tenantID)
+	if locationID != nil {
+		query = query.Where("amenity_stocks.location_id = ?", *locationID)
+	}
+	err := query.Find(&stocks).Error
+	return stocks, err
+}
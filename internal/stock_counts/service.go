@@ -0,0 +1,527 @@
+package stock_counts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/locations"
+	"concierge-be/internal/suppliers"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	repo          *Repository
+	amenityRepo   *amenities.Repository
+	locationsRepo *locations.Repository
+	supplierRepo  *suppliers.Repository
+}
+
+func NewService() *Service {
+	return &Service{
+		repo:          NewRepository(),
+		amenityRepo:   amenities.NewRepository(),
+		locationsRepo: locations.NewRepository(),
+		supplierRepo:  suppliers.NewRepository(),
+	}
+}
+
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	return s.withTx(s.repo.db.WithContext(ctx))
+}
+
+// withTx returns a copy of the service whose repositories all use db
+func (s *Service) withTx(db *gorm.DB) *Service {
+	copied := *s
+	copied.repo = s.repo.WithTx(db)
+	copied.amenityRepo = s.amenityRepo.WithTx(db)
+	copied.locationsRepo = s.locationsRepo.WithTx(db)
+	copied.supplierRepo = s.supplierRepo.WithTx(db)
+	return &copied
+}
+
+// CreateSession starts a count of a tenant, or of one location, freezing
+// the current stock of every amenity held there as the expected quantity
+func (s *Service) CreateSession(req *CreateSessionRequest, actorID *string) (*CountSession, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("count session name is required")
+	}
+
+	var locationID *string
+	if req.LocationID != nil && *req.LocationID != "" {
+		location, err := s.locationsRepo.GetByID(*req.LocationID)
+		if err != nil || location.TenantID != req.TenantID {
+			return nil, errors.New("location not found")
+		}
+		locationID = &location.ID
+	}
+
+	now := time.Now()
+	session := &CountSession{
+		ID:         uuid.New().String(),
+		TenantID:   req.TenantID,
+		LocationID: locationID,
+		Reference:  newReference(now),
+		Name:       strings.TrimSpace(req.Name),
+		Note:       req.Note,
+		Status:     StatusOpen,
+		StartedBy:  actorID,
+		StartedAt:  now,
+	}
+
+	stocks, err := s.repo.GetStockSnapshot(req.TenantID, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stock: %w", err)
+	}
+	amenityIDs := make([]string, 0, len(stocks))
+	for _, stock := range stocks {
+		amenityIDs = append(amenityIDs, stock.AmenityID)
+	}
+	costs, err := s.unitCosts(amenityIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, stock := range stocks {
+		session.Lines = append(session.Lines, CountLine{
+			ID:               uuid.New().String(),
+			SessionID:        session.ID,
+			AmenityID:        stock.AmenityID,
+			LocationID:       stock.LocationID,
+			ExpectedQuantity: stock.Quantity,
+			UnitCost:         costs[stock.AmenityID],
+		})
+	}
+
+	if err := s.repo.Create(session); err != nil {
+		return nil, fmt.Errorf("failed to create count session: %w", err)
+	}
+
+	return s.repo.GetByID(session.ID, true)
+}
+
+// GetSession retrieves a session with its lines
+func (s *Service) GetSession(id string) (*CountSession, error) {
+	return s.repo.GetByID(id, true)
+}
+
+// GetSessionsByTenantID retrieves a tenant's sessions, optionally by status
+func (s *Service) GetSessionsByTenantID(tenantID, status string, page, pageSize int) ([]CountSession, int64, error) {
+	if status != "" && status != StatusOpen && status != StatusApproved && status != StatusCancelled {
+		return nil, 0, errors.New("invalid count session status")
+	}
+	return s.repo.GetByTenantID(tenantID, status, page, pageSize)
+}
+
+// GetEntries retrieves every count submitted to a session
+func (s *Service) GetEntries(id string) ([]CountEntry, error) {
+	if _, err := s.repo.GetByID(id, false); err != nil {
+		return nil, err
+	}
+	return s.repo.GetEntries(id)
+}
+
+// SubmitCounts records counted quantities from one device. Counts for an
+// amenity the session did not expect at a location add a line expecting
+// zero. Each submission bumps the session's version, so an approval made
+// against an older variance report is rejected.
+func (s *Service) SubmitCounts(id string, req *SubmitCountsRequest, actorID *string) (*CountSession, error) {
+	if len(req.Entries) == 0 {
+		return nil, errors.New("no counts to submit")
+	}
+
+	err := s.repo.db.Transaction(func(tx *gorm.DB) error {
+		service := s.withTx(tx)
+		repo := service.repo
+		session, err := repo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if session.Status != StatusOpen {
+			return errors.New("count session is not open")
+		}
+
+		now := time.Now()
+		for _, entry := range req.Entries {
+			mode := entry.Mode
+			if mode == "" {
+				mode = ModeSet
+			}
+			if mode != ModeSet && mode != ModeAdd {
+				return errors.New("mode must be set or add")
+			}
+			if entry.Quantity < 0 {
+				return errors.New("quantity cannot be negative")
+			}
+
+			locationID, err := service.entryLocation(session, entry.LocationID)
+			if err != nil {
+				return err
+			}
+
+			line, err := repo.GetLine(session.ID, entry.AmenityID, locationID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				line, err = service.addLine(repo, session, entry.AmenityID, locationID)
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := repo.RecordCount(line.ID, mode, entry.Quantity, actorID, now); err != nil {
+				return err
+			}
+			if err := repo.CreateEntry(&CountEntry{
+				ID:        uuid.New().String(),
+				SessionID: session.ID,
+				LineID:    line.ID,
+				Mode:      mode,
+				Quantity:  entry.Quantity,
+				DeviceID:  req.DeviceID,
+				ActorID:   actorID,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return repo.Update(session)
+	})
+	if err != nil {
+		return nil, countError(err, "failed to record counts")
+	}
+
+	return s.repo.GetByID(id, true)
+}
+
+// GetVarianceReport compares counted with expected quantities and flags
+// stock movements recorded on the counted items since the count started
+func (s *Service) GetVarianceReport(id string) (*VarianceReport, error) {
+	session, err := s.repo.GetByID(id, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildReport(session, session.Lines)
+}
+
+// ApproveSession posts the variance of every counted line as an adjustment
+// movement and closes the session. version is the session version of the
+// variance report the approver reviewed.
+func (s *Service) ApproveSession(id string, req *ApproveRequest, version int64, actorID *string) (*ApproveResult, error) {
+	var movements []amenities.StockMovement
+	err := s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		amenityRepo := s.amenityRepo.WithTx(tx)
+
+		session, err := repo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if session.Version != version {
+			return database.ErrVersionConflict
+		}
+		if session.Status != StatusOpen {
+			return errors.New("count session is not open")
+		}
+
+		lines, err := repo.GetLines(session.ID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, line := range lines {
+			counted := line.CountedQuantity
+			if counted == nil {
+				if !req.TreatUncountedAsZero {
+					continue
+				}
+				zero := 0
+				counted = &zero
+				if err := repo.RecordCount(line.ID, ModeSet, 0, actorID, now); err != nil {
+					return err
+				}
+			}
+
+			variance := *counted - line.ExpectedQuantity
+			if variance == 0 {
+				continue
+			}
+			locationID := line.LocationID
+			movement := amenities.StockMovement{
+				AmenityID:  line.AmenityID,
+				LocationID: &locationID,
+				Delta:      variance,
+				Reason:     amenities.MovementAdjustment,
+				Reference:  session.Reference,
+				Note:       req.Note,
+				ActorID:    actorID,
+			}
+			if err := amenityRepo.ApplyMovement(&movement); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					// The amenity was deleted during the count
+					continue
+				}
+				if err.Error() == "stock quantity cannot be negative" {
+					return errors.New("adjustment would take stock below zero; recount the items that moved during the count")
+				}
+				return err
+			}
+			// Stock is valued at its average cost now, not when the count
+			// started, so the report shows what was actually posted
+			if err := repo.SetPostedValue(line.ID, movement.Value); err != nil {
+				return err
+			}
+			movements = append(movements, movement)
+		}
+
+		session.Status = StatusApproved
+		session.ApprovedBy = actorID
+		session.ApprovedAt = &now
+		return repo.Update(session)
+	})
+	if err != nil {
+		return nil, countError(err, "failed to approve count session")
+	}
+
+	session, err := s.repo.GetByID(id, true)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.buildReport(session, session.Lines)
+	if err != nil {
+		return nil, err
+	}
+	return &ApproveResult{Session: session, Report: report, Movements: movements}, nil
+}
+
+// CancelSession abandons an open count without posting anything
+func (s *Service) CancelSession(id string) (*CountSession, error) {
+	err := s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		session, err := repo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if session.Status != StatusOpen {
+			return errors.New("count session is not open")
+		}
+
+		now := time.Now()
+		session.Status = StatusCancelled
+		session.CancelledAt = &now
+		return repo.Update(session)
+	})
+	if err != nil {
+		return nil, countError(err, "failed to cancel count session")
+	}
+
+	return s.repo.GetByID(id, false)
+}
+
+// buildReport loads the movements recorded during the count and builds the
+// variance report
+func (s *Service) buildReport(session *CountSession, lines []CountLine) (*VarianceReport, error) {
+	// Movements after approval are not part of the count
+	var activity []MovementActivity
+	if session.Status == StatusOpen {
+		var err error
+		activity, err = s.repo.GetMovementsSince(session.TenantID, session.StartedAt, session.Reference)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load movements: %w", err)
+		}
+	}
+	return BuildReport(session, lines, activity), nil
+}
+
+// BuildReport compares each line's counted quantity with the frozen
+// expected quantity and values the difference at the line's unit cost, or
+// at the value its adjustment posted once approved. Uncounted lines have no
+// variance. activity is the stock movements
+// recorded since the count started; any on a line produce a warning, since
+// the count may or may not include them.
+func BuildReport(session *CountSession, lines []CountLine, activity []MovementActivity) *VarianceReport {
+	byKey := make(map[string]MovementActivity, len(activity))
+	for _, moved := range activity {
+		byKey[moved.AmenityID+":"+moved.LocationID] = moved
+	}
+
+	report := &VarianceReport{
+		SessionID:  session.ID,
+		Status:     session.Status,
+		Version:    session.Version,
+		LinesTotal: len(lines),
+		Warnings:   []string{},
+		Lines:      make([]VarianceLine, 0, len(lines)),
+	}
+
+	movedLines := 0
+	for _, line := range lines {
+		row := VarianceLine{
+			LineID:           line.ID,
+			AmenityID:        line.AmenityID,
+			LocationID:       line.LocationID,
+			ExpectedQuantity: line.ExpectedQuantity,
+			CountedQuantity:  line.CountedQuantity,
+			UnitCost:         line.UnitCost,
+		}
+		if line.Amenity != nil {
+			row.ItemName = line.Amenity.ItemName
+		}
+		if line.Location != nil {
+			row.LocationName = line.Location.Name
+		}
+		if moved, ok := byKey[line.AmenityID+":"+line.LocationID]; ok {
+			row.MovementsDuringCount = moved.Movements
+			row.NetMovementDuringCount = moved.NetDelta
+			movedLines++
+		}
+
+		if line.CountedQuantity != nil {
+			report.LinesCounted++
+			row.Variance = *line.CountedQuantity - line.ExpectedQuantity
+			row.ValueImpact = int64(row.Variance) * line.UnitCost
+			if line.PostedValue != nil {
+				row.ValueImpact = *line.PostedValue
+			}
+			switch {
+			case row.Variance > 0:
+				report.LinesWithVariance++
+				report.UnitsOver += row.Variance
+				report.ValueOver += row.ValueImpact
+			case row.Variance < 0:
+				report.LinesWithVariance++
+				report.UnitsShort -= row.Variance
+				report.ValueShort -= row.ValueImpact
+			}
+		}
+		report.Lines = append(report.Lines, row)
+	}
+	report.NetValueImpact = report.ValueOver - report.ValueShort
+
+	sort.SliceStable(report.Lines, func(i, j int) bool {
+		a, b := report.Lines[i], report.Lines[j]
+		if a.ItemName != b.ItemName {
+			return a.ItemName < b.ItemName
+		}
+		return a.LocationName < b.LocationName
+	})
+
+	if movedLines > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"%d line(s) had stock movements since the count started; approving keeps those movements on top of the counted quantities", movedLines))
+	}
+	if uncounted := report.LinesTotal - report.LinesCounted; uncounted > 0 && session.Status == StatusOpen {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d line(s) have not been counted", uncounted))
+	}
+	return report
+}
+
+// entryLocation resolves the location a count entry is for, checking it is
+// part of the session
+func (s *Service) entryLocation(session *CountSession, locationID string) (string, error) {
+	if locationID == "" {
+		if session.LocationID != nil {
+			return *session.LocationID, nil
+		}
+		location, err := s.locationsRepo.EnsureDefault(session.TenantID)
+		if err != nil {
+			return "", err
+		}
+		return location.ID, nil
+	}
+
+	if session.LocationID != nil {
+		if locationID != *session.LocationID {
+			return "", errors.New("location is not part of this count")
+		}
+		return locationID, nil
+	}
+	location, err := s.locationsRepo.GetByID(locationID)
+	if err != nil || location.TenantID != session.TenantID {
+		return "", errors.New("location not found")
+	}
+	return location.ID, nil
+}
+
+// addLine adds a line for an amenity found where the session expected none
+func (s *Service) addLine(repo *Repository, session *CountSession, amenityID, locationID string) (*CountLine, error) {
+	amenity, err := s.amenityRepo.GetByID(amenityID)
+	if err != nil || amenity.TenantID != session.TenantID {
+		return nil, errors.New("amenity not found")
+	}
+	if amenity.IsKit() {
+		return nil, errors.New("kits are counted through their components")
+	}
+	costs, err := s.unitCosts([]string{amenity.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	line := &CountLine{
+		ID:         uuid.New().String(),
+		SessionID:  session.ID,
+		AmenityID:  amenity.ID,
+		LocationID: locationID,
+		UnitCost:   costs[amenity.ID],
+	}
+	if err := repo.CreateLine(line); err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+// unitCosts values amenities at the weighted-average cost of their stock,
+// or at their preferred supplier's unit price while they have no stock value
+func (s *Service) unitCosts(amenityIDs []string) (map[string]int64, error) {
+	costs, err := s.amenityRepo.GetAverageCosts(amenityIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unit costs: %w", err)
+	}
+	unvalued := make([]string, 0, len(amenityIDs))
+	for _, id := range amenityIDs {
+		if _, ok := costs[id]; !ok {
+			unvalued = append(unvalued, id)
+		}
+	}
+	if len(unvalued) == 0 {
+		return costs, nil
+	}
+
+	items, err := s.supplierRepo.GetItemsByAmenityIDs(unvalued)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unit costs: %w", err)
+	}
+	for _, item := range items {
+		if _, ok := costs[item.AmenityID]; !ok {
+			costs[item.AmenityID] = item.UnitPrice
+		}
+	}
+	return costs, nil
+}
+
+// countError passes known count errors through and wraps the rest
+func countError(err error, action string) error {
+	if errors.Is(err, database.ErrVersionConflict) {
+		return err
+	}
+	switch err.Error() {
+	case "count session not found", "count session is not open", "no counts to submit", "count session name is required",
+		"mode must be set or add", "quantity cannot be negative", "location not found",
+		"location is not part of this count", "amenity not found",
+		"adjustment would take stock below zero; recount the items that moved during the count":
+		return err
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
+
+// newReference returns a count reference such as CNT-20260301-1A2B3C4D
+func newReference(now time.Time) string {
+	suffix := strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:8])
+	return fmt.Sprintf("CNT-%s-%s", now.Format("20060102"), suffix)
+}
@@ -0,0 +1,154 @@
+package stock_counts
+
+import (
+	"testing"
+	"time"
+
+	"concierge-be/database/dbtest"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/locations"
+	"concierge-be/internal/suppliers"
+)
+
+func TestUnitCosts(t *testing.T) {
+	db := dbtest.Open(t, &amenities.Amenity{}, &suppliers.Supplier{}, &suppliers.SupplierItem{})
+
+	fixtures := []interface{}{
+		// 40 units worth 10.00 average 25 each, whatever the supplier charges
+		&amenities.Amenity{ID: "valued", TenantID: "tenant-1", CategoryID: "c", ItemName: "Shampoo", Stock: 40, StockValue: 1000},
+		// Stock with no value yet falls back to the supplier's price
+		&amenities.Amenity{ID: "unvalued", TenantID: "tenant-1", CategoryID: "c", ItemName: "Soap", Stock: 12},
+		&amenities.Amenity{ID: "unpriced", TenantID: "tenant-1", CategoryID: "c", ItemName: "Comb", Stock: 3},
+		&suppliers.Supplier{ID: "supplier-1", TenantID: "tenant-1", Name: "Linen Co", IsActive: true},
+		&suppliers.SupplierItem{ID: "item-1", TenantID: "tenant-1", SupplierID: "supplier-1", AmenityID: "valued", SKU: "SH-1", UnitPrice: 90, IsPreferred: true},
+		&suppliers.SupplierItem{ID: "item-2", TenantID: "tenant-1", SupplierID: "supplier-1", AmenityID: "unvalued", SKU: "SO-1", UnitPrice: 30, IsPreferred: true},
+	}
+	for _, fixture := range fixtures {
+		if err := db.Create(fixture).Error; err != nil {
+			t.Fatalf("create fixture: %v", err)
+		}
+	}
+
+	costs, err := NewService().unitCosts([]string{"valued", "unvalued", "unpriced"})
+	if err != nil {
+		t.Fatalf("unitCosts: %v", err)
+	}
+	want := map[string]int64{"valued": 25, "unvalued": 30}
+	if len(costs) != len(want) {
+		t.Errorf("costs = %v, want %v", costs, want)
+	}
+	for id, cost := range want {
+		if costs[id] != cost {
+			t.Errorf("cost of %s = %d, want %d", id, costs[id], cost)
+		}
+	}
+}
+
+func TestApproveSessionOverExpiredLots(t *testing.T) {
+	db := dbtest.Open(t, &amenities.Amenity{}, &amenities.AmenityStock{}, &amenities.StockMovement{},
+		&amenities.StockLot{}, &amenities.StockMovementLot{}, &locations.Location{},
+		&suppliers.Supplier{}, &suppliers.SupplierItem{},
+		&CountSession{}, &CountLine{}, &CountEntry{})
+
+	amenity := &amenities.Amenity{ID: "shampoo", TenantID: "tenant-1", CategoryID: "c", ItemName: "Shampoo"}
+	if err := db.Create(amenity).Error; err != nil {
+		t.Fatalf("create amenity: %v", err)
+	}
+	// Everything on the shelf is past its date
+	expiredAt := time.Now().AddDate(0, 0, -3)
+	receipt := &amenities.StockMovement{AmenityID: amenity.ID, Delta: 5, Reason: amenities.MovementRestock,
+		Lot: &amenities.LotSpec{LotNumber: "L1", ExpiresAt: &expiredAt}}
+	if err := amenities.NewRepository().ApplyMovement(receipt); err != nil {
+		t.Fatalf("receive lot: %v", err)
+	}
+
+	service := NewService()
+	session, err := service.CreateSession(&CreateSessionRequest{TenantID: "tenant-1", Name: "Monthly"}, nil)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	session, err = service.SubmitCounts(session.ID, &SubmitCountsRequest{
+		Entries: []CountEntryRequest{{AmenityID: amenity.ID, Quantity: 3}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("SubmitCounts: %v", err)
+	}
+
+	// The count reconciles the expired stock rather than waiting for a write-off
+	result, err := service.ApproveSession(session.ID, &ApproveRequest{}, session.Version, nil)
+	if err != nil {
+		t.Fatalf("ApproveSession: %v", err)
+	}
+	if len(result.Movements) != 1 || result.Movements[0].Delta != -2 {
+		t.Fatalf("movements = %+v, want one adjustment of -2", result.Movements)
+	}
+
+	var stored amenities.Amenity
+	if err := db.First(&stored, "id = ?", amenity.ID).Error; err != nil {
+		t.Fatalf("load amenity: %v", err)
+	}
+	var lot amenities.StockLot
+	if err := db.First(&lot, "amenity_id = ?", amenity.ID).Error; err != nil {
+		t.Fatalf("load lot: %v", err)
+	}
+	if stored.Stock != 3 || lot.Quantity != 3 {
+		t.Errorf("stock = %d, expired lot = %d, want 3 and 3", stored.Stock, lot.Quantity)
+	}
+}
+
+func TestApprovedReportShowsPostedValue(t *testing.T) {
+	db := dbtest.Open(t, &amenities.Amenity{}, &amenities.AmenityStock{}, &amenities.StockMovement{},
+		&amenities.StockLot{}, &amenities.StockMovementLot{}, &locations.Location{},
+		&suppliers.Supplier{}, &suppliers.SupplierItem{},
+		&CountSession{}, &CountLine{}, &CountEntry{})
+
+	amenity := &amenities.Amenity{ID: "shampoo", TenantID: "tenant-1", CategoryID: "c", ItemName: "Shampoo"}
+	if err := db.Create(amenity).Error; err != nil {
+		t.Fatalf("create amenity: %v", err)
+	}
+	repo := amenities.NewRepository()
+	receive := func(quantity int, cost int64) {
+		t.Helper()
+		movement := &amenities.StockMovement{AmenityID: amenity.ID, Delta: quantity, Reason: amenities.MovementRestock, UnitCost: &cost}
+		if err := repo.ApplyMovement(movement); err != nil {
+			t.Fatalf("receive stock: %v", err)
+		}
+	}
+	// 10 at 100 each when the count starts
+	receive(10, 100)
+
+	service := NewService()
+	session, err := service.CreateSession(&CreateSessionRequest{TenantID: "tenant-1", Name: "Monthly"}, nil)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	session, err = service.SubmitCounts(session.ID, &SubmitCountsRequest{
+		Entries: []CountEntryRequest{{AmenityID: amenity.ID, Quantity: 8}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("SubmitCounts: %v", err)
+	}
+	report, err := service.GetVarianceReport(session.ID)
+	if err != nil {
+		t.Fatalf("GetVarianceReport: %v", err)
+	}
+	if report.Lines[0].ValueImpact != -200 {
+		t.Errorf("open value impact = %d, want -200 at the cost the count started at", report.Lines[0].ValueImpact)
+	}
+
+	// A dearer delivery during the count raises the average cost to 200
+	receive(10, 300)
+
+	result, err := service.ApproveSession(session.ID, &ApproveRequest{}, session.Version, nil)
+	if err != nil {
+		t.Fatalf("ApproveSession: %v", err)
+	}
+	if len(result.Movements) != 1 || result.Movements[0].Value != -400 {
+		t.Fatalf("movements = %+v, want one adjustment worth -400", result.Movements)
+	}
+	line := result.Report.Lines[0]
+	if line.ValueImpact != -400 || result.Report.ValueShort != 400 || result.Report.NetValueImpact != -400 {
+		t.Errorf("approved report = line %d, short %d, net %d, want the -400 posted",
+			line.ValueImpact, result.Report.ValueShort, result.Report.NetValueImpact)
+	}
+}
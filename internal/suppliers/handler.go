@@ -0,0 +1,231 @@
+package suppliers
+
+import (
+	"errors"
+	"net/http"
+
+	"concierge-be/database"
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
+// CreateSupplier handles POST /api/v1/suppliers
+func (h *Handler) CreateSupplier(c *gin.Context) {
+	var req CreateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	supplier, err := h.serviceFor(c).CreateSupplier(&req)
+	if err != nil {
+		switch err.Error() {
+		case "supplier name is required", "lead time cannot be negative":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case "supplier name already exists":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "Success",
+		Data:    supplier,
+	})
+}
+
+// GetSupplier handles GET /api/v1/suppliers/:id
+func (h *Handler) GetSupplier(c *gin.Context) {
+	supplier, err := h.serviceFor(c).GetSupplierByID(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SetETag(c, supplier.Version)
+	utils.SuccessResponse(c, supplier)
+}
+
+// GetSuppliers handles GET /api/v1/suppliers?tenantId=
+func (h *Handler) GetSuppliers(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+
+	suppliers, err := h.serviceFor(c).GetSuppliersByTenantID(tenantID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, suppliers)
+}
+
+// UpdateSupplier handles PUT /api/v1/suppliers/:id
+// Requires If-Match with the version from the supplier's ETag
+func (h *Handler) UpdateSupplier(c *gin.Context) {
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	supplier, err := h.serviceFor(c).UpdateSupplier(c.Param("id"), &req, version)
+	if err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			utils.ErrorResponse(c, http.StatusPreconditionFailed, "Supplier has been modified; reload and retry")
+			return
+		}
+		switch err.Error() {
+		case "supplier not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case "lead time cannot be negative":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case "supplier name already exists":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SetETag(c, supplier.Version)
+	utils.SuccessResponse(c, supplier)
+}
+
+// DeleteSupplier handles DELETE /api/v1/suppliers/:id
+func (h *Handler) DeleteSupplier(c *gin.Context) {
+	if err := h.serviceFor(c).DeleteSupplier(c.Param("id")); err != nil {
+		if err.Error() == "supplier not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Supplier deleted successfully"})
+}
+
+// GetItems handles GET /api/v1/suppliers/:id/items
+func (h *Handler) GetItems(c *gin.Context) {
+	items, err := h.serviceFor(c).GetItems(c.Param("id"))
+	if err != nil {
+		if err.Error() == "supplier not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, items)
+}
+
+// CreateItem handles POST /api/v1/suppliers/:id/items
+func (h *Handler) CreateItem(c *gin.Context) {
+	var req CreateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item, err := h.serviceFor(c).CreateItem(c.Param("id"), &req)
+	if err != nil {
+		switch err.Error() {
+		case "supplier not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case "amenity not found", "sku is required", "pack size must be at least 1", "unit price cannot be negative",
+			"kits cannot be supplied; list their components instead":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case "supplier already lists this amenity or SKU":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "Success",
+		Data:    item,
+	})
+}
+
+// UpdateItem handles PUT /api/v1/suppliers/:id/items/:itemId
+// Requires If-Match with the catalogue entry's version
+func (h *Handler) UpdateItem(c *gin.Context) {
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item, err := h.serviceFor(c).UpdateItem(c.Param("id"), c.Param("itemId"), &req, version)
+	if err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			utils.ErrorResponse(c, http.StatusPreconditionFailed, "Supplier item has been modified; reload and retry")
+			return
+		}
+		switch err.Error() {
+		case "supplier item not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case "sku is required", "pack size must be at least 1", "unit price cannot be negative":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case "supplier already lists this amenity or SKU":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SetETag(c, item.Version)
+	utils.SuccessResponse(c, item)
+}
+
+// DeleteItem handles DELETE /api/v1/suppliers/:id/items/:itemId
+func (h *Handler) DeleteItem(c *gin.Context) {
+	if err := h.serviceFor(c).DeleteItem(c.Param("id"), c.Param("itemId")); err != nil {
+		if err.Error() == "supplier item not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Supplier item deleted successfully"})
+}
@@ -0,0 +1,94 @@
+package suppliers
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Supplier is a vendor a tenant orders amenities from. LeadTimeDays is the
+// supplier's quoted delivery time, used until orders show the real one.
+type Supplier struct {
+	ID           string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID     string         `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	Name         string         `gorm:"type:varchar(100);not null" json:"name"`
+	ContactName  string         `gorm:"type:varchar(100)" json:"contactName"`
+	Email        string         `gorm:"type:varchar(100)" json:"email"`
+	Phone        string         `gorm:"type:varchar(50)" json:"phone"`
+	Notes        string         `gorm:"type:text" json:"notes"`
+	LeadTimeDays int            `gorm:"not null;default:0" json:"leadTimeDays"`
+	IsActive     bool           `gorm:"not null" json:"isActive"`
+	Version      int64          `gorm:"not null;default:1" json:"version"`
+	CreatedAt    time.Time      `json:"createdAt"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Supplier) TableName() string {
+	return "suppliers"
+}
+
+// SupplierItem is a catalogue entry mapping an amenity to what the supplier
+// sells: its SKU, how many units come in a pack and the price per unit in
+// minor currency units (e.g. cents). The preferred item is used when draft
+// purchase orders are generated.
+type SupplierItem struct {
+	ID          string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID    string    `gorm:"type:varchar(36);not null;index" json:"tenantId"`
+	SupplierID  string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_supplier_amenity;uniqueIndex:idx_supplier_sku" json:"supplierId"`
+	AmenityID   string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_supplier_amenity;index" json:"amenityId"`
+	SKU         string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_supplier_sku" json:"sku"`
+	PackSize    int       `gorm:"not null;default:1" json:"packSize"`
+	UnitPrice   int64     `gorm:"not null;default:0" json:"unitPrice"`
+	IsPreferred bool      `gorm:"not null" json:"isPreferred"`
+	Version     int64     `gorm:"not null;default:1" json:"version"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	// Relationships
+	Supplier *Supplier `gorm:"foreignKey:SupplierID;references:ID" json:"supplier,omitempty"`
+}
+
+func (SupplierItem) TableName() string {
+	return "supplier_items"
+}
+
+// CreateSupplierRequest represents the request body for creating a supplier
+type CreateSupplierRequest struct {
+	TenantID     string `json:"tenantId" binding:"required"`
+	Name         string `json:"name" binding:"required"`
+	ContactName  string `json:"contactName"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	Notes        string `json:"notes"`
+	LeadTimeDays int    `json:"leadTimeDays"`
+	IsActive     *bool  `json:"isActive"`
+}
+
+// UpdateSupplierRequest represents the request body for updating a supplier
+type UpdateSupplierRequest struct {
+	Name         string  `json:"name"`
+	ContactName  *string `json:"contactName"`
+	Email        *string `json:"email"`
+	Phone        *string `json:"phone"`
+	Notes        *string `json:"notes"`
+	LeadTimeDays *int    `json:"leadTimeDays"`
+	IsActive     *bool   `json:"isActive"`
+}
+
+// CreateItemRequest represents the request body for adding a catalogue entry
+type CreateItemRequest struct {
+	AmenityID   string `json:"amenityId" binding:"required"`
+	SKU         string `json:"sku" binding:"required"`
+	PackSize    int    `json:"packSize"`
+	UnitPrice   int64  `json:"unitPrice"`
+	IsPreferred bool   `json:"isPreferred"`
+}
+
+// UpdateItemRequest represents the request body for updating a catalogue entry
+type UpdateItemRequest struct {
+	SKU         string `json:"sku"`
+	PackSize    *int   `json:"packSize"`
+	UnitPrice   *int64 `json:"unitPrice"`
+	IsPreferred *bool  `json:"isPreferred"`
+}
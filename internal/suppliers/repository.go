@@ -0,0 +1,156 @@
+package suppliers
+
+import (
+	"errors"
+
+	"concierge-be/database"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		db: database.GetDB(),
+	}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// Create creates a new supplier
+func (r *Repository) Create(supplier *Supplier) error {
+	return r.db.Create(supplier).Error
+}
+
+// GetByID retrieves a supplier by ID
+func (r *Repository) GetByID(id string) (*Supplier, error) {
+	var supplier Supplier
+	err := r.db.Where("id = ?", id).First(&supplier).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("supplier not found")
+		}
+		return nil, err
+	}
+	return &supplier, nil
+}
+
+// TenantOf returns the tenant of supplier id, "" if it does not exist
+func (r *Repository) TenantOf(id string) (string, error) {
+	var tenantIDs []string
+	if err := r.db.Model(&Supplier{}).Where("id = ?", id).Pluck("tenant_id", &tenantIDs).Error; err != nil || len(tenantIDs) == 0 {
+		return "", err
+	}
+	return tenantIDs[0], nil
+}
+
+// GetByTenantID retrieves all suppliers for a tenant
+func (r *Repository) GetByTenantID(tenantID string) ([]Supplier, error) {
+	var suppliers []Supplier
+	err := r.db.Where("tenant_id = ?", tenantID).Order("name ASC").Find(&suppliers).Error
+	return suppliers, err
+}
+
+// CheckNameExists checks if a supplier name is already used in a tenant
+func (r *Repository) CheckNameExists(tenantID, name, excludeID string) (bool, error) {
+	var count int64
+	query := r.db.Model(&Supplier{}).Where("tenant_id = ? AND name = ?", tenantID, name)
+	if excludeID != "" {
+		query = query.Where("id != ?", excludeID)
+	}
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// Update updates a supplier if it is still at supplier.Version,
+// returning database.ErrVersionConflict otherwise
+func (r *Repository) Update(supplier *Supplier) error {
+	return database.UpdateVersioned(r.db, supplier, &supplier.Version)
+}
+
+// Delete soft deletes a supplier and removes its catalogue
+func (r *Repository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("supplier_id = ?", id).Delete(&SupplierItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Supplier{}, "id = ?", id).Error
+	})
+}
+
+// CreateItem adds a catalogue entry
+func (r *Repository) CreateItem(item *SupplierItem) error {
+	return r.db.Create(item).Error
+}
+
+// GetItemByID retrieves a catalogue entry by ID
+func (r *Repository) GetItemByID(id string) (*SupplierItem, error) {
+	var item SupplierItem
+	err := r.db.Where("id = ?", id).First(&item).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("supplier item not found")
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetItemsBySupplierID retrieves a supplier's catalogue
+func (r *Repository) GetItemsBySupplierID(supplierID string) ([]SupplierItem, error) {
+	var items []SupplierItem
+	err := r.db.Where("supplier_id = ?", supplierID).Order("sku ASC").Find(&items).Error
+	return items, err
+}
+
+// GetItemsByAmenityIDs retrieves the catalogue entries of active suppliers
+// for the given amenities, preferred entries and lower prices first
+func (r *Repository) GetItemsByAmenityIDs(amenityIDs []string) ([]SupplierItem, error) {
+	var items []SupplierItem
+	if len(amenityIDs) == 0 {
+		return items, nil
+	}
+	err := r.db.Preload("Supplier").
+		Joins("JOIN suppliers ON suppliers.id = supplier_items.supplier_id AND suppliers.deleted_at IS NULL AND suppliers.is_active = ?", true).
+		Where("supplier_items.amenity_id IN ?", amenityIDs).
+		Order("supplier_items.is_preferred DESC, supplier_items.unit_price ASC, supplier_items.id ASC").
+		Find(&items).Error
+	return items, err
+}
+
+// CheckItemExists checks if a supplier already lists an amenity or SKU
+func (r *Repository) CheckItemExists(supplierID, amenityID, sku, excludeID string) (bool, error) {
+	var count int64
+	query := r.db.Model(&SupplierItem{}).
+		Where("supplier_id = ? AND (amenity_id = ? OR sku = ?)", supplierID, amenityID, sku)
+	if excludeID != "" {
+		query = query.Where("id != ?", excludeID)
+	}
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateItem updates a catalogue entry if it is still at item.Version,
+// returning database.ErrVersionConflict otherwise
+func (r *Repository) UpdateItem(item *SupplierItem) error {
+	return database.UpdateVersioned(r.db, item, &item.Version)
+}
+
+// ClearPreferred unsets the preferred flag on every other supplier's entry
+// for an amenity
+func (r *Repository) ClearPreferred(amenityID, exceptID string) error {
+	return r.db.Model(&SupplierItem{}).
+		Where("amenity_id = ? AND id != ? AND is_preferred = ?", amenityID, exceptID, true).
+		Update("is_preferred", false).Error
+}
+
+// DeleteItem removes a catalogue entry
+func (r *Repository) DeleteItem(id string) error {
+	return r.db.Delete(&SupplierItem{}, "id = ?", id).Error
+}
@@ -0,0 +1,302 @@
+package suppliers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"concierge-be/database"
+	"concierge-be/internal/amenities"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	repo        *Repository
+	amenityRepo *amenities.Repository
+}
+
+func NewService() *Service {
+	return &Service{
+		repo:        NewRepository(),
+		amenityRepo: amenities.NewRepository(),
+	}
+}
+
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	db := s.repo.db.WithContext(ctx)
+	copied := *s
+	copied.repo = s.repo.WithTx(db)
+	copied.amenityRepo = s.amenityRepo.WithTx(db)
+	return &copied
+}
+
+// CreateSupplier creates a new supplier
+func (s *Service) CreateSupplier(req *CreateSupplierRequest) (*Supplier, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("supplier name is required")
+	}
+	if req.LeadTimeDays < 0 {
+		return nil, errors.New("lead time cannot be negative")
+	}
+
+	exists, err := s.repo.CheckNameExists(req.TenantID, name, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check supplier name: %w", err)
+	}
+	if exists {
+		return nil, errors.New("supplier name already exists")
+	}
+
+	supplier := &Supplier{
+		ID:           uuid.New().String(),
+		TenantID:     req.TenantID,
+		Name:         name,
+		ContactName:  req.ContactName,
+		Email:        req.Email,
+		Phone:        req.Phone,
+		Notes:        req.Notes,
+		LeadTimeDays: req.LeadTimeDays,
+		IsActive:     true,
+	}
+	if req.IsActive != nil {
+		supplier.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.Create(supplier); err != nil {
+		return nil, fmt.Errorf("failed to create supplier: %w", err)
+	}
+
+	return supplier, nil
+}
+
+// GetSupplierByID retrieves a supplier by ID
+func (s *Service) GetSupplierByID(id string) (*Supplier, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetSuppliersByTenantID retrieves all suppliers for a tenant
+func (s *Service) GetSuppliersByTenantID(tenantID string) ([]Supplier, error) {
+	return s.repo.GetByTenantID(tenantID)
+}
+
+// UpdateSupplier updates a supplier the caller last read at version
+func (s *Service) UpdateSupplier(id string, req *UpdateSupplierRequest, version int64) (*Supplier, error) {
+	supplier, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if supplier.Version != version {
+		return nil, database.ErrVersionConflict
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" && name != supplier.Name {
+		exists, err := s.repo.CheckNameExists(supplier.TenantID, name, supplier.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check supplier name: %w", err)
+		}
+		if exists {
+			return nil, errors.New("supplier name already exists")
+		}
+		supplier.Name = name
+	}
+	if req.ContactName != nil {
+		supplier.ContactName = *req.ContactName
+	}
+	if req.Email != nil {
+		supplier.Email = *req.Email
+	}
+	if req.Phone != nil {
+		supplier.Phone = *req.Phone
+	}
+	if req.Notes != nil {
+		supplier.Notes = *req.Notes
+	}
+	if req.LeadTimeDays != nil {
+		if *req.LeadTimeDays < 0 {
+			return nil, errors.New("lead time cannot be negative")
+		}
+		supplier.LeadTimeDays = *req.LeadTimeDays
+	}
+	if req.IsActive != nil {
+		supplier.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.Update(supplier); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update supplier: %w", err)
+	}
+
+	return supplier, nil
+}
+
+// DeleteSupplier deletes a supplier and its catalogue
+func (s *Service) DeleteSupplier(id string) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return fmt.Errorf("failed to delete supplier: %w", err)
+	}
+	return nil
+}
+
+// GetItems retrieves a supplier's catalogue
+func (s *Service) GetItems(supplierID string) ([]SupplierItem, error) {
+	if _, err := s.repo.GetByID(supplierID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetItemsBySupplierID(supplierID)
+}
+
+// CreateItem adds an amenity to a supplier's catalogue
+func (s *Service) CreateItem(supplierID string, req *CreateItemRequest) (*SupplierItem, error) {
+	supplier, err := s.repo.GetByID(supplierID)
+	if err != nil {
+		return nil, err
+	}
+
+	amenity, err := s.amenityRepo.GetByID(req.AmenityID)
+	if err != nil || amenity.TenantID != supplier.TenantID {
+		return nil, errors.New("amenity not found")
+	}
+	if amenity.IsKit() {
+		return nil, errors.New("kits cannot be supplied; list their components instead")
+	}
+
+	packSize := req.PackSize
+	if packSize == 0 {
+		packSize = 1
+	}
+	if err := validateItem(req.SKU, packSize, req.UnitPrice); err != nil {
+		return nil, err
+	}
+
+	sku := strings.TrimSpace(req.SKU)
+	exists, err := s.repo.CheckItemExists(supplier.ID, amenity.ID, sku, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check supplier item: %w", err)
+	}
+	if exists {
+		return nil, errors.New("supplier already lists this amenity or SKU")
+	}
+
+	item := &SupplierItem{
+		ID:          uuid.New().String(),
+		TenantID:    supplier.TenantID,
+		SupplierID:  supplier.ID,
+		AmenityID:   amenity.ID,
+		SKU:         sku,
+		PackSize:    packSize,
+		UnitPrice:   req.UnitPrice,
+		IsPreferred: req.IsPreferred,
+	}
+
+	err = s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		if err := repo.CreateItem(item); err != nil {
+			return err
+		}
+		if item.IsPreferred {
+			return repo.ClearPreferred(item.AmenityID, item.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create supplier item: %w", err)
+	}
+
+	return item, nil
+}
+
+// UpdateItem updates a catalogue entry the caller last read at version.
+// Marking an entry preferred unmarks the amenity's other entries.
+func (s *Service) UpdateItem(supplierID, itemID string, req *UpdateItemRequest, version int64) (*SupplierItem, error) {
+	item, err := s.repo.GetItemByID(itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.SupplierID != supplierID {
+		return nil, errors.New("supplier item not found")
+	}
+	if item.Version != version {
+		return nil, database.ErrVersionConflict
+	}
+
+	if sku := strings.TrimSpace(req.SKU); sku != "" && sku != item.SKU {
+		exists, err := s.repo.CheckItemExists(item.SupplierID, "", sku, item.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check supplier item: %w", err)
+		}
+		if exists {
+			return nil, errors.New("supplier already lists this amenity or SKU")
+		}
+		item.SKU = sku
+	}
+	if req.PackSize != nil {
+		item.PackSize = *req.PackSize
+	}
+	if req.UnitPrice != nil {
+		item.UnitPrice = *req.UnitPrice
+	}
+	if req.IsPreferred != nil {
+		item.IsPreferred = *req.IsPreferred
+	}
+	if err := validateItem(item.SKU, item.PackSize, item.UnitPrice); err != nil {
+		return nil, err
+	}
+
+	err = s.repo.db.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		if err := repo.UpdateItem(item); err != nil {
+			return err
+		}
+		if item.IsPreferred {
+			return repo.ClearPreferred(item.AmenityID, item.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update supplier item: %w", err)
+	}
+
+	return item, nil
+}
+
+// DeleteItem removes an amenity from a supplier's catalogue
+func (s *Service) DeleteItem(supplierID, itemID string) error {
+	item, err := s.repo.GetItemByID(itemID)
+	if err != nil {
+		return err
+	}
+	if item.SupplierID != supplierID {
+		return errors.New("supplier item not found")
+	}
+	if err := s.repo.DeleteItem(item.ID); err != nil {
+		return fmt.Errorf("failed to delete supplier item: %w", err)
+	}
+	return nil
+}
+
+// validateItem checks a catalogue entry's SKU, pack size and price
+func validateItem(sku string, packSize int, unitPrice int64) error {
+	if strings.TrimSpace(sku) == "" {
+		return errors.New("sku is required")
+	}
+	if packSize < 1 {
+		return errors.New("pack size must be at least 1")
+	}
+	if unitPrice < 0 {
+		return errors.New("unit price cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package tenant_archive
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
+// ExportTenant handles GET /api/v1/tenants/:id/export
+// Streams NDJSON by default; ?format=json returns a single JSON document
+func (h *Handler) ExportTenant(c *gin.Context) {
+	id := c.Param("id")
+	format := c.DefaultQuery("format", "ndjson")
+	if format != "ndjson" && format != "json" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "format must be ndjson or json")
+		return
+	}
+
+	// Resolve the tenant before any bytes are written so a 404 is still possible
+	service := h.serviceFor(c)
+	if _, err := service.GetTenant(id); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	var err error
+	if format == "json" {
+		c.Header("Content-Type", "application/json")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=tenant-%s.json", id))
+		c.Status(http.StatusOK)
+		err = service.ExportJSON(id, c.Writer)
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=tenant-%s.ndjson", id))
+		c.Status(http.StatusOK)
+		err = service.ExportNDJSON(id, c.Writer)
+	}
+
+	// Headers are already sent, so a failure can only be logged and the stream cut short
+	if err != nil {
+		log.Printf("tenant export %s failed: %v", id, err)
+		c.Abort()
+	}
+}
+
+// ImportTenant handles POST /api/v1/tenants/import
+// Accepts an NDJSON archive, or a JSON archive when Content-Type is application/json.
+// Supports ?dryRun=true and ?domain= to override the archived domain. The caller
+// becomes the new tenant's admin; ?linkMembers=true also grants the archived
+// memberships to existing users and is limited to platform admins.
+func (h *Handler) ImportTenant(c *gin.Context) {
+	linkMembers := c.Query("linkMembers") == "true"
+	if linkMembers && !utils.IsPlatformAdmin(c) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only platform admins can link archived members to existing users")
+		return
+	}
+
+	var archive *Archive
+	var err error
+
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		archive, err = ParseJSON(c.Request.Body)
+	} else {
+		archive, err = ParseNDJSON(c.Request.Body)
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.serviceFor(c).Import(archive, ImportOptions{
+		DryRun:      c.Query("dryRun") == "true",
+		Domain:      c.Query("domain"),
+		OwnerID:     c.GetString("user_id"),
+		LinkMembers: linkMembers,
+	})
+	if err != nil {
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusUnprocessableEntity, utils.Response{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "invalid archive",
+				Data:    gin.H{"problems": validationErr.Problems},
+			})
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.DryRun {
+		utils.SuccessResponse(c, result)
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "Success",
+		Data:    result,
+	})
+}
@@ -0,0 +1,107 @@
+package tenant_archive
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"concierge-be/internal/alerts"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/amenities_categories"
+	"concierge-be/internal/locations"
+	"concierge-be/internal/tenants"
+)
+
+// ArchiveVersion is the current archive format version. Imports reject
+// archives written by a newer version. Version 2 added branding,
+// notification settings, locations, stock by location and lots; version 1
+// archives are imported with all stock at the default location.
+const ArchiveVersion = 2
+
+// Record types used in NDJSON archives
+const (
+	RecordHeader               = "header"
+	RecordTenant               = "tenant"
+	RecordBranding             = "branding"
+	RecordNotificationSettings = "notificationSettings"
+	RecordLocation             = "location"
+	RecordCategory             = "category"
+	RecordAmenity              = "amenity"
+	RecordStock                = "stock"
+	RecordLot                  = "lot"
+	RecordMembership           = "membership"
+)
+
+// Header describes an archive
+type Header struct {
+	Version    int       `json:"version"`
+	TenantID   string    `json:"tenantId"`
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// Membership is an exported user-tenant membership. Password hashes are
+// never exported; users are matched by username or email on import.
+type Membership struct {
+	UserID   string `json:"userId"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	FullName string `json:"fullName"`
+	Role     string `json:"role"`
+}
+
+// Archive is the full document form of a tenant export. Webhook secrets
+// and the storage keys of branding images are never exported.
+type Archive struct {
+	Header
+	Tenant               *tenants.Tenant                        `json:"tenant"`
+	Branding             *tenants.TenantBranding                `json:"branding,omitempty"`
+	NotificationSettings *alerts.NotificationSettings           `json:"notificationSettings,omitempty"`
+	Locations            []locations.Location                   `json:"locations"`
+	Categories           []amenities_categories.AmenityCategory `json:"categories"`
+	Amenities            []amenities.Amenity                    `json:"amenities"`
+	Stocks               []amenities.AmenityStock               `json:"stocks"`
+	Lots                 []amenities.StockLot                   `json:"lots"`
+	Memberships          []Membership                           `json:"memberships"`
+}
+
+// Record is a single line of an NDJSON archive
+type Record struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ImportOptions controls how an archive is imported
+type ImportOptions struct {
+	DryRun bool
+	// Domain overrides the archived tenant domain. Either way the domain is
+	// only imported as a pending claim that must be verified again.
+	Domain string
+	// OwnerID is the user running the import, who becomes the new tenant's
+	// admin
+	OwnerID string
+	// LinkMembers grants the archived memberships to the existing users
+	// matched by username or email. Otherwise they are all skipped.
+	LinkMembers bool
+}
+
+// ImportResult summarizes an import or dry run
+type ImportResult struct {
+	DryRun             bool     `json:"dryRun"`
+	TenantID           string   `json:"tenantId"`
+	Locations          int      `json:"locations"`
+	Categories         int      `json:"categories"`
+	Amenities          int      `json:"amenities"`
+	Lots               int      `json:"lots"`
+	Memberships        int      `json:"memberships"`
+	PendingDomain      string   `json:"pendingDomain,omitempty"`
+	SkippedMemberships []string `json:"skippedMemberships"`
+}
+
+// ValidationError lists every problem found in an archive
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid archive: " + strings.Join(e.Problems, "; ")
+}
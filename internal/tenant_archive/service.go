@@ -0,0 +1,890 @@
+package tenant_archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/alerts"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/amenities_categories"
+	"concierge-be/internal/barcodes"
+	"concierge-be/internal/locations"
+	"concierge-be/internal/organizations"
+	"concierge-be/internal/tenants"
+	"concierge-be/internal/users"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many amenities are loaded per query while streaming
+const exportBatchSize = 500
+
+// errDryRun rolls back the import transaction after a successful dry run
+var errDryRun = errors.New("dry run")
+
+type Service struct {
+	db           *gorm.DB
+	tenantRepo   *tenants.Repository
+	categoryRepo *amenities_categories.Repository
+	amenityRepo  *amenities.Repository
+	locationRepo *locations.Repository
+	alertRepo    *alerts.Repository
+	userRepo     *users.Repository
+}
+
+func NewService() *Service {
+	return &Service{
+		db:           database.GetDB(),
+		tenantRepo:   tenants.NewRepository(),
+		categoryRepo: amenities_categories.NewRepository(),
+		amenityRepo:  amenities.NewRepository(),
+		locationRepo: locations.NewRepository(),
+		alertRepo:    alerts.NewRepository(),
+		userRepo:     users.NewRepository(),
+	}
+}
+
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	copied := *s
+	copied.db = s.db.WithContext(ctx)
+	copied.tenantRepo = s.tenantRepo.WithTx(copied.db)
+	copied.categoryRepo = s.categoryRepo.WithTx(copied.db)
+	copied.amenityRepo = s.amenityRepo.WithTx(copied.db)
+	copied.locationRepo = s.locationRepo.WithTx(copied.db)
+	copied.alertRepo = s.alertRepo.WithTx(copied.db)
+	copied.userRepo = s.userRepo.WithTx(copied.db)
+	return &copied
+}
+
+// GetTenant retrieves the tenant to export
+func (s *Service) GetTenant(tenantID string) (*tenants.Tenant, error) {
+	return s.tenantRepo.GetTenantByID(tenantID)
+}
+
+// ExportNDJSON streams a tenant archive to w, one record per line
+func (s *Service) ExportNDJSON(tenantID string, w io.Writer) error {
+	tenant, err := s.tenantRepo.GetTenantByID(tenantID)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	write := func(recordType string, data interface{}) error {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(Record{Type: recordType, Data: raw})
+	}
+
+	header := Header{Version: ArchiveVersion, TenantID: tenant.ID, ExportedAt: time.Now()}
+	if err := write(RecordHeader, header); err != nil {
+		return err
+	}
+	if err := write(RecordTenant, tenant); err != nil {
+		return err
+	}
+
+	branding, settings, err := s.loadSettings(tenantID)
+	if err != nil {
+		return err
+	}
+	if branding != nil {
+		if err := write(RecordBranding, branding); err != nil {
+			return err
+		}
+	}
+	if settings != nil {
+		if err := write(RecordNotificationSettings, settings); err != nil {
+			return err
+		}
+	}
+
+	tenantLocations, err := s.locationRepo.GetByTenantID(tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+	for _, location := range tenantLocations {
+		if err := write(RecordLocation, location); err != nil {
+			return err
+		}
+	}
+
+	categories, err := s.categoryRepo.GetByTenantID(tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load categories: %w", err)
+	}
+	for _, category := range categories {
+		if err := write(RecordCategory, category); err != nil {
+			return err
+		}
+	}
+	flush(w)
+
+	err = s.amenityRepo.FindInBatchesByTenantID(tenantID, exportBatchSize, func(batch []amenities.Amenity) error {
+		for _, amenity := range batch {
+			if err := write(RecordAmenity, amenity); err != nil {
+				return err
+			}
+		}
+		flush(w)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export amenities: %w", err)
+	}
+
+	stocks, lots, err := s.loadStock(tenantID)
+	if err != nil {
+		return err
+	}
+	for _, stock := range stocks {
+		if err := write(RecordStock, stock); err != nil {
+			return err
+		}
+	}
+	for _, lot := range lots {
+		if err := write(RecordLot, lot); err != nil {
+			return err
+		}
+	}
+
+	memberships, err := s.loadMemberships(tenantID)
+	if err != nil {
+		return err
+	}
+	for _, membership := range memberships {
+		if err := write(RecordMembership, membership); err != nil {
+			return err
+		}
+	}
+	flush(w)
+
+	return nil
+}
+
+// ExportJSON writes a tenant archive to w as a single JSON document
+func (s *Service) ExportJSON(tenantID string, w io.Writer) error {
+	tenant, err := s.tenantRepo.GetTenantByID(tenantID)
+	if err != nil {
+		return err
+	}
+
+	archive := Archive{
+		Header: Header{Version: ArchiveVersion, TenantID: tenant.ID, ExportedAt: time.Now()},
+		Tenant: tenant,
+	}
+
+	archive.Branding, archive.NotificationSettings, err = s.loadSettings(tenantID)
+	if err != nil {
+		return err
+	}
+
+	archive.Locations, err = s.locationRepo.GetByTenantID(tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+
+	archive.Categories, err = s.categoryRepo.GetByTenantID(tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load categories: %w", err)
+	}
+
+	err = s.amenityRepo.FindInBatchesByTenantID(tenantID, exportBatchSize, func(batch []amenities.Amenity) error {
+		archive.Amenities = append(archive.Amenities, batch...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export amenities: %w", err)
+	}
+
+	archive.Stocks, archive.Lots, err = s.loadStock(tenantID)
+	if err != nil {
+		return err
+	}
+
+	archive.Memberships, err = s.loadMemberships(tenantID)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(archive)
+}
+
+// ParseNDJSON reads an NDJSON archive into its document form
+func ParseNDJSON(r io.Reader) (*Archive, error) {
+	archive := &Archive{}
+	dec := json.NewDecoder(r)
+	line := 0
+
+	for {
+		var record Record
+		if err := dec.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, &ValidationError{Problems: []string{fmt.Sprintf("record %d: %v", line+1, err)}}
+		}
+		line++
+
+		if line == 1 && record.Type != RecordHeader {
+			return nil, &ValidationError{Problems: []string{"first record must be the archive header"}}
+		}
+
+		var err error
+		switch record.Type {
+		case RecordHeader:
+			err = json.Unmarshal(record.Data, &archive.Header)
+		case RecordTenant:
+			archive.Tenant = &tenants.Tenant{}
+			err = json.Unmarshal(record.Data, archive.Tenant)
+		case RecordBranding:
+			archive.Branding = &tenants.TenantBranding{}
+			err = json.Unmarshal(record.Data, archive.Branding)
+		case RecordNotificationSettings:
+			archive.NotificationSettings = &alerts.NotificationSettings{}
+			err = json.Unmarshal(record.Data, archive.NotificationSettings)
+		case RecordLocation:
+			var location locations.Location
+			err = json.Unmarshal(record.Data, &location)
+			archive.Locations = append(archive.Locations, location)
+		case RecordCategory:
+			var category amenities_categories.AmenityCategory
+			err = json.Unmarshal(record.Data, &category)
+			archive.Categories = append(archive.Categories, category)
+		case RecordAmenity:
+			var amenity amenities.Amenity
+			err = json.Unmarshal(record.Data, &amenity)
+			archive.Amenities = append(archive.Amenities, amenity)
+		case RecordStock:
+			var stock amenities.AmenityStock
+			err = json.Unmarshal(record.Data, &stock)
+			archive.Stocks = append(archive.Stocks, stock)
+		case RecordLot:
+			var lot amenities.StockLot
+			err = json.Unmarshal(record.Data, &lot)
+			archive.Lots = append(archive.Lots, lot)
+		case RecordMembership:
+			var membership Membership
+			err = json.Unmarshal(record.Data, &membership)
+			archive.Memberships = append(archive.Memberships, membership)
+		default:
+			err = fmt.Errorf("unknown record type %q", record.Type)
+		}
+		if err != nil {
+			return nil, &ValidationError{Problems: []string{fmt.Sprintf("record %d: %v", line, err)}}
+		}
+	}
+
+	return archive, nil
+}
+
+// ParseJSON reads a single-document JSON archive
+func ParseJSON(r io.Reader) (*Archive, error) {
+	archive := &Archive{}
+	if err := json.NewDecoder(r).Decode(archive); err != nil {
+		return nil, &ValidationError{Problems: []string{err.Error()}}
+	}
+	return archive, nil
+}
+
+// Validate checks an archive for structural problems before anything is written
+func Validate(archive *Archive) error {
+	var problems []string
+
+	if archive.Version == 0 {
+		problems = append(problems, "missing archive version")
+	} else if archive.Version > ArchiveVersion {
+		problems = append(problems, fmt.Sprintf("unsupported archive version %d", archive.Version))
+	}
+
+	if archive.Tenant == nil {
+		problems = append(problems, "archive has no tenant record")
+	} else if archive.Tenant.Name == "" {
+		problems = append(problems, "tenant name is required")
+	} else if archive.Tenant.Currency != "" && !tenants.ValidCurrency(archive.Tenant.Currency) {
+		problems = append(problems, fmt.Sprintf("tenant currency %q is not an ISO 4217 code", archive.Tenant.Currency))
+	}
+
+	locationIDs := make(map[string]bool, len(archive.Locations))
+	defaults := 0
+	for i, location := range archive.Locations {
+		if location.ID == "" {
+			problems = append(problems, fmt.Sprintf("location %d: id is required", i))
+		}
+		if location.Name == "" {
+			problems = append(problems, fmt.Sprintf("location %d: name is required", i))
+		}
+		if location.Kind != "" && !locations.ValidKind(location.Kind) {
+			problems = append(problems, fmt.Sprintf("location %d: invalid kind %q", i, location.Kind))
+		}
+		if locationIDs[location.ID] {
+			problems = append(problems, fmt.Sprintf("location %d: duplicate id %s", i, location.ID))
+		}
+		if location.IsDefault {
+			defaults++
+		}
+		locationIDs[location.ID] = true
+	}
+	if defaults > 1 {
+		problems = append(problems, "more than one default location")
+	}
+	for i, location := range archive.Locations {
+		if location.ParentID == nil {
+			continue
+		}
+		if !locationIDs[*location.ParentID] {
+			problems = append(problems, fmt.Sprintf("location %d: unknown parentId %s", i, *location.ParentID))
+		} else if locations.IsDescendant(archive.Locations, *location.ParentID, location.ID) {
+			problems = append(problems, fmt.Sprintf("location %d: parentId %s forms a cycle", i, *location.ParentID))
+		}
+	}
+
+	// Names are unique among siblings, so they are keyed by parent
+	categoryIDs := make(map[string]bool, len(archive.Categories))
+	categoryNames := make(map[string]bool, len(archive.Categories))
+	for i, category := range archive.Categories {
+		nameKey := category.Name
+		if category.ParentID != nil {
+			nameKey = *category.ParentID + "/" + category.Name
+		}
+		if category.ID == "" {
+			problems = append(problems, fmt.Sprintf("category %d: id is required", i))
+		}
+		if category.Name == "" {
+			problems = append(problems, fmt.Sprintf("category %d: name is required", i))
+		}
+		if categoryIDs[category.ID] {
+			problems = append(problems, fmt.Sprintf("category %d: duplicate id %s", i, category.ID))
+		}
+		if categoryNames[nameKey] {
+			problems = append(problems, fmt.Sprintf("category %d: duplicate name %q", i, category.Name))
+		}
+		categoryIDs[category.ID] = true
+		categoryNames[nameKey] = true
+	}
+	for i, category := range archive.Categories {
+		if category.ParentID == nil {
+			continue
+		}
+		if !categoryIDs[*category.ParentID] {
+			problems = append(problems, fmt.Sprintf("category %d: unknown parentId %s", i, *category.ParentID))
+		} else if amenities_categories.IsDescendant(archive.Categories, *category.ParentID, category.ID) {
+			problems = append(problems, fmt.Sprintf("category %d: parentId %s forms a cycle", i, *category.ParentID))
+		} else if amenities_categories.Depth(archive.Categories, category.ID) > amenities_categories.MaxDepth {
+			problems = append(problems, fmt.Sprintf("category %d: nested more than %d levels deep", i, amenities_categories.MaxDepth))
+		}
+	}
+
+	itemNames := make(map[string]bool, len(archive.Amenities))
+	codes := map[string]bool{}
+	kinds := make(map[string]string, len(archive.Amenities))
+	for _, amenity := range archive.Amenities {
+		kinds[amenity.ID] = amenity.Kind
+	}
+	for i, amenity := range archive.Amenities {
+		if amenity.SKU != "" {
+			if codes[amenity.SKU] {
+				problems = append(problems, fmt.Sprintf("amenity %d: sku %q is used twice", i, amenity.SKU))
+			}
+			codes[amenity.SKU] = true
+		}
+		for _, barcode := range amenity.Barcodes {
+			if err := barcodes.Validate(barcode.Symbology, barcode.Code); err != nil {
+				problems = append(problems, fmt.Sprintf("amenity %d: invalid barcode %q: %v", i, barcode.Code, err))
+			}
+			if codes[barcode.Code] {
+				problems = append(problems, fmt.Sprintf("amenity %d: barcode %q is used twice", i, barcode.Code))
+			}
+			codes[barcode.Code] = true
+		}
+		if amenity.ItemName == "" {
+			problems = append(problems, fmt.Sprintf("amenity %d: itemName is required", i))
+		}
+		if itemNames[amenity.ItemName] {
+			problems = append(problems, fmt.Sprintf("amenity %d: duplicate itemName %q", i, amenity.ItemName))
+		}
+		if !categoryIDs[amenity.CategoryID] {
+			problems = append(problems, fmt.Sprintf("amenity %d: unknown categoryId %s", i, amenity.CategoryID))
+		}
+		if amenity.Stock < 0 || amenity.MinimumStock < 0 {
+			problems = append(problems, fmt.Sprintf("amenity %d: stock cannot be negative", i))
+		}
+		if amenity.StockValue < 0 {
+			problems = append(problems, fmt.Sprintf("amenity %d: stockValue cannot be negative", i))
+		}
+		if err := amenities.ValidateUnits(&amenity); err != nil {
+			problems = append(problems, fmt.Sprintf("amenity %d: %v", i, err))
+		}
+		switch amenity.Kind {
+		case "", amenities.KindItem:
+			if len(amenity.Components) > 0 {
+				problems = append(problems, fmt.Sprintf("amenity %d: components only apply to kits", i))
+			}
+		case amenities.KindKit:
+			if len(amenity.Components) == 0 {
+				problems = append(problems, fmt.Sprintf("amenity %d: a kit needs at least one component", i))
+			}
+			if amenity.Stock != 0 || amenity.StockValue != 0 || amenity.MinimumStock != 0 {
+				problems = append(problems, fmt.Sprintf("amenity %d: kits hold no stock of their own", i))
+			}
+			for _, component := range amenity.Components {
+				kind, ok := kinds[component.AmenityID]
+				if !ok || kind == amenities.KindKit {
+					problems = append(problems, fmt.Sprintf("amenity %d: component %s is not an item in the archive", i, component.AmenityID))
+				} else if component.Quantity <= 0 {
+					problems = append(problems, fmt.Sprintf("amenity %d: component quantity must be greater than zero", i))
+				}
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("amenity %d: kind must be item or kit", i))
+		}
+		itemNames[amenity.ItemName] = true
+	}
+
+	// Stock rows, when an amenity has any, must add up to its stock, and
+	// lots must fit in the stock at their location
+	stocked := make(map[string]int, len(archive.Stocks))
+	held := make(map[string]int, len(archive.Stocks))
+	for i, stock := range archive.Stocks {
+		key := stock.AmenityID + "/" + stock.LocationID
+		if kind, ok := kinds[stock.AmenityID]; !ok || kind == amenities.KindKit {
+			problems = append(problems, fmt.Sprintf("stock %d: amenity %s is not an item in the archive", i, stock.AmenityID))
+		}
+		if !locationIDs[stock.LocationID] {
+			problems = append(problems, fmt.Sprintf("stock %d: unknown locationId %s", i, stock.LocationID))
+		}
+		if stock.Quantity < 0 || stock.MinimumStock < 0 {
+			problems = append(problems, fmt.Sprintf("stock %d: quantities cannot be negative", i))
+		}
+		if _, ok := held[key]; ok {
+			problems = append(problems, fmt.Sprintf("stock %d: duplicate location %s for amenity %s", i, stock.LocationID, stock.AmenityID))
+		}
+		stocked[stock.AmenityID] += stock.Quantity
+		held[key] = stock.Quantity
+	}
+	for i, amenity := range archive.Amenities {
+		if total, ok := stocked[amenity.ID]; ok && total != amenity.Stock {
+			problems = append(problems, fmt.Sprintf("amenity %d: stock by location adds up to %d, not %d", i, total, amenity.Stock))
+		}
+	}
+	for i, lot := range archive.Lots {
+		key := lot.AmenityID + "/" + lot.LocationID
+		if lot.Quantity <= 0 {
+			problems = append(problems, fmt.Sprintf("lot %d: quantity must be greater than zero", i))
+		}
+		if _, ok := held[key]; !ok {
+			problems = append(problems, fmt.Sprintf("lot %d: amenity %s holds no stock at location %s", i, lot.AmenityID, lot.LocationID))
+			continue
+		}
+		if held[key] -= lot.Quantity; held[key] < 0 {
+			problems = append(problems, fmt.Sprintf("lot %d: lots hold more than the stock at location %s", i, lot.LocationID))
+		}
+	}
+
+	for i, membership := range archive.Memberships {
+		if membership.Username == "" && membership.Email == "" {
+			problems = append(problems, fmt.Sprintf("membership %d: username or email is required", i))
+		}
+		if !organizations.ValidRole(membership.Role) {
+			problems = append(problems, fmt.Sprintf("membership %d: invalid role %q", i, membership.Role))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Import validates an archive and recreates it as a new tenant. Every ID is
+// remapped, so an archive can be imported into the environment it came from.
+// A dry run performs the full import inside a transaction and rolls it back.
+func (s *Service) Import(archive *Archive, opts ImportOptions) (*ImportResult, error) {
+	if err := Validate(archive); err != nil {
+		return nil, err
+	}
+
+	domain := ""
+	if archive.Tenant.Domain != nil {
+		domain = *archive.Tenant.Domain
+	}
+	if opts.Domain != "" {
+		domain = opts.Domain
+	}
+
+	result := &ImportResult{DryRun: opts.DryRun, SkippedMemberships: []string{}}
+
+	err := s.tenantRepo.Transaction(func(tx *gorm.DB) error {
+		tenantRepo := s.tenantRepo.WithTx(tx)
+		categoryRepo := s.categoryRepo.WithTx(tx)
+		amenityRepo := s.amenityRepo.WithTx(tx)
+		locationRepo := s.locationRepo.WithTx(tx)
+		userRepo := s.userRepo.WithTx(tx)
+
+		tenant := &tenants.Tenant{
+			ID:          uuid.New().String(),
+			Name:        archive.Tenant.Name,
+			Description: archive.Tenant.Description,
+			IsActive:    archive.Tenant.IsActive,
+			Currency:    archive.Tenant.Currency,
+		}
+		if tenant.Currency == "" {
+			tenant.Currency = tenants.DefaultCurrency
+		}
+		if err := tenantRepo.CreateTenant(tenant); err != nil {
+			return fmt.Errorf("failed to create tenant: %w", err)
+		}
+		result.TenantID = tenant.ID
+
+		if opts.OwnerID != "" {
+			owner := &users.UserTenant{
+				ID:       uuid.New().String(),
+				UserID:   opts.OwnerID,
+				TenantID: tenant.ID,
+				Role:     organizations.RoleAdmin,
+			}
+			if err := userRepo.CreateUserTenant(owner); err != nil {
+				return fmt.Errorf("failed to make the importer tenant admin: %w", err)
+			}
+		}
+
+		if err := s.importSettings(tx, tenant.ID, archive); err != nil {
+			return err
+		}
+
+		// Domains must be proven again in the target environment, so the
+		// archived domain is only recorded as a pending claim
+		if domain != "" {
+			if err := tenantRepo.CreateDomain(tenants.NewPendingDomain(tenant.ID, domain, "")); err != nil {
+				return fmt.Errorf("failed to request domain %q: %w", domain, err)
+			}
+			result.PendingDomain = domain
+		}
+
+		locationMap, err := importLocations(locationRepo, tenant.ID, archive.Locations)
+		if err != nil {
+			return err
+		}
+		result.Locations = len(archive.Locations)
+
+		// Parents are created first so children can point at their new IDs
+		categoryMap := make(map[string]string, len(archive.Categories))
+		for _, category := range amenities_categories.SortByDepth(archive.Categories) {
+			imported := &amenities_categories.AmenityCategory{
+				ID:          uuid.New().String(),
+				TenantID:    tenant.ID,
+				Name:        category.Name,
+				SortOrder:   category.SortOrder,
+				Description: category.Description,
+				CreatedAt:   category.CreatedAt,
+			}
+			if category.ParentID != nil {
+				parentID := categoryMap[*category.ParentID]
+				imported.ParentID = &parentID
+			}
+			if err := categoryRepo.Create(imported); err != nil {
+				return fmt.Errorf("failed to create category %q: %w", category.Name, err)
+			}
+			categoryMap[category.ID] = imported.ID
+			result.Categories++
+		}
+
+		// Items are created before the kits made of them
+		amenityMap := make(map[string]string, len(archive.Amenities))
+		for _, amenity := range archive.Amenities {
+			amenityMap[amenity.ID] = uuid.New().String()
+		}
+		ordered := make([]amenities.Amenity, 0, len(archive.Amenities))
+		for _, amenity := range archive.Amenities {
+			if !amenity.IsKit() {
+				ordered = append(ordered, amenity)
+			}
+		}
+		for _, amenity := range archive.Amenities {
+			if amenity.IsKit() {
+				ordered = append(ordered, amenity)
+			}
+		}
+
+		for _, amenity := range ordered {
+			imported := &amenities.Amenity{
+				ID:            amenityMap[amenity.ID],
+				TenantID:      tenant.ID,
+				CategoryID:    categoryMap[amenity.CategoryID],
+				ItemName:      amenity.ItemName,
+				SKU:           amenity.SKU,
+				Kind:          amenity.Kind,
+				Description:   amenity.Description,
+				Stock:         amenity.Stock,
+				StockValue:    amenity.StockValue,
+				MinimumStock:  amenity.MinimumStock,
+				BaseUnit:      amenity.BaseUnit,
+				PreferredUnit: amenity.PreferredUnit,
+				Available:     amenity.Available,
+				CreatedAt:     amenity.CreatedAt,
+			}
+			imported.Units = amenities.CopyUnits(tenant.ID, imported.ID, amenity.Units)
+			imported.Components, _ = amenities.CopyComponents(tenant.ID, imported.ID, amenity.Components, amenityMap)
+			for i, barcode := range amenity.Barcodes {
+				imported.Barcodes = append(imported.Barcodes, amenities.AmenityBarcode{
+					ID:        uuid.New().String(),
+					TenantID:  tenant.ID,
+					AmenityID: imported.ID,
+					Code:      barcode.Code,
+					Symbology: barcode.Symbology,
+					SortOrder: i,
+				})
+			}
+			openings := openingStocks(archive, amenity, locationMap)
+			if err := amenityRepo.CreateWithOpeningStocks(imported, openings, nil); err != nil {
+				return fmt.Errorf("failed to create amenity %q: %w", amenity.ItemName, err)
+			}
+			for _, opening := range openings {
+				result.Lots += len(opening.Lots)
+			}
+			result.Amenities++
+		}
+
+		for _, membership := range archive.Memberships {
+			if !opts.LinkMembers {
+				result.SkippedMemberships = append(result.SkippedMemberships, membership.Username)
+				continue
+			}
+			user, err := userRepo.GetUserByUsername(membership.Username)
+			if err != nil && membership.Email != "" {
+				user, err = userRepo.GetUserByEmail(membership.Email)
+			}
+			if err != nil || user.ID == opts.OwnerID {
+				result.SkippedMemberships = append(result.SkippedMemberships, membership.Username)
+				continue
+			}
+
+			userTenant := &users.UserTenant{
+				ID:       uuid.New().String(),
+				UserID:   user.ID,
+				TenantID: tenant.ID,
+				Role:     membership.Role,
+			}
+			if err := userRepo.CreateUserTenant(userTenant); err != nil {
+				return fmt.Errorf("failed to create membership for %q: %w", membership.Username, err)
+			}
+			result.Memberships++
+		}
+
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	// Nothing was written, so there is no tenant ID to hand back
+	if opts.DryRun {
+		result.TenantID = ""
+	}
+
+	return result, nil
+}
+
+// importSettings copies the archived branding and notification settings to
+// the new tenant. Branding images stay in the source environment's storage
+// and are uploaded again, and webhook secrets are never exported.
+func (s *Service) importSettings(tx *gorm.DB, tenantID string, archive *Archive) error {
+	if branding := archive.Branding; branding != nil {
+		imported := &tenants.TenantBranding{
+			TenantID:        tenantID,
+			PrimaryColor:    branding.PrimaryColor,
+			SecondaryColor:  branding.SecondaryColor,
+			AccentColor:     branding.AccentColor,
+			BackgroundColor: branding.BackgroundColor,
+			TextColor:       branding.TextColor,
+			DisplayNames:    branding.DisplayNames,
+			ContactEmail:    branding.ContactEmail,
+			ContactPhone:    branding.ContactPhone,
+			Website:         branding.Website,
+			Address:         branding.Address,
+		}
+		if err := s.tenantRepo.WithTx(tx).SaveBranding(imported); err != nil {
+			return fmt.Errorf("failed to create branding: %w", err)
+		}
+	}
+
+	if settings := archive.NotificationSettings; settings != nil {
+		imported := &alerts.NotificationSettings{
+			TenantID:          tenantID,
+			EmailRecipients:   settings.EmailRecipients,
+			WebhookURL:        settings.WebhookURL,
+			InAppEnabled:      settings.InAppEnabled,
+			QuietHoursStart:   settings.QuietHoursStart,
+			QuietHoursEnd:     settings.QuietHoursEnd,
+			Timezone:          settings.Timezone,
+			ExpiryWarningDays: settings.ExpiryWarningDays,
+		}
+		if err := s.alertRepo.WithTx(tx).SaveSettings(imported); err != nil {
+			return fmt.Errorf("failed to create notification settings: %w", err)
+		}
+	}
+	return nil
+}
+
+// importLocations recreates the archived locations, parents first, and
+// returns their new IDs by archived ID. The archived default location
+// becomes the new tenant's default location.
+func importLocations(repo *locations.Repository, tenantID string, archived []locations.Location) (map[string]string, error) {
+	locationMap := make(map[string]string, len(archived))
+	pending := archived
+	for len(pending) > 0 {
+		var waiting []locations.Location
+		for _, location := range pending {
+			var parentID *string
+			if location.ParentID != nil {
+				id, ok := locationMap[*location.ParentID]
+				if !ok {
+					waiting = append(waiting, location)
+					continue
+				}
+				parentID = &id
+			}
+
+			kind := location.Kind
+			if kind == "" {
+				kind = locations.KindOther
+			}
+			if location.IsDefault {
+				imported, err := repo.EnsureDefault(tenantID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create default location: %w", err)
+				}
+				imported.ParentID, imported.Name, imported.Kind, imported.Description = parentID, location.Name, kind, location.Description
+				if err := repo.Update(imported); err != nil {
+					return nil, fmt.Errorf("failed to update default location: %w", err)
+				}
+				locationMap[location.ID] = imported.ID
+				continue
+			}
+
+			imported := &locations.Location{
+				ID:          uuid.New().String(),
+				TenantID:    tenantID,
+				ParentID:    parentID,
+				Name:        location.Name,
+				Kind:        kind,
+				Description: location.Description,
+				CreatedAt:   location.CreatedAt,
+			}
+			if err := repo.Create(imported); err != nil {
+				return nil, fmt.Errorf("failed to create location %q: %w", location.Name, err)
+			}
+			locationMap[location.ID] = imported.ID
+		}
+		// Validate rules out cycles, so every pass places at least one
+		if len(waiting) == len(pending) {
+			return nil, errors.New("locations form a cycle")
+		}
+		pending = waiting
+	}
+	return locationMap, nil
+}
+
+// openingStocks returns the stock an archived amenity opens with at each
+// new location, with its lots. Amenities without stock rows, as in version
+// 1 archives, hold all of their stock at the default location.
+func openingStocks(archive *Archive, amenity amenities.Amenity, locationMap map[string]string) []amenities.OpeningStock {
+	var openings []amenities.OpeningStock
+	for _, stock := range archive.Stocks {
+		if stock.AmenityID != amenity.ID {
+			continue
+		}
+		opening := amenities.OpeningStock{
+			LocationID:   locationMap[stock.LocationID],
+			Quantity:     stock.Quantity,
+			MinimumStock: stock.MinimumStock,
+		}
+		for _, lot := range archive.Lots {
+			if lot.AmenityID == amenity.ID && lot.LocationID == stock.LocationID {
+				opening.Lots = append(opening.Lots, amenities.StockLot{
+					LotNumber:  lot.LotNumber,
+					Quantity:   lot.Quantity,
+					ReceivedAt: lot.ReceivedAt,
+					ExpiresAt:  lot.ExpiresAt,
+				})
+			}
+		}
+		openings = append(openings, opening)
+	}
+	if openings == nil && amenity.Stock > 0 {
+		openings = []amenities.OpeningStock{{Quantity: amenity.Stock}}
+	}
+	return openings
+}
+
+// loadSettings loads a tenant's branding and notification settings, either
+// of which is nil if the tenant never saved any
+func (s *Service) loadSettings(tenantID string) (*tenants.TenantBranding, *alerts.NotificationSettings, error) {
+	branding, err := s.tenantRepo.GetBranding(tenantID)
+	if err != nil {
+		if err.Error() != "branding not found" {
+			return nil, nil, fmt.Errorf("failed to load branding: %w", err)
+		}
+		branding = nil
+	}
+
+	settings, err := s.alertRepo.GetSettings(tenantID)
+	if err != nil {
+		if err.Error() != "notification settings not found" {
+			return nil, nil, fmt.Errorf("failed to load notification settings: %w", err)
+		}
+		settings = nil
+	}
+	return branding, settings, nil
+}
+
+// loadStock loads a tenant's stock by location and the lots with stock left
+func (s *Service) loadStock(tenantID string) ([]amenities.AmenityStock, []amenities.StockLot, error) {
+	stocks, err := s.amenityRepo.FindStocksByTenantID(tenantID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load stock by location: %w", err)
+	}
+	lots, err := s.amenityRepo.FindLotsByTenantID(tenantID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load lots: %w", err)
+	}
+	return stocks, lots, nil
+}
+
+// loadMemberships loads a tenant's memberships without password hashes
+func (s *Service) loadMemberships(tenantID string) ([]Membership, error) {
+	userTenants, err := s.userRepo.GetTenantUsers(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load memberships: %w", err)
+	}
+
+	memberships := make([]Membership, 0, len(userTenants))
+	for _, ut := range userTenants {
+		memberships = append(memberships, Membership{
+			UserID:   ut.UserID,
+			Username: ut.User.Username,
+			Email:    ut.User.Email,
+			FullName: ut.User.FullName,
+			Role:     ut.Role,
+		})
+	}
+	return memberships, nil
+}
+
+// flush pushes buffered output to the client when the writer supports it
+func flush(w io.Writer) {
+	if f, ok := w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,148 @@
+package tenant_archive
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"concierge-be/database/dbtest"
+	"concierge-be/internal/alerts"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/amenities_categories"
+	"concierge-be/internal/locations"
+	"concierge-be/internal/organizations"
+	"concierge-be/internal/tenants"
+	"concierge-be/internal/users"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	db := dbtest.Open(t, &tenants.Tenant{}, &tenants.TenantDomain{}, &tenants.TenantBranding{},
+		&alerts.NotificationSettings{}, &locations.Location{}, &amenities_categories.AmenityCategory{},
+		&amenities.Amenity{}, &amenities.AmenityBarcode{}, &amenities.AmenityUnit{}, &amenities.KitComponent{},
+		&amenities.AmenityStock{}, &amenities.StockMovement{}, &amenities.StockLot{}, &amenities.StockMovementLot{},
+		&users.User{}, &users.UserTenant{})
+
+	floorID := "floor-2"
+	expiresAt := time.Now().AddDate(0, 2, 0).Truncate(24 * time.Hour)
+	fixtures := []interface{}{
+		&tenants.Tenant{ID: "tenant-1", Name: "Grand Hotel", IsActive: true, Currency: "EUR"},
+		&tenants.TenantBranding{TenantID: "tenant-1", PrimaryColor: "#112233", LogoURL: "https://cdn.example.com/logo.png"},
+		&alerts.NotificationSettings{TenantID: "tenant-1", EmailRecipients: []string{"ops@example.com"}, WebhookSecret: "s3cret", ExpiryWarningDays: 14},
+		&locations.Location{ID: floorID, TenantID: "tenant-1", Name: "Floor 2", Kind: locations.KindFloor},
+		&amenities_categories.AmenityCategory{ID: "bath", TenantID: "tenant-1", Name: "Bath"},
+		&amenities.Amenity{ID: "shampoo", TenantID: "tenant-1", CategoryID: "bath", ItemName: "Shampoo", Kind: amenities.KindItem,
+			Units: []amenities.AmenityUnit{{ID: "case", TenantID: "tenant-1", Name: "case", Factor: 12}}},
+		&amenities.Amenity{ID: "kit", TenantID: "tenant-1", CategoryID: "bath", ItemName: "Bath kit", Kind: amenities.KindKit,
+			Components: []amenities.KitComponent{{ID: "component", TenantID: "tenant-1", AmenityID: "shampoo", Quantity: 2}}},
+		&users.User{ID: "user-1", Username: "alice", Email: "alice@example.com", Password: "x"},
+		&users.UserTenant{ID: "membership-1", UserID: "user-1", TenantID: "tenant-1", Role: organizations.RoleManager},
+	}
+	for _, fixture := range fixtures {
+		if err := db.Create(fixture).Error; err != nil {
+			t.Fatalf("create fixture: %v", err)
+		}
+	}
+
+	// 6 at the default location, 4 on the floor of which 3 are in a lot
+	repo := amenities.NewRepository()
+	cost := int64(50)
+	movements := []*amenities.StockMovement{
+		{AmenityID: "shampoo", Delta: 6, Reason: amenities.MovementRestock, UnitCost: &cost},
+		{AmenityID: "shampoo", LocationID: &floorID, Delta: 3, Reason: amenities.MovementRestock, UnitCost: &cost,
+			Lot: &amenities.LotSpec{LotNumber: "L-42", ExpiresAt: &expiresAt}},
+		{AmenityID: "shampoo", LocationID: &floorID, Delta: 1, Reason: amenities.MovementRestock, UnitCost: &cost},
+	}
+	for _, movement := range movements {
+		if err := repo.ApplyMovement(movement); err != nil {
+			t.Fatalf("apply movement: %v", err)
+		}
+	}
+	if _, err := repo.SetLocationMinimum(&amenities.Amenity{ID: "shampoo", TenantID: "tenant-1"}, floorID, 2); err != nil {
+		t.Fatalf("set location minimum: %v", err)
+	}
+
+	service := NewService()
+	var buf bytes.Buffer
+	if err := service.ExportNDJSON("tenant-1", &buf); err != nil {
+		t.Fatalf("ExportNDJSON: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("s3cret")) {
+		t.Error("archive contains the webhook secret")
+	}
+	archive, err := ParseNDJSON(&buf)
+	if err != nil {
+		t.Fatalf("ParseNDJSON: %v", err)
+	}
+	if archive.Version != ArchiveVersion {
+		t.Errorf("version = %d, want %d", archive.Version, ArchiveVersion)
+	}
+
+	result, err := service.Import(archive, ImportOptions{OwnerID: "user-1"})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if result.Locations != 2 || result.Amenities != 2 || result.Lots != 1 || result.Memberships != 0 {
+		t.Errorf("result = %+v, want 2 locations, 2 amenities, 1 lot and no memberships", result)
+	}
+	tenantID := result.TenantID
+
+	var branding tenants.TenantBranding
+	if err := db.Where("tenant_id = ?", tenantID).First(&branding).Error; err != nil {
+		t.Fatalf("load branding: %v", err)
+	}
+	if branding.PrimaryColor != "#112233" || branding.LogoURL != "" {
+		t.Errorf("branding = %+v, want the colors without the images", branding)
+	}
+	var settings alerts.NotificationSettings
+	if err := db.Where("tenant_id = ?", tenantID).First(&settings).Error; err != nil {
+		t.Fatalf("load notification settings: %v", err)
+	}
+	if len(settings.EmailRecipients) != 1 || settings.ExpiryWarningDays != 14 || settings.WebhookSecret != "" {
+		t.Errorf("settings = %+v", settings)
+	}
+
+	var shampoo amenities.Amenity
+	err = db.Preload("Units").Where("tenant_id = ? AND item_name = ?", tenantID, "Shampoo").First(&shampoo).Error
+	if err != nil {
+		t.Fatalf("load amenity: %v", err)
+	}
+	if shampoo.Stock != 10 || shampoo.StockValue != 500 || len(shampoo.Units) != 1 {
+		t.Errorf("shampoo = stock %d value %d units %d, want 10, 500 and 1", shampoo.Stock, shampoo.StockValue, len(shampoo.Units))
+	}
+	var kit amenities.Amenity
+	if err := db.Preload("Components").Where("tenant_id = ? AND item_name = ?", tenantID, "Bath kit").First(&kit).Error; err != nil {
+		t.Fatalf("load kit: %v", err)
+	}
+	if len(kit.Components) != 1 || kit.Components[0].AmenityID != shampoo.ID || kit.Components[0].Quantity != 2 {
+		t.Errorf("kit components = %+v, want 2 of the imported shampoo", kit.Components)
+	}
+
+	var floor locations.Location
+	if err := db.Where("tenant_id = ? AND name = ?", tenantID, "Floor 2").First(&floor).Error; err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	var stock amenities.AmenityStock
+	if err := db.Where("amenity_id = ? AND location_id = ?", shampoo.ID, floor.ID).First(&stock).Error; err != nil {
+		t.Fatalf("load floor stock: %v", err)
+	}
+	if stock.Quantity != 4 || stock.MinimumStock != 2 {
+		t.Errorf("floor stock = %d minimum %d, want 4 and 2", stock.Quantity, stock.MinimumStock)
+	}
+	var lots []amenities.StockLot
+	if err := db.Where("amenity_id = ?", shampoo.ID).Find(&lots).Error; err != nil {
+		t.Fatalf("load lots: %v", err)
+	}
+	if len(lots) != 1 || lots[0].LocationID != floor.ID || lots[0].LotNumber != "L-42" || lots[0].Quantity != 3 ||
+		lots[0].ExpiresAt == nil || !lots[0].ExpiresAt.Equal(expiresAt) {
+		t.Errorf("lots = %+v, want lot L-42 of 3 on the floor", lots)
+	}
+
+	// The importer is admin; archived members are not linked by default
+	var memberships []users.UserTenant
+	if err := db.Where("tenant_id = ?", tenantID).Find(&memberships).Error; err != nil {
+		t.Fatalf("load memberships: %v", err)
+	}
+	if len(memberships) != 1 || memberships[0].UserID != "user-1" || memberships[0].Role != organizations.RoleAdmin {
+		t.Errorf("memberships = %+v, want only the importer as admin", memberships)
+	}
+}
@@ -1,9 +1,17 @@
 package tenants
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"concierge-be/config"
+	"concierge-be/database"
 	"concierge-be/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -18,6 +26,11 @@ func NewHandler() *Handler {
 	}
 }
 
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
 // CreateTenant creates a new tenant
 func (h *Handler) CreateTenant(c *gin.Context) {
 	var tenant Tenant
@@ -26,7 +39,11 @@ func (h *Handler) CreateTenant(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.CreateTenant(&tenant); err != nil {
+	if err := h.serviceFor(c).CreateTenant(&tenant); err != nil {
+		if strings.HasPrefix(err.Error(), "currency") {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -42,12 +59,13 @@ func (h *Handler) GetTenant(c *gin.Context) {
 		return
 	}
 
-	tenant, err := h.service.GetTenantByID(id)
+	tenant, err := h.serviceFor(c).GetTenantByID(id)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
 		return
 	}
 
+	utils.SetETag(c, tenant.Version)
 	utils.SuccessResponse(c, tenant)
 }
 
@@ -63,7 +81,7 @@ func (h *Handler) GetAllTenants(c *gin.Context) {
 		pageSize = 10
 	}
 
-	tenants, total, err := h.service.GetAllTenants(page, pageSize)
+	tenants, total, err := h.serviceFor(c).GetAllTenants(page, pageSize)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -73,6 +91,7 @@ func (h *Handler) GetAllTenants(c *gin.Context) {
 }
 
 // UpdateTenant updates a tenant
+// Requires If-Match with the version from the tenant's ETag
 func (h *Handler) UpdateTenant(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -80,6 +99,11 @@ func (h *Handler) UpdateTenant(c *gin.Context) {
 		return
 	}
 
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	var tenant Tenant
 	if err := c.ShouldBindJSON(&tenant); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data")
@@ -87,11 +111,21 @@ func (h *Handler) UpdateTenant(c *gin.Context) {
 	}
 
 	tenant.ID = id
-	if err := h.service.UpdateTenant(&tenant); err != nil {
+	tenant.Version = version
+	if err := h.serviceFor(c).UpdateTenant(&tenant); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			utils.ErrorResponse(c, http.StatusPreconditionFailed, "Tenant has been modified; reload and retry")
+			return
+		}
+		if strings.HasPrefix(err.Error(), "currency") {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	utils.SetETag(c, tenant.Version)
 	utils.SuccessResponse(c, tenant)
 }
 
@@ -103,10 +137,242 @@ func (h *Handler) DeleteTenant(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeleteTenant(id); err != nil {
+	if err := h.serviceFor(c).DeleteTenant(id); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	utils.SuccessResponse(c, gin.H{"message": "Tenant deleted successfully"})
 }
+
+// RequestDomain claims a custom domain for a tenant and returns the
+// verification instructions
+func (h *Handler) RequestDomain(c *gin.Context) {
+	var req RequestDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	domain, err := h.serviceFor(c).RequestDomain(c.Param("id"), &req)
+	if err != nil {
+		switch err.Error() {
+		case "tenant not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case "domain already requested for this tenant", "domain is already verified by another tenant":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.Response{
+		Code:    http.StatusCreated,
+		Message: "Success",
+		Data:    domain,
+	})
+}
+
+// GetDomains lists a tenant's custom domains
+func (h *Handler) GetDomains(c *gin.Context) {
+	domains, err := h.serviceFor(c).GetDomains(c.Param("id"))
+	if err != nil {
+		if err.Error() == "tenant not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, domains)
+}
+
+// VerifyDomain checks the published proof for a domain
+func (h *Handler) VerifyDomain(c *gin.Context) {
+	domain, err := h.serviceFor(c).VerifyDomain(c.Param("id"), c.Param("domainId"))
+	if err != nil {
+		switch {
+		case err.Error() == "domain not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case err.Error() == "domain is already verified by another tenant":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		case err.Error() == "domain request has expired; request the domain again":
+			utils.ErrorResponse(c, http.StatusGone, err.Error())
+		case strings.HasPrefix(err.Error(), "domain verification failed"):
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, domain)
+}
+
+// SetPrimaryDomain makes a verified domain the tenant's primary domain
+func (h *Handler) SetPrimaryDomain(c *gin.Context) {
+	domain, err := h.serviceFor(c).SetPrimaryDomain(c.Param("id"), c.Param("domainId"))
+	if err != nil {
+		switch err.Error() {
+		case "domain not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case "domain is not verified":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, domain)
+}
+
+// RemoveDomain deletes a tenant's custom domain
+func (h *Handler) RemoveDomain(c *gin.Context) {
+	if err := h.serviceFor(c).RemoveDomain(c.Param("id"), c.Param("domainId")); err != nil {
+		if err.Error() == "domain not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Domain removed successfully"})
+}
+
+// GetBranding gets a tenant's branding
+func (h *Handler) GetBranding(c *gin.Context) {
+	branding, err := h.serviceFor(c).GetBranding(c.Param("id"))
+	if err != nil {
+		if err.Error() == "tenant not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, branding)
+}
+
+// UpdateBranding updates a tenant's colours, display names and contact details
+func (h *Handler) UpdateBranding(c *gin.Context) {
+	var req UpdateBrandingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	branding, err := h.serviceFor(c).UpdateBranding(c.Param("id"), &req)
+	if err != nil {
+		switch {
+		case err.Error() == "tenant not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case strings.HasPrefix(err.Error(), "invalid branding"):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, branding)
+}
+
+// UploadBrandingImage uploads a logo or cover image as multipart field "file"
+func (h *Handler) UploadBrandingImage(c *gin.Context) {
+	kind := c.Param("kind")
+	if kind != BrandingLogo && kind != BrandingCover {
+		utils.ErrorResponse(c, http.StatusNotFound, "branding image must be logo or cover")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	if maxSize := config.AppConfig.Storage.MaxUploadSize; maxSize > 0 && fileHeader.Size > maxSize {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file must not exceed %d bytes", maxSize))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	branding, err := h.serviceFor(c).UploadBrandingImage(c.Param("id"), kind, file)
+	if err != nil {
+		switch {
+		case err.Error() == "tenant not found":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case strings.HasPrefix(err.Error(), "invalid branding"):
+			utils.ErrorResponse(c, http.StatusUnsupportedMediaType, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, branding)
+}
+
+// DeleteBrandingImage removes a logo or cover image
+func (h *Handler) DeleteBrandingImage(c *gin.Context) {
+	kind := c.Param("kind")
+	if kind != BrandingLogo && kind != BrandingCover {
+		utils.ErrorResponse(c, http.StatusNotFound, "branding image must be logo or cover")
+		return
+	}
+
+	branding, err := h.serviceFor(c).DeleteBrandingImage(c.Param("id"), kind)
+	if err != nil {
+		if err.Error() == "tenant not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, branding)
+}
+
+// GetPublicBranding serves a tenant's branding by verified domain without
+// authentication. Responses carry an ETag so front-ends and CDNs can revalidate cheaply.
+func (h *Handler) GetPublicBranding(c *gin.Context) {
+	branding, lastModified, err := h.serviceFor(c).GetPublicBranding(c.Param("domain"))
+	if err != nil {
+		if err.Error() == "tenant not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	body, err := json.Marshal(utils.Response{Code: http.StatusOK, Message: "Success", Data: branding})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+
+	if match := c.GetHeader("If-None-Match"); match != "" && (match == etag || match == "*") {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
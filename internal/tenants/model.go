@@ -8,16 +8,134 @@ import (
 
 // Tenant represents a tenant in the system
 type Tenant struct {
-	ID          string    `gorm:"type:varchar(36);primaryKey" json:"id"`
-	Name        string    `gorm:"type:varchar(100);not null" json:"name"`
-	Description string    `gorm:"type:text" json:"description"`
-	Domain      string    `gorm:"type:varchar(100);uniqueIndex" json:"domain"`
-	IsActive    bool      `gorm:"default:true" json:"isActive"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             string         `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Name           string         `gorm:"type:varchar(100);not null" json:"name"`
+	Description    string         `gorm:"type:text" json:"description"`
+	Domain         *string        `gorm:"type:varchar(100);uniqueIndex" json:"domain"` // primary verified domain, nil until one is verified
+	IsActive       bool           `gorm:"default:true" json:"isActive"`
+	Currency       string         `gorm:"type:varchar(3);not null;default:'USD'" json:"currency"` // ISO 4217 code stock is valued in
+	OrganizationID *string        `gorm:"type:varchar(36);index" json:"organizationId,omitempty"` // parent hotel group, if any
+	PlanID         *string        `gorm:"type:varchar(36);index" json:"planId,omitempty"`         // subscription tier; nil means unlimited
+	Version        int64          `gorm:"not null;default:1" json:"version"`                      // optimistic concurrency version, sent as ETag
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (Tenant) TableName() string {
 	return "tenants"
 }
+
+// DefaultCurrency is the currency of tenants that do not name one
+const DefaultCurrency = "USD"
+
+// Domain verification methods and statuses
+const (
+	DomainMethodDNS  = "dns"
+	DomainMethodHTTP = "http"
+
+	DomainStatusPending  = "pending"
+	DomainStatusVerified = "verified"
+)
+
+// TenantDomain is a custom domain claimed by a tenant. It only routes to the
+// tenant once ownership has been proven with a DNS TXT record or an HTTP
+// well-known file containing Token. A pending claim expires at ExpiresAt and
+// must then be requested again for a fresh token.
+type TenantDomain struct {
+	ID            string     `gorm:"type:varchar(36);primaryKey" json:"id"`
+	TenantID      string     `gorm:"type:varchar(36);not null;uniqueIndex:idx_tenant_domain" json:"tenantId"`
+	Domain        string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_tenant_domain;index" json:"domain"`
+	Method        string     `gorm:"type:varchar(10);not null" json:"method"`
+	Token         string     `gorm:"type:varchar(64);not null" json:"token"`
+	Status        string     `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	IsPrimary     bool       `gorm:"default:false" json:"isPrimary"`
+	VerifiedAt    *time.Time `json:"verifiedAt"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"` // nil once verified
+	LastCheckedAt *time.Time `json:"lastCheckedAt"`
+	LastError     string     `gorm:"type:varchar(255)" json:"lastError,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+func (TenantDomain) TableName() string {
+	return "tenant_domains"
+}
+
+// Expired reports whether a pending claim can no longer be verified
+func (d *TenantDomain) Expired(now time.Time) bool {
+	return d.Status == DomainStatusPending && d.ExpiresAt != nil && !now.Before(*d.ExpiresAt)
+}
+
+// RequestDomainRequest represents the request body for claiming a custom domain
+type RequestDomainRequest struct {
+	Domain string `json:"domain" binding:"required,fqdn"`
+	Method string `json:"method" binding:"omitempty,oneof=dns http"`
+}
+
+// DomainInstructions tells the tenant how to prove ownership of a domain
+type DomainInstructions struct {
+	TenantDomain
+	RecordName  string `json:"recordName,omitempty"`
+	RecordValue string `json:"recordValue,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Body        string `json:"body,omitempty"`
+}
+
+// TenantBranding holds the guest-facing look of a tenant's amenity catalog
+type TenantBranding struct {
+	TenantID        string            `gorm:"type:varchar(36);primaryKey" json:"tenantId"`
+	LogoURL         string            `gorm:"type:varchar(255)" json:"logoUrl"`
+	LogoKey         string            `gorm:"type:varchar(255)" json:"-"`
+	CoverURL        string            `gorm:"type:varchar(255)" json:"coverUrl"`
+	CoverKey        string            `gorm:"type:varchar(255)" json:"-"`
+	PrimaryColor    string            `gorm:"type:varchar(7)" json:"primaryColor"`
+	SecondaryColor  string            `gorm:"type:varchar(7)" json:"secondaryColor"`
+	AccentColor     string            `gorm:"type:varchar(7)" json:"accentColor"`
+	BackgroundColor string            `gorm:"type:varchar(7)" json:"backgroundColor"`
+	TextColor       string            `gorm:"type:varchar(7)" json:"textColor"`
+	DisplayNames    map[string]string `gorm:"type:text;serializer:json" json:"displayNames"` // keyed by locale, e.g. "en", "zh-CN"
+	ContactEmail    string            `gorm:"type:varchar(100)" json:"contactEmail"`
+	ContactPhone    string            `gorm:"type:varchar(50)" json:"contactPhone"`
+	Website         string            `gorm:"type:varchar(255)" json:"website"`
+	Address         string            `gorm:"type:text" json:"address"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	UpdatedAt       time.Time         `json:"updatedAt"`
+}
+
+func (TenantBranding) TableName() string {
+	return "tenant_brandings"
+}
+
+// UpdateBrandingRequest represents the request body for updating branding.
+// Omitted fields are left unchanged; an empty string clears a field.
+type UpdateBrandingRequest struct {
+	PrimaryColor    *string           `json:"primaryColor"`
+	SecondaryColor  *string           `json:"secondaryColor"`
+	AccentColor     *string           `json:"accentColor"`
+	BackgroundColor *string           `json:"backgroundColor"`
+	TextColor       *string           `json:"textColor"`
+	DisplayNames    map[string]string `json:"displayNames"`
+	ContactEmail    *string           `json:"contactEmail"`
+	ContactPhone    *string           `json:"contactPhone"`
+	Website         *string           `json:"website"`
+	Address         *string           `json:"address"`
+}
+
+// PublicBranding is the unauthenticated, cacheable view of a tenant's branding
+type PublicBranding struct {
+	Name            string            `json:"name"`
+	Domain          string            `json:"domain"`
+	LogoURL         string            `json:"logoUrl"`
+	CoverURL        string            `json:"coverUrl"`
+	PrimaryColor    string            `json:"primaryColor"`
+	SecondaryColor  string            `json:"secondaryColor"`
+	AccentColor     string            `json:"accentColor"`
+	BackgroundColor string            `json:"backgroundColor"`
+	TextColor       string            `json:"textColor"`
+	DisplayNames    map[string]string `json:"displayNames"`
+	ContactEmail    string            `json:"contactEmail"`
+	ContactPhone    string            `json:"contactPhone"`
+	Website         string            `json:"website"`
+	Address         string            `json:"address"`
+}
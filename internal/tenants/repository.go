@@ -16,6 +16,16 @@ func NewRepository() *Repository {
 	}
 }
 
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// Transaction runs fn inside a database transaction
+func (r *Repository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
 // Tenant repository methods
 func (r *Repository) CreateTenant(tenant *Tenant) error {
 	return r.db.Create(tenant).Error
@@ -33,9 +43,21 @@ func (r *Repository) GetTenantByID(id string) (*Tenant, error) {
 	return &tenant, nil
 }
 
+// TenantOf returns id if the tenant exists, "" if not
+func (r *Repository) TenantOf(id string) (string, error) {
+	var tenantIDs []string
+	if err := r.db.Model(&Tenant{}).Where("id = ?", id).Pluck("id", &tenantIDs).Error; err != nil || len(tenantIDs) == 0 {
+		return "", err
+	}
+	return tenantIDs[0], nil
+}
+
+// GetTenantByDomain resolves a tenant from any of its verified domains
 func (r *Repository) GetTenantByDomain(domain string) (*Tenant, error) {
 	var tenant Tenant
-	err := r.db.Where("domain = ?", domain).First(&tenant).Error
+	err := r.db.Joins("JOIN tenant_domains td ON td.tenant_id = tenants.id").
+		Where("td.domain = ? AND td.status = ?", domain, DomainStatusVerified).
+		First(&tenant).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("tenant not found")
@@ -61,10 +83,104 @@ func (r *Repository) GetAllTenants(page, pageSize int) ([]Tenant, int64, error)
 	return tenants, total, err
 }
 
+// UpdateTenant updates a tenant if it is still at tenant.Version,
+// returning database.ErrVersionConflict otherwise
 func (r *Repository) UpdateTenant(tenant *Tenant) error {
-	return r.db.Save(tenant).Error
+	return database.UpdateVersioned(r.db, tenant, &tenant.Version)
 }
 
 func (r *Repository) DeleteTenant(id string) error {
 	return r.db.Delete(&Tenant{}, "id = ?", id).Error
 }
+
+// TenantDomain repository methods
+func (r *Repository) CreateDomain(domain *TenantDomain) error {
+	return r.db.Create(domain).Error
+}
+
+func (r *Repository) GetDomain(tenantID, id string) (*TenantDomain, error) {
+	var domain TenantDomain
+	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, id).First(&domain).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("domain not found")
+		}
+		return nil, err
+	}
+	return &domain, nil
+}
+
+func (r *Repository) GetDomainsByTenantID(tenantID string) ([]TenantDomain, error) {
+	var domains []TenantDomain
+	err := r.db.Where("tenant_id = ?", tenantID).Order("is_primary DESC, domain ASC").Find(&domains).Error
+	return domains, err
+}
+
+// GetDomainByName retrieves a tenant's claim on a domain, or nil if it has none
+func (r *Repository) GetDomainByName(tenantID, domain string) (*TenantDomain, error) {
+	var claim TenantDomain
+	err := r.db.Where("tenant_id = ? AND domain = ?", tenantID, domain).First(&claim).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+// CheckDomainVerifiedElsewhere checks if another tenant has already verified a domain
+func (r *Repository) CheckDomainVerifiedElsewhere(tenantID, domain string) (bool, error) {
+	var count int64
+	err := r.db.Model(&TenantDomain{}).
+		Where("domain = ? AND status = ? AND tenant_id != ?", domain, DomainStatusVerified, tenantID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *Repository) UpdateDomain(domain *TenantDomain) error {
+	return r.db.Save(domain).Error
+}
+
+func (r *Repository) DeleteDomain(tenantID, id string) error {
+	return r.db.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&TenantDomain{}).Error
+}
+
+// SetPrimaryDomain marks a verified domain as the tenant's primary domain and
+// mirrors it onto the tenant. A nil domain clears the primary domain.
+func (r *Repository) SetPrimaryDomain(tenantID string, domain *TenantDomain) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&TenantDomain{}).Where("tenant_id = ?", tenantID).
+			Update("is_primary", false).Error; err != nil {
+			return err
+		}
+
+		var value *string
+		if domain != nil {
+			if err := tx.Model(&TenantDomain{}).Where("id = ?", domain.ID).
+				Update("is_primary", true).Error; err != nil {
+				return err
+			}
+			value = &domain.Domain
+		}
+
+		return tx.Model(&Tenant{}).Where("id = ?", tenantID).Update("domain", value).Error
+	})
+}
+
+// TenantBranding repository methods
+func (r *Repository) GetBranding(tenantID string) (*TenantBranding, error) {
+	var branding TenantBranding
+	err := r.db.Where("tenant_id = ?", tenantID).First(&branding).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("branding not found")
+		}
+		return nil, err
+	}
+	return &branding, nil
+}
+
+func (r *Repository) SaveBranding(branding *TenantBranding) error {
+	return r.db.Save(branding).Error
+}
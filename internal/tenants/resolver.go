@@ -0,0 +1,129 @@
+package tenants
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Verification record locations
+const (
+	dnsRecordPrefix  = "_concierge-verification."
+	dnsValuePrefix   = "concierge-verification="
+	wellKnownPath    = "/.well-known/concierge-verification.txt"
+	wellKnownMaxSize = 1024
+)
+
+// DomainResolver looks up the proof a tenant publishes for a domain.
+// NetResolver talks to real DNS and HTTP; FakeResolver is used in tests.
+type DomainResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	FetchWellKnown(ctx context.Context, domain string) (string, error)
+}
+
+// dnsRecordName returns the TXT record name checked for a domain
+func dnsRecordName(domain string) string {
+	return dnsRecordPrefix + domain
+}
+
+// wellKnownURL returns the URL checked for HTTP verification
+func wellKnownURL(domain string) string {
+	return "http://" + domain + wellKnownPath
+}
+
+// NetResolver resolves verification proofs over the network
+type NetResolver struct {
+	resolver *net.Resolver
+	client   *http.Client
+}
+
+func NewNetResolver() *NetResolver {
+	return &NetResolver{
+		resolver: net.DefaultResolver,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// LookupTXT returns the TXT records for name
+func (r *NetResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return r.resolver.LookupTXT(ctx, name)
+}
+
+// FetchWellKnown returns the body of the domain's verification file
+func (r *NetResolver) FetchWellKnown(ctx context.Context, domain string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnownURL(domain), nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, wellKnownMaxSize))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// FakeResolver serves verification proofs from memory
+type FakeResolver struct {
+	mu        sync.RWMutex
+	txt       map[string][]string
+	wellKnown map[string]string
+}
+
+func NewFakeResolver() *FakeResolver {
+	return &FakeResolver{
+		txt:       make(map[string][]string),
+		wellKnown: make(map[string]string),
+	}
+}
+
+// SetTXT publishes TXT records for name
+func (r *FakeResolver) SetTXT(name string, records ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.txt[name] = records
+}
+
+// SetWellKnown publishes the verification file body for domain
+func (r *FakeResolver) SetWellKnown(domain, body string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.wellKnown[domain] = body
+}
+
+// LookupTXT returns the TXT records published for name
+func (r *FakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	records, ok := r.txt[name]
+	if !ok {
+		return nil, fmt.Errorf("no TXT records for %s", name)
+	}
+	return records, nil
+}
+
+// FetchWellKnown returns the verification file body published for domain
+func (r *FakeResolver) FetchWellKnown(_ context.Context, domain string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	body, ok := r.wellKnown[domain]
+	if !ok {
+		return "", fmt.Errorf("no verification file for %s", domain)
+	}
+	return body, nil
+}
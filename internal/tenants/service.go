@@ -1,20 +1,55 @@
 package tenants
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"concierge-be/storage"
 )
 
+// domainVerifyTimeout bounds a single verification lookup
+const domainVerifyTimeout = 15 * time.Second
+
+// DomainRequestTTL is how long a domain claim can be verified for
+const DomainRequestTTL = 7 * 24 * time.Hour
+
 type Service struct {
-	repo *Repository
+	repo     *Repository
+	resolver DomainResolver
 }
 
 func NewService() *Service {
 	return &Service{
-		repo: NewRepository(),
+		repo:     NewRepository(),
+		resolver: NewNetResolver(),
 	}
 }
 
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	copied := *s
+	copied.repo = s.repo.WithTx(s.repo.db.WithContext(ctx))
+	return &copied
+}
+
+// WithResolver replaces the domain resolver, e.g. with a FakeResolver in tests
+func (s *Service) WithResolver(resolver DomainResolver) *Service {
+	s.resolver = resolver
+	return s
+}
+
 // generateUUID generates a new UUID
 func generateUUID() string {
 	b := make([]byte, 16)
@@ -28,7 +63,25 @@ func (s *Service) CreateTenant(tenant *Tenant) error {
 	if tenant.ID == "" {
 		tenant.ID = generateUUID()
 	}
-	return s.repo.CreateTenant(tenant)
+
+	if err := normalizeCurrency(tenant, DefaultCurrency); err != nil {
+		return err
+	}
+
+	// A domain given at creation is only a claim until it is verified
+	requested := tenant.Domain
+	tenant.Domain = nil
+
+	if err := s.repo.CreateTenant(tenant); err != nil {
+		return err
+	}
+
+	if requested != nil && *requested != "" {
+		if _, err := s.RequestDomain(tenant.ID, &RequestDomainRequest{Domain: *requested}); err != nil {
+			return fmt.Errorf("tenant created but domain request failed: %w", err)
+		}
+	}
+	return nil
 }
 
 func (s *Service) GetTenantByID(id string) (*Tenant, error) {
@@ -44,9 +97,496 @@ func (s *Service) GetAllTenants(page, pageSize int) ([]Tenant, int64, error) {
 }
 
 func (s *Service) UpdateTenant(tenant *Tenant) error {
+	existing, err := s.repo.GetTenantByID(tenant.ID)
+	if err != nil {
+		return err
+	}
+
+	// Domain, organization and plan are managed by their own endpoints
+	tenant.Domain = existing.Domain
+	tenant.OrganizationID = existing.OrganizationID
+	tenant.PlanID = existing.PlanID
+	tenant.CreatedAt = existing.CreatedAt
+	if err := normalizeCurrency(tenant, existing.Currency); err != nil {
+		return err
+	}
+
 	return s.repo.UpdateTenant(tenant)
 }
 
+// currencyPattern matches an ISO 4217 currency code
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// normalizeCurrency upper-cases the tenant's currency, falling back to
+// fallback when it is empty, and checks it is a currency code. Amounts
+// already recorded are not converted when it changes.
+func normalizeCurrency(tenant *Tenant, fallback string) error {
+	tenant.Currency = strings.ToUpper(strings.TrimSpace(tenant.Currency))
+	if tenant.Currency == "" {
+		tenant.Currency = fallback
+	}
+	if !ValidCurrency(tenant.Currency) {
+		return errors.New("currency must be a three-letter ISO 4217 code")
+	}
+	return nil
+}
+
+// ValidCurrency reports whether code is an upper-case ISO 4217 currency code
+func ValidCurrency(code string) bool {
+	return currencyPattern.MatchString(code)
+}
+
 func (s *Service) DeleteTenant(id string) error {
 	return s.repo.DeleteTenant(id)
 }
+
+// generateDomainToken generates a random verification token
+func generateDomainToken() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NormalizeDomain lowercases a domain and strips any trailing dot
+func NormalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
+
+// NewPendingDomain builds an unverified domain claim with a fresh token.
+// DNS verification is used when method is empty.
+func NewPendingDomain(tenantID, domain, method string) *TenantDomain {
+	if method == "" {
+		method = DomainMethodDNS
+	}
+	expiresAt := time.Now().Add(DomainRequestTTL)
+	return &TenantDomain{
+		ID:        generateUUID(),
+		TenantID:  tenantID,
+		Domain:    NormalizeDomain(domain),
+		Method:    method,
+		Token:     generateDomainToken(),
+		Status:    DomainStatusPending,
+		ExpiresAt: &expiresAt,
+	}
+}
+
+// domainInstructions describes where the tenant must publish the token
+func domainInstructions(domain *TenantDomain) *DomainInstructions {
+	instructions := &DomainInstructions{TenantDomain: *domain}
+	if domain.Method == DomainMethodHTTP {
+		instructions.URL = wellKnownURL(domain.Domain)
+		instructions.Body = domain.Token
+	} else {
+		instructions.RecordName = dnsRecordName(domain.Domain)
+		instructions.RecordValue = dnsValuePrefix + domain.Token
+	}
+	return instructions
+}
+
+// RequestDomain claims a domain for a tenant. Requesting a domain whose
+// earlier claim expired renews that claim with a fresh token.
+func (s *Service) RequestDomain(tenantID string, req *RequestDomainRequest) (*DomainInstructions, error) {
+	if _, err := s.repo.GetTenantByID(tenantID); err != nil {
+		return nil, err
+	}
+
+	name := NormalizeDomain(req.Domain)
+
+	claim, err := s.repo.GetDomainByName(tenantID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check domain: %w", err)
+	}
+	if claim != nil && !claim.Expired(time.Now()) {
+		return nil, errors.New("domain already requested for this tenant")
+	}
+
+	taken, err := s.repo.CheckDomainVerifiedElsewhere(tenantID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check domain: %w", err)
+	}
+	if taken {
+		return nil, errors.New("domain is already verified by another tenant")
+	}
+
+	domain := NewPendingDomain(tenantID, name, req.Method)
+	if claim != nil {
+		renewed := *claim
+		renewed.Method, renewed.Token, renewed.ExpiresAt = domain.Method, domain.Token, domain.ExpiresAt
+		renewed.LastCheckedAt, renewed.LastError = nil, ""
+		if err := s.repo.UpdateDomain(&renewed); err != nil {
+			return nil, fmt.Errorf("failed to request domain: %w", err)
+		}
+		return domainInstructions(&renewed), nil
+	}
+	if err := s.repo.CreateDomain(domain); err != nil {
+		return nil, fmt.Errorf("failed to request domain: %w", err)
+	}
+
+	return domainInstructions(domain), nil
+}
+
+func (s *Service) GetDomains(tenantID string) ([]DomainInstructions, error) {
+	if _, err := s.repo.GetTenantByID(tenantID); err != nil {
+		return nil, err
+	}
+
+	domains, err := s.repo.GetDomainsByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]DomainInstructions, 0, len(domains))
+	for i := range domains {
+		list = append(list, *domainInstructions(&domains[i]))
+	}
+	return list, nil
+}
+
+// VerifyDomain checks the published proof for a pending domain. The first
+// verified domain becomes the tenant's primary domain.
+func (s *Service) VerifyDomain(tenantID, domainID string) (*DomainInstructions, error) {
+	domain, err := s.repo.GetDomain(tenantID, domainID)
+	if err != nil {
+		return nil, err
+	}
+	if domain.Status == DomainStatusVerified {
+		return domainInstructions(domain), nil
+	}
+	if domain.Expired(time.Now()) {
+		return nil, errors.New("domain request has expired; request the domain again")
+	}
+
+	taken, err := s.repo.CheckDomainVerifiedElsewhere(tenantID, domain.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check domain: %w", err)
+	}
+	if taken {
+		return nil, errors.New("domain is already verified by another tenant")
+	}
+
+	now := time.Now()
+	domain.LastCheckedAt = &now
+	verifyErr := s.checkProof(domain)
+	if verifyErr != nil {
+		domain.LastError = verifyErr.Error()
+		if err := s.repo.UpdateDomain(domain); err != nil {
+			return nil, fmt.Errorf("failed to update domain: %w", err)
+		}
+		return nil, fmt.Errorf("domain verification failed: %w", verifyErr)
+	}
+
+	domain.Status = DomainStatusVerified
+	domain.VerifiedAt = &now
+	domain.ExpiresAt = nil
+	domain.LastError = ""
+	if err := s.repo.UpdateDomain(domain); err != nil {
+		return nil, fmt.Errorf("failed to update domain: %w", err)
+	}
+
+	tenant, err := s.repo.GetTenantByID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if tenant.Domain == nil {
+		if err := s.repo.SetPrimaryDomain(tenantID, domain); err != nil {
+			return nil, fmt.Errorf("failed to set primary domain: %w", err)
+		}
+		domain.IsPrimary = true
+	}
+
+	return domainInstructions(domain), nil
+}
+
+// SetPrimaryDomain makes a verified domain the tenant's primary domain
+func (s *Service) SetPrimaryDomain(tenantID, domainID string) (*DomainInstructions, error) {
+	domain, err := s.repo.GetDomain(tenantID, domainID)
+	if err != nil {
+		return nil, err
+	}
+	if domain.Status != DomainStatusVerified {
+		return nil, errors.New("domain is not verified")
+	}
+
+	if err := s.repo.SetPrimaryDomain(tenantID, domain); err != nil {
+		return nil, fmt.Errorf("failed to set primary domain: %w", err)
+	}
+	domain.IsPrimary = true
+
+	return domainInstructions(domain), nil
+}
+
+// RemoveDomain deletes a domain. Removing the primary domain promotes another
+// verified domain, if there is one.
+func (s *Service) RemoveDomain(tenantID, domainID string) error {
+	domain, err := s.repo.GetDomain(tenantID, domainID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteDomain(tenantID, domainID); err != nil {
+		return fmt.Errorf("failed to remove domain: %w", err)
+	}
+
+	if !domain.IsPrimary {
+		return nil
+	}
+
+	remaining, err := s.repo.GetDomainsByTenantID(tenantID)
+	if err != nil {
+		return err
+	}
+	for i := range remaining {
+		if remaining[i].Status == DomainStatusVerified {
+			return s.repo.SetPrimaryDomain(tenantID, &remaining[i])
+		}
+	}
+	return s.repo.SetPrimaryDomain(tenantID, nil)
+}
+
+// checkProof looks up the token the tenant published for the domain
+func (s *Service) checkProof(domain *TenantDomain) error {
+	ctx, cancel := context.WithTimeout(context.Background(), domainVerifyTimeout)
+	defer cancel()
+
+	if domain.Method == DomainMethodHTTP {
+		body, err := s.resolver.FetchWellKnown(ctx, domain.Domain)
+		if err != nil {
+			return err
+		}
+		if body != domain.Token {
+			return errors.New("verification file does not contain the expected token")
+		}
+		return nil
+	}
+
+	records, err := s.resolver.LookupTXT(ctx, dnsRecordName(domain.Domain))
+	if err != nil {
+		return err
+	}
+	expected := dnsValuePrefix + domain.Token
+	for _, record := range records {
+		if strings.TrimSpace(record) == expected {
+			return nil
+		}
+	}
+	return errors.New("TXT record with the expected token not found")
+}
+
+// Branding image kinds
+const (
+	BrandingLogo  = "logo"
+	BrandingCover = "cover"
+)
+
+var (
+	hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	localePattern   = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z]{2,4})?$`)
+
+	// brandingImageTypes maps accepted image content types to file extensions.
+	// SVG is deliberately excluded because it can carry scripts.
+	brandingImageTypes = map[string]string{
+		"image/png":  ".png",
+		"image/jpeg": ".jpg",
+		"image/webp": ".webp",
+		"image/gif":  ".gif",
+	}
+)
+
+// TenantBranding service methods
+func (s *Service) GetBranding(tenantID string) (*TenantBranding, error) {
+	if _, err := s.repo.GetTenantByID(tenantID); err != nil {
+		return nil, err
+	}
+
+	branding, err := s.repo.GetBranding(tenantID)
+	if err != nil {
+		if err.Error() == "branding not found" {
+			return &TenantBranding{TenantID: tenantID, DisplayNames: map[string]string{}}, nil
+		}
+		return nil, err
+	}
+	return branding, nil
+}
+
+func (s *Service) UpdateBranding(tenantID string, req *UpdateBrandingRequest) (*TenantBranding, error) {
+	branding, err := s.GetBranding(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	colors := []struct {
+		name  string
+		value *string
+		field *string
+	}{
+		{"primaryColor", req.PrimaryColor, &branding.PrimaryColor},
+		{"secondaryColor", req.SecondaryColor, &branding.SecondaryColor},
+		{"accentColor", req.AccentColor, &branding.AccentColor},
+		{"backgroundColor", req.BackgroundColor, &branding.BackgroundColor},
+		{"textColor", req.TextColor, &branding.TextColor},
+	}
+	for _, color := range colors {
+		if color.value == nil {
+			continue
+		}
+		if *color.value != "" && !hexColorPattern.MatchString(*color.value) {
+			return nil, fmt.Errorf("invalid branding: %s must be a #RRGGBB colour", color.name)
+		}
+		*color.field = strings.ToUpper(*color.value)
+	}
+
+	if req.DisplayNames != nil {
+		for locale := range req.DisplayNames {
+			if !localePattern.MatchString(locale) {
+				return nil, fmt.Errorf("invalid branding: %q is not a valid locale", locale)
+			}
+		}
+		branding.DisplayNames = req.DisplayNames
+	}
+
+	if req.ContactEmail != nil {
+		if *req.ContactEmail != "" {
+			if _, err := mail.ParseAddress(*req.ContactEmail); err != nil {
+				return nil, errors.New("invalid branding: contactEmail is not a valid email address")
+			}
+		}
+		branding.ContactEmail = *req.ContactEmail
+	}
+	if req.Website != nil {
+		if *req.Website != "" {
+			u, err := url.Parse(*req.Website)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, errors.New("invalid branding: website must be an http or https URL")
+			}
+		}
+		branding.Website = *req.Website
+	}
+	if req.ContactPhone != nil {
+		branding.ContactPhone = *req.ContactPhone
+	}
+	if req.Address != nil {
+		branding.Address = *req.Address
+	}
+
+	if err := s.repo.SaveBranding(branding); err != nil {
+		return nil, fmt.Errorf("failed to save branding: %w", err)
+	}
+	return branding, nil
+}
+
+// UploadBrandingImage stores a logo or cover image and replaces the previous one
+func (s *Service) UploadBrandingImage(tenantID, kind string, file io.Reader) (*TenantBranding, error) {
+	branding, err := s.GetBranding(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sniff the real content type instead of trusting the upload headers
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	head = head[:n]
+	ext, ok := brandingImageTypes[http.DetectContentType(head)]
+	if !ok {
+		return nil, errors.New("invalid branding: image must be PNG, JPEG, WebP or GIF")
+	}
+
+	key := fmt.Sprintf("tenants/%s/branding/%s-%s%s", tenantID, kind, generateDomainToken()[:12], ext)
+	location, err := storage.GetStorage().Save(key, io.MultiReader(bytes.NewReader(head), file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+
+	var oldKey string
+	if kind == BrandingCover {
+		oldKey = branding.CoverKey
+		branding.CoverKey, branding.CoverURL = key, location
+	} else {
+		oldKey = branding.LogoKey
+		branding.LogoKey, branding.LogoURL = key, location
+	}
+
+	if err := s.repo.SaveBranding(branding); err != nil {
+		storage.GetStorage().Delete(key)
+		return nil, fmt.Errorf("failed to save branding: %w", err)
+	}
+	if oldKey != "" {
+		if err := storage.GetStorage().Delete(oldKey); err != nil {
+			log.Printf("failed to delete old branding image %s: %v", oldKey, err)
+		}
+	}
+
+	return branding, nil
+}
+
+// DeleteBrandingImage removes a logo or cover image
+func (s *Service) DeleteBrandingImage(tenantID, kind string) (*TenantBranding, error) {
+	branding, err := s.GetBranding(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var oldKey string
+	if kind == BrandingCover {
+		oldKey = branding.CoverKey
+		branding.CoverKey, branding.CoverURL = "", ""
+	} else {
+		oldKey = branding.LogoKey
+		branding.LogoKey, branding.LogoURL = "", ""
+	}
+
+	if err := s.repo.SaveBranding(branding); err != nil {
+		return nil, fmt.Errorf("failed to save branding: %w", err)
+	}
+	if oldKey != "" {
+		if err := storage.GetStorage().Delete(oldKey); err != nil {
+			log.Printf("failed to delete branding image %s: %v", oldKey, err)
+		}
+	}
+
+	return branding, nil
+}
+
+// GetPublicBranding resolves a verified domain to the tenant's public branding
+func (s *Service) GetPublicBranding(domain string) (*PublicBranding, time.Time, error) {
+	tenant, err := s.repo.GetTenantByDomain(NormalizeDomain(domain))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if !tenant.IsActive {
+		return nil, time.Time{}, errors.New("tenant not found")
+	}
+
+	branding, err := s.GetBranding(tenant.ID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	public := &PublicBranding{
+		Name:            tenant.Name,
+		Domain:          NormalizeDomain(domain),
+		LogoURL:         branding.LogoURL,
+		CoverURL:        branding.CoverURL,
+		PrimaryColor:    branding.PrimaryColor,
+		SecondaryColor:  branding.SecondaryColor,
+		AccentColor:     branding.AccentColor,
+		BackgroundColor: branding.BackgroundColor,
+		TextColor:       branding.TextColor,
+		DisplayNames:    branding.DisplayNames,
+		ContactEmail:    branding.ContactEmail,
+		ContactPhone:    branding.ContactPhone,
+		Website:         branding.Website,
+		Address:         branding.Address,
+	}
+	if public.DisplayNames == nil {
+		public.DisplayNames = map[string]string{}
+	}
+
+	lastModified := branding.UpdatedAt
+	if tenant.UpdatedAt.After(lastModified) {
+		lastModified = tenant.UpdatedAt
+	}
+	return public, lastModified, nil
+}
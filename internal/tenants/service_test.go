@@ -0,0 +1,168 @@
+package tenants
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"concierge-be/database/dbtest"
+
+	"gorm.io/gorm"
+)
+
+// newDomainTest sets up a tenant and a service verifying through a fake resolver
+func newDomainTest(t *testing.T) (*gorm.DB, *Service, *FakeResolver) {
+	t.Helper()
+	db := dbtest.Open(t, &Tenant{}, &TenantDomain{})
+	if err := db.Create(&Tenant{ID: "tenant-1", Name: "Grand Hotel"}).Error; err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	resolver := NewFakeResolver()
+	return db, NewService().WithResolver(resolver), resolver
+}
+
+func TestRequestDomain(t *testing.T) {
+	_, service, _ := newDomainTest(t)
+
+	claim, err := service.RequestDomain("tenant-1", &RequestDomainRequest{Domain: "Grand-Hotel.com."})
+	if err != nil {
+		t.Fatalf("RequestDomain: %v", err)
+	}
+	if claim.Domain != "grand-hotel.com" || claim.Status != DomainStatusPending || claim.Method != DomainMethodDNS {
+		t.Errorf("claim = %s %s %s, want grand-hotel.com pending dns", claim.Domain, claim.Status, claim.Method)
+	}
+	if claim.RecordName != "_concierge-verification.grand-hotel.com" || claim.RecordValue != "concierge-verification="+claim.Token {
+		t.Errorf("instructions = %s %s", claim.RecordName, claim.RecordValue)
+	}
+	if claim.ExpiresAt == nil || claim.ExpiresAt.Before(time.Now().Add(DomainRequestTTL-time.Minute)) {
+		t.Errorf("expiresAt = %v, want about %v from now", claim.ExpiresAt, DomainRequestTTL)
+	}
+
+	if _, err := service.RequestDomain("tenant-1", &RequestDomainRequest{Domain: "grand-hotel.com"}); err == nil ||
+		err.Error() != "domain already requested for this tenant" {
+		t.Errorf("second request: err = %v", err)
+	}
+}
+
+func TestVerifyDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		publish func(r *FakeResolver, claim *DomainInstructions)
+		wantErr string
+	}{
+		{
+			name:   "dns record matches",
+			method: DomainMethodDNS,
+			publish: func(r *FakeResolver, claim *DomainInstructions) {
+				r.SetTXT(claim.RecordName, "v=spf1 -all", claim.RecordValue)
+			},
+		},
+		{
+			name:   "well-known file matches",
+			method: DomainMethodHTTP,
+			publish: func(r *FakeResolver, claim *DomainInstructions) {
+				r.SetWellKnown(claim.Domain, claim.Body)
+			},
+		},
+		{
+			name:   "txt record has another token",
+			method: DomainMethodDNS,
+			publish: func(r *FakeResolver, claim *DomainInstructions) {
+				r.SetTXT(claim.RecordName, "concierge-verification=someone-elses-token")
+			},
+			wantErr: "TXT record with the expected token not found",
+		},
+		{
+			name:    "nothing published",
+			method:  DomainMethodDNS,
+			publish: func(*FakeResolver, *DomainInstructions) {},
+			wantErr: "no TXT records",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, service, resolver := newDomainTest(t)
+			claim, err := service.RequestDomain("tenant-1", &RequestDomainRequest{Domain: "grand-hotel.com", Method: tt.method})
+			if err != nil {
+				t.Fatalf("RequestDomain: %v", err)
+			}
+			tt.publish(resolver, claim)
+
+			verified, err := service.VerifyDomain("tenant-1", claim.ID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), "domain verification failed") || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want verification failure containing %q", err, tt.wantErr)
+				}
+				domains, _ := service.GetDomains("tenant-1")
+				if domains[0].Status != DomainStatusPending || domains[0].LastError == "" || domains[0].LastCheckedAt == nil {
+					t.Errorf("domain = %s, lastError %q, want pending with the failure recorded", domains[0].Status, domains[0].LastError)
+				}
+				if _, err := service.GetTenantByDomain("grand-hotel.com"); err == nil {
+					t.Error("unverified domain resolves to the tenant")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("VerifyDomain: %v", err)
+			}
+			if verified.Status != DomainStatusVerified || !verified.IsPrimary || verified.ExpiresAt != nil {
+				t.Errorf("domain = %s primary=%v expiresAt=%v, want verified primary without expiry", verified.Status, verified.IsPrimary, verified.ExpiresAt)
+			}
+			tenant, err := service.GetTenantByDomain("grand-hotel.com")
+			if err != nil || tenant.ID != "tenant-1" {
+				t.Fatalf("GetTenantByDomain = %v, %v", tenant, err)
+			}
+			if tenant.Domain == nil || *tenant.Domain != "grand-hotel.com" {
+				t.Errorf("tenant domain = %v, want grand-hotel.com", tenant.Domain)
+			}
+		})
+	}
+}
+
+func TestExpiredDomainRequest(t *testing.T) {
+	db, service, resolver := newDomainTest(t)
+
+	claim, err := service.RequestDomain("tenant-1", &RequestDomainRequest{Domain: "grand-hotel.com"})
+	if err != nil {
+		t.Fatalf("RequestDomain: %v", err)
+	}
+	resolver.SetTXT(claim.RecordName, claim.RecordValue)
+	if err := db.Model(&TenantDomain{}).Where("id = ?", claim.ID).
+		Update("expires_at", time.Now().Add(-time.Hour)).Error; err != nil {
+		t.Fatalf("expire claim: %v", err)
+	}
+
+	// The proof is published, but too late
+	if _, err := service.VerifyDomain("tenant-1", claim.ID); err == nil ||
+		err.Error() != "domain request has expired; request the domain again" {
+		t.Fatalf("verify expired claim: err = %v", err)
+	}
+
+	// Requesting again renews the same claim with a fresh token
+	renewed, err := service.RequestDomain("tenant-1", &RequestDomainRequest{Domain: "grand-hotel.com"})
+	if err != nil {
+		t.Fatalf("re-request: %v", err)
+	}
+	if renewed.ID != claim.ID || renewed.Token == claim.Token {
+		t.Fatalf("renewed claim %s token %s, want claim %s with a new token", renewed.ID, renewed.Token, claim.ID)
+	}
+	if renewed.ExpiresAt == nil || !renewed.ExpiresAt.After(time.Now()) {
+		t.Errorf("renewed expiresAt = %v, want in the future", renewed.ExpiresAt)
+	}
+
+	// The old token no longer proves anything; the new one does
+	if _, err := service.VerifyDomain("tenant-1", claim.ID); err == nil {
+		t.Fatal("verified with the token of the expired claim")
+	}
+	resolver.SetTXT(renewed.RecordName, renewed.RecordValue)
+	verified, err := service.VerifyDomain("tenant-1", claim.ID)
+	if err != nil {
+		t.Fatalf("verify renewed claim: %v", err)
+	}
+	if verified.Status != DomainStatusVerified {
+		t.Errorf("status = %s, want verified", verified.Status)
+	}
+}
@@ -0,0 +1,116 @@
+package trash
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"concierge-be/internal/quotas"
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
+// tenantScope returns the tenant a trash request is held to: a tenant
+// admin's own tenant, or the optional tenantId of a platform admin. Only
+// platform admins reach the trash of records outside any tenant.
+func tenantScope(c *gin.Context) (string, bool) {
+	if utils.IsPlatformAdmin(c) {
+		return c.Query("tenantId"), true
+	}
+	if res, err := findResource(c.Param("resource")); err == nil && res.tenantColumn == "" {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only platform admins can manage this trash")
+		return "", false
+	}
+	return c.GetString("tenant_id"), true
+}
+
+// ListDeleted handles GET /api/v1/trash/:resource?tenantId=
+// tenantId is required for resources that belong to a tenant, and on every
+// trash request of a tenant admin
+func (h *Handler) ListDeleted(c *gin.Context) {
+	tenantID, ok := tenantScope(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	items, total, err := h.serviceFor(c).ListDeleted(c.Param("resource"), tenantID, page, pageSize)
+	if err != nil {
+		trashErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponseWithPagination(c, items, page, pageSize, int(total))
+}
+
+// Restore handles POST /api/v1/trash/:resource/:id/restore
+func (h *Handler) Restore(c *gin.Context) {
+	tenantID, ok := tenantScope(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.serviceFor(c).Restore(c.Param("resource"), tenantID, c.Param("id"))
+	if err != nil {
+		trashErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, result)
+}
+
+// Purge handles DELETE /api/v1/trash/:resource/:id
+func (h *Handler) Purge(c *gin.Context) {
+	tenantID, ok := tenantScope(c)
+	if !ok {
+		return
+	}
+
+	if err := h.serviceFor(c).Purge(c.Param("resource"), tenantID, c.Param("id")); err != nil {
+		trashErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"message": "Record purged permanently"})
+}
+
+// trashErrorResponse maps trash errors to HTTP responses
+func trashErrorResponse(c *gin.Context, err error) {
+	var conflictErr *ConflictError
+	switch {
+	case errors.As(err, &conflictErr):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, quotas.ErrQuotaExceeded):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrUnknownResource), err.Error() == "deleted record not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case err.Error() == "tenantId query parameter is required":
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,63 @@
+package trash
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RetentionJob periodically purges records deleted longer ago than the
+// configured retention
+type RetentionJob struct {
+	service  *Service
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewRetentionJob creates a job that runs every interval
+func NewRetentionJob(interval time.Duration) *RetentionJob {
+	return &RetentionJob{
+		service:  NewService(),
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Start runs the job in the background until ctx is cancelled
+func (j *RetentionJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			j.Run()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Run purges what has expired once, logging what it did
+func (j *RetentionJob) Run() {
+	if j.service.Retention() <= 0 {
+		return
+	}
+	report, err := j.service.PurgeExpired(j.now().Add(-j.service.Retention()))
+	if err != nil {
+		log.Printf("trash retention: %v", err)
+	}
+	if report == nil {
+		return
+	}
+	for resource, n := range report.Purged {
+		if n > 0 {
+			log.Printf("trash retention: purged %d %s", n, resource)
+		}
+	}
+	for resource, n := range report.Skipped {
+		log.Printf("trash retention: kept %d expired %s still referenced by other records", n, resource)
+	}
+}
@@ -0,0 +1,60 @@
+package trash
+
+import (
+	"errors"
+	"time"
+)
+
+// Resources with a trash, named as in their API routes
+const (
+	ResourceTenants       = "tenants"
+	ResourceUsers         = "users"
+	ResourceOrganizations = "organizations"
+	ResourcePlans         = "plans"
+	ResourceCategories    = "amenities-categories"
+	ResourceAmenities     = "amenities"
+	ResourceLocations     = "locations"
+	ResourceSuppliers     = "suppliers"
+)
+
+// Item is a soft-deleted record. TenantID is nil for records outside a
+// tenant. PurgeAt is when the retention job will delete it for good, nil
+// while retention is off.
+type Item struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	TenantID  *string    `json:"tenantId,omitempty"`
+	DeletedAt time.Time  `json:"deletedAt"`
+	PurgeAt   *time.Time `gorm:"-" json:"purgeAt,omitempty"`
+}
+
+// ConflictError reports why a record cannot be restored or purged
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// conflict builds a ConflictError
+func conflict(message string) error {
+	return &ConflictError{Message: message}
+}
+
+// ErrUnknownResource is returned for a resource without a trash
+var ErrUnknownResource = errors.New("unknown trash resource")
+
+// RestoreResult is a restored record
+type RestoreResult struct {
+	Resource string `json:"resource"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+}
+
+// PurgeReport counts what a retention run deleted, per resource, and how
+// many expired records it had to keep because others still reference them
+type PurgeReport struct {
+	Purged  map[string]int `json:"purged"`
+	Skipped map[string]int `json:"skipped"`
+}
@@ -0,0 +1,134 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"concierge-be/database"
+
+	"gorm.io/gorm"
+)
+
+// Repository reads and changes soft-deleted rows of any resource table.
+// Soft-deleted rows are invisible to the models' default scope, so it
+// works on tables directly.
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		db: database.GetDB(),
+	}
+}
+
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
+// Transaction runs fn in a database transaction
+func (r *Repository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+// deleted returns a query over a resource's soft-deleted rows as Items
+func (r *Repository) deleted(res *resource) *gorm.DB {
+	tenantColumn := "NULL"
+	if res.tenantColumn != "" {
+		tenantColumn = res.tenantColumn
+	}
+	return r.db.Table(res.table).
+		Select(fmt.Sprintf("id, %s AS name, %s AS tenant_id, deleted_at", res.nameColumn, tenantColumn)).
+		Where("deleted_at IS NOT NULL")
+}
+
+// ListDeleted retrieves a page of a resource's deleted records, most
+// recently deleted first, optionally only those of one tenant
+func (r *Repository) ListDeleted(res *resource, tenantID string, page, pageSize int) ([]Item, int64, error) {
+	var items []Item
+	var total int64
+
+	query := r.deleted(res)
+	if tenantID != "" && res.tenantColumn != "" {
+		query = query.Where(res.tenantColumn+" = ?", tenantID)
+	}
+	query = query.Session(&gorm.Session{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("deleted_at DESC, id ASC").Offset(offset).Limit(pageSize).Scan(&items).Error
+
+	return items, total, err
+}
+
+// ListExpired retrieves up to limit records deleted before cutoff, oldest
+// first, skipping the first offset of them
+func (r *Repository) ListExpired(res *resource, cutoff time.Time, offset, limit int) ([]Item, error) {
+	var items []Item
+	err := r.deleted(res).Where("deleted_at < ?", cutoff).
+		Order("deleted_at ASC, id ASC").Offset(offset).Limit(limit).
+		Scan(&items).Error
+	return items, err
+}
+
+// GetDeleted retrieves one deleted record
+func (r *Repository) GetDeleted(res *resource, id string) (*Item, error) {
+	var items []Item
+	if err := r.deleted(res).Where("id = ?", id).Limit(1).Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("deleted record not found")
+	}
+	return &items[0], nil
+}
+
+// Restore clears a record's deletion mark, advancing its version
+func (r *Repository) Restore(res *resource, id string) error {
+	return r.db.Table(res.table).Where("id = ? AND deleted_at IS NOT NULL", id).Updates(map[string]interface{}{
+		"deleted_at": nil,
+		"version":    gorm.Expr("version + 1"),
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// Purge permanently deletes a soft-deleted record. It goes through gorm
+// rather than raw SQL so the audit log records what was purged.
+func (r *Repository) Purge(res *resource, id string) error {
+	return r.db.Table(res.table).Where("id = ? AND deleted_at IS NOT NULL", id).Delete(map[string]interface{}{}).Error
+}
+
+// count counts the rows of table matching a condition, deleted or not
+func (r *Repository) count(table, condition string, args ...interface{}) (int64, error) {
+	var count int64
+	err := r.db.Table(table).Where(condition, args...).Count(&count).Error
+	return count, err
+}
+
+// column reads one column of the row with id, deleted or not
+func (r *Repository) column(table, column, id string) (*string, error) {
+	var values []*string
+	err := r.db.Table(table).Where("id = ?", id).Limit(1).Pluck(column, &values).Error
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// values reads one column of the rows of table matching a condition
+func (r *Repository) values(table, column, condition string, args ...interface{}) ([]string, error) {
+	var values []string
+	err := r.db.Table(table).Where(condition, args...).Pluck(column, &values).Error
+	return values, err
+}
+
+// deleteWhere permanently deletes the rows of table matching a condition.
+// Raw SQL skips model hooks, so append-only ledgers can be removed with the
+// record they belong to.
+func (r *Repository) deleteWhere(table, condition string, args ...interface{}) error {
+	return r.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", table, condition), args...).Error
+}
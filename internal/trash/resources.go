@@ -0,0 +1,347 @@
+package trash
+
+import (
+	"fmt"
+
+	"concierge-be/internal/quotas"
+)
+
+// resource describes the trash of one soft-deleted model.
+//
+// checkRestore rejects restores that would break a rule the live rows keep,
+// such as a name that is unique per tenant. purge removes the rows that
+// only exist as part of the record, or rejects the purge while records of
+// other resources still reference it; the record itself is deleted after.
+// It returns storage keys of files to remove once the purge is committed.
+type resource struct {
+	name         string
+	table        string
+	nameColumn   string
+	tenantColumn string // "" for records outside a tenant
+	quota        string // quota a restored record counts against, "" for none
+	checkRestore func(r *Repository, item *Item) error
+	purge        func(r *Repository, item *Item) ([]string, error)
+}
+
+// resources lists every trash in the order the retention job empties them:
+// records before the records they reference
+var resources = []*resource{
+	{
+		name:         ResourceAmenities,
+		table:        "amenities",
+		nameColumn:   "item_name",
+		tenantColumn: "tenant_id",
+		quota:        quotas.ResourceAmenities,
+		checkRestore: checkAmenityRestore,
+		purge:        purgeAmenity,
+	},
+	{
+		name:         ResourceSuppliers,
+		table:        "suppliers",
+		nameColumn:   "name",
+		tenantColumn: "tenant_id",
+		checkRestore: checkSupplierRestore,
+		purge:        purgeSupplier,
+	},
+	{
+		name:         ResourceCategories,
+		table:        "amenities_categories",
+		nameColumn:   "name",
+		tenantColumn: "tenant_id",
+		quota:        quotas.ResourceCategories,
+		checkRestore: checkCategoryRestore,
+		purge:        purgeCategory,
+	},
+	{
+		name:         ResourceLocations,
+		table:        "locations",
+		nameColumn:   "name",
+		tenantColumn: "tenant_id",
+		checkRestore: checkLocationRestore,
+		purge:        purgeLocation,
+	},
+	{
+		name:       ResourceUsers,
+		table:      "users",
+		nameColumn: "username",
+		purge:      purgeUser,
+	},
+	{
+		name:         ResourceTenants,
+		table:        "tenants",
+		nameColumn:   "name",
+		tenantColumn: "id",
+		purge:        purgeTenant,
+	},
+	{
+		name:       ResourceOrganizations,
+		table:      "organizations",
+		nameColumn: "name",
+		purge:      purgeOrganization,
+	},
+	{
+		name:       ResourcePlans,
+		table:      "plans",
+		nameColumn: "name",
+		purge:      purgePlan,
+	},
+}
+
+// findResource returns the resource with a name
+func findResource(name string) (*resource, error) {
+	for _, res := range resources {
+		if res.name == name {
+			return res, nil
+		}
+	}
+	return nil, ErrUnknownResource
+}
+
+// refuseIfReferenced rejects a purge while rows of table match condition
+func refuseIfReferenced(r *Repository, message, table, condition string, args ...interface{}) error {
+	count, err := r.count(table, condition, args...)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return conflict(message)
+	}
+	return nil
+}
+
+// checkLive rejects a restore when the row of table that column of the
+// record points at is missing or deleted
+func checkLive(r *Repository, recordTable string, item *Item, column, table, message string) error {
+	id, err := r.column(recordTable, column, item.ID)
+	if err != nil || id == nil {
+		return err
+	}
+	live, err := r.count(table, "id = ? AND deleted_at IS NULL", *id)
+	if err != nil {
+		return err
+	}
+	if live == 0 {
+		return conflict(message)
+	}
+	return nil
+}
+
+// checkSiblingName rejects a restore when a live sibling under the same
+// parent now uses the record's name
+func checkSiblingName(r *Repository, table string, item *Item, message string) error {
+	parentID, err := r.column(table, "parent_id", item.ID)
+	if err != nil {
+		return err
+	}
+	condition := "tenant_id = ? AND name = ? AND id <> ? AND deleted_at IS NULL AND parent_id IS NULL"
+	args := []interface{}{*item.TenantID, item.Name, item.ID}
+	if parentID != nil {
+		condition = "tenant_id = ? AND name = ? AND id <> ? AND deleted_at IS NULL AND parent_id = ?"
+		args = append(args, *parentID)
+	}
+	taken, err := r.count(table, condition, args...)
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		return conflict(message)
+	}
+	return nil
+}
+
+func checkAmenityRestore(r *Repository, item *Item) error {
+	if err := checkLive(r, "amenities", item, "category_id", "amenities_categories", "category of this amenity is deleted; restore it or reassign the amenity first"); err != nil {
+		return err
+	}
+	taken, err := r.count("amenities", "tenant_id = ? AND item_name = ? AND id <> ? AND deleted_at IS NULL", *item.TenantID, item.Name, item.ID)
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		return conflict("item name already exists for this tenant")
+	}
+	// Its SKU and barcodes may have been given to another amenity meanwhile
+	codes := "(SELECT code FROM amenity_barcodes WHERE amenity_id = ?) UNION (SELECT sku FROM amenities WHERE id = ? AND sku <> '')"
+	taken, err = r.count("amenities", "tenant_id = ? AND id <> ? AND deleted_at IS NULL AND sku IN ("+codes+")", *item.TenantID, item.ID, item.ID, item.ID)
+	if err != nil {
+		return err
+	}
+	if taken == 0 {
+		taken, err = r.count("amenity_barcodes", "tenant_id = ? AND amenity_id <> ? AND amenity_id IN (SELECT id FROM amenities WHERE deleted_at IS NULL) AND code IN ("+codes+")", *item.TenantID, item.ID, item.ID, item.ID)
+		if err != nil {
+			return err
+		}
+	}
+	if taken > 0 {
+		return conflict("sku or barcode of this amenity is now used by another amenity")
+	}
+	// A kit needs every one of its components
+	missing, err := r.count("kit_components", "kit_id = ? AND amenity_id NOT IN (SELECT id FROM amenities WHERE deleted_at IS NULL)", item.ID)
+	if err != nil {
+		return err
+	}
+	if missing > 0 {
+		return conflict("a component of this kit is deleted; restore it first")
+	}
+	return nil
+}
+
+func purgeAmenity(r *Repository, item *Item) ([]string, error) {
+	if err := refuseIfReferenced(r, "amenity is referenced by purchase orders", "purchase_order_lines", "amenity_id = ?", item.ID); err != nil {
+		return nil, err
+	}
+	if err := refuseIfReferenced(r, "amenity is referenced by stock counts", "count_lines", "amenity_id = ?", item.ID); err != nil {
+		return nil, err
+	}
+	if err := refuseIfReferenced(r, "amenity is a component of kits, including deleted ones", "kit_components", "amenity_id = ?", item.ID); err != nil {
+		return nil, err
+	}
+	if err := r.deleteWhere("kit_components", "kit_id = ?", item.ID); err != nil {
+		return nil, err
+	}
+	if err := r.deleteWhere("stock_movement_lots", "lot_id IN (SELECT id FROM stock_lots WHERE amenity_id = ?)", item.ID); err != nil {
+		return nil, err
+	}
+	for _, table := range []string{"alerts", "supplier_items", "amenity_stocks", "stock_lots", "stock_movements", "amenity_barcodes", "amenity_units"} {
+		if err := r.deleteWhere(table, "amenity_id = ?", item.ID); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func checkSupplierRestore(r *Repository, item *Item) error {
+	taken, err := r.count("suppliers", "tenant_id = ? AND name = ? AND id <> ? AND deleted_at IS NULL", *item.TenantID, item.Name, item.ID)
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		return conflict("supplier name already exists")
+	}
+	return nil
+}
+
+func purgeSupplier(r *Repository, item *Item) ([]string, error) {
+	if err := refuseIfReferenced(r, "supplier is referenced by purchase orders", "purchase_orders", "supplier_id = ?", item.ID); err != nil {
+		return nil, err
+	}
+	return nil, r.deleteWhere("supplier_items", "supplier_id = ?", item.ID)
+}
+
+func checkCategoryRestore(r *Repository, item *Item) error {
+	if err := checkLive(r, "amenities_categories", item, "parent_id", "amenities_categories", "parent category is deleted; restore it first"); err != nil {
+		return err
+	}
+	return checkSiblingName(r, "amenities_categories", item, "category name already exists under this parent")
+}
+
+func purgeCategory(r *Repository, item *Item) ([]string, error) {
+	// Deleted amenities and subcategories still point at the category
+	if err := refuseIfReferenced(r, "category is still used by amenities, including deleted ones", "amenities", "category_id = ?", item.ID); err != nil {
+		return nil, err
+	}
+	if err := refuseIfReferenced(r, "category still has subcategories, including deleted ones", "amenities_categories", "parent_id = ?", item.ID); err != nil {
+		return nil, err
+	}
+	if err := r.deleteWhere("category_merge_items", "merge_id IN (SELECT id FROM category_merges WHERE target_id = ?)", item.ID); err != nil {
+		return nil, err
+	}
+	return nil, r.deleteWhere("category_merges", "target_id = ?", item.ID)
+}
+
+func checkLocationRestore(r *Repository, item *Item) error {
+	if err := checkLive(r, "locations", item, "parent_id", "locations", "parent location is deleted; restore it first"); err != nil {
+		return err
+	}
+	return checkSiblingName(r, "locations", item, "location name already exists at this level")
+}
+
+func purgeLocation(r *Repository, item *Item) ([]string, error) {
+	references := []struct{ table, message string }{
+		{"locations", "location still has child locations, including deleted ones"},
+		{"amenity_stocks", "location still has stock records"},
+		{"stock_movements", "location is referenced by stock movements"},
+		{"count_sessions", "location is referenced by stock counts"},
+		{"count_lines", "location is referenced by stock counts"},
+		{"purchase_orders", "location is referenced by purchase orders"},
+	}
+	for _, ref := range references {
+		column := "location_id"
+		if ref.table == "locations" {
+			column = "parent_id"
+		}
+		if err := refuseIfReferenced(r, ref.message, ref.table, column+" = ?", item.ID); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func purgeUser(r *Repository, item *Item) ([]string, error) {
+	if err := r.deleteWhere("user_tenants", "user_id = ?", item.ID); err != nil {
+		return nil, err
+	}
+	return nil, r.deleteWhere("organization_members", "user_id = ?", item.ID)
+}
+
+func purgeOrganization(r *Repository, item *Item) ([]string, error) {
+	if err := refuseIfReferenced(r, "organization still has tenants, including deleted ones", "tenants", "organization_id = ?", item.ID); err != nil {
+		return nil, err
+	}
+	return nil, r.deleteWhere("organization_members", "organization_id = ?", item.ID)
+}
+
+func purgePlan(r *Repository, item *Item) ([]string, error) {
+	return nil, refuseIfReferenced(r, "plan is still assigned to tenants, including deleted ones", "tenants", "plan_id = ?", item.ID)
+}
+
+// tenantData lists every table holding a tenant's data, children before
+// the tables they reference, with the condition selecting the tenant's rows
+var tenantData = []struct{ table, condition string }{
+	{"count_entries", "session_id IN (SELECT id FROM count_sessions WHERE tenant_id = ?)"},
+	{"count_lines", "session_id IN (SELECT id FROM count_sessions WHERE tenant_id = ?)"},
+	{"count_sessions", "tenant_id = ?"},
+	{"purchase_order_lines", "purchase_order_id IN (SELECT id FROM purchase_orders WHERE tenant_id = ?)"},
+	{"purchase_orders", "tenant_id = ?"},
+	{"supplier_items", "tenant_id = ?"},
+	{"suppliers", "tenant_id = ?"},
+	{"alerts", "tenant_id = ?"},
+	{"notifications", "tenant_id = ?"},
+	{"notification_settings", "tenant_id = ?"},
+	{"stock_movement_lots", "tenant_id = ?"},
+	{"stock_lots", "tenant_id = ?"},
+	{"stock_movements", "tenant_id = ?"},
+	{"amenity_stocks", "tenant_id = ?"},
+	{"amenity_barcodes", "tenant_id = ?"},
+	{"amenity_units", "tenant_id = ?"},
+	{"kit_components", "tenant_id = ?"},
+	{"amenities", "tenant_id = ?"},
+	{"category_merge_items", "merge_id IN (SELECT id FROM category_merges WHERE tenant_id = ?)"},
+	{"category_merges", "tenant_id = ?"},
+	{"amenities_categories", "tenant_id = ?"},
+	{"locations", "tenant_id = ?"},
+	{"tenant_domains", "tenant_id = ?"},
+	{"tenant_brandings", "tenant_id = ?"},
+	{"usage_counters", "tenant_id = ?"},
+	{"user_tenants", "tenant_id = ?"},
+}
+
+// purgeTenant deletes everything the tenant owns, live or deleted, and
+// hands back its branding images for removal
+func purgeTenant(r *Repository, item *Item) ([]string, error) {
+	var files []string
+	for _, column := range []string{"logo_key", "cover_key"} {
+		keys, err := r.values("tenant_brandings", column, "tenant_id = ? AND "+column+" <> ''", item.ID)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, keys...)
+	}
+
+	for _, data := range tenantData {
+		if err := r.deleteWhere(data.table, data.condition, item.ID); err != nil {
+			return nil, fmt.Errorf("failed to purge %s: %w", data.table, err)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,224 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"concierge-be/config"
+	"concierge-be/internal/quotas"
+	"concierge-be/storage"
+
+	"gorm.io/gorm"
+)
+
+// purgeBatchSize is how many expired records a retention run loads at a time
+const purgeBatchSize = 100
+
+type Service struct {
+	repo      *Repository
+	quotas    *quotas.Service
+	retention time.Duration
+}
+
+func NewService() *Service {
+	retention := time.Duration(0)
+	if config.AppConfig != nil {
+		retention = time.Duration(config.AppConfig.Trash.RetentionDays) * 24 * time.Hour
+	}
+	return &Service{
+		repo:      NewRepository(),
+		quotas:    quotas.NewService(),
+		retention: retention,
+	}
+}
+
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	copied := *s
+	copied.repo = s.repo.WithTx(s.repo.db.WithContext(ctx))
+	return &copied
+}
+
+// Retention returns how long deleted records are kept, 0 for forever
+func (s *Service) Retention() time.Duration {
+	return s.retention
+}
+
+// ListDeleted retrieves a page of a resource's deleted records. Records of
+// tenant-scoped resources are listed per tenant.
+func (s *Service) ListDeleted(resourceName, tenantID string, page, pageSize int) ([]Item, int64, error) {
+	res, err := findResource(resourceName)
+	if err != nil {
+		return nil, 0, err
+	}
+	if res.tenantColumn != "" && res.name != ResourceTenants && tenantID == "" {
+		return nil, 0, errors.New("tenantId query parameter is required")
+	}
+
+	items, total, err := s.repo.ListDeleted(res, tenantID, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	if s.retention > 0 {
+		for i := range items {
+			purgeAt := items[i].DeletedAt.Add(s.retention)
+			items[i].PurgeAt = &purgeAt
+		}
+	}
+	return items, total, nil
+}
+
+// Restore brings a deleted record back. It is refused with a ConflictError
+// when the record would clash with a live one, when what it belongs to is
+// itself deleted, or when its tenant is; the quota it counts against must
+// also leave room for it. With a tenantID, only a record of that tenant is
+// restored.
+func (s *Service) Restore(resourceName, tenantID, id string) (*RestoreResult, error) {
+	res, err := findResource(resourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *RestoreResult
+	err = s.repo.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		item, err := repo.GetDeleted(res, id)
+		if err != nil {
+			return err
+		}
+		if !inTenant(item, tenantID) {
+			return errors.New("deleted record not found")
+		}
+
+		if res.tenantColumn != "" && res.name != ResourceTenants {
+			live, err := repo.count("tenants", "id = ? AND deleted_at IS NULL", *item.TenantID)
+			if err != nil {
+				return err
+			}
+			if live == 0 {
+				return conflict("tenant is deleted; restore it first")
+			}
+		}
+		if res.quota != "" {
+			if err := s.quotas.WithTx(tx).CheckLimit(*item.TenantID, res.quota); err != nil {
+				return err
+			}
+		}
+		if res.checkRestore != nil {
+			if err := res.checkRestore(repo, item); err != nil {
+				return err
+			}
+		}
+
+		if err := repo.Restore(res, id); err != nil {
+			return fmt.Errorf("failed to restore record: %w", err)
+		}
+		result = &RestoreResult{Resource: res.name, ID: item.ID, Name: item.Name}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Purge permanently deletes a deleted record with the rows that only exist
+// as part of it. It is refused with a ConflictError while other records
+// still reference it. With a tenantID, only a record of that tenant is
+// purged.
+func (s *Service) Purge(resourceName, tenantID, id string) error {
+	res, err := findResource(resourceName)
+	if err != nil {
+		return err
+	}
+	return s.purge(res, tenantID, id)
+}
+
+// inTenant reports whether item belongs to tenantID; every item does when
+// tenantID is ""
+func inTenant(item *Item, tenantID string) bool {
+	return tenantID == "" || (item.TenantID != nil && *item.TenantID == tenantID)
+}
+
+// purge deletes one record in its own transaction, then removes its files
+func (s *Service) purge(res *resource, tenantID, id string) error {
+	var files []string
+	err := s.repo.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		item, err := repo.GetDeleted(res, id)
+		if err != nil {
+			return err
+		}
+		if !inTenant(item, tenantID) {
+			return errors.New("deleted record not found")
+		}
+		if files, err = res.purge(repo, item); err != nil {
+			return err
+		}
+		if err := repo.Purge(res, id); err != nil {
+			return fmt.Errorf("failed to purge record: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Files go only once the rows are gone, so a failed purge loses nothing
+	for _, key := range files {
+		if err := storage.GetStorage().Delete(key); err != nil {
+			log.Printf("failed to delete file %s of purged %s %s: %v", key, res.name, id, err)
+		}
+	}
+	return nil
+}
+
+// PurgeExpired permanently deletes every record deleted before cutoff that
+// nothing else references. A record is kept while others reference it;
+// when they are purged in the same run, it is retried.
+func (s *Service) PurgeExpired(cutoff time.Time) (*PurgeReport, error) {
+	report := &PurgeReport{Purged: map[string]int{}, Skipped: map[string]int{}}
+
+	for _, res := range resources {
+		for {
+			purged, skipped := 0, 0
+			for {
+				batch, err := s.repo.ListExpired(res, cutoff, skipped, purgeBatchSize)
+				if err != nil {
+					return report, fmt.Errorf("failed to list expired %s: %w", res.name, err)
+				}
+				if len(batch) == 0 {
+					break
+				}
+				for _, item := range batch {
+					err := s.purge(res, "", item.ID)
+					var conflictErr *ConflictError
+					switch {
+					case errors.As(err, &conflictErr):
+						skipped++
+					case err != nil:
+						return report, fmt.Errorf("failed to purge %s %s: %w", res.name, item.ID, err)
+					default:
+						purged++
+					}
+				}
+			}
+
+			report.Purged[res.name] += purged
+			// Purging may have freed records skipped earlier in the pass,
+			// such as a category whose last subcategory went after it
+			if purged == 0 || skipped == 0 {
+				if skipped > 0 {
+					report.Skipped[res.name] = skipped
+				}
+				break
+			}
+		}
+	}
+	return report, nil
+}
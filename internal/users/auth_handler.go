@@ -1,8 +1,10 @@
 package users
 
 import (
+	"errors"
 	"net/http"
 
+	"concierge-be/database"
 	"concierge-be/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -32,16 +34,24 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	// Check if username already exists
-	_, err := h.service.GetUserByUsername(req.Username)
-	if err == nil {
+	// Check if username already exists, deleted users included
+	exists, err := h.serviceFor(c).CheckUsernameExists(req.Username, "")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if exists {
 		utils.ErrorResponse(c, http.StatusBadRequest, "username already exists")
 		return
 	}
 
-	// Check if email already exists
-	_, err = h.service.GetUserByEmail(req.Email)
-	if err == nil {
+	// Check if email already exists, deleted users included
+	exists, err = h.serviceFor(c).CheckEmailExists(req.Email, "")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if exists {
 		utils.ErrorResponse(c, http.StatusBadRequest, "email already exists")
 		return
 	}
@@ -54,7 +64,7 @@ func (h *Handler) Register(c *gin.Context) {
 		FullName: req.FullName,
 	}
 
-	if err := h.service.CreateUser(user); err != nil {
+	if err := h.serviceFor(c).CreateUser(user); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -75,14 +85,14 @@ func (h *Handler) Login(c *gin.Context) {
 	}
 
 	// Find user
-	user, err := h.service.GetUserByUsername(req.Username)
+	user, err := h.serviceFor(c).GetUserByUsername(req.Username)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "invalid username or password")
 		return
 	}
 
 	// Verify password
-	if !h.service.VerifyPassword(user, req.Password) {
+	if !h.serviceFor(c).VerifyPassword(user, req.Password) {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "invalid username or password")
 		return
 	}
@@ -110,7 +120,7 @@ func (h *Handler) GetCurrentUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.service.GetUserByID(userID.(string))
+	user, err := h.serviceFor(c).GetUserByID(userID.(string))
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
 		return
@@ -118,10 +128,12 @@ func (h *Handler) GetCurrentUser(c *gin.Context) {
 
 	// Clear password from response
 	user.Password = ""
+	utils.SetETag(c, user.Version)
 	utils.SuccessResponse(c, user)
 }
 
 // UpdateCurrentUser updates the current authenticated user
+// Requires If-Match with the version from GET /me's ETag
 func (h *Handler) UpdateCurrentUser(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -129,20 +141,39 @@ func (h *Handler) UpdateCurrentUser(c *gin.Context) {
 		return
 	}
 
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	var req UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	user, err := h.service.GetUserByID(userID.(string))
+	user, err := h.serviceFor(c).GetUserByID(userID.(string))
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
 		return
 	}
 
+	if user.Version != version {
+		utils.ErrorResponse(c, http.StatusPreconditionFailed, "User has been modified; reload and retry")
+		return
+	}
+
 	// Update user information
-	if req.Email != "" {
+	if req.Email != "" && req.Email != user.Email {
+		exists, err := h.serviceFor(c).CheckEmailExists(req.Email, user.ID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if exists {
+			utils.ErrorResponse(c, http.StatusBadRequest, "email already exists")
+			return
+		}
 		user.Email = req.Email
 	}
 	if req.FullName != "" {
@@ -152,12 +183,17 @@ func (h *Handler) UpdateCurrentUser(c *gin.Context) {
 		user.Password = req.Password
 	}
 
-	if err := h.service.UpdateUser(user); err != nil {
+	if err := h.serviceFor(c).UpdateUser(user); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			utils.ErrorResponse(c, http.StatusPreconditionFailed, "User has been modified; reload and retry")
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	// Clear password from response
 	user.Password = ""
+	utils.SetETag(c, user.Version)
 	utils.SuccessResponse(c, user)
 }
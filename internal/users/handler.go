@@ -1,9 +1,13 @@
 package users
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"concierge-be/database"
+	"concierge-be/internal/organizations"
+	"concierge-be/internal/quotas"
 	"concierge-be/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -18,6 +22,11 @@ func NewHandler() *Handler {
 	}
 }
 
+// serviceFor returns the service bound to the request
+func (h *Handler) serviceFor(c *gin.Context) *Service {
+	return h.service.WithContext(c.Request.Context())
+}
+
 // CreateUser creates a new user
 func (h *Handler) CreateUser(c *gin.Context) {
 	var user User
@@ -26,7 +35,7 @@ func (h *Handler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.CreateUser(&user); err != nil {
+	if err := h.serviceFor(c).CreateUser(&user); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -44,7 +53,7 @@ func (h *Handler) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.service.GetUserByID(id)
+	user, err := h.serviceFor(c).GetUserByID(id)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
 		return
@@ -52,6 +61,7 @@ func (h *Handler) GetUser(c *gin.Context) {
 
 	// Clear password from response
 	user.Password = ""
+	utils.SetETag(c, user.Version)
 	utils.SuccessResponse(c, user)
 }
 
@@ -67,7 +77,7 @@ func (h *Handler) GetAllUsers(c *gin.Context) {
 		pageSize = 10
 	}
 
-	users, total, err := h.service.GetAllUsers(page, pageSize)
+	users, total, err := h.serviceFor(c).GetAllUsers(page, pageSize)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -82,6 +92,7 @@ func (h *Handler) GetAllUsers(c *gin.Context) {
 }
 
 // UpdateUser updates a user
+// Requires If-Match with the version from the user's ETag
 func (h *Handler) UpdateUser(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -89,6 +100,11 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	version, ok := utils.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	var user User
 	if err := c.ShouldBindJSON(&user); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request data")
@@ -96,13 +112,19 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 	}
 
 	user.ID = id
-	if err := h.service.UpdateUser(&user); err != nil {
+	user.Version = version
+	if err := h.serviceFor(c).UpdateUser(&user); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			utils.ErrorResponse(c, http.StatusPreconditionFailed, "User has been modified; reload and retry")
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	// Clear password from response
 	user.Password = ""
+	utils.SetETag(c, user.Version)
 	utils.SuccessResponse(c, user)
 }
 
@@ -114,7 +136,7 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeleteUser(id); err != nil {
+	if err := h.serviceFor(c).DeleteUser(id); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -130,7 +152,7 @@ func (h *Handler) CreateTenant(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.CreateTenant(&tenant); err != nil {
+	if err := h.serviceFor(c).CreateTenant(&tenant); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -146,7 +168,7 @@ func (h *Handler) GetTenant(c *gin.Context) {
 		return
 	}
 
-	tenant, err := h.service.GetTenantByID(id)
+	tenant, err := h.serviceFor(c).GetTenantByID(id)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
 		return
@@ -167,7 +189,7 @@ func (h *Handler) GetAllTenants(c *gin.Context) {
 		pageSize = 10
 	}
 
-	tenants, total, err := h.service.GetAllTenants(page, pageSize)
+	tenants, total, err := h.serviceFor(c).GetAllTenants(page, pageSize)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -191,7 +213,7 @@ func (h *Handler) UpdateTenant(c *gin.Context) {
 	}
 
 	tenant.ID = id
-	if err := h.service.UpdateTenant(&tenant); err != nil {
+	if err := h.serviceFor(c).UpdateTenant(&tenant); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -207,7 +229,7 @@ func (h *Handler) DeleteTenant(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeleteTenant(id); err != nil {
+	if err := h.serviceFor(c).DeleteTenant(id); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -229,10 +251,22 @@ func (h *Handler) AddUserToTenant(c *gin.Context) {
 	}
 
 	if req.Role == "" {
-		req.Role = "member"
-	}
-
-	if err := h.service.AddUserToTenant(req.UserID, req.TenantID, req.Role); err != nil {
+		req.Role = organizations.RoleMember
+	}
+
+	if err := h.serviceFor(c).AddUserToTenant(req.UserID, req.TenantID, req.Role); err != nil {
+		if errors.Is(err, quotas.ErrQuotaExceeded) {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+		if err.Error() == "role must be one of admin, manager, member or viewer" {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err.Error() == "tenant not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -248,7 +282,7 @@ func (h *Handler) GetUserTenants(c *gin.Context) {
 		return
 	}
 
-	userTenants, err := h.service.GetUserTenants(userID)
+	userTenants, err := h.serviceFor(c).GetUserTenants(userID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -257,6 +291,24 @@ func (h *Handler) GetUserTenants(c *gin.Context) {
 	utils.SuccessResponse(c, userTenants)
 }
 
+// GetUserTenantAccess gets the effective tenant roles for a user, including
+// roles inherited from organizations
+func (h *Handler) GetUserTenantAccess(c *gin.Context) {
+	userID := c.Param("userId")
+	if userID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	access, err := h.serviceFor(c).GetTenantAccess(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, access)
+}
+
 // GetTenantUsers gets all users for a tenant
 func (h *Handler) GetTenantUsers(c *gin.Context) {
 	tenantID := c.Param("tenantId")
@@ -265,7 +317,7 @@ func (h *Handler) GetTenantUsers(c *gin.Context) {
 		return
 	}
 
-	userTenants, err := h.service.GetTenantUsers(tenantID)
+	userTenants, err := h.serviceFor(c).GetTenantUsers(tenantID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -284,7 +336,7 @@ func (h *Handler) RemoveUserFromTenant(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.RemoveUserFromTenant(userID, tenantID); err != nil {
+	if err := h.serviceFor(c).RemoveUserFromTenant(userID, tenantID); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -13,6 +13,7 @@ type User struct {
 	Email     string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"email"`
 	Password  string    `gorm:"type:varchar(255);not null" json:"-"`
 	FullName  string    `gorm:"type:varchar(100)" json:"fullName"`
+	Version   int64     `gorm:"not null;default:1" json:"version"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -41,6 +42,14 @@ func (UserTenant) TableName() string {
 	return "user_tenants"
 }
 
+// TenantAccess describes a user's effective role in a tenant and where it comes from
+type TenantAccess struct {
+	TenantID       string  `json:"tenantId"`
+	Role           string  `json:"role"`
+	Source         string  `json:"source"` // direct or organization
+	OrganizationID *string `json:"organizationId,omitempty"`
+}
+
 // Tenant represents a tenant in the system
 type Tenant struct {
 	ID          string    `gorm:"type:varchar(36);primaryKey" json:"id"`
@@ -16,6 +16,11 @@ func NewRepository() *Repository {
 	}
 }
 
+// WithTx returns a repository bound to the given transaction
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{db: tx}
+}
+
 // User repository methods
 func (r *Repository) CreateUser(user *User) error {
 	return r.db.Create(user).Error
@@ -57,6 +62,22 @@ func (r *Repository) GetUserByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
+// CheckUsernameExists checks if a user other than excludeID holds username.
+// Deleted users count: the unique index still covers them until purged.
+func (r *Repository) CheckUsernameExists(username, excludeID string) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&User{}).Where("username = ? AND id <> ?", username, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+// CheckEmailExists checks if a user other than excludeID holds email,
+// including deleted users for the same reason as CheckUsernameExists
+func (r *Repository) CheckEmailExists(email, excludeID string) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&User{}).Where("email = ? AND id <> ?", email, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
 func (r *Repository) GetAllUsers(page, pageSize int) ([]User, int64, error) {
 	var users []User
 	var total int64
@@ -73,8 +94,10 @@ func (r *Repository) GetAllUsers(page, pageSize int) ([]User, int64, error) {
 	return users, total, err
 }
 
+// UpdateUser updates a user if it is still at user.Version,
+// returning database.ErrVersionConflict otherwise
 func (r *Repository) UpdateUser(user *User) error {
-	return r.db.Save(user).Error
+	return database.UpdateVersioned(r.db, user, &user.Version)
 }
 
 func (r *Repository) DeleteUser(id string) error {
@@ -1,21 +1,38 @@
 package users
 
 import (
+	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
+
+	"concierge-be/internal/organizations"
+	"concierge-be/internal/quotas"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type Service struct {
-	repo *Repository
+	repo    *Repository
+	orgRepo *organizations.Repository
+	quotas  *quotas.Service
 }
 
 func NewService() *Service {
 	return &Service{
-		repo: NewRepository(),
+		repo:    NewRepository(),
+		orgRepo: organizations.NewRepository(),
+		quotas:  quotas.NewService(),
 	}
 }
 
+// WithContext returns a copy of the service whose statements run with ctx
+func (s *Service) WithContext(ctx context.Context) *Service {
+	copied := *s
+	copied.repo = s.repo.WithTx(s.repo.db.WithContext(ctx))
+	return &copied
+}
+
 // generateUUID generates a new UUID
 func generateUUID() string {
 	b := make([]byte, 16)
@@ -52,6 +69,18 @@ func (s *Service) GetUserByEmail(email string) (*User, error) {
 	return s.repo.GetUserByEmail(email)
 }
 
+// CheckUsernameExists checks if username is taken by a user other than
+// excludeID, deleted ones included
+func (s *Service) CheckUsernameExists(username, excludeID string) (bool, error) {
+	return s.repo.CheckUsernameExists(username, excludeID)
+}
+
+// CheckEmailExists checks if email is taken by a user other than excludeID,
+// deleted ones included
+func (s *Service) CheckEmailExists(email, excludeID string) (bool, error) {
+	return s.repo.CheckEmailExists(email, excludeID)
+}
+
 func (s *Service) GetAllUsers(page, pageSize int) ([]User, int64, error) {
 	return s.repo.GetAllUsers(page, pageSize)
 }
@@ -79,13 +108,24 @@ func (s *Service) VerifyPassword(user *User, password string) bool {
 
 // UserTenant service methods
 func (s *Service) AddUserToTenant(userID, tenantID, role string) error {
+	if !organizations.ValidRole(role) {
+		return errors.New("role must be one of admin, manager, member or viewer")
+	}
+
 	userTenant := &UserTenant{
 		ID:       generateUUID(),
 		UserID:   userID,
 		TenantID: tenantID,
 		Role:     role,
 	}
-	return s.repo.CreateUserTenant(userTenant)
+	// The quota is checked under the tenant's row lock in the creating
+	// transaction, so concurrent adds cannot both take the last seat
+	return s.repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.quotas.WithTx(tx).CheckLimit(tenantID, quotas.ResourceMembers); err != nil {
+			return err
+		}
+		return s.repo.WithTx(tx).CreateUserTenant(userTenant)
+	})
 }
 
 func (s *Service) GetUserTenants(userID string) ([]UserTenant, error) {
@@ -101,6 +141,9 @@ func (s *Service) GetUserTenant(userID, tenantID string) (*UserTenant, error) {
 }
 
 func (s *Service) UpdateUserTenantRole(userID, tenantID, role string) error {
+	if !organizations.ValidRole(role) {
+		return errors.New("role must be one of admin, manager, member or viewer")
+	}
 	userTenant, err := s.repo.GetUserTenant(userID, tenantID)
 	if err != nil {
 		return err
@@ -144,7 +187,7 @@ func (s *Service) DeleteTenant(id string) error {
 
 // Helper methods
 func (s *Service) IsUserInTenant(userID, tenantID string) (bool, error) {
-	_, err := s.repo.GetUserTenant(userID, tenantID)
+	_, err := s.GetUserRoleInTenant(userID, tenantID)
 	if err != nil {
 		if err.Error() == "user-tenant relationship not found" {
 			return false, nil
@@ -154,10 +197,70 @@ func (s *Service) IsUserInTenant(userID, tenantID string) (bool, error) {
 	return true, nil
 }
 
+// GetUserRoleInTenant resolves a user's role in a tenant. A direct
+// user_tenants membership takes precedence over a role inherited from the
+// organization that owns the tenant.
 func (s *Service) GetUserRoleInTenant(userID, tenantID string) (string, error) {
 	userTenant, err := s.repo.GetUserTenant(userID, tenantID)
-	if err != nil {
+	if err == nil {
+		return userTenant.Role, nil
+	}
+	if err.Error() != "user-tenant relationship not found" {
 		return "", err
 	}
-	return userTenant.Role, nil
+
+	member, orgErr := s.orgRepo.GetInheritedRole(userID, tenantID)
+	if orgErr != nil {
+		if orgErr.Error() == "organization member not found" {
+			return "", err
+		}
+		return "", orgErr
+	}
+	return member.Role, nil
+}
+
+// GetTenantAccess lists every tenant a user can access, combining direct
+// memberships with roles inherited from organizations
+func (s *Service) GetTenantAccess(userID string) ([]TenantAccess, error) {
+	userTenants, err := s.repo.GetUserTenants(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	access := make([]TenantAccess, 0, len(userTenants))
+	seen := make(map[string]bool, len(userTenants))
+	for _, ut := range userTenants {
+		access = append(access, TenantAccess{
+			TenantID: ut.TenantID,
+			Role:     ut.Role,
+			Source:   "direct",
+		})
+		seen[ut.TenantID] = true
+	}
+
+	memberships, err := s.orgRepo.GetMembershipsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range memberships {
+		tenantIDs, err := s.orgRepo.GetTenantIDs(m.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		for _, tenantID := range tenantIDs {
+			if seen[tenantID] {
+				continue
+			}
+			orgID := m.OrganizationID
+			access = append(access, TenantAccess{
+				TenantID:       tenantID,
+				Role:           m.Role,
+				Source:         "organization",
+				OrganizationID: &orgID,
+			})
+			seen[tenantID] = true
+		}
+	}
+
+	return access, nil
 }
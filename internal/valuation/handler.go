@@ -0,0 +1,94 @@
+package valuation
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"concierge-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		service: NewService(),
+	}
+}
+
+// GetStockValue handles GET /api/v1/valuation/stock?tenantId=&groupBy=
+// Values the tenant's stock at weighted-average cost; groupBy is category
+// (the default), location or amenity
+func (h *Handler) GetStockValue(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+
+	report, err := h.service.GetStockValue(tenantID, c.Query("groupBy"))
+	if err != nil {
+		valuationErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, report)
+}
+
+// GetConsumptionCost handles GET /api/v1/valuation/consumption?tenantId=&groupBy=&from=&to=
+// Reports what the stock consumed, damaged or written off as expired cost
+// between from and to (RFC3339 or YYYY-MM-DD, to inclusive when a date),
+// this month by default
+func (h *Handler) GetConsumptionCost(c *gin.Context) {
+	tenantID := c.Query("tenantId")
+	if tenantID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tenantId query parameter is required")
+		return
+	}
+
+	var from, to *time.Time
+	if value := c.Query("from"); value != "" {
+		t, _, err := utils.ParseDateParam(value)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "from must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		from = &t
+	}
+	if value := c.Query("to"); value != "" {
+		t, dateOnly, err := utils.ParseDateParam(value)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "to must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = &t
+	}
+
+	report, err := h.service.GetConsumptionCost(tenantID, c.Query("groupBy"), from, to)
+	if err != nil {
+		valuationErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, report)
+}
+
+// valuationErrorResponse maps valuation errors to HTTP responses
+func valuationErrorResponse(c *gin.Context, err error) {
+	message := err.Error()
+	switch {
+	case message == "tenant not found":
+		utils.ErrorResponse(c, http.StatusNotFound, message)
+	case strings.HasPrefix(message, "groupBy"), message == "from must be before to":
+		utils.ErrorResponse(c, http.StatusBadRequest, message)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, message)
+	}
+}
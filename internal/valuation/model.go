@@ -0,0 +1,67 @@
+package valuation
+
+import "time"
+
+// Report groupings
+const (
+	GroupCategory = "category"
+	GroupLocation = "location"
+	GroupAmenity  = "amenity"
+)
+
+// ValidGroupBy reports whether groupBy is a known report grouping
+func ValidGroupBy(groupBy string) bool {
+	switch groupBy {
+	case GroupCategory, GroupLocation, GroupAmenity:
+		return true
+	}
+	return false
+}
+
+// StockValueReport is what a tenant's stock cost at weighted-average cost,
+// in total and per category, location or amenity. Amounts are in minor
+// units of Currency; group values always add up to Value.
+type StockValueReport struct {
+	TenantID string       `json:"tenantId"`
+	Currency string       `json:"currency"`
+	GroupBy  string       `json:"groupBy"`
+	Value    int64        `json:"value"`
+	Groups   []ValueGroup `json:"groups"`
+}
+
+// ValueGroup is the stock value of one category, location or amenity.
+// Quantity and AverageCost are only given per amenity, as quantities of
+// different amenities do not add up.
+type ValueGroup struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Amenities   int    `json:"amenities"`
+	Quantity    int    `json:"quantity,omitempty"`
+	AverageCost int64  `json:"averageCost,omitempty"`
+	Value       int64  `json:"value"`
+}
+
+// ConsumptionReport is what the stock a tenant used up in [From, To) cost,
+// in total and per category, location or amenity, split by why it was
+// used up. Amounts are in minor units of Currency.
+type ConsumptionReport struct {
+	TenantID string      `json:"tenantId"`
+	Currency string      `json:"currency"`
+	GroupBy  string      `json:"groupBy"`
+	From     time.Time   `json:"from"`
+	To       time.Time   `json:"to"`
+	Cost     int64       `json:"cost"`
+	Groups   []CostGroup `json:"groups"`
+}
+
+// CostGroup is the cost of the stock one category, location or amenity
+// used up. Quantity is only given per amenity.
+type CostGroup struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Quantity    int    `json:"quantity,omitempty"`
+	Consumption int64  `json:"consumption"`
+	Damage      int64  `json:"damage"`
+	Expired     int64  `json:"expired"`
+	Cost        int64  `json:"cost"`
+}
@@ -0,0 +1,114 @@
+package valuation
+
+import (
+	"errors"
+	"time"
+
+	"concierge-be/database"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/tenants"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		db: database.GetDB(),
+	}
+}
+
+// GetCurrency retrieves the currency a tenant values its stock in
+func (r *Repository) GetCurrency(tenantID string) (string, error) {
+	var tenant tenants.Tenant
+	err := r.db.Select("id", "currency").Where("id = ?", tenantID).First(&tenant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", errors.New("tenant not found")
+	}
+	return tenant.Currency, err
+}
+
+// AmenityValue is the stock and stock value of one amenity
+type AmenityValue struct {
+	ID           string
+	ItemName     string
+	CategoryID   string
+	CategoryName string
+	Stock        int
+	StockValue   int64
+}
+
+// GetAmenityValues retrieves the stock and stock value of a tenant's live
+// amenities that hold any
+func (r *Repository) GetAmenityValues(tenantID string) ([]AmenityValue, error) {
+	var values []AmenityValue
+	err := r.db.Model(&amenities.Amenity{}).
+		Select("amenities.id, amenities.item_name, amenities.category_id, amenities_categories.name AS category_name, amenities.stock, amenities.stock_value").
+		Joins("LEFT JOIN amenities_categories ON amenities_categories.id = amenities.category_id").
+		Where("amenities.tenant_id = ? AND (amenities.stock > 0 OR amenities.stock_value <> 0)", tenantID).
+		Order("amenities.id ASC").
+		Scan(&values).Error
+	return values, err
+}
+
+// LocationQuantity is the quantity of one amenity held at one location
+type LocationQuantity struct {
+	AmenityID    string
+	LocationID   string
+	LocationName string
+	Quantity     int
+}
+
+// GetLocationQuantities retrieves where a tenant's live amenities are held
+func (r *Repository) GetLocationQuantities(tenantID string) ([]LocationQuantity, error) {
+	var quantities []LocationQuantity
+	err := r.db.Model(&amenities.AmenityStock{}).
+		Select("amenity_stocks.amenity_id, amenity_stocks.location_id, locations.name AS location_name, amenity_stocks.quantity").
+		Joins("JOIN amenities ON amenities.id = amenity_stocks.amenity_id AND amenities.deleted_at IS NULL").
+		Joins("LEFT JOIN locations ON locations.id = amenity_stocks.location_id").
+		Where("amenity_stocks.tenant_id = ? AND amenity_stocks.quantity > 0", tenantID).
+		Order("amenity_stocks.amenity_id ASC, amenity_stocks.location_id ASC").
+		Scan(&quantities).Error
+	return quantities, err
+}
+
+// CostRow is the cost of what one group used up for one reason
+type CostRow struct {
+	GroupID   string
+	GroupName string
+	Reason    string
+	Quantity  int
+	Cost      int64
+}
+
+// costReasons are the movement reasons that use stock up
+var costReasons = []string{amenities.MovementConsumption, amenities.MovementDamage, amenities.MovementExpired}
+
+// groupColumns are the ID and name columns each grouping reads
+var groupColumns = map[string][2]string{
+	GroupCategory: {"amenities.category_id", "amenities_categories.name"},
+	GroupLocation: {"stock_movements.location_id", "locations.name"},
+	GroupAmenity:  {"stock_movements.amenity_id", "amenities.item_name"},
+}
+
+// GetCosts sums the value of the stock a tenant used up in [from, to) per
+// group and reason. Amenities, categories and locations deleted since are
+// still counted.
+func (r *Repository) GetCosts(tenantID, groupBy string, from, to time.Time) ([]CostRow, error) {
+	columns := groupColumns[groupBy]
+	var rows []CostRow
+	err := r.db.Model(&amenities.StockMovement{}).
+		Select("COALESCE("+columns[0]+", '') AS group_id, COALESCE("+columns[1]+", '') AS group_name, stock_movements.reason, "+
+			"-SUM(stock_movements.delta) AS quantity, -SUM(stock_movements.value) AS cost").
+		Joins("JOIN amenities ON amenities.id = stock_movements.amenity_id").
+		Joins("LEFT JOIN amenities_categories ON amenities_categories.id = amenities.category_id").
+		Joins("LEFT JOIN locations ON locations.id = stock_movements.location_id").
+		Where("stock_movements.tenant_id = ? AND stock_movements.reason IN ? AND stock_movements.created_at >= ? AND stock_movements.created_at < ?",
+			tenantID, costReasons, from, to).
+		Group(columns[0] + ", " + columns[1] + ", stock_movements.reason").
+		Scan(&rows).Error
+	return rows, err
+}
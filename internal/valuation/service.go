@@ -0,0 +1,186 @@
+package valuation
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"concierge-be/internal/amenities"
+)
+
+type Service struct {
+	repo *Repository
+	now  func() time.Time
+}
+
+func NewService() *Service {
+	return &Service{
+		repo: NewRepository(),
+		now:  time.Now,
+	}
+}
+
+// checkGroupBy fills in the default grouping and validates it
+func checkGroupBy(groupBy string) (string, error) {
+	if groupBy == "" {
+		return GroupCategory, nil
+	}
+	if !ValidGroupBy(groupBy) {
+		return "", errors.New("groupBy must be category, location or amenity")
+	}
+	return groupBy, nil
+}
+
+// GetStockValue values a tenant's stock at weighted-average cost, grouped
+// by category (the default), location or amenity. Stock at a location is
+// valued as its share of the amenity's value, with the last location
+// taking what rounding leaves so the groups add up to the total.
+func (s *Service) GetStockValue(tenantID, groupBy string) (*StockValueReport, error) {
+	groupBy, err := checkGroupBy(groupBy)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := s.repo.GetCurrency(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	values, err := s.repo.GetAmenityValues(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stock values: %w", err)
+	}
+
+	report := &StockValueReport{TenantID: tenantID, Currency: currency, GroupBy: groupBy, Groups: []ValueGroup{}}
+	groups := make(map[string]*ValueGroup)
+	add := func(id, name string, value int64) *ValueGroup {
+		group, ok := groups[id]
+		if !ok {
+			group = &ValueGroup{ID: id, Name: name}
+			groups[id] = group
+		}
+		group.Amenities++
+		group.Value += value
+		return group
+	}
+
+	byID := make(map[string]AmenityValue, len(values))
+	for _, amenity := range values {
+		report.Value += amenity.StockValue
+		byID[amenity.ID] = amenity
+		switch groupBy {
+		case GroupCategory:
+			add(amenity.CategoryID, amenity.CategoryName, amenity.StockValue)
+		case GroupAmenity:
+			group := add(amenity.ID, amenity.ItemName, amenity.StockValue)
+			group.Quantity = amenity.Stock
+			if amenity.Stock > 0 {
+				group.AverageCost = amenities.ShareOf(amenity.StockValue, 1, amenity.Stock)
+			}
+		}
+	}
+
+	if groupBy == GroupLocation {
+		quantities, err := s.repo.GetLocationQuantities(tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load location stock: %w", err)
+		}
+		// Rows come ordered by amenity, so each amenity's locations are
+		// consecutive and the last of them takes the remainder
+		allocated := int64(0)
+		for i, held := range quantities {
+			amenity := byID[held.AmenityID]
+			value := amenities.ShareOf(amenity.StockValue, held.Quantity, amenity.Stock)
+			if i == len(quantities)-1 || quantities[i+1].AmenityID != held.AmenityID {
+				value = amenity.StockValue - allocated
+				allocated = 0
+			} else {
+				allocated += value
+			}
+			add(held.LocationID, held.LocationName, value)
+		}
+	}
+
+	for _, group := range groups {
+		report.Groups = append(report.Groups, *group)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.Value != b.Value {
+			return a.Value > b.Value
+		}
+		return a.Name < b.Name
+	})
+	return report, nil
+}
+
+// GetConsumptionCost reports what the stock a tenant consumed, damaged or
+// wrote off as expired in [from, to) cost, grouped by category (the
+// default), location or amenity. A nil from is the start of the current
+// month and a nil to is now.
+func (s *Service) GetConsumptionCost(tenantID, groupBy string, from, to *time.Time) (*ConsumptionReport, error) {
+	groupBy, err := checkGroupBy(groupBy)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if from == nil {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		from = &start
+	}
+	if to == nil {
+		to = &now
+	}
+	if !from.Before(*to) {
+		return nil, errors.New("from must be before to")
+	}
+	currency, err := s.repo.GetCurrency(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.GetCosts(tenantID, groupBy, *from, *to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load consumption: %w", err)
+	}
+
+	report := &ConsumptionReport{
+		TenantID: tenantID,
+		Currency: currency,
+		GroupBy:  groupBy,
+		From:     *from,
+		To:       *to,
+		Groups:   []CostGroup{},
+	}
+	groups := make(map[string]*CostGroup)
+	for _, row := range rows {
+		group, ok := groups[row.GroupID]
+		if !ok {
+			group = &CostGroup{ID: row.GroupID, Name: row.GroupName}
+			groups[row.GroupID] = group
+		}
+		switch row.Reason {
+		case amenities.MovementConsumption:
+			group.Consumption += row.Cost
+		case amenities.MovementDamage:
+			group.Damage += row.Cost
+		case amenities.MovementExpired:
+			group.Expired += row.Cost
+		}
+		if groupBy == GroupAmenity {
+			group.Quantity += row.Quantity
+		}
+		group.Cost += row.Cost
+		report.Cost += row.Cost
+	}
+
+	for _, group := range groups {
+		report.Groups = append(report.Groups, *group)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		return a.Name < b.Name
+	})
+	return report, nil
+}
@@ -1,14 +1,30 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"time"
 
 	"concierge-be/config"
 	"concierge-be/database"
+	"concierge-be/internal/alerts"
+	"concierge-be/internal/amenities"
+	"concierge-be/internal/amenities_categories"
+	"concierge-be/internal/audit"
+	"concierge-be/internal/locations"
+	"concierge-be/internal/organizations"
+	"concierge-be/internal/purchase_orders"
+	"concierge-be/internal/quotas"
+	"concierge-be/internal/search"
+	"concierge-be/internal/stock_counts"
+	"concierge-be/internal/suppliers"
+	"concierge-be/internal/tenants"
+	"concierge-be/internal/trash"
 	"concierge-be/internal/users"
 	"concierge-be/router"
+	"concierge-be/storage"
 	"concierge-be/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -30,11 +46,95 @@ func main() {
 	// 初始化数据库
 	database.InitDB()
 
+	// 注册审计插件，记录所有模型的增删改
+	if err := database.GetDB().Use(audit.NewPlugin()); err != nil {
+		log.Fatal("Failed to register audit plugin:", err)
+	}
+
+	// 初始化文件存储
+	storage.InitStorage()
+
 	// 自动迁移数据库表
-	if err := database.GetDB().AutoMigrate(&users.User{}, &users.UserTenant{}, &users.Tenant{}); err != nil {
+	if err := database.GetDB().AutoMigrate(
+		&users.User{},
+		&users.UserTenant{},
+		&users.Tenant{},
+		&tenants.Tenant{},
+		&tenants.TenantDomain{},
+		&tenants.TenantBranding{},
+		&organizations.Organization{},
+		&organizations.OrganizationMember{},
+		&quotas.Plan{},
+		&quotas.UsageCounter{},
+		&amenities_categories.AmenityCategory{},
+		&amenities_categories.CategoryMerge{},
+		&amenities_categories.CategoryMergeItem{},
+		&amenities.Amenity{},
+		&amenities.AmenityBarcode{},
+		&amenities.AmenityUnit{},
+		&amenities.KitComponent{},
+		&amenities.StockLot{},
+		&amenities.StockMovementLot{},
+		&amenities.StockMovement{},
+		&locations.Location{},
+		&amenities.AmenityStock{},
+		&alerts.Alert{},
+		&alerts.NotificationSettings{},
+		&alerts.Notification{},
+		&suppliers.Supplier{},
+		&suppliers.SupplierItem{},
+		&purchase_orders.PurchaseOrder{},
+		&purchase_orders.PurchaseOrderLine{},
+		&stock_counts.CountSession{},
+		&stock_counts.CountLine{},
+		&stock_counts.CountEntry{},
+		&audit.AuditLog{},
+	); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// 创建搜索所需的 FULLTEXT 索引
+	if err := search.EnsureIndexes(database.GetDB()); err != nil {
+		log.Fatal("Failed to create search indexes:", err)
+	}
+
+	// 将启用库位前的库存迁入各租户的默认库位
+	if moved, err := amenities.NewService().BackfillLocationStock(); err != nil {
+		log.Fatal("Failed to backfill location stock:", err)
+	} else if moved > 0 {
+		log.Printf("Moved stock of %d amenities to default locations", moved)
+	}
+
+	// 启动低库存告警评估任务
+	if config.AppConfig.Alerts.Enabled {
+		interval := time.Duration(config.AppConfig.Alerts.Interval) * time.Second
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		alerts.NewEvaluator(interval).Start(context.Background())
+		log.Printf("Low-stock alert evaluator running every %s", interval)
+	}
+
+	// 启动回收站过期记录清理任务
+	if config.AppConfig.Trash.RetentionDays > 0 {
+		interval := time.Duration(config.AppConfig.Trash.Interval) * time.Second
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		trash.NewRetentionJob(interval).Start(context.Background())
+		log.Printf("Trash retention purging records deleted over %d days ago, every %s", config.AppConfig.Trash.RetentionDays, interval)
+	}
+
+	// 启动审计日志过期清理任务
+	if config.AppConfig.Audit.RetentionDays > 0 {
+		interval := time.Duration(config.AppConfig.Audit.Interval) * time.Second
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		audit.NewRetentionJob(interval).Start(context.Background())
+		log.Printf("Audit log retention deleting entries older than %d days, every %s", config.AppConfig.Audit.RetentionDays, interval)
+	}
+
 	// 设置路由
 	r := router.SetupRouter()
 
@@ -51,3 +51,18 @@ func JWTAuth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalJWTAuth 可选 JWT 认证：携带有效 Token 时写入用户信息，否则按匿名请求放行
+func OptionalJWTAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if claims, err := utils.ParseToken(parts[1]); err == nil {
+				c.Set("user_id", claims.UserID)
+				c.Set("username", claims.Username)
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -20,12 +20,13 @@ func Logger() gin.HandlerFunc {
 		statusCode := c.Writer.Status()
 		clientIP := c.ClientIP()
 
-		log.Printf("| %3d | %13v | %15s | %s | %s |",
+		log.Printf("| %3d | %13v | %15s | %s | %s | %s |",
 			statusCode,
 			latencyTime,
 			clientIP,
 			reqMethod,
 			reqUri,
+			c.GetString("request_id"),
 		)
 	}
 }
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationRoleResolver 解析用户在组织中的角色
+type OrganizationRoleResolver interface {
+	GetMemberRole(orgID, userID string) (string, error)
+}
+
+// OrganizationAccess 要求登录用户是 :id 所指组织的成员，指定 roles 时还需拥有其中之一。
+// 未登录返回 401，不是成员或角色不符时返回 403
+func OrganizationAccess(resolver OrganizationRoleResolver, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if userID == "" {
+			abortWithError(c, http.StatusUnauthorized, "Authentication is required to access an organization")
+			return
+		}
+
+		role, err := resolver.GetMemberRole(c.Param("id"), userID)
+		if err != nil {
+			if err.Error() == "organization member not found" {
+				abortWithError(c, http.StatusForbidden, "You do not have access to this organization")
+			} else {
+				abortWithError(c, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		if len(roles) == 0 {
+			c.Next()
+			return
+		}
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		abortWithError(c, http.StatusForbidden, "Your role in this organization does not allow this")
+	}
+}
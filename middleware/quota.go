@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"concierge-be/internal/quotas"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestQuota 按 ResolveTenant 解析出的租户统计每日请求数，超出套餐限制时返回 429
+// 无论是否登录都计数，须在 TenantAccess 之前执行；不存在的租户不计数，避免为任意租户 ID 创建计数
+func RequestQuota() gin.HandlerFunc {
+	service := quotas.NewService()
+
+	return func(c *gin.Context) {
+		tenantID := c.GetString("tenant_id")
+		if tenantID == "" {
+			c.Next()
+			return
+		}
+
+		if err := service.RecordRequest(tenantID); err != nil {
+			if errors.Is(err, quotas.ErrQuotaExceeded) {
+				c.JSON(http.StatusTooManyRequests, gin.H{
+					"code":    http.StatusTooManyRequests,
+					"message": err.Error(),
+				})
+				c.Abort()
+				return
+			}
+			// 计量失败不应影响正常请求
+			log.Printf("request quota: %v", err)
+		}
+
+		c.Next()
+	}
+}
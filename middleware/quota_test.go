@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"concierge-be/database/dbtest"
+	"concierge-be/internal/organizations"
+	"concierge-be/internal/quotas"
+	"concierge-be/internal/tenants"
+	"concierge-be/internal/users"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestQuotaCountsRequestedTenants(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, &tenants.Tenant{}, &users.User{}, &users.UserTenant{},
+		&organizations.Organization{}, &organizations.OrganizationMember{},
+		&quotas.Plan{}, &quotas.UsageCounter{})
+
+	for _, fixture := range []interface{}{
+		&tenants.Tenant{ID: "tenant-1", Name: "Harbour North"},
+		&tenants.Tenant{ID: "tenant-2", Name: "Lakeside Inn"},
+		&users.UserTenant{ID: "ut-1", UserID: "member", TenantID: "tenant-1", Role: "member"},
+	} {
+		if err := db.Create(fixture).Error; err != nil {
+			t.Fatalf("create fixture: %v", err)
+		}
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Set("user_id", userID)
+		}
+	})
+	r.Use(ResolveTenant(nil), RequestQuota(), TenantAccess(users.NewService()))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	requests := []struct {
+		user, tenant string
+		status       int
+	}{
+		{"member", "tenant-1", http.StatusOK},
+		{"member", "tenant-1", http.StatusOK},
+		{"", "tenant-1", http.StatusUnauthorized},       // anonymous: refused, but counted
+		{"", "made-up-tenant", http.StatusUnauthorized}, // unknown tenant: not counted
+		{"member", "tenant-2", http.StatusForbidden},    // no access: refused, but counted
+		{"member", "made-up-tenant", http.StatusForbidden},
+	}
+	for _, req := range requests {
+		httpReq := httptest.NewRequest(http.MethodGet, "/?tenantId="+req.tenant, nil)
+		if req.user != "" {
+			httpReq.Header.Set("X-Test-User", req.user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httpReq)
+		if w.Code != req.status {
+			t.Fatalf("%s on %s: status = %d, want %d", req.user, req.tenant, w.Code, req.status)
+		}
+	}
+
+	var counters []quotas.UsageCounter
+	if err := db.Find(&counters).Error; err != nil {
+		t.Fatalf("load counters: %v", err)
+	}
+	want := map[string]int64{"tenant-1": 3, "tenant-2": 1}
+	if len(counters) != len(want) {
+		t.Fatalf("counters = %+v, want %v", counters, want)
+	}
+	for _, counter := range counters {
+		if counter.Value != want[counter.TenantID] {
+			t.Errorf("%s counted %d requests, want %d", counter.TenantID, counter.Value, want[counter.TenantID])
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"concierge-be/internal/audit"
+	"concierge-be/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 请求 ID 的请求头与响应头
+const RequestIDHeader = "X-Request-ID"
+
+// RequestContext 为每个请求分配请求 ID（客户端传入 X-Request-ID 时沿用），写入响应头，
+// 并将请求 ID、客户端 IP 与登录用户放入请求上下文，供审计日志记录变更来源
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.New().String()
+		}
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		// 登录用户由之后的认证中间件写入，记录变更时再读取
+		ctx := audit.WithRequest(c.Request.Context(), &audit.Request{
+			ID: requestID,
+			IP: c.ClientIP(),
+			Actor: func() *string {
+				return utils.ActorID(c)
+			},
+		})
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
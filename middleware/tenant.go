@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"concierge-be/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// TenantRoleResolver 解析用户在租户中的角色：直接成员关系优先，其次为所属组织继承的角色
+type TenantRoleResolver interface {
+	GetUserRoleInTenant(userID, tenantID string) (string, error)
+}
+
+// TenantLookup 返回 :id 路径参数所指记录所属的租户，记录不存在时返回空字符串
+type TenantLookup func(id string) (string, error)
+
+// RequestTenant 返回请求指定的租户：X-Tenant-ID 请求头、tenantId 查询参数或 :tenantId 路径参数
+func RequestTenant(c *gin.Context) string {
+	if tenantID := c.GetHeader("X-Tenant-ID"); tenantID != "" {
+		return tenantID
+	}
+	if tenantID := c.Query("tenantId"); tenantID != "" {
+		return tenantID
+	}
+	return c.Param("tenantId")
+}
+
+// ResolveTenant 解析请求所属的租户写入上下文（tenant_id），不校验访问权限：
+// lookups 中路由（如 /api/v1/amenities/:id）的 :id 所指记录所属的租户优先，其次为 RequestTenant。
+// 记录不存在时不写入，由处理器返回 404
+func ResolveTenant(lookups map[string]TenantLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := ""
+		route := c.FullPath()
+		for prefix, lookup := range lookups {
+			if route == prefix || strings.HasPrefix(route, prefix+"/") {
+				owner, err := lookup(c.Param("id"))
+				if err != nil {
+					abortWithError(c, http.StatusInternalServerError, err.Error())
+					return
+				}
+				tenantID = owner
+				break
+			}
+		}
+		if tenantID == "" {
+			tenantID = RequestTenant(c)
+		}
+		if tenantID != "" {
+			c.Set("tenant_id", tenantID)
+		}
+		c.Next()
+	}
+}
+
+// TenantAccess 校验登录用户对 ResolveTenant 解析出的租户的访问权限，组织管理员通过继承角色访问组织内所有租户。
+// RequestTenant 指定了其他租户时同样需有权访问。通过校验后将角色写入上下文（tenant_role）；
+// 未登录返回 401，无权访问时返回 403。平台管理员可访问所有租户，不写入角色。未解析出租户的请求直接放行
+func TenantAccess(resolver TenantRoleResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.GetString("tenant_id")
+		if tenantID == "" {
+			c.Next()
+			return
+		}
+
+		userID := c.GetString("user_id")
+		if userID == "" {
+			abortWithError(c, http.StatusUnauthorized, "Authentication is required to access a tenant")
+			return
+		}
+		if utils.IsPlatformAdmin(c) {
+			c.Next()
+			return
+		}
+
+		role, ok := tenantRole(c, resolver, userID, tenantID)
+		if !ok {
+			return
+		}
+		if requested := RequestTenant(c); requested != "" && requested != tenantID {
+			if _, ok := tenantRole(c, resolver, userID, requested); !ok {
+				return
+			}
+		}
+
+		c.Set("tenant_role", role)
+		c.Next()
+	}
+}
+
+// tenantRole 返回用户在租户中的角色；无权访问或解析失败时终止请求并返回 false
+func tenantRole(c *gin.Context, resolver TenantRoleResolver, userID, tenantID string) (string, bool) {
+	role, err := resolver.GetUserRoleInTenant(userID, tenantID)
+	if err != nil {
+		if err.Error() == "user-tenant relationship not found" {
+			abortWithError(c, http.StatusForbidden, "You do not have access to this tenant")
+		} else {
+			abortWithError(c, http.StatusInternalServerError, err.Error())
+		}
+		return "", false
+	}
+	return role, true
+}
+
+// RequireTenantRole 要求登录用户在 TenantAccess 解析出的租户中拥有 roles 之一，否则返回 403；平台管理员直接放行
+func RequireTenantRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("user_id") == "" {
+			abortWithError(c, http.StatusUnauthorized, "Authentication is required to access a tenant")
+			return
+		}
+		if utils.IsPlatformAdmin(c) {
+			c.Next()
+			return
+		}
+		role := c.GetString("tenant_role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		abortWithError(c, http.StatusForbidden, "Your role in this tenant does not allow this")
+	}
+}
+
+// abortWithError 以统一的 JSON 格式返回错误并终止请求
+func abortWithError(c *gin.Context, status int, message string) {
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": message,
+	})
+	c.Abort()
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"concierge-be/config"
+	"concierge-be/database/dbtest"
+	"concierge-be/internal/organizations"
+	"concierge-be/internal/tenants"
+	"concierge-be/internal/users"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTenantAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, &tenants.Tenant{}, &users.User{}, &users.UserTenant{},
+		&organizations.Organization{}, &organizations.OrganizationMember{})
+
+	orgID := "org-1"
+	fixtures := []interface{}{
+		&organizations.Organization{ID: orgID, Name: "Harbour Hotels"},
+		&tenants.Tenant{ID: "tenant-in-org", Name: "Harbour North", OrganizationID: &orgID},
+		&tenants.Tenant{ID: "tenant-alone", Name: "Lakeside Inn"},
+		&organizations.OrganizationMember{ID: "m-1", OrganizationID: orgID, UserID: "org-admin", Role: "admin"},
+		&organizations.OrganizationMember{ID: "m-2", OrganizationID: orgID, UserID: "demoted", Role: "admin"},
+		&users.UserTenant{ID: "ut-1", UserID: "demoted", TenantID: "tenant-in-org", Role: "viewer"},
+	}
+	for _, fixture := range fixtures {
+		if err := db.Create(fixture).Error; err != nil {
+			t.Fatalf("create fixture: %v", err)
+		}
+	}
+
+	// Records whose tenant is resolved from the :id path parameter
+	lookups := map[string]TenantLookup{
+		"/things/:id": func(id string) (string, error) {
+			return map[string]string{"thing-in-org": "tenant-in-org", "thing-alone": "tenant-alone"}[id], nil
+		},
+	}
+
+	tests := []struct {
+		name   string
+		userID string
+		path   string
+		status int
+		role   string
+	}{
+		{"inherited from organization", "org-admin", "/?tenantId=tenant-in-org", http.StatusOK, "admin"},
+		{"direct membership overrides inherited", "demoted", "/?tenantId=tenant-in-org", http.StatusOK, "viewer"},
+		{"no membership", "org-admin", "/?tenantId=tenant-alone", http.StatusForbidden, ""},
+		{"no membership anywhere", "stranger", "/?tenantId=tenant-in-org", http.StatusForbidden, ""},
+		{"anonymous request is refused", "", "/?tenantId=tenant-in-org", http.StatusUnauthorized, ""},
+		{"no tenant requested", "", "/", http.StatusOK, ""},
+		{"record in an accessible tenant", "org-admin", "/things/thing-in-org", http.StatusOK, "admin"},
+		{"record in another tenant", "org-admin", "/things/thing-alone", http.StatusForbidden, ""},
+		{"record and requested tenant both checked", "org-admin", "/things/thing-in-org?tenantId=tenant-alone", http.StatusForbidden, ""},
+		{"anonymous request for a record", "", "/things/thing-in-org", http.StatusUnauthorized, ""},
+		{"missing record left to the handler", "stranger", "/things/missing", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tt.userID != "" {
+					c.Set("user_id", tt.userID)
+				}
+			})
+			r.Use(ResolveTenant(lookups), TenantAccess(users.NewService()))
+			var role string
+			handler := func(c *gin.Context) {
+				role = c.GetString("tenant_role")
+				c.Status(http.StatusOK)
+			}
+			r.GET("/", handler)
+			r.GET("/things/:id", handler)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if role != tt.role {
+				t.Errorf("tenant_role = %q, want %q", role, tt.role)
+			}
+		})
+	}
+
+	// Only admins of the tenant, and platform admins, get past RequireTenantRole
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{Auth: config.AuthConfig{AdminUsers: []string{"platform-admin"}}}
+	t.Cleanup(func() { config.AppConfig = previous })
+	for userID, status := range map[string]int{
+		"org-admin":      http.StatusOK,
+		"demoted":        http.StatusForbidden,
+		"platform-admin": http.StatusOK,
+	} {
+		r := gin.New()
+		r.Use(func(c *gin.Context) { c.Set("user_id", userID) })
+		r.Use(ResolveTenant(nil), TenantAccess(users.NewService()))
+		r.PUT("/", RequireTenantRole(organizations.RoleAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/?tenantId=tenant-in-org", nil))
+		if w.Code != status {
+			t.Errorf("%s: status = %d, want %d", userID, w.Code, status)
+		}
+	}
+}
@@ -1,10 +1,25 @@
 package router
 
 import (
+	"concierge-be/config"
+	"concierge-be/internal/alerts"
 	"concierge-be/internal/amenities"
 	"concierge-be/internal/amenities_categories"
+	"concierge-be/internal/audit"
+	"concierge-be/internal/catalog_io"
+	"concierge-be/internal/locations"
+	"concierge-be/internal/organizations"
+	"concierge-be/internal/purchase_orders"
+	"concierge-be/internal/quotas"
+	"concierge-be/internal/replenishment"
+	"concierge-be/internal/search"
+	"concierge-be/internal/stock_counts"
+	"concierge-be/internal/suppliers"
+	"concierge-be/internal/tenant_archive"
 	"concierge-be/internal/tenants"
+	"concierge-be/internal/trash"
 	"concierge-be/internal/users"
+	"concierge-be/internal/valuation"
 	"concierge-be/middleware"
 	"github.com/gin-gonic/gin"
 )
@@ -14,7 +29,26 @@ func SetupRouter() *gin.Engine {
 
 	// 使用中间件
 	r.Use(middleware.CORS())
+	r.Use(middleware.RequestContext())
+	r.Use(middleware.OptionalJWTAuth())
+	r.Use(middleware.ResolveTenant(map[string]middleware.TenantLookup{
+		"/api/v1/tenants/:id":              tenants.NewRepository().TenantOf,
+		"/api/v1/amenities/:id":            amenities.NewRepository().TenantOf,
+		"/api/v1/amenities-categories/:id": amenities_categories.NewRepository().TenantOf,
+		"/api/v1/locations/:id":            locations.NewRepository().TenantOf,
+		"/api/v1/suppliers/:id":            suppliers.NewRepository().TenantOf,
+		"/api/v1/stock-counts/:id":         stock_counts.NewRepository().TenantOf,
+		"/api/v1/purchase-orders/:id":      purchase_orders.NewRepository().TenantOf,
+	}))
 	r.Use(middleware.Logger())
+	r.Use(middleware.RequestQuota())
+	userService := users.NewService()
+	r.Use(middleware.TenantAccess(userService))
+
+	// 上传文件静态访问
+	if config.AppConfig.Storage.BaseURL != "" {
+		r.Static(config.AppConfig.Storage.BaseURL, config.AppConfig.Storage.Path)
+	}
 
 	// API 版本分组
 	v1 := r.Group("/api/v1")
@@ -56,13 +90,56 @@ func SetupRouter() *gin.Engine {
 
 		// Tenant routes
 		tenantHandler := tenants.NewHandler()
+		archiveHandler := tenant_archive.NewHandler()
+		quotaHandler := quotas.NewHandler()
+		alertHandler := alerts.NewHandler()
+		tenantAdmin := middleware.RequireTenantRole(organizations.RoleAdmin)
 		tenantRoutes := v1.Group("/tenants")
 		{
 			tenantRoutes.POST("", tenantHandler.CreateTenant)
 			tenantRoutes.GET("/:id", tenantHandler.GetTenant)
 			tenantRoutes.GET("", tenantHandler.GetAllTenants)
-			tenantRoutes.PUT("/:id", tenantHandler.UpdateTenant)
-			tenantRoutes.DELETE("/:id", tenantHandler.DeleteTenant)
+			tenantRoutes.PUT("/:id", tenantAdmin, tenantHandler.UpdateTenant)
+			tenantRoutes.DELETE("/:id", tenantAdmin, tenantHandler.DeleteTenant)
+			tenantRoutes.GET("/:id/export", tenantAdmin, archiveHandler.ExportTenant)
+			tenantRoutes.POST("/import", middleware.JWTAuth(), archiveHandler.ImportTenant)
+			tenantRoutes.PUT("/:id/plan", tenantAdmin, quotaHandler.AssignPlan)
+			tenantRoutes.GET("/:id/usage", quotaHandler.GetUsage)
+			tenantRoutes.GET("/:id/domains", tenantHandler.GetDomains)
+			tenantRoutes.POST("/:id/domains", tenantAdmin, tenantHandler.RequestDomain)
+			tenantRoutes.POST("/:id/domains/:domainId/verify", tenantAdmin, tenantHandler.VerifyDomain)
+			tenantRoutes.PUT("/:id/domains/:domainId/primary", tenantAdmin, tenantHandler.SetPrimaryDomain)
+			tenantRoutes.DELETE("/:id/domains/:domainId", tenantAdmin, tenantHandler.RemoveDomain)
+			tenantRoutes.GET("/:id/branding", tenantHandler.GetBranding)
+			tenantRoutes.PUT("/:id/branding", tenantAdmin, tenantHandler.UpdateBranding)
+			tenantRoutes.POST("/:id/branding/:kind", tenantAdmin, tenantHandler.UploadBrandingImage)
+			tenantRoutes.DELETE("/:id/branding/:kind", tenantAdmin, tenantHandler.DeleteBrandingImage)
+			tenantRoutes.GET("/:id/notification-settings", alertHandler.GetSettings)
+			tenantRoutes.PUT("/:id/notification-settings", tenantAdmin, alertHandler.UpdateSettings)
+		}
+
+		// Low-stock alert and in-app notification routes
+		v1.GET("/alerts", alertHandler.GetAlerts)
+		notificationRoutes := v1.Group("/notifications")
+		{
+			notificationRoutes.GET("", alertHandler.GetNotifications)
+			notificationRoutes.POST("/:id/read", alertHandler.MarkNotificationRead)
+		}
+
+		// Public guest-facing routes (no authentication required)
+		publicRoutes := v1.Group("/public")
+		{
+			publicRoutes.GET("/tenants/:domain/branding", tenantHandler.GetPublicBranding)
+		}
+
+		// Plan routes
+		planRoutes := v1.Group("/plans")
+		{
+			planRoutes.POST("", quotaHandler.CreatePlan)
+			planRoutes.GET("/:id", quotaHandler.GetPlan)
+			planRoutes.GET("", quotaHandler.GetAllPlans)
+			planRoutes.PUT("/:id", quotaHandler.UpdatePlan)
+			planRoutes.DELETE("/:id", quotaHandler.DeletePlan)
 		}
 
 		// User-Tenant relationship routes
@@ -70,16 +147,50 @@ func SetupRouter() *gin.Engine {
 		{
 			userTenantRoutes.POST("", userHandler.AddUserToTenant)
 			userTenantRoutes.GET("/users/:userId", userHandler.GetUserTenants)
+			userTenantRoutes.GET("/users/:userId/access", userHandler.GetUserTenantAccess)
 			userTenantRoutes.GET("/tenants/:tenantId", userHandler.GetTenantUsers)
 			userTenantRoutes.DELETE("/users/:userId/tenants/:tenantId", userHandler.RemoveUserFromTenant)
 		}
 
+		// Organization (hotel group) routes
+		// Members can read an organization; only its admins can change it
+		organizationHandler := organizations.NewHandler(userService)
+		organizationService := organizations.NewService()
+		orgMember := middleware.OrganizationAccess(organizationService)
+		orgAdmin := middleware.OrganizationAccess(organizationService, organizations.RoleAdmin)
+		organizationRoutes := v1.Group("/organizations")
+		organizationRoutes.Use(middleware.JWTAuth())
+		{
+			organizationRoutes.POST("", organizationHandler.CreateOrganization)
+			organizationRoutes.GET("/:id", orgMember, organizationHandler.GetOrganization)
+			organizationRoutes.GET("", organizationHandler.GetAllOrganizations)
+			organizationRoutes.PUT("/:id", orgAdmin, organizationHandler.UpdateOrganization)
+			organizationRoutes.DELETE("/:id", orgAdmin, organizationHandler.DeleteOrganization)
+			organizationRoutes.GET("/:id/tenants", orgMember, organizationHandler.GetTenants)
+			organizationRoutes.POST("/:id/tenants", orgAdmin, organizationHandler.AddTenant)
+			organizationRoutes.DELETE("/:id/tenants/:tenantId", orgAdmin, organizationHandler.RemoveTenant)
+			organizationRoutes.GET("/:id/members", orgMember, organizationHandler.GetMembers)
+			organizationRoutes.POST("/:id/members", orgAdmin, organizationHandler.AddMember)
+			organizationRoutes.DELETE("/:id/members/:userId", orgAdmin, organizationHandler.RemoveMember)
+			organizationRoutes.GET("/:id/low-stock", orgMember, organizationHandler.GetLowStock)
+			organizationRoutes.POST("/:id/catalog-copy", orgAdmin, organizationHandler.CopyCatalog)
+		}
+
 		// Amenity Categories routes
 		categoriesHandler := amenities_categories.NewHandler()
+		catalogIOHandler := catalog_io.NewHandler()
 		categoriesRoutes := v1.Group("/amenities-categories")
 		{
 			categoriesRoutes.POST("", categoriesHandler.CreateCategory)
+			categoriesRoutes.POST("/import", catalogIOHandler.ImportCategories)
+			categoriesRoutes.GET("/export", catalogIOHandler.ExportCategories)
+			categoriesRoutes.GET("/tree", categoriesHandler.GetCategoryTree)
+			categoriesRoutes.GET("/merges", categoriesHandler.GetMerges)
+			categoriesRoutes.GET("/merges/:mergeId", categoriesHandler.GetMerge)
 			categoriesRoutes.GET("/:id", categoriesHandler.GetCategory)
+			categoriesRoutes.GET("/:id/subtree", categoriesHandler.GetSubtree)
+			categoriesRoutes.POST("/:id/move", categoriesHandler.MoveCategory)
+			categoriesRoutes.POST("/:id/merge", categoriesHandler.MergeCategory)
 			categoriesRoutes.GET("", categoriesHandler.GetAllCategories)
 			categoriesRoutes.PUT("/:id", categoriesHandler.UpdateCategory)
 			categoriesRoutes.DELETE("/:id", categoriesHandler.DeleteCategory)
@@ -87,15 +198,129 @@ func SetupRouter() *gin.Engine {
 
 		// Amenities routes
 		amenitiesHandler := amenities.NewHandler()
+		replenishmentHandler := replenishment.NewHandler()
 		amenitiesRoutes := v1.Group("/amenities")
 		{
 			amenitiesRoutes.POST("", amenitiesHandler.CreateAmenity)
 			amenitiesRoutes.GET("/:id", amenitiesHandler.GetAmenity)
+			amenitiesRoutes.GET("/by-barcode/*code", amenitiesHandler.GetAmenityByCode)
+			amenitiesRoutes.POST("/scans", amenitiesHandler.ApplyScans)
+			amenitiesRoutes.POST("/labels", amenitiesHandler.PrintLabels)
+			amenitiesRoutes.GET("/:id/label", amenitiesHandler.GetLabel)
+			amenitiesRoutes.GET("/:id/convert", amenitiesHandler.ConvertQuantity)
+			amenitiesRoutes.GET("/lots/expiring", amenitiesHandler.GetExpiringLots)
+			amenitiesRoutes.POST("/lots/write-off-expired", amenitiesHandler.WriteOffExpired)
+			amenitiesRoutes.GET("/:id/lots", amenitiesHandler.GetLots)
+			amenitiesRoutes.POST("/:id/lots/:lotId/write-off", amenitiesHandler.WriteOffLot)
 			amenitiesRoutes.GET("", amenitiesHandler.GetAllAmenities)
+			amenitiesRoutes.GET("/recommendations", replenishmentHandler.GetRecommendations)
+			amenitiesRoutes.POST("/import", catalogIOHandler.ImportAmenities)
+			amenitiesRoutes.GET("/export", catalogIOHandler.ExportAmenities)
 			amenitiesRoutes.PUT("/:id", amenitiesHandler.UpdateAmenity)
 			amenitiesRoutes.PATCH("/:id/stock", amenitiesHandler.UpdateStock)
+			amenitiesRoutes.POST("/:id/stock/increment", amenitiesHandler.IncrementStock)
+			amenitiesRoutes.POST("/:id/stock/decrement", amenitiesHandler.DecrementStock)
+			amenitiesRoutes.POST("/:id/transfers", amenitiesHandler.TransferStock)
+			amenitiesRoutes.PUT("/:id/locations/:locationId/minimum", amenitiesHandler.SetLocationMinimum)
+			amenitiesRoutes.GET("/:id/movements", amenitiesHandler.GetMovements)
+			amenitiesRoutes.POST("/:id/issue", amenitiesHandler.IssueKit)
+			amenitiesRoutes.GET("/:id/availability", amenitiesHandler.GetKitAvailability)
+			amenitiesRoutes.GET("/:id/recommendation", replenishmentHandler.GetRecommendation)
+			amenitiesRoutes.POST("/:id/recommendation/accept", replenishmentHandler.AcceptRecommendation)
 			amenitiesRoutes.DELETE("/:id", amenitiesHandler.DeleteAmenity)
 		}
+
+		// Search routes
+		searchHandler := search.NewHandler()
+		v1.GET("/search", searchHandler.Search)
+
+		// Trash and audit log routes are for platform admins, and for tenant
+		// admins within their tenant
+		adminOnly := middleware.RequireTenantRole(organizations.RoleAdmin)
+
+		// Trash routes: deleted records of every resource
+		trashHandler := trash.NewHandler()
+		trashRoutes := v1.Group("/trash")
+		trashRoutes.Use(middleware.JWTAuth(), adminOnly)
+		{
+			trashRoutes.GET("/:resource", trashHandler.ListDeleted)
+			trashRoutes.POST("/:resource/:id/restore", trashHandler.Restore)
+			trashRoutes.DELETE("/:resource/:id", trashHandler.Purge)
+		}
+
+		// Audit log routes: who changed what, field by field
+		auditHandler := audit.NewHandler()
+		auditRoutes := v1.Group("/audit-logs")
+		auditRoutes.Use(middleware.JWTAuth(), adminOnly)
+		{
+			auditRoutes.GET("", auditHandler.GetAuditLogs)
+			auditRoutes.GET("/:id", auditHandler.GetAuditLog)
+		}
+
+		// Stock location (storeroom, floor, cart) routes
+		locationsHandler := locations.NewHandler()
+		locationRoutes := v1.Group("/locations")
+		{
+			locationRoutes.POST("", locationsHandler.CreateLocation)
+			locationRoutes.GET("", locationsHandler.GetLocations)
+			locationRoutes.GET("/low-stock", amenitiesHandler.GetLowStockAtLocations)
+			locationRoutes.GET("/:id", locationsHandler.GetLocation)
+			locationRoutes.PUT("/:id", locationsHandler.UpdateLocation)
+			locationRoutes.DELETE("/:id", locationsHandler.DeleteLocation)
+			locationRoutes.GET("/:id/stock", amenitiesHandler.GetLocationStock)
+		}
+
+		// Inventory valuation reports
+		valuationHandler := valuation.NewHandler()
+		valuationRoutes := v1.Group("/valuation")
+		{
+			valuationRoutes.GET("/stock", valuationHandler.GetStockValue)
+			valuationRoutes.GET("/consumption", valuationHandler.GetConsumptionCost)
+		}
+
+		// Stock count session routes
+		stockCountsHandler := stock_counts.NewHandler()
+		stockCountRoutes := v1.Group("/stock-counts")
+		{
+			stockCountRoutes.POST("", stockCountsHandler.CreateSession)
+			stockCountRoutes.GET("", stockCountsHandler.GetSessions)
+			stockCountRoutes.GET("/:id", stockCountsHandler.GetSession)
+			stockCountRoutes.POST("/:id/counts", stockCountsHandler.SubmitCounts)
+			stockCountRoutes.GET("/:id/entries", stockCountsHandler.GetEntries)
+			stockCountRoutes.GET("/:id/variance", stockCountsHandler.GetVarianceReport)
+			stockCountRoutes.POST("/:id/approve", stockCountsHandler.ApproveSession)
+			stockCountRoutes.POST("/:id/cancel", stockCountsHandler.CancelSession)
+		}
+
+		// Supplier and supplier catalogue routes
+		suppliersHandler := suppliers.NewHandler()
+		supplierRoutes := v1.Group("/suppliers")
+		{
+			supplierRoutes.POST("", suppliersHandler.CreateSupplier)
+			supplierRoutes.GET("", suppliersHandler.GetSuppliers)
+			supplierRoutes.GET("/:id", suppliersHandler.GetSupplier)
+			supplierRoutes.PUT("/:id", suppliersHandler.UpdateSupplier)
+			supplierRoutes.DELETE("/:id", suppliersHandler.DeleteSupplier)
+			supplierRoutes.GET("/:id/items", suppliersHandler.GetItems)
+			supplierRoutes.POST("/:id/items", suppliersHandler.CreateItem)
+			supplierRoutes.PUT("/:id/items/:itemId", suppliersHandler.UpdateItem)
+			supplierRoutes.DELETE("/:id/items/:itemId", suppliersHandler.DeleteItem)
+		}
+
+		// Purchase order routes
+		purchaseOrdersHandler := purchase_orders.NewHandler()
+		purchaseOrderRoutes := v1.Group("/purchase-orders")
+		{
+			purchaseOrderRoutes.POST("", purchaseOrdersHandler.CreateOrder)
+			purchaseOrderRoutes.GET("", purchaseOrdersHandler.GetOrders)
+			purchaseOrderRoutes.POST("/generate", purchaseOrdersHandler.GenerateDrafts)
+			purchaseOrderRoutes.GET("/:id", purchaseOrdersHandler.GetOrder)
+			purchaseOrderRoutes.PUT("/:id", purchaseOrdersHandler.UpdateOrder)
+			purchaseOrderRoutes.DELETE("/:id", purchaseOrdersHandler.DeleteOrder)
+			purchaseOrderRoutes.POST("/:id/send", purchaseOrdersHandler.SendOrder)
+			purchaseOrderRoutes.POST("/:id/receive", purchaseOrdersHandler.ReceiveOrder)
+			purchaseOrderRoutes.POST("/:id/close", purchaseOrdersHandler.CloseOrder)
+		}
 	}
 
 	return r
@@ -6,6 +6,7 @@ CREATE TABLE IF NOT EXISTS `amenities_categories` (
     `tenant_id` VARCHAR(36) NOT NULL COMMENT '租户ID',
     `name` VARCHAR(100) NOT NULL COMMENT '分类名称',
     `description` TEXT COMMENT '描述',
+    `version` BIGINT NOT NULL DEFAULT 1 COMMENT '版本号（乐观锁）',
     `created_at` DATETIME(3) DEFAULT NULL COMMENT '创建时间',
     `updated_at` DATETIME(3) DEFAULT NULL COMMENT '更新时间',
     `deleted_at` DATETIME(3) DEFAULT NULL COMMENT '删除时间',
@@ -25,6 +26,7 @@ CREATE TABLE IF NOT EXISTS `amenities` (
     `stock` INT NOT NULL DEFAULT 0 COMMENT '库存数量',
     `minimum_stock` INT NOT NULL DEFAULT 0 COMMENT '最小库存',
     `available` TINYINT(1) DEFAULT 1 COMMENT '是否可用',
+    `version` BIGINT NOT NULL DEFAULT 1 COMMENT '版本号（乐观锁）',
     `created_at` DATETIME(3) DEFAULT NULL COMMENT '创建时间',
     `updated_at` DATETIME(3) DEFAULT NULL COMMENT '更新时间',
     `deleted_at` DATETIME(3) DEFAULT NULL COMMENT '删除时间',
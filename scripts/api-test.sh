@@ -88,6 +88,7 @@ echo ""
 echo -e "${YELLOW}5. 更新当前用户信息...${NC}"
 response=$(curl -s -X PUT "$BASE_URL/me" \
     -H "Authorization: Bearer $TOKEN" \
+    -H 'If-Match: "1"' \
     -H "Content-Type: application/json" \
     -d '{
         "full_name": "Updated Test User",
@@ -10,6 +10,7 @@ CREATE TABLE IF NOT EXISTS `users` (
     `email` VARCHAR(100) NOT NULL COMMENT '邮箱',
     `password` VARCHAR(255) NOT NULL COMMENT '密码（加密后）',
     `full_name` VARCHAR(100) DEFAULT NULL COMMENT '全名',
+    `version` BIGINT NOT NULL DEFAULT 1 COMMENT '版本号（乐观锁）',
     `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
     `deleted_at` DATETIME DEFAULT NULL COMMENT '删除时间',
@@ -30,6 +31,7 @@ CREATE TABLE IF NOT EXISTS `tenants` (
     `description` TEXT COMMENT '描述',
     `domain` VARCHAR(100) COMMENT '域名',
     `is_active` TINYINT(1) DEFAULT 1 COMMENT '是否激活',
+    `version` BIGINT NOT NULL DEFAULT 1 COMMENT '版本号（乐观锁）',
     `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
     `deleted_at` DATETIME DEFAULT NULL COMMENT '删除时间',
@@ -59,6 +61,7 @@ CREATE TABLE IF NOT EXISTS `amenities_categories` (
     `tenant_id` VARCHAR(36) NOT NULL COMMENT '租户ID',
     `name` VARCHAR(100) NOT NULL COMMENT '分类名称',
     `description` TEXT COMMENT '描述',
+    `version` BIGINT NOT NULL DEFAULT 1 COMMENT '版本号（乐观锁）',
     `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
     `deleted_at` DATETIME DEFAULT NULL COMMENT '删除时间',
@@ -78,6 +81,7 @@ CREATE TABLE IF NOT EXISTS `amenities` (
     `stock` INT NOT NULL DEFAULT 0 COMMENT '库存数量',
     `minimum_stock` INT NOT NULL DEFAULT 0 COMMENT '最小库存',
     `available` TINYINT(1) DEFAULT 1 COMMENT '是否可用',
+    `version` BIGINT NOT NULL DEFAULT 1 COMMENT '版本号（乐观锁）',
     `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
     `deleted_at` DATETIME DEFAULT NULL COMMENT '删除时间',
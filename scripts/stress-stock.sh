@@ -0,0 +1,73 @@
+#!/bin/bash
+
+# Stress test for relative stock adjustments
+# Fires parallel decrement requests at one amenity and checks that no update
+# was lost: final stock == initial stock - successful decrements, and the
+# ledger recorded exactly one movement per success.
+#
+# Usage: ./scripts/stress-stock.sh [requests] [concurrency]
+
+BASE_URL="http://localhost:8080/api/v1"
+REQUESTS=${1:-200}
+CONCURRENCY=${2:-20}
+INITIAL_STOCK=$((REQUESTS / 2))
+GREEN='\033[0;32m'
+RED='\033[0;31m'
+BLUE='\033[0;34m'
+NC='\033[0m' # No Color
+
+echo "================================================"
+echo "🧪 Stock Concurrency Stress Test"
+echo "   $REQUESTS decrements, $CONCURRENCY in parallel, initial stock $INITIAL_STOCK"
+echo "================================================"
+echo ""
+
+echo -e "${BLUE}📋 Setting up tenant, category and amenity...${NC}"
+TENANT_ID=$(curl -s -X POST "$BASE_URL/tenants" \
+  -H "Content-Type: application/json" \
+  -d '{"name": "Stress Hotel", "description": "Stock stress test"}' | jq -r '.data.id')
+
+CATEGORY_ID=$(curl -s -X POST "$BASE_URL/amenities-categories" \
+  -H "Content-Type: application/json" \
+  -d '{"tenantId": "'$TENANT_ID'", "name": "Stress"}' | jq -r '.data.id')
+
+AMENITY_ID=$(curl -s -X POST "$BASE_URL/amenities" \
+  -H "Content-Type: application/json" \
+  -d '{
+    "tenantId": "'$TENANT_ID'",
+    "categoryId": "'$CATEGORY_ID'",
+    "itemName": "Stress Towel",
+    "stock": '$INITIAL_STOCK'
+  }' | jq -r '.data.id')
+echo -e "${GREEN}✅ Amenity $AMENITY_ID created with stock $INITIAL_STOCK${NC}"
+echo ""
+
+echo -e "${BLUE}📋 Sending decrements...${NC}"
+RESULTS=$(seq "$REQUESTS" | xargs -P "$CONCURRENCY" -I{} \
+  curl -s -o /dev/null -w "%{http_code}\n" -X POST "$BASE_URL/amenities/$AMENITY_ID/stock/decrement" \
+    -H "Content-Type: application/json" \
+    -d '{"quantity": 1, "reference": "stress-{}"}')
+
+SUCCESSES=$(echo "$RESULTS" | grep -c '^200$')
+CONFLICTS=$(echo "$RESULTS" | grep -c '^409$')
+OTHERS=$((REQUESTS - SUCCESSES - CONFLICTS))
+
+FINAL_STOCK=$(curl -s "$BASE_URL/amenities/$AMENITY_ID" | jq -r '.data.stock')
+MOVEMENTS=$(curl -s "$BASE_URL/amenities/$AMENITY_ID/movements?reason=consumption&pageSize=1" | jq -r '.pagination.total')
+EXPECTED=$((INITIAL_STOCK - SUCCESSES))
+
+echo "   200 OK:        $SUCCESSES"
+echo "   409 Conflict:  $CONFLICTS"
+echo "   Other:         $OTHERS"
+echo "   Final stock:   $FINAL_STOCK (expected $EXPECTED)"
+echo "   Movements:     $MOVEMENTS (expected $SUCCESSES)"
+echo ""
+
+if [ "$FINAL_STOCK" -eq "$EXPECTED" ] && [ "$FINAL_STOCK" -ge 0 ] && \
+   [ "$MOVEMENTS" -eq "$SUCCESSES" ] && [ "$OTHERS" -eq 0 ]; then
+  echo -e "${GREEN}✅ No lost updates and stock never went negative${NC}"
+  exit 0
+fi
+
+echo -e "${RED}❌ Stock or ledger does not match the successful requests${NC}"
+exit 1
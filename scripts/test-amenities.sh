@@ -145,6 +145,7 @@ echo ""
 echo -e "${BLUE}📋 Step 9: Updating amenity...${NC}"
 curl -s -X PUT "$BASE_URL/amenities/$AMENITY1_ID" \
   -H "Content-Type: application/json" \
+  -H 'If-Match: "1"' \
   -d '{
     "itemName": "King Size Bed Sheet - Premium",
     "description": "Premium white cotton bed sheet",
@@ -170,6 +171,7 @@ echo ""
 echo -e "${BLUE}📋 Step 12: Updating category...${NC}"
 curl -s -X PUT "$BASE_URL/amenities-categories/$CATEGORY1_ID" \
   -H "Content-Type: application/json" \
+  -H 'If-Match: "1"' \
   -d '{
     "name": "Bedding & Linen",
     "description": "All bedding and linen items for guest rooms"
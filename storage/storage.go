@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"concierge-be/config"
+)
+
+// Storage stores uploaded files and returns the URL they are served from
+type Storage interface {
+	Save(key string, r io.Reader) (string, error)
+	Delete(key string) error
+}
+
+var Files Storage
+
+// InitStorage 初始化文件存储
+func InitStorage() {
+	cfg := config.AppConfig.Storage
+
+	local, err := NewLocalStorage(cfg.Path, cfg.BaseURL)
+	if err != nil {
+		log.Fatal("Failed to initialize file storage:", err)
+	}
+	Files = local
+
+	log.Printf("File storage initialized at %s", cfg.Path)
+}
+
+func GetStorage() Storage {
+	return Files
+}
+
+// LocalStorage keeps files on the local disk under root
+type LocalStorage struct {
+	root    string
+	baseURL string
+}
+
+func NewLocalStorage(root, baseURL string) (*LocalStorage, error) {
+	if root == "" {
+		root = "./uploads"
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{root: root, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Root returns the directory files are stored in
+func (s *LocalStorage) Root() string {
+	return s.root
+}
+
+// Save writes r to key and returns its public URL
+func (s *LocalStorage) Save(key string, r io.Reader) (string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	return s.baseURL + "/" + key, nil
+}
+
+// Delete removes the file stored at key; missing files are not an error
+func (s *LocalStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a key to a file path, rejecting keys that escape the root
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.root, clean), nil
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"time"
+
+	"concierge-be/config"
+	"github.com/gin-gonic/gin"
+)
+
+// ActorID 返回当前请求的登录用户 ID，未登录时返回 nil
+func ActorID(c *gin.Context) *string {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		return nil
+	}
+	return &userID
+}
+
+// IsPlatformAdmin 判断当前请求的登录用户是否为平台管理员（配置项 auth.admin_users）
+func IsPlatformAdmin(c *gin.Context) bool {
+	userID := c.GetString("user_id")
+	if userID == "" || config.AppConfig == nil {
+		return false
+	}
+	for _, adminID := range config.AppConfig.Auth.AdminUsers {
+		if adminID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDateParam 解析 RFC3339 时间或本地时区的 YYYY-MM-DD 日期，dateOnly 表示输入为日期
+func ParseDateParam(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, value)
+	return t, false, err
+}
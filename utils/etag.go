@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetETag 将资源版本号写入 ETag 响应头
+func SetETag(c *gin.Context, version int64) {
+	c.Header("ETag", `"`+strconv.FormatInt(version, 10)+`"`)
+}
+
+// IfMatchVersion 从 If-Match 请求头解析客户端持有的资源版本号
+// 缺少请求头时返回 428，格式不正确时返回 400，此时 ok 为 false 且响应已写出
+func IfMatchVersion(c *gin.Context) (version int64, ok bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		ErrorResponse(c, http.StatusPreconditionRequired, "If-Match header is required")
+		return 0, false
+	}
+
+	// 接受 "3"、W/"3" 或 3
+	value := strings.TrimPrefix(header, "W/")
+	value = strings.Trim(value, `"`)
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version < 1 {
+		ErrorResponse(c, http.StatusBadRequest, "If-Match must contain a resource version")
+		return 0, false
+	}
+	return version, true
+}
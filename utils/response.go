@@ -18,10 +18,11 @@ type PaginationResponse struct {
 }
 
 type Pagination struct {
-	Page      int   `json:"page"`
-	PageSize  int   `json:"page_size"`
-	Total     int64 `json:"total"`
-	TotalPage int   `json:"total_page"`
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	Total      int64  `json:"total"`
+	TotalPage  int    `json:"total_page"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func SuccessResponse(c *gin.Context, data interface{}) {
@@ -53,3 +54,19 @@ func SuccessResponseWithPagination(c *gin.Context, data interface{}, page, pageS
 		},
 	})
 }
+
+// SuccessResponseWithCursor 返回游标分页结果，nextCursor 为空表示已到最后一页
+func SuccessResponseWithCursor(c *gin.Context, data interface{}, pageSize, total int, nextCursor string) {
+	totalPage := (total + pageSize - 1) / pageSize
+	c.JSON(200, PaginationResponse{
+		Code:    200,
+		Message: "Success",
+		Data:    data,
+		Pagination: Pagination{
+			PageSize:   pageSize,
+			Total:      int64(total),
+			TotalPage:  totalPage,
+			NextCursor: nextCursor,
+		},
+	})
+}